@@ -2,34 +2,74 @@ package awsconn
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/kubernetes-incubator/kube-aws/model"
 )
 
-// NewSessionFromRegion creaes an AWS session from AWS region and a debug flag
-func NewSessionFromRegion(region model.Region, debug bool) (*session.Session, error) {
+// NewSessionFromRegion creaes an AWS session from AWS region, the endpoints overriding the default ones, the credentials and a debug flag
+func NewSessionFromRegion(region model.Region, awsEndpoints model.AWSEndpoints, awsCredentials model.AWSCredentials, debug bool) (*session.Session, error) {
+	if err := awsCredentials.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid aws credentials: %v", err)
+	}
+
 	awsConfig := aws.NewConfig().
 		WithRegion(region.String()).
 		WithCredentialsChainVerboseErrors(true)
 
+	if !awsEndpoints.IsEmpty() {
+		awsConfig = awsConfig.
+			WithEndpointResolver(endpointResolver(awsEndpoints)).
+			WithS3ForcePathStyle(awsEndpoints.S3ForcePathStyle)
+	}
+
 	if debug {
 		awsConfig = awsConfig.WithLogLevel(aws.LogDebug)
 	}
 
-	session, err := newSession(awsConfig)
+	session, err := newSession(awsConfig, awsCredentials.Profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to establish aws session: %v", err)
 	}
-	return session, nil
+
+	if awsCredentials.RoleARN == "" {
+		return session, nil
+	}
+	// Validated above
+	duration, _ := awsCredentials.Duration()
+	roleCredentials := stscreds.NewCredentials(session, awsCredentials.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = fmt.Sprintf("kube-aws-%d", time.Now().Unix())
+		if awsCredentials.ExternalID != "" {
+			p.ExternalID = aws.String(awsCredentials.ExternalID)
+		}
+		if duration != 0 {
+			p.Duration = duration
+		}
+	})
+	return session.Copy(aws.NewConfig().WithCredentials(roleCredentials)), nil
+}
+
+// endpointResolver resolves the endpoints overridden, and the default endpoints for the other services
+func endpointResolver(awsEndpoints model.AWSEndpoints) endpoints.Resolver {
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if url := awsEndpoints.ForService(service); url != "" {
+			return endpoints.ResolvedEndpoint{URL: url, SigningRegion: region}, nil
+		}
+		return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+	})
 }
 
 // newSession returns an AWS session which supports source_profile and assume role with MFA
 // See #1231 for more details
-func newSession(config *aws.Config) (*session.Session, error) {
+func newSession(config *aws.Config, profile string) (*session.Session, error) {
 	return session.NewSessionWithOptions(session.Options{
 		Config: *config,
+		// AWS_PROFILE or the default profile is used when empty
+		Profile: profile,
 		// This seems to be required for AWS_SDK_LOAD_CONFIG
 		SharedConfigState: session.SharedConfigEnable,
 		// This seems to be required by MFA
@@ -0,0 +1,52 @@
+package awsconn
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// ServiceClients are the clients of the AWS APIs called while creating, updating, describing and destroying a cluster.
+// They can be replaced with in-memory fakes to test those operations without AWS
+type ServiceClients struct {
+	// Session is used for the APIs not covered by the clients, e.g. encrypting credentials with KMS
+	Session        *session.Session
+	CloudFormation cloudformationiface.CloudFormationAPI
+	CloudWatchLogs cloudwatchlogsiface.CloudWatchLogsAPI
+	EC2            ec2iface.EC2API
+	ELB            elbiface.ELBAPI
+	ELBV2          elbv2iface.ELBV2API
+	Route53        route53iface.Route53API
+	S3             s3iface.S3API
+	STS            stsiface.STSAPI
+}
+
+// NewServiceClients returns the clients of the AWS APIs sharing the session
+func NewServiceClients(session *session.Session) *ServiceClients {
+	return &ServiceClients{
+		Session:        session,
+		CloudFormation: cloudformation.New(session),
+		CloudWatchLogs: cloudwatchlogs.New(session),
+		EC2:            ec2.New(session),
+		ELB:            elb.New(session),
+		ELBV2:          elbv2.New(session),
+		Route53:        route53.New(session),
+		S3:             s3.New(session),
+		STS:            sts.New(session),
+	}
+}
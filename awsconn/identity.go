@@ -0,0 +1,31 @@
+package awsconn
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// CallerIdentity is the AWS account and the IAM user or role which kube-aws calls AWS APIs as
+type CallerIdentity struct {
+	Account string
+	ARN     string
+}
+
+func (i CallerIdentity) String() string {
+	return fmt.Sprintf("%s in the AWS account %s", i.ARN, i.Account)
+}
+
+// GetCallerIdentity returns the identity resolved from the credentials of the session the client is created with
+func GetCallerIdentity(stsSvc stsiface.STSAPI) (*CallerIdentity, error) {
+	resp, err := stsSvc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %v", err)
+	}
+	return &CallerIdentity{
+		Account: aws.StringValue(resp.Account),
+		ARN:     aws.StringValue(resp.Arn),
+	}, nil
+}
@@ -58,6 +58,7 @@ func (a assetsImpl) FindAssetByStackAndFileName(stack string, file string) (mode
 type AssetsBuilder interface {
 	Add(filename string, content string) (model.Asset, error)
 	AddUserDataPart(userdata model.UserData, part string, assetName string) error
+	AddStackTemplate(filename string, template string, extraResources map[string]interface{}) error
 	Build() Assets
 }
 
@@ -98,6 +99,30 @@ func (b *assetsBuilderImpl) AddUserDataPart(userdata model.UserData, part string
 	return nil // it is not an error if part is not found
 }
 
+// AddStackTemplate adds the stack template under the filename.
+// A template exceeding CFN_TEMPLATE_SIZE_LIMIT is split into itself and the template of a nested stack added as another asset,
+// in which the resources appended by plugins are moved along with IAM policies and security group rules
+func (b *assetsBuilderImpl) AddStackTemplate(filename string, template string, extraResources map[string]interface{}) error {
+	names := []string{}
+	for n := range extraResources {
+		names = append(names, n)
+	}
+
+	template, err := SplitTemplate(template, CFN_TEMPLATE_SIZE_LIMIT, names, func(extras string) (string, error) {
+		asset, err := b.Add(fmt.Sprintf("%s-%s", EXTRAS_TEMPLATE_ASSET_NAME, fingerprint.SHA256(extras)), extras)
+		if err != nil {
+			return "", err
+		}
+		return asset.URL()
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = b.Add(filename, template)
+	return err
+}
+
 func (b *assetsBuilderImpl) Build() Assets {
 	return assetsImpl{
 		underlying: b.assets,
@@ -0,0 +1,208 @@
+package cfnstack
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxDeleteObjects is the maximum number of keys accepted by a DeleteObjects request
+const maxDeleteObjects = 1000
+
+// fingerprintedAssetKey matches the keys of assets named with fingerprints of their contents, e.g. `.../control-plane/userdata-controller-<sha256>`.
+// Other assets like `stack.json` are overwritten on every upload and therefore never accumulate
+var fingerprintedAssetKey = regexp.MustCompile(`^(.+)-[0-9a-f]{64}$`)
+
+// AssetsGCCloudFormationService is the set of CloudFormation APIs required for finding the assets referenced by the deployed templates
+type AssetsGCCloudFormationService interface {
+	DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+	DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error)
+	GetTemplate(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error)
+}
+
+// AssetsGCS3Service is the set of S3 APIs required for listing and deleting assets
+type AssetsGCS3Service interface {
+	ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+	DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error)
+}
+
+// GarbageAssets are the assets in a bucket which are no longer referenced by the deployed stacks
+type GarbageAssets struct {
+	Bucket  string
+	Objects []*s3.Object
+}
+
+func (g *GarbageAssets) String() string {
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "KEY\tLAST MODIFIED\tSIZE\n")
+	for _, o := range g.Objects {
+		fmt.Fprintf(w, "s3://%s/%s\t%s\t%d\n", g.Bucket, aws.StringValue(o.Key), aws.TimeValue(o.LastModified).UTC().Format(time.RFC3339), aws.Int64Value(o.Size))
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// FindGarbageAssets returns the fingerprinted assets under s3URI which are referenced by none of the templates of the stack and its nested stacks.
+// The latest keep generations of each asset are kept even when unreferenced, so that the stacks can still be rolled back to them
+func FindGarbageAssets(cfSvc AssetsGCCloudFormationService, s3Svc AssetsGCS3Service, stackName string, s3URI string, keep int) (*GarbageAssets, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("the number of generations to keep must not be negative but was %d", keep)
+	}
+
+	stack, err := describeStack(cfSvc, stackName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe stack %s: %v", stackName, err)
+	}
+	if status := aws.StringValue(stack.StackStatus); ClassifyStackStatus(status) != StackConditionReady {
+		return nil, fmt.Errorf("stack %s is in %s. Garbage can only be collected once the stack is ready, as the previous assets may be needed for a rollback", stackName, status)
+	}
+
+	templates, err := deployedTemplates(cfSvc, aws.StringValue(stack.StackId))
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := S3URIFromString(s3URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse s3 uri %s: %v", s3URI, err)
+	}
+	prefix := strings.Join(uri.PathComponents(), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	objects, err := listObjects(s3Svc, uri.Bucket(), prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GarbageAssets{
+		Bucket:  uri.Bucket(),
+		Objects: unreferencedAssets(objects, templates, keep),
+	}, nil
+}
+
+// DeleteGarbageAssets deletes the garbage assets from the bucket
+func DeleteGarbageAssets(s3Svc AssetsGCS3Service, garbage *GarbageAssets) error {
+	for i := 0; i < len(garbage.Objects); i += maxDeleteObjects {
+		end := i + maxDeleteObjects
+		if end > len(garbage.Objects) {
+			end = len(garbage.Objects)
+		}
+
+		ids := []*s3.ObjectIdentifier{}
+		for _, o := range garbage.Objects[i:end] {
+			ids = append(ids, &s3.ObjectIdentifier{Key: o.Key})
+		}
+
+		out, err := s3Svc.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(garbage.Bucket),
+			Delete: &s3.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects in %s: %v", garbage.Bucket, err)
+		}
+		if len(out.Errors) > 0 {
+			msgs := []string{}
+			for _, e := range out.Errors {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", aws.StringValue(e.Key), aws.StringValue(e.Message)))
+			}
+			return fmt.Errorf("failed to delete %d object(s) in %s:\n%s", len(out.Errors), garbage.Bucket, strings.Join(msgs, "\n"))
+		}
+	}
+	return nil
+}
+
+// unreferencedAssets returns the fingerprinted objects whose keys appear in none of the templates,
+// except the latest keep objects of each asset, sorted by key
+func unreferencedAssets(objects []*s3.Object, templates []string, keep int) []*s3.Object {
+	generations := map[string][]*s3.Object{}
+	for _, o := range objects {
+		key := aws.StringValue(o.Key)
+		m := fingerprintedAssetKey.FindStringSubmatch(key)
+		if m == nil || referencedByAny(key, templates) {
+			continue
+		}
+		generations[m[1]] = append(generations[m[1]], o)
+	}
+
+	garbage := []*s3.Object{}
+	for _, gens := range generations {
+		sort.Slice(gens, func(i, j int) bool {
+			return aws.TimeValue(gens[i].LastModified).After(aws.TimeValue(gens[j].LastModified))
+		})
+		if len(gens) > keep {
+			garbage = append(garbage, gens[keep:]...)
+		}
+	}
+	sort.Slice(garbage, func(i, j int) bool {
+		return aws.StringValue(garbage[i].Key) < aws.StringValue(garbage[j].Key)
+	})
+	return garbage
+}
+
+func referencedByAny(key string, templates []string) bool {
+	for _, t := range templates {
+		if strings.Contains(t, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// deployedTemplates returns the templates of the stack and all its nested stacks
+func deployedTemplates(cfSvc AssetsGCCloudFormationService, stackID string) ([]string, error) {
+	name := nestedStackName(stackID)
+
+	out, err := cfSvc.GetTemplate(&cloudformation.GetTemplateInput{StackName: aws.String(stackID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template of %s: %v", name, err)
+	}
+	templates := []string{aws.StringValue(out.TemplateBody)}
+
+	resources, err := cfSvc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{StackName: aws.String(stackID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe resources of %s: %v", name, err)
+	}
+	for _, r := range resources.StackResources {
+		nestedID := aws.StringValue(r.PhysicalResourceId)
+		if aws.StringValue(r.ResourceType) != "AWS::CloudFormation::Stack" || nestedID == "" {
+			continue
+		}
+		nested, err := deployedTemplates(cfSvc, nestedID)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, nested...)
+	}
+	return templates, nil
+}
+
+func listObjects(s3Svc AssetsGCS3Service, bucket string, prefix string) ([]*s3.Object, error) {
+	objects := []*s3.Object{}
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		out, err := s3Svc.ListObjectsV2(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in s3://%s/%s: %v", bucket, prefix, err)
+		}
+		objects = append(objects, out.Contents...)
+		if !aws.BoolValue(out.IsTruncated) {
+			return objects, nil
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+}
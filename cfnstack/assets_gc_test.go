@@ -0,0 +1,165 @@
+package cfnstack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	gcPrefix       = "kube-aws/clusters/mycluster/exported/stacks"
+	fingerprintOld = "1111111111111111111111111111111111111111111111111111111111111111"
+	fingerprintMid = "2222222222222222222222222222222222222222222222222222222222222222"
+	fingerprintNew = "3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+type dummyAssetsGCCloudFormationService struct {
+	status    string
+	templates map[string]string
+	resources map[string][]*cloudformation.StackResource
+}
+
+func (s dummyAssetsGCCloudFormationService) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{StackId: aws.String(rootStackID), StackName: aws.String("mycluster"), StackStatus: aws.String(s.status)},
+		},
+	}, nil
+}
+
+func (s dummyAssetsGCCloudFormationService) DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	return &cloudformation.DescribeStackResourcesOutput{StackResources: s.resources[*input.StackName]}, nil
+}
+
+func (s dummyAssetsGCCloudFormationService) GetTemplate(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+	return &cloudformation.GetTemplateOutput{TemplateBody: aws.String(s.templates[*input.StackName])}, nil
+}
+
+type dummyAssetsGCS3Service struct {
+	// pages are returned in order by ListObjectsV2
+	pages   [][]*s3.Object
+	deleted []string
+}
+
+func (s *dummyAssetsGCS3Service) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	page := 0
+	if input.ContinuationToken != nil {
+		page = len(*input.ContinuationToken)
+	}
+	out := &s3.ListObjectsV2Output{}
+	for _, o := range s.pages[page] {
+		if strings.HasPrefix(*o.Key, *input.Prefix) {
+			out.Contents = append(out.Contents, o)
+		}
+	}
+	if page+1 < len(s.pages) {
+		out.IsTruncated = aws.Bool(true)
+		out.NextContinuationToken = aws.String(strings.Repeat("x", page+1))
+	}
+	return out, nil
+}
+
+func (s *dummyAssetsGCS3Service) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	for _, o := range input.Delete.Objects {
+		s.deleted = append(s.deleted, *o.Key)
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func s3Object(key string, daysAgo int) *s3.Object {
+	return &s3.Object{
+		Key:          aws.String(key),
+		LastModified: aws.Time(time.Now().Add(-time.Duration(daysAgo) * 24 * time.Hour)),
+		Size:         aws.Int64(100),
+	}
+}
+
+func TestFindAndDeleteGarbageAssets(t *testing.T) {
+	cfSvc := dummyAssetsGCCloudFormationService{
+		status: cloudformation.StackStatusUpdateComplete,
+		templates: map[string]string{
+			rootStackID:         `{"Resources":{"Controlplane":{"Properties":{"TemplateURL":"https://s3.amazonaws.com/mybucket/` + gcPrefix + `/control-plane/stack.json"}}}}`,
+			controlPlaneStackID: `{"UserData":"aws s3 cp s3://mybucket/` + gcPrefix + `/control-plane/userdata-controller-` + fingerprintMid + ` /var/run/coreos/userdata-controller"}`,
+		},
+		resources: map[string][]*cloudformation.StackResource{
+			rootStackID: {
+				{LogicalResourceId: aws.String("Controlplane"), ResourceType: aws.String("AWS::CloudFormation::Stack"), PhysicalResourceId: aws.String(controlPlaneStackID)},
+				{LogicalResourceId: aws.String("Role"), ResourceType: aws.String("AWS::IAM::Role"), PhysicalResourceId: aws.String("role")},
+			},
+		},
+	}
+	s3Svc := &dummyAssetsGCS3Service{
+		pages: [][]*s3.Object{
+			{
+				s3Object(gcPrefix+"/control-plane/stack.json", 0),
+				s3Object(gcPrefix+"/control-plane/userdata-controller-"+fingerprintOld, 3),
+				// Referenced even though it's older than the unreferenced newer one e.g. after the update has been rolled back
+				s3Object(gcPrefix+"/control-plane/userdata-controller-"+fingerprintMid, 2),
+			},
+			{
+				s3Object(gcPrefix+"/control-plane/userdata-controller-"+fingerprintNew, 1),
+				s3Object(gcPrefix+"/pool1/userdata-worker-"+fingerprintOld, 5),
+				s3Object(gcPrefix+"/pool1/userdata-worker-"+fingerprintNew, 4),
+				s3Object("other/userdata-worker-"+fingerprintOld, 5),
+			},
+		},
+	}
+
+	testCases := []struct {
+		keep     int
+		expected []string
+	}{
+		{
+			keep: 0,
+			expected: []string{
+				gcPrefix + "/control-plane/userdata-controller-" + fingerprintOld,
+				gcPrefix + "/control-plane/userdata-controller-" + fingerprintNew,
+				gcPrefix + "/pool1/userdata-worker-" + fingerprintOld,
+				gcPrefix + "/pool1/userdata-worker-" + fingerprintNew,
+			},
+		},
+		{
+			keep: 1,
+			expected: []string{
+				gcPrefix + "/control-plane/userdata-controller-" + fingerprintOld,
+				gcPrefix + "/pool1/userdata-worker-" + fingerprintOld,
+			},
+		},
+		{
+			keep:     2,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		garbage, err := FindGarbageAssets(cfSvc, s3Svc, "mycluster", "s3://mybucket/"+gcPrefix, tc.keep)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if garbage.Bucket != "mybucket" {
+			t.Errorf("unexpected bucket: %s", garbage.Bucket)
+		}
+
+		s3Svc.deleted = []string{}
+		if err := DeleteGarbageAssets(s3Svc, garbage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(s3Svc.deleted, tc.expected) {
+			t.Errorf("unexpected assets deleted when keeping %d generations:\nexpected=%v\nactual=%v", tc.keep, tc.expected, s3Svc.deleted)
+		}
+	}
+}
+
+func TestFindGarbageAssetsRequiresReadyStack(t *testing.T) {
+	cfSvc := dummyAssetsGCCloudFormationService{status: cloudformation.StackStatusUpdateRollbackInProgress}
+
+	_, err := FindGarbageAssets(cfSvc, &dummyAssetsGCS3Service{}, "mycluster", "s3://mybucket/"+gcPrefix, 1)
+	if err == nil || !strings.Contains(err.Error(), "UPDATE_ROLLBACK_IN_PROGRESS") {
+		t.Errorf("expected an error for the stack in progress but was %v", err)
+	}
+}
@@ -17,6 +17,11 @@ type UpdateService interface {
 	UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error)
 }
 
+// ValidationService is the CloudFormation API required for validating stack templates
+type ValidationService interface {
+	ValidateTemplate(input *cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error)
+}
+
 type CRUDService interface {
 	CreateStack(*cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error)
 	UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error)
@@ -25,17 +30,22 @@ type CRUDService interface {
 	EstimateTemplateCost(input *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error)
 }
 
+// S3ObjectPutterService is the set of S3 APIs required for uploading assets.
+// HeadObject is used to skip uploading assets whose contents haven't changed since the last upload
 type S3ObjectPutterService interface {
+	HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
 	PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error)
 }
 
+// StackEventErrMsgs returns messages for the events of resources which failed to be created, updated or deleted
 func StackEventErrMsgs(events []*cloudformation.StackEvent) []string {
 	var errMsgs []string
 
 	for _, event := range events {
-		if aws.StringValue(event.ResourceStatus) == cloudformation.ResourceStatusCreateFailed {
+		switch aws.StringValue(event.ResourceStatus) {
+		case cloudformation.ResourceStatusCreateFailed, cloudformation.ResourceStatusUpdateFailed, cloudformation.ResourceStatusDeleteFailed:
 			// Only show actual failures, not cancelled dependent resources.
-			if aws.StringValue(event.ResourceStatusReason) != "Resource creation cancelled" {
+			if reason := aws.StringValue(event.ResourceStatusReason); reason != "Resource creation cancelled" && reason != "Resource update cancelled" {
 				errMsgs = append(errMsgs,
 					strings.TrimSpace(
 						strings.Join([]string{
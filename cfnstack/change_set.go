@@ -0,0 +1,388 @@
+package cfnstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/naming"
+	"github.com/kubernetes-incubator/kube-aws/progress"
+)
+
+// noChangesReason is the status reason CloudFormation returns for a change set which contains no changes
+const noChangesReason = "The submitted information didn't contain changes"
+
+type ChangeSetService interface {
+	CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error)
+	DescribeChangeSet(input *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error)
+	DeleteChangeSet(input *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error)
+	ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error)
+	DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+	DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error)
+	DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error)
+}
+
+// ChangeSet is a summary of the changes CloudFormation would make to a stack
+type ChangeSet struct {
+	// Name is the name of the kube-aws sub-stack e.g. "control-plane" or a node pool name. Empty for the root stack
+	Name            string
+	StackName       string
+	StackID         string
+	ChangeSetID     string
+	Status          string
+	StatusReason    string
+	ExecutionStatus string
+	Changes         []ResourceChange
+}
+
+type ResourceChange struct {
+	Action             string
+	LogicalResourceID  string
+	PhysicalResourceID string
+	ResourceType       string
+	Replacement        string
+	Scope              []string
+}
+
+// RequiresReplacement returns true when CloudFormation may replace the resource i.e. `Replacement: True` or `Replacement: Conditional`
+func (r ResourceChange) RequiresReplacement() bool {
+	return r.Replacement == cloudformation.ReplacementTrue || r.Replacement == cloudformation.ReplacementConditional
+}
+
+// HasChanges returns true when the change set contains any change
+func (s *ChangeSet) HasChanges() bool {
+	return len(s.Changes) > 0
+}
+
+// Replacements returns the resource changes which would result in replacements
+func (s *ChangeSet) Replacements() []ResourceChange {
+	replacements := []ResourceChange{}
+	for _, c := range s.Changes {
+		if c.RequiresReplacement() {
+			replacements = append(replacements, c)
+		}
+	}
+	return replacements
+}
+
+func (s *ChangeSet) String() string {
+	buf := new(bytes.Buffer)
+
+	name := s.Name
+	if name == "" {
+		name = "root"
+	}
+	fmt.Fprintf(buf, "Stack: %s (%s)\n", s.StackName, name)
+
+	if !s.HasChanges() {
+		fmt.Fprintf(buf, "  No changes\n")
+		return buf.String()
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 1, '\t', 0)
+	for _, c := range s.Changes {
+		replacement := c.Replacement
+		if replacement == "" {
+			replacement = "N/A"
+		}
+		marker := ""
+		if c.RequiresReplacement() {
+			marker = "\t<-- REPLACEMENT"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\tReplacement: %s%s\n", c.Action, c.LogicalResourceID, c.ResourceType, replacement, marker)
+	}
+	w.Flush()
+
+	if n := len(s.Replacements()); n > 0 {
+		fmt.Fprintf(buf, "  %d resource(s) would be replaced\n", n)
+	}
+
+	return buf.String()
+}
+
+// ChangeSets is the list of change sets for the root stack and its nested stacks
+type ChangeSets []*ChangeSet
+
+func (ss ChangeSets) String() string {
+	reports := []string{}
+	for _, s := range ss {
+		reports = append(reports, s.String())
+	}
+	return strings.Join(reports, "\n")
+}
+
+func (c *Provisioner) baseCreateChangeSetInput(stackName string, changeSetName string) *cloudformation.CreateChangeSetInput {
+	input := &cloudformation.CreateChangeSetInput{
+		Capabilities:  []*string{aws.String(cloudformation.CapabilityCapabilityIam), aws.String(cloudformation.CapabilityCapabilityNamedIam)},
+		ChangeSetName: aws.String(changeSetName),
+		ChangeSetType: aws.String(cloudformation.ChangeSetTypeUpdate),
+		StackName:     aws.String(stackName),
+	}
+	if c.roleARN != "" {
+		input = input.SetRoleARN(c.roleARN)
+	}
+	return input
+}
+
+// CreateChangeSetAtURLAndWait creates a change set named `changeSetName` for updating the stack to the template at `templateURL` and waits until its changes are computed
+func (c *Provisioner) CreateChangeSetAtURLAndWait(cfSvc ChangeSetService, changeSetName string, templateURL string) (*ChangeSet, error) {
+	input := c.baseCreateChangeSetInput(c.stackName, changeSetName)
+	input.TemplateURL = aws.String(templateURL)
+	return c.createChangeSetAndWait(cfSvc, "", input)
+}
+
+// CreateNestedChangeSetsAtURLsAndWait creates change sets for the nested stacks of the stack.
+// `templateURLs` maps the names of kube-aws sub-stacks to the URLs of their new templates, and `parentTemplateBody` is the new template of the stack passing parameters to them.
+// A nested stack which doesn't exist yet is skipped because the change set for the parent stack already shows its addition.
+// The change sets created so far are returned even on error so that the caller can delete them
+func (c *Provisioner) CreateNestedChangeSetsAtURLsAndWait(cfSvc ChangeSetService, changeSetName string, templateURLs map[string]string, parentTemplateBody string) (ChangeSets, error) {
+	changeSets := ChangeSets{}
+
+	resources, err := cfSvc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{
+		StackName: aws.String(c.stackName),
+	})
+	if err != nil {
+		return changeSets, fmt.Errorf("failed to describe resources of stack %s: %v", c.stackName, err)
+	}
+
+	physicalIDs := map[string]string{}
+	for _, r := range resources.StackResources {
+		if aws.StringValue(r.ResourceType) == "AWS::CloudFormation::Stack" {
+			physicalIDs[aws.StringValue(r.LogicalResourceId)] = aws.StringValue(r.PhysicalResourceId)
+		}
+	}
+
+	parentTemplate := struct {
+		Resources map[string]struct {
+			Properties struct {
+				Parameters map[string]interface{}
+			}
+		}
+	}{}
+	if err := json.Unmarshal([]byte(parentTemplateBody), &parentTemplate); err != nil {
+		return changeSets, fmt.Errorf("failed to parse the template of stack %s: %v", c.stackName, err)
+	}
+
+	names := []string{}
+	for name := range templateURLs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		logicalID := naming.FromStackToCfnResource(name)
+		stackID, ok := physicalIDs[logicalID]
+		if !ok || stackID == "" {
+			progress.Printf("skipped creating a change set for the stack %s: it doesn't exist yet\n", name)
+			continue
+		}
+
+		params, err := renderedParameters(cfSvc, stackID, parentTemplate.Resources[logicalID].Properties.Parameters, physicalIDs)
+		if err != nil {
+			return changeSets, err
+		}
+
+		input := c.baseCreateChangeSetInput(stackID, changeSetName)
+		input.TemplateURL = aws.String(templateURLs[name])
+		input.Parameters = params
+
+		s, err := c.createChangeSetAndWait(cfSvc, name, input)
+		if err != nil {
+			return changeSets, err
+		}
+		changeSets = append(changeSets, s)
+	}
+	return changeSets, nil
+}
+
+// renderedParameters returns the values of the parameters passed from the parent stack template to the nested stack, so that changes to them show up in the change set.
+// Literal values are passed as-is, and `Fn::GetAtt` to outputs of sibling stacks are resolved from the existing stacks.
+// Any other intrinsic function can't be resolved until the parent stack is updated, so the previous value is used when the parameter already exists
+func renderedParameters(cfSvc ChangeSetService, stackID string, rendered map[string]interface{}, siblingIDs map[string]string) ([]*cloudformation.Parameter, error) {
+	previous, err := describeStack(cfSvc, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe stack %s: %v", stackID, err)
+	}
+	existing := map[string]bool{}
+	for _, p := range previous.Parameters {
+		existing[aws.StringValue(p.ParameterKey)] = true
+	}
+
+	keys := []string{}
+	for k := range rendered {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := []*cloudformation.Parameter{}
+	for _, k := range keys {
+		value, resolved, err := resolveParameterValue(cfSvc, rendered[k], siblingIDs)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resolved:
+			params = append(params, &cloudformation.Parameter{
+				ParameterKey:   aws.String(k),
+				ParameterValue: aws.String(value),
+			})
+		case existing[k]:
+			params = append(params, &cloudformation.Parameter{
+				ParameterKey:     aws.String(k),
+				UsePreviousValue: aws.Bool(true),
+			})
+		default:
+			progress.Printf("skipped passing the parameter %s to the stack %s: its value is unknown until the parent stack is updated\n", k, stackID)
+		}
+	}
+	return params, nil
+}
+
+func resolveParameterValue(cfSvc ChangeSetService, v interface{}, siblingIDs map[string]string) (string, bool, error) {
+	switch value := v.(type) {
+	case string:
+		return value, true, nil
+	case float64, bool:
+		return fmt.Sprint(value), true, nil
+	case map[string]interface{}:
+		getAtt, ok := value["Fn::GetAtt"].([]interface{})
+		if !ok || len(getAtt) != 2 {
+			return "", false, nil
+		}
+		logicalID, _ := getAtt[0].(string)
+		attr, _ := getAtt[1].(string)
+		siblingID, ok := siblingIDs[logicalID]
+		if !ok || !strings.HasPrefix(attr, "Outputs.") {
+			return "", false, nil
+		}
+		sibling, err := describeStack(cfSvc, siblingID)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to describe stack %s: %v", siblingID, err)
+		}
+		for _, o := range sibling.Outputs {
+			if aws.StringValue(o.OutputKey) == strings.TrimPrefix(attr, "Outputs.") {
+				return aws.StringValue(o.OutputValue), true, nil
+			}
+		}
+	}
+	return "", false, nil
+}
+
+func (c *Provisioner) createChangeSetAndWait(cfSvc ChangeSetService, name string, input *cloudformation.CreateChangeSetInput) (*ChangeSet, error) {
+	resp, err := cfSvc.CreateChangeSet(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create change set for stack %s: %v", aws.StringValue(input.StackName), err)
+	}
+	return waitUntilChangeSetGetsCreated(cfSvc, name, aws.StringValue(resp.Id))
+}
+
+// changeSetCreationTimeout is how long to wait for CloudFormation to compute the changes of a change set
+var changeSetCreationTimeout = 10 * time.Minute
+
+// waitUntilChangeSetGetsCreated waits until the changes of the change set are computed.
+// It gives up when the change set is still pending or in progress after changeSetCreationTimeout
+func waitUntilChangeSetGetsCreated(cfSvc ChangeSetService, name string, changeSetID string) (*ChangeSet, error) {
+	deadline := time.Now().Add(changeSetCreationTimeout)
+	for {
+		s, err := describeChangeSet(cfSvc, name, changeSetID)
+		if err != nil {
+			return nil, err
+		}
+		switch s.Status {
+		case cloudformation.ChangeSetStatusCreateComplete:
+			return s, nil
+		case cloudformation.ChangeSetStatusFailed:
+			if strings.HasPrefix(s.StatusReason, noChangesReason) {
+				return s, nil
+			}
+			return nil, fmt.Errorf("failed to create change set for stack %s: %s", s.StackName, s.StatusReason)
+		case cloudformation.ChangeSetStatusCreatePending, cloudformation.ChangeSetStatusCreateInProgress:
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("timed out after %v waiting for change set %s for stack %s to be created: the status is still %s", changeSetCreationTimeout, changeSetID, s.StackName, s.Status)
+			}
+			time.Sleep(stackPollInterval)
+			continue
+		default:
+			return nil, fmt.Errorf("unexpected change set status: %s", s.Status)
+		}
+	}
+}
+
+func describeChangeSet(cfSvc ChangeSetService, name string, changeSetID string) (*ChangeSet, error) {
+	s := &ChangeSet{Name: name, ChangeSetID: changeSetID}
+	input := &cloudformation.DescribeChangeSetInput{ChangeSetName: aws.String(changeSetID)}
+	for {
+		resp, err := cfSvc.DescribeChangeSet(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe change set %s: %v", changeSetID, err)
+		}
+		s.StackName = aws.StringValue(resp.StackName)
+		s.StackID = aws.StringValue(resp.StackId)
+		s.Status = aws.StringValue(resp.Status)
+		s.StatusReason = aws.StringValue(resp.StatusReason)
+		s.ExecutionStatus = aws.StringValue(resp.ExecutionStatus)
+		for _, c := range resp.Changes {
+			if c.ResourceChange == nil {
+				continue
+			}
+			r := c.ResourceChange
+			s.Changes = append(s.Changes, ResourceChange{
+				Action:             aws.StringValue(r.Action),
+				LogicalResourceID:  aws.StringValue(r.LogicalResourceId),
+				PhysicalResourceID: aws.StringValue(r.PhysicalResourceId),
+				ResourceType:       aws.StringValue(r.ResourceType),
+				Replacement:        aws.StringValue(r.Replacement),
+				Scope:              aws.StringValueSlice(r.Scope),
+			})
+		}
+		if resp.NextToken == nil {
+			return s, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// DescribeChangeSet returns the current state of the change set identified by `changeSetID`
+func (c *Provisioner) DescribeChangeSet(cfSvc ChangeSetService, name string, changeSetID string) (*ChangeSet, error) {
+	return describeChangeSet(cfSvc, name, changeSetID)
+}
+
+// ExecuteChangeSetAndWait executes the change set and waits until the stack gets updated.
+// It fails when the change set is no longer executable e.g. because the stack has been updated since the change set was created
+func (c *Provisioner) ExecuteChangeSetAndWait(cfSvc ChangeSetService, changeSetID string) error {
+	s, err := describeChangeSet(cfSvc, "", changeSetID)
+	if err != nil {
+		return err
+	}
+	if s.ExecutionStatus != cloudformation.ExecutionStatusAvailable {
+		return fmt.Errorf("change set %s can not be executed: execution status is %s: %s", changeSetID, s.ExecutionStatus, s.StatusReason)
+	}
+
+	if _, err := cfSvc.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
+		ChangeSetName: aws.String(changeSetID),
+	}); err != nil {
+		return fmt.Errorf("failed to execute change set %s: %v", changeSetID, err)
+	}
+
+	return waitUntilStackUpdateCompletes(cfSvc, s.StackID)
+}
+
+// DeleteChangeSets deletes all the change sets. It is used to clean up change sets created only for previewing changes
+func (c *Provisioner) DeleteChangeSets(cfSvc ChangeSetService, changeSets ChangeSets) error {
+	for _, s := range changeSets {
+		_, err := cfSvc.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+			ChangeSetName: aws.String(s.ChangeSetID),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete change set %s: %v", s.ChangeSetID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package cfnstack
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+type dummyChangeSetService struct {
+	changes         map[string][]*cloudformation.Change
+	created         []*cloudformation.CreateChangeSetInput
+	deleted         []string
+	executed        []string
+	executionStatus string
+	resources       []*cloudformation.StackResource
+	// failingStack is the stack for which CreateChangeSet fails
+	failingStack string
+	// status overrides the status of every change set when set
+	status string
+}
+
+func (s *dummyChangeSetService) CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+	if *input.StackName == s.failingStack {
+		return nil, fmt.Errorf("failed to create change set for %s", s.failingStack)
+	}
+	s.created = append(s.created, input)
+	return &cloudformation.CreateChangeSetOutput{
+		Id:      aws.String(fmt.Sprintf("%s/%s", *input.StackName, *input.ChangeSetName)),
+		StackId: input.StackName,
+	}, nil
+}
+
+func (s *dummyChangeSetService) DescribeChangeSet(input *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error) {
+	stackName := strings.Split(*input.ChangeSetName, "/")[0]
+	changes := s.changes[stackName]
+	output := &cloudformation.DescribeChangeSetOutput{
+		ChangeSetId:     input.ChangeSetName,
+		StackName:       aws.String(stackName),
+		StackId:         aws.String(stackName),
+		Status:          aws.String(cloudformation.ChangeSetStatusCreateComplete),
+		ExecutionStatus: aws.String(cloudformation.ExecutionStatusAvailable),
+		Changes:         changes,
+	}
+	if s.executionStatus != "" {
+		output.ExecutionStatus = aws.String(s.executionStatus)
+	}
+	if len(changes) == 0 {
+		output.Status = aws.String(cloudformation.ChangeSetStatusFailed)
+		output.StatusReason = aws.String(noChangesReason + ". Submit different information to create a change set.")
+	}
+	if s.status != "" {
+		output.Status = aws.String(s.status)
+	}
+	return output, nil
+}
+
+func (s *dummyChangeSetService) DeleteChangeSet(input *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error) {
+	s.deleted = append(s.deleted, *input.ChangeSetName)
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+
+func (s *dummyChangeSetService) ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
+	s.executed = append(s.executed, *input.ChangeSetName)
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+func (s *dummyChangeSetService) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{
+				StackName:   input.StackName,
+				StackStatus: aws.String(cloudformation.StackStatusUpdateComplete),
+				Parameters: []*cloudformation.Parameter{
+					{ParameterKey: aws.String("NetworkStackName"), ParameterValue: aws.String("mycluster-Network-ABC")},
+					{ParameterKey: aws.String("EtcdStackName"), ParameterValue: aws.String("mycluster-Etcd-XYZ")},
+				},
+				Outputs: []*cloudformation.Output{
+					{OutputKey: aws.String("StackName"), OutputValue: input.StackName},
+				},
+			},
+		},
+	}, nil
+}
+
+func (s *dummyChangeSetService) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	return &cloudformation.DescribeStackEventsOutput{}, nil
+}
+
+func (s *dummyChangeSetService) DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	return &cloudformation.DescribeStackResourcesOutput{StackResources: s.resources}, nil
+}
+
+func resourceChange(action, logicalID, resourceType, replacement string) *cloudformation.Change {
+	return &cloudformation.Change{
+		Type: aws.String(cloudformation.ChangeTypeResource),
+		ResourceChange: &cloudformation.ResourceChange{
+			Action:            aws.String(action),
+			LogicalResourceId: aws.String(logicalID),
+			ResourceType:      aws.String(resourceType),
+			Replacement:       aws.String(replacement),
+		},
+	}
+}
+
+func TestCreateChangeSets(t *testing.T) {
+	cfSvc := &dummyChangeSetService{
+		changes: map[string][]*cloudformation.Change{
+			"mycluster": {
+				resourceChange("Modify", "Controlplane", "AWS::CloudFormation::Stack", "False"),
+			},
+			"mycluster-Controlplane-XYZ": {
+				resourceChange("Modify", "LaunchConfigurationController", "AWS::AutoScaling::LaunchConfiguration", "True"),
+				resourceChange("Modify", "Controllers", "AWS::AutoScaling::AutoScalingGroup", "Conditional"),
+			},
+		},
+		resources: []*cloudformation.StackResource{
+			{
+				LogicalResourceId:  aws.String("Controlplane"),
+				PhysicalResourceId: aws.String("mycluster-Controlplane-XYZ"),
+				ResourceType:       aws.String("AWS::CloudFormation::Stack"),
+			},
+			{
+				LogicalResourceId:  aws.String("Etcd"),
+				PhysicalResourceId: aws.String("mycluster-Etcd-XYZ"),
+				ResourceType:       aws.String("AWS::CloudFormation::Stack"),
+			},
+			{
+				LogicalResourceId:  aws.String("Network"),
+				PhysicalResourceId: aws.String("mycluster-Network-ABC"),
+				ResourceType:       aws.String("AWS::CloudFormation::Stack"),
+			},
+		},
+	}
+
+	p := NewProvisioner("mycluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil, "arn:aws:iam::123456789012:role/cfn")
+
+	root, err := p.CreateChangeSetAtURLAndWait(cfSvc, "kube-aws-diff-1", "https://s3.amazonaws.com/mybucket/mycluster/stack.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(root.Changes) != 1 || len(root.Replacements()) != 0 {
+		t.Errorf("unexpected changes for the root stack: %+v", root.Changes)
+	}
+
+	nested, err := p.CreateNestedChangeSetsAtURLsAndWait(cfSvc, "kube-aws-diff-1", map[string]string{
+		"control-plane": "https://s3.amazonaws.com/mybucket/control-plane/stack.json",
+		"etcd":          "https://s3.amazonaws.com/mybucket/etcd/stack.json",
+		"pool1":         "https://s3.amazonaws.com/mybucket/pool1/stack.json",
+	}, testParentTemplate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nested) != 2 {
+		t.Fatalf("expected change sets for control-plane and etcd but got %d change sets: %+v", len(nested), nested)
+	}
+
+	cp := nested[0]
+	if cp.Name != "control-plane" {
+		t.Errorf("unexpected name of the first nested change set: %s", cp.Name)
+	}
+	if len(cp.Replacements()) != 2 {
+		t.Errorf("expected 2 replacements but got: %+v", cp.Replacements())
+	}
+
+	etcd := nested[1]
+	if etcd.HasChanges() {
+		t.Errorf("expected no changes for etcd but got: %+v", etcd.Changes)
+	}
+
+	for _, input := range cfSvc.created {
+		if aws.StringValue(input.RoleARN) != "arn:aws:iam::123456789012:role/cfn" {
+			t.Errorf("expected the role arn to be passed to CreateChangeSet but it wasn't: %+v", input)
+		}
+	}
+	nestedInput := cfSvc.created[1]
+	params := map[string]string{}
+	for _, p := range nestedInput.Parameters {
+		if aws.BoolValue(p.UsePreviousValue) {
+			params[*p.ParameterKey] = "(previous)"
+		} else {
+			params[*p.ParameterKey] = aws.StringValue(p.ParameterValue)
+		}
+	}
+	expectedParams := map[string]string{
+		// Resolved from the outputs of the existing sibling stacks
+		"NetworkStackName": "mycluster-Network-ABC",
+		"EtcdStackName":    "mycluster-Etcd-XYZ",
+		// Passed as rendered, so that the change shows up
+		"InstanceType": "t2.large",
+		// CloudWatchLogGroupARN is omitted as it is new and unknown until the parent stack is updated
+	}
+	if !reflect.DeepEqual(params, expectedParams) {
+		t.Errorf("unexpected parameters of the nested change set: %v", params)
+	}
+
+	report := ChangeSets(append(ChangeSets{root}, nested...)).String()
+	for _, expected := range []string{
+		"Stack: mycluster (root)",
+		"Stack: mycluster-Controlplane-XYZ (control-plane)",
+		"Replacement: True",
+		"2 resource(s) would be replaced",
+		"No changes",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected the report to contain \"%s\" but it didn't:\n%s", expected, report)
+		}
+	}
+
+	if err := p.DeleteChangeSets(cfSvc, append(ChangeSets{root}, nested...)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfSvc.deleted) != 3 {
+		t.Errorf("expected 3 change sets to be deleted but got: %v", cfSvc.deleted)
+	}
+}
+
+func TestExecuteChangeSetAndWait(t *testing.T) {
+	p := NewProvisioner("mycluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+
+	t.Run("Available", func(t *testing.T) {
+		cfSvc := &dummyChangeSetService{}
+		if err := p.ExecuteChangeSetAndWait(cfSvc, "mycluster/kube-aws-plan-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfSvc.executed) != 1 {
+			t.Errorf("expected the change set to be executed but it wasn't")
+		}
+	})
+
+	t.Run("Obsolete", func(t *testing.T) {
+		cfSvc := &dummyChangeSetService{executionStatus: cloudformation.ExecutionStatusObsolete}
+		if err := p.ExecuteChangeSetAndWait(cfSvc, "mycluster/kube-aws-plan-1"); err == nil {
+			t.Fatal("expected an error but got none")
+		}
+		if len(cfSvc.executed) != 0 {
+			t.Errorf("expected the obsolete change set not to be executed but it was")
+		}
+	})
+}
+
+const testParentTemplate = `{
+  "Resources": {
+    "Controlplane": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {
+        "Parameters": {
+          "EtcdStackName": {"Fn::GetAtt": ["Etcd", "Outputs.StackName"]},
+          "NetworkStackName": {"Fn::GetAtt": ["Network", "Outputs.StackName"]},
+          "CloudWatchLogGroupARN": {"Fn::GetAtt": ["CloudWatchLogGroup", "Arn"]},
+          "InstanceType": "t2.large"
+        }
+      }
+    },
+    "Etcd": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {
+        "Parameters": {
+          "NetworkStackName": {"Fn::GetAtt": ["Network", "Outputs.StackName"]}
+        }
+      }
+    }
+  }
+}`
+
+func TestCreateNestedChangeSetsReturnsChangeSetsCreatedBeforeFailure(t *testing.T) {
+	cfSvc := &dummyChangeSetService{
+		changes: map[string][]*cloudformation.Change{
+			"mycluster-Controlplane-XYZ": {
+				resourceChange("Modify", "Controllers", "AWS::AutoScaling::AutoScalingGroup", "False"),
+			},
+		},
+		resources: []*cloudformation.StackResource{
+			{
+				LogicalResourceId:  aws.String("Controlplane"),
+				PhysicalResourceId: aws.String("mycluster-Controlplane-XYZ"),
+				ResourceType:       aws.String("AWS::CloudFormation::Stack"),
+			},
+			{
+				LogicalResourceId:  aws.String("Etcd"),
+				PhysicalResourceId: aws.String("mycluster-Etcd-XYZ"),
+				ResourceType:       aws.String("AWS::CloudFormation::Stack"),
+			},
+		},
+		failingStack: "mycluster-Etcd-XYZ",
+	}
+
+	p := NewProvisioner("mycluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+	created, err := p.CreateNestedChangeSetsAtURLsAndWait(cfSvc, "kube-aws-diff-1", map[string]string{
+		"control-plane": "https://s3.amazonaws.com/mybucket/control-plane/stack.json",
+		"etcd":          "https://s3.amazonaws.com/mybucket/etcd/stack.json",
+	}, testParentTemplate)
+	if err == nil {
+		t.Fatal("expected an error but got none")
+	}
+	if len(created) != 1 || created[0].Name != "control-plane" {
+		t.Fatalf("expected the change set created before the failure to be returned for cleanup: %+v", created)
+	}
+
+	if err := p.DeleteChangeSets(cfSvc, created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfSvc.deleted) != 1 || cfSvc.deleted[0] != created[0].ChangeSetID {
+		t.Errorf("unexpected deleted change sets: %v", cfSvc.deleted)
+	}
+}
+
+func TestCreateChangeSetTimesOut(t *testing.T) {
+	origInterval, origTimeout := stackPollInterval, changeSetCreationTimeout
+	defer func() { stackPollInterval, changeSetCreationTimeout = origInterval, origTimeout }()
+	stackPollInterval = time.Millisecond
+	changeSetCreationTimeout = 10 * time.Millisecond
+
+	cfSvc := &dummyChangeSetService{status: cloudformation.ChangeSetStatusCreatePending}
+	p := NewProvisioner("mycluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+
+	_, err := p.CreateChangeSetAtURLAndWait(cfSvc, "kube-aws-diff", "https://s3.amazonaws.com/mybucket/mydir/stack.json")
+	if err == nil || !strings.Contains(err.Error(), "the status is still CREATE_PENDING") {
+		t.Errorf("expected the wait for the change set to time out but was %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package cfnstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type localTemplate struct {
+	Parameters map[string]interface{} `json:"Parameters"`
+	Resources  map[string]interface{} `json:"Resources"`
+}
+
+// ValidateTemplateLocally runs structural checks against a rendered stack template without calling CloudFormation.
+// It verifies that the template is valid JSON, fits within CFN_TEMPLATE_SIZE_LIMIT as AddStackTemplate splits templates into,
+// and that every `Ref` and `Fn::GetAtt` points to a parameter, a pseudo parameter or a resource defined in the template
+func ValidateTemplateLocally(body string) error {
+	if len(body) > CFN_TEMPLATE_SIZE_LIMIT {
+		return fmt.Errorf("stack template is %d bytes, which exceeds the limit of %d bytes", len(body), CFN_TEMPLATE_SIZE_LIMIT)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		return fmt.Errorf("stack template is not a valid json: %v", err)
+	}
+
+	t := localTemplate{}
+	if err := json.Unmarshal([]byte(body), &t); err != nil {
+		return fmt.Errorf("stack template is malformed: %v", err)
+	}
+	if len(t.Resources) == 0 {
+		return fmt.Errorf("stack template has no resources")
+	}
+
+	dangling := map[string]bool{}
+	walkTemplate(raw, func(fn string, arg interface{}) {
+		switch fn {
+		case "Ref":
+			name, ok := arg.(string)
+			if !ok {
+				return
+			}
+			if _, ok := t.Parameters[name]; ok {
+				return
+			}
+			if _, ok := t.Resources[name]; ok {
+				return
+			}
+			if strings.HasPrefix(name, "AWS::") {
+				return
+			}
+			dangling[fmt.Sprintf("Ref: %s", name)] = true
+		case "Fn::GetAtt":
+			var name string
+			switch a := arg.(type) {
+			case []interface{}:
+				if len(a) != 2 {
+					dangling[fmt.Sprintf("Fn::GetAtt: %v (expected [resource, attribute])", a)] = true
+					return
+				}
+				s, ok := a[0].(string)
+				if !ok {
+					return
+				}
+				name = s
+			case string:
+				name = strings.SplitN(a, ".", 2)[0]
+			default:
+				return
+			}
+			if _, ok := t.Resources[name]; !ok {
+				dangling[fmt.Sprintf("Fn::GetAtt: %s", name)] = true
+			}
+		}
+	})
+
+	if len(dangling) > 0 {
+		msgs := []string{}
+		for m := range dangling {
+			msgs = append(msgs, m)
+		}
+		sort.Strings(msgs)
+		return fmt.Errorf("stack template has dangling references:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	return nil
+}
+
+// walkTemplate calls f for every single-key object in the template, which is how intrinsic functions are represented
+func walkTemplate(node interface{}, f func(string, interface{})) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(n) == 1 {
+			for k, v := range n {
+				f(k, v)
+			}
+		}
+		for _, v := range n {
+			walkTemplate(v, f)
+		}
+	case []interface{}:
+		for _, v := range n {
+			walkTemplate(v, f)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cfnstack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplateLocally(t *testing.T) {
+	valid := `{
+  "Parameters": {"NetworkStackName": {"Type": "String"}},
+  "Resources": {
+    "SecurityGroup": {
+      "Type": "AWS::EC2::SecurityGroup",
+      "Properties": {
+        "VpcId": {"Fn::ImportValue": {"Fn::Sub": "${NetworkStackName}-VPC"}},
+        "Tags": [{"Key": "Name", "Value": {"Ref": "AWS::StackName"}}]
+      }
+    },
+    "SecurityGroupIngress": {
+      "Type": "AWS::EC2::SecurityGroupIngress",
+      "Properties": {
+        "GroupId": {"Ref": "SecurityGroup"},
+        "SourceSecurityGroupId": {"Fn::GetAtt": ["SecurityGroup", "GroupId"]},
+        "Description": {"Ref": "NetworkStackName"}
+      }
+    }
+  },
+  "Outputs": {
+    "SecurityGroup": {"Value": {"Fn::GetAtt": "SecurityGroup.GroupId"}}
+  }
+}`
+
+	if err := ValidateTemplateLocally(valid); err != nil {
+		t.Errorf("expected the template to be valid but it wasn't: %v", err)
+	}
+
+	testCases := []struct {
+		context  string
+		template string
+		expected []string
+	}{
+		{
+			context:  "InvalidJSON",
+			template: `{"Resources": {`,
+			expected: []string{"not a valid json"},
+		},
+		{
+			context:  "NoResources",
+			template: `{"Parameters": {}}`,
+			expected: []string{"no resources"},
+		},
+		{
+			context: "DanglingReferences",
+			template: `{
+  "Resources": {
+    "Role": {"Type": "AWS::IAM::Role", "Properties": {"Path": {"Ref": "MissingParam"}}},
+    "Profile": {"Type": "AWS::IAM::InstanceProfile", "Properties": {"Roles": [{"Fn::GetAtt": ["MissingRole", "Arn"]}]}}
+  },
+  "Outputs": {"Arn": {"Value": {"Fn::GetAtt": "MissingProfile.Arn"}}}
+}`,
+			expected: []string{"Ref: MissingParam", "Fn::GetAtt: MissingRole", "Fn::GetAtt: MissingProfile"},
+		},
+		{
+			context:  "TooLarge",
+			template: `{"Resources": {"Topic": {"Type": "AWS::SNS::Topic", "Properties": {"DisplayName": "` + strings.Repeat("a", CFN_TEMPLATE_SIZE_LIMIT) + `"}}}}`,
+			expected: []string{"exceeds the limit"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.context, func(t *testing.T) {
+			err := ValidateTemplateLocally(tc.template)
+			if err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			for _, e := range tc.expected {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("expected the error to contain \"%s\" but it didn't: %v", e, err)
+				}
+			}
+		})
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/kubernetes-incubator/kube-aws/fingerprint"
 	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 	"strings"
 	"time"
 )
@@ -20,6 +22,7 @@ type Provisioner struct {
 	s3URI           string
 	roleARN         string
 	region          model.Region
+	s3Assets        model.S3Assets
 }
 
 func NewProvisioner(name string, stackTags map[string]string, s3URI string, region model.Region, stackPolicyBody string, session *session.Session, options ...string) *Provisioner {
@@ -40,25 +43,85 @@ func NewProvisioner(name string, stackTags map[string]string, s3URI string, regi
 	return p
 }
 
+// WithS3Assets sets the encryption, the ACL and the tags applied to the uploaded assets
+func (c *Provisioner) WithS3Assets(s3Assets model.S3Assets) *Provisioner {
+	c.s3Assets = s3Assets
+	return c
+}
+
+// assetFingerprintMetadataKey is the key of the S3 object metadata containing the fingerprint of an uploaded asset
+const assetFingerprintMetadataKey = "kube-aws-fingerprint"
+
 func (c *Provisioner) uploadAsset(s3Svc S3ObjectPutterService, asset model.Asset) error {
 	bucket := asset.Bucket
 	key := asset.Key
 	content := asset.Content
 	contentLength := int64(len(content))
 	body := strings.NewReader(content)
+	fp := c.assetFingerprint(content)
 
-	_, err := s3Svc.PutObject(&s3.PutObjectInput{
+	if uploadedAssetFingerprint(s3Svc, bucket, key) == fp {
+		return nil
+	}
+
+	input := &s3.PutObjectInput{
 		Bucket:        aws.String(bucket),
 		Key:           aws.String(key),
 		Body:          body,
 		ContentLength: aws.Int64(contentLength),
 		ContentType:   aws.String("application/json"),
-	})
+		Metadata:      map[string]*string{assetFingerprintMetadataKey: aws.String(fp)},
+	}
+	if c.s3Assets.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(c.s3Assets.ServerSideEncryption)
+	}
+	if c.s3Assets.KMSKeyARN != "" {
+		input.SSEKMSKeyId = aws.String(c.s3Assets.KMSKeyARN)
+	}
+	if c.s3Assets.ACL != "" {
+		input.ACL = aws.String(c.s3Assets.ACL)
+	}
+	if tagging := c.s3Assets.Tagging(); tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+
+	_, err := s3Svc.PutObject(input)
 
 	return err
 }
 
+// assetFingerprint returns the fingerprint of the asset content along with the S3 settings applied on upload,
+// so that the asset is uploaded again when the settings change even though the content doesn't
+func (c *Provisioner) assetFingerprint(content string) string {
+	settings := []string{c.s3Assets.ServerSideEncryption, c.s3Assets.KMSKeyARN, c.s3Assets.ACL, c.s3Assets.Tagging()}
+	if strings.Join(settings, "") == "" {
+		return fingerprint.SHA256(content)
+	}
+	return fingerprint.SHA256(strings.Join(append([]string{content}, settings...), "\n"))
+}
+
+// uploadedAssetFingerprint returns the fingerprint of the asset previously uploaded to the bucket and the key.
+// It returns an empty string when the asset is missing, was uploaded by an older kube-aws without the fingerprint, or can't be checked e.g. due to missing permissions,
+// so that the asset is uploaded anyway
+func uploadedAssetFingerprint(s3Svc S3ObjectPutterService, bucket string, key string) string {
+	out, err := s3Svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return ""
+	}
+	// Metadata keys are returned in the canonical form of HTTP headers e.g. `Kube-Aws-Fingerprint`
+	for k, v := range out.Metadata {
+		if strings.EqualFold(k, assetFingerprintMetadataKey) {
+			return aws.StringValue(v)
+		}
+	}
+	return ""
+}
+
 func (c *Provisioner) UploadAssets(s3Svc S3ObjectPutterService, assets Assets) error {
+	s3Svc = NewRetryingS3ObjectPutterService(s3Svc, DefaultRetryPolicy)
 	for _, a := range assets.AsMap() {
 		err := c.uploadAsset(s3Svc, a)
 		if err != nil {
@@ -69,6 +132,7 @@ func (c *Provisioner) UploadAssets(s3Svc S3ObjectPutterService, assets Assets) e
 }
 
 func (c *Provisioner) EstimateTemplateCost(cfSvc CRUDService, body string, parameters []*cloudformation.Parameter) (*cloudformation.EstimateTemplateCostOutput, error) {
+	cfSvc = NewRetryingCRUDService(cfSvc, DefaultRetryPolicy)
 
 	input := cloudformation.EstimateTemplateCostInput{
 		TemplateBody: &body,
@@ -79,6 +143,7 @@ func (c *Provisioner) EstimateTemplateCost(cfSvc CRUDService, body string, param
 }
 
 func (c *Provisioner) CreateStackAtURLAndWait(cfSvc CRUDService, templateURL string) error {
+	cfSvc = NewRetryingCRUDService(cfSvc, DefaultRetryPolicy)
 	resp, err := c.createStackFromTemplateURL(cfSvc, templateURL)
 	if err != nil {
 		return err
@@ -87,46 +152,19 @@ func (c *Provisioner) CreateStackAtURLAndWait(cfSvc CRUDService, templateURL str
 }
 
 func (c *Provisioner) waitUntilStackGetsCreated(cfSvc CRUDService, resp *cloudformation.CreateStackOutput) error {
-	req := cloudformation.DescribeStacksInput{
-		StackName: resp.StackId,
+	stack, err := waitUntilStackSettles(cfSvc, aws.StringValue(resp.StackId))
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(stack.StackStatus) == cloudformation.StackStatusCreateComplete {
+		return nil
 	}
 
-	for {
-		resp, err := cfSvc.DescribeStacks(&req)
-		if err != nil {
-			return err
-		}
-		if len(resp.Stacks) == 0 {
-			return fmt.Errorf("stack not found")
-		}
-		statusString := aws.StringValue(resp.Stacks[0].StackStatus)
-		switch statusString {
-		case cloudformation.ResourceStatusCreateComplete:
-			return nil
-		case cloudformation.ResourceStatusCreateFailed:
-			errMsg := fmt.Sprintf(
-				"Stack creation failed: %s : %s",
-				statusString,
-				aws.StringValue(resp.Stacks[0].StackStatusReason),
-			)
-			errMsg = errMsg + "\n\nPrinting the most recent failed stack events:\n"
-
-			stackEventsOutput, err := cfSvc.DescribeStackEvents(
-				&cloudformation.DescribeStackEventsInput{
-					StackName: resp.Stacks[0].StackName,
-				})
-			if err != nil {
-				return err
-			}
-			errMsg = errMsg + strings.Join(StackEventErrMsgs(stackEventsOutput.StackEvents), "\n")
-			return errors.New(errMsg)
-		case cloudformation.ResourceStatusCreateInProgress:
-			time.Sleep(3 * time.Second)
-			continue
-		default:
-			return fmt.Errorf("unexpected stack status: %s", statusString)
-		}
+	state, err := stackStateOf(cfSvc, stack)
+	if err != nil {
+		return err
 	}
+	return fmt.Errorf("Stack creation failed: %s", state)
 }
 
 func (c *Provisioner) baseCreateStackInput() *cloudformation.CreateStackInput {
@@ -176,6 +214,7 @@ func (c *Provisioner) updateStackWithTemplateURL(cfSvc UpdateService, templateUR
 }
 
 func (c *Provisioner) UpdateStackAtURLAndWait(cfSvc CRUDService, templateURL string) (string, error) {
+	cfSvc = NewRetryingCRUDService(cfSvc, DefaultRetryPolicy)
 	updateOutput, err := c.updateStackWithTemplateURL(cfSvc, templateURL)
 	if err != nil {
 		return "", fmt.Errorf("error updating cloudformation stack: %v", err)
@@ -184,34 +223,35 @@ func (c *Provisioner) UpdateStackAtURLAndWait(cfSvc CRUDService, templateURL str
 }
 
 func (c *Provisioner) waitUntilStackGetsUpdated(cfSvc CRUDService, updateOutput *cloudformation.UpdateStackOutput) (string, error) {
-	req := cloudformation.DescribeStacksInput{
-		StackName: updateOutput.StackId,
+	if err := waitUntilStackUpdateCompletes(cfSvc, aws.StringValue(updateOutput.StackId)); err != nil {
+		return "", err
 	}
-	for {
-		resp, err := cfSvc.DescribeStacks(&req)
-		if err != nil {
-			return "", err
-		}
-		if len(resp.Stacks) == 0 {
-			return "", fmt.Errorf("stack not found")
-		}
-		statusString := aws.StringValue(resp.Stacks[0].StackStatus)
-		switch statusString {
-		case cloudformation.ResourceStatusUpdateComplete:
-			return updateOutput.String(), nil
-		case cloudformation.ResourceStatusUpdateFailed, cloudformation.StackStatusUpdateRollbackComplete, cloudformation.StackStatusUpdateRollbackFailed:
-			errMsg := fmt.Sprintf("Stack status: %s : %s", statusString, aws.StringValue(resp.Stacks[0].StackStatusReason))
-			return "", errors.New(errMsg)
-		case cloudformation.ResourceStatusUpdateInProgress, cloudformation.StackStatusUpdateCompleteCleanupInProgress:
-			time.Sleep(3 * time.Second)
-			continue
-		default:
-			return "", fmt.Errorf("unexpected stack status: %s", statusString)
-		}
+	return updateOutput.String(), nil
+}
+
+type stackDescriber interface {
+	DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+}
+
+// waitUntilStackUpdateCompletes waits until the update and the rollback on failure completes.
+// On failure, the returned error describes the first failure in the stack or its nested stacks and how to recover
+func waitUntilStackUpdateCompletes(cfSvc stackStateDescriber, stackID string) error {
+	stack, err := waitUntilStackSettles(cfSvc, stackID)
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(stack.StackStatus) == cloudformation.StackStatusUpdateComplete {
+		return nil
+	}
+
+	state, err := stackStateOf(cfSvc, stack)
+	if err != nil {
+		return err
 	}
+	return errors.New(state.String())
 }
 
-func (c *Provisioner) ValidateStackAtURL(templateURL string) (string, error) {
+func (c *Provisioner) ValidateStackAtURL(cfSvc ValidationService, templateURL string) (string, error) {
 	if templateURL == "" {
 		return "", errors.New("[bug] ValidateStackAtURL: templateURL must not be nil")
 	}
@@ -220,7 +260,6 @@ func (c *Provisioner) ValidateStackAtURL(templateURL string) (string, error) {
 		TemplateURL: aws.String(templateURL),
 	}
 
-	cfSvc := cloudformation.New(c.session)
 	validationReport, err := cfSvc.ValidateTemplate(&validateInput)
 	if err != nil {
 		return "", fmt.Errorf("invalid cloudformation stack template %s: %v", templateURL, err)
@@ -229,33 +268,42 @@ func (c *Provisioner) ValidateStackAtURL(templateURL string) (string, error) {
 	return validationReport.String(), nil
 }
 
+// DeletionService is the CloudFormation API required for destroying stacks
+type DeletionService interface {
+	DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error)
+}
+
 type Destroyer struct {
 	roleARN   string
 	stackName string
-	session   *session.Session
+	cfSvc     DeletionService
 }
 
-func NewDestroyer(stackName string, session *session.Session, roleARN string) *Destroyer {
+func NewDestroyer(stackName string, cfSvc DeletionService, roleARN string) *Destroyer {
 	return &Destroyer{
 		stackName: stackName,
-		session:   session,
+		cfSvc:     cfSvc,
 		roleARN:   roleARN,
 	}
 }
 
 func (c *Destroyer) Destroy() error {
-	cfSvc := cloudformation.New(c.session)
 	dreq := &cloudformation.DeleteStackInput{
 		StackName: aws.String(c.stackName),
 	}
 	if c.roleARN != "" {
 		dreq = dreq.SetRoleARN(c.roleARN)
 	}
-	_, err := cfSvc.DeleteStack(dreq)
+	_, err := c.cfSvc.DeleteStack(dreq)
 	return err
 }
 
-func (c *Provisioner) StreamEventsNested(q chan struct{}, f *cloudformation.CloudFormation, stackId string, headStackName string, t time.Time) error {
+// StackEventsStreamingService is the CloudFormation API required for streaming stack events
+type StackEventsStreamingService interface {
+	DescribeStackEventsPages(input *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error
+}
+
+func (c *Provisioner) StreamEventsNested(q chan struct{}, f StackEventsStreamingService, stackId string, headStackName string, t time.Time) error {
 	nestedStacks := make(map[string]bool)
 	nestedQuit := make(chan struct{}, 1)
 	var lastSeenEventId string
@@ -265,22 +313,26 @@ func (c *Provisioner) StreamEventsNested(q chan struct{}, f *cloudformation.Clou
 		case <-q:
 			return nil
 		case <-time.After(1 * time.Second):
-			events := make([]cloudformation.StackEvent, 0)
-
-			_ = f.DescribeStackEventsPages(
-				&cloudformation.DescribeStackEventsInput{StackName: &stackId},
-				func(page *cloudformation.DescribeStackEventsOutput, lastPage bool) bool {
-					for _, e := range page.StackEvents {
-						if (e.Timestamp).Before(t) {
-							return false
-						}
-						if *e.EventId == lastSeenEventId {
-							return false
+			var events []cloudformation.StackEvent
+
+			// Events are streamed on a best-effort basis. Errors including exhausted retries are ignored until the next poll
+			_ = DefaultRetryPolicy.Read("DescribeStackEvents", func() error {
+				events = make([]cloudformation.StackEvent, 0)
+				return f.DescribeStackEventsPages(
+					&cloudformation.DescribeStackEventsInput{StackName: &stackId},
+					func(page *cloudformation.DescribeStackEventsOutput, lastPage bool) bool {
+						for _, e := range page.StackEvents {
+							if (e.Timestamp).Before(t) {
+								return false
+							}
+							if *e.EventId == lastSeenEventId {
+								return false
+							}
+							events = append(events, *e)
 						}
-						events = append(events, *e)
-					}
-					return true
-				})
+						return true
+					})
+			})
 
 			for i := len(events) - 1; i >= 0; i-- {
 				e := events[i]
@@ -306,9 +358,9 @@ func eventPrettyPrint(e cloudformation.StackEvent, n string, t time.Time) {
 	s := int((*e.Timestamp).Sub(t).Seconds())
 	d := fmt.Sprintf("+%.2d:%.2d:%.2d", s/3600, (s/60)%60, s%60)
 	if e.ResourceStatusReason != nil {
-		fmt.Printf("%s%s\t%s\t\t%s\t\"%s\"\n", d, n, resize(*e.ResourceStatus, 24), resize(*e.LogicalResourceId, 22), *e.ResourceStatusReason)
+		progress.Printf("%s%s\t%s\t\t%s\t\"%s\"\n", d, n, resize(*e.ResourceStatus, 24), resize(*e.LogicalResourceId, 22), *e.ResourceStatusReason)
 	} else {
-		fmt.Printf("%s%s\t%s\t\t%s\n", d, n, resize(*e.ResourceStatus, 24), resize(*e.LogicalResourceId, 22))
+		progress.Printf("%s%s\t%s\t\t%s\n", d, n, resize(*e.ResourceStatus, 24), resize(*e.LogicalResourceId, 22))
 	}
 }
 
@@ -3,7 +3,13 @@ package cfnstack
 import (
 	"bytes"
 	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/kubernetes-incubator/kube-aws/fingerprint"
+	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
 )
 
 type dummyS3ObjectPutterService struct {
@@ -64,3 +70,86 @@ func (s3Svc dummyS3ObjectPutterService) PutObject(input *s3.PutObjectInput) (*s3
 
 	return resp, nil
 }
+
+func TestUploadAssetsSkipsUnchangedAssets(t *testing.T) {
+	builder := NewAssetsBuilder("test-cluster", "s3://mybucket/mydir", model.RegionForName("us-west-1"))
+	unchanged, err := builder.Add("userdata-worker", "unchanged")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changed, err := builder.Add("stack.json", `{"Resources":{}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	throttler := &helper.Throttler{}
+	// PutObject fails for any asset other than the changed one
+	s3Svc := helper.DummyS3ObjectPutterService{
+		ExpectedBucket:        changed.Bucket,
+		ExpectedKey:           changed.Key,
+		ExpectedBody:          changed.Content,
+		ExpectedContentType:   "application/json",
+		ExpectedContentLength: int64(len(changed.Content)),
+		Throttler:             throttler,
+		UploadedFingerprints: map[string]string{
+			unchanged.Key: fingerprint.SHA256(unchanged.Content),
+			changed.Key:   fingerprint.SHA256("previous"),
+		},
+	}
+
+	p := NewProvisioner("test-cluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+	if err := p.UploadAssets(s3Svc, builder.Build()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if throttler.Calls != 1 {
+		t.Errorf("expected only the changed asset to be uploaded but PutObject was called %d times", throttler.Calls)
+	}
+}
+
+type recordingS3ObjectPutterService struct {
+	helper.DummyS3ObjectPutterService
+	inputs *[]s3.PutObjectInput
+}
+
+func (s recordingS3ObjectPutterService) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	*s.inputs = append(*s.inputs, *input)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestUploadAssetsWithS3AssetsSettings(t *testing.T) {
+	builder := NewAssetsBuilder("test-cluster", "s3://mybucket/mydir", model.RegionForName("us-west-1"))
+	asset, err := builder.Add("stack.json", `{"Resources":{}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inputs := []s3.PutObjectInput{}
+	// The asset was previously uploaded without the settings
+	s3Svc := recordingS3ObjectPutterService{
+		DummyS3ObjectPutterService: helper.DummyS3ObjectPutterService{
+			UploadedFingerprints: map[string]string{asset.Key: fingerprint.SHA256(asset.Content)},
+		},
+		inputs: &inputs,
+	}
+
+	p := NewProvisioner("test-cluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil).WithS3Assets(model.S3Assets{
+		ServerSideEncryption: "aws:kms",
+		KMSKeyARN:            "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxx",
+		ACL:                  "bucket-owner-full-control",
+		Tags:                 map[string]string{"Owner": "platform-team"},
+	})
+	if err := p.UploadAssets(s3Svc, builder.Build()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(inputs) != 1 {
+		t.Fatalf("expected the asset to be uploaded again with the new settings but was uploaded %d times", len(inputs))
+	}
+	input := inputs[0]
+	if aws.StringValue(input.ServerSideEncryption) != "aws:kms" ||
+		aws.StringValue(input.SSEKMSKeyId) != "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxx" ||
+		aws.StringValue(input.ACL) != "bucket-owner-full-control" ||
+		aws.StringValue(input.Tagging) != "Owner=platform-team" {
+		t.Errorf("unexpected put object input: %+v", input)
+	}
+}
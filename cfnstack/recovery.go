@@ -0,0 +1,276 @@
+package cfnstack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// StackCondition classifies a stack status by what can be done with the stack next
+type StackCondition string
+
+const (
+	// StackConditionInProgress means an operation is still in progress and the stack needs to be waited for
+	StackConditionInProgress StackCondition = "in-progress"
+	// StackConditionReady means the stack can be updated, including when the last update has been rolled back
+	StackConditionReady StackCondition = "ready"
+	// StackConditionUpdateRollbackFailed means the stack is stuck in UPDATE_ROLLBACK_FAILED and requires continuing the rollback
+	StackConditionUpdateRollbackFailed StackCondition = "update-rollback-failed"
+	// StackConditionNeverCreated means the stack has failed to be created and can only be deleted
+	StackConditionNeverCreated StackCondition = "never-created"
+	// StackConditionDeleteFailed means the stack has failed to be deleted
+	StackConditionDeleteFailed StackCondition = "delete-failed"
+	// StackConditionDeleted means the stack has been deleted
+	StackConditionDeleted StackCondition = "deleted"
+)
+
+// stackPollInterval is the interval between describing a stack while waiting for an operation to complete
+var stackPollInterval = 3 * time.Second
+
+// ClassifyStackStatus returns the condition of a stack in the status
+func ClassifyStackStatus(status string) StackCondition {
+	switch status {
+	case cloudformation.StackStatusCreateComplete, cloudformation.StackStatusUpdateComplete, cloudformation.StackStatusUpdateRollbackComplete:
+		return StackConditionReady
+	case cloudformation.StackStatusUpdateRollbackFailed:
+		return StackConditionUpdateRollbackFailed
+	case cloudformation.StackStatusCreateFailed, cloudformation.StackStatusRollbackComplete, cloudformation.StackStatusRollbackFailed:
+		return StackConditionNeverCreated
+	case cloudformation.StackStatusDeleteFailed:
+		return StackConditionDeleteFailed
+	case cloudformation.StackStatusDeleteComplete:
+		return StackConditionDeleted
+	default:
+		return StackConditionInProgress
+	}
+}
+
+// Remediation returns how to recover the stack in the condition, or an empty string when there's nothing to recover
+func (c StackCondition) Remediation() string {
+	switch c {
+	case StackConditionUpdateRollbackFailed:
+		return "Run `kube-aws recover` to continue rolling back the update. Specify resources which can't be rolled back with `--skip-resources`"
+	case StackConditionNeverCreated:
+		return "The stack has never been created successfully and can only be deleted. Run `kube-aws recover` to delete it, and then `kube-aws up` again"
+	case StackConditionDeleteFailed:
+		return "Delete the resources which failed to be deleted, and then run `kube-aws destroy` again"
+	default:
+		return ""
+	}
+}
+
+// StackState is the status of a stack along with the first failure found in it or its nested stacks
+type StackState struct {
+	StackID   string
+	StackName string
+	Status    string
+	Reason    string
+	Condition StackCondition
+	// FailedStackName is the name of the innermost stack which failed first
+	FailedStackName string
+	// Failures are the failed events in the stack named FailedStackName, oldest first
+	Failures []string
+}
+
+func (s *StackState) String() string {
+	msg := fmt.Sprintf("Stack status: %s : %s", s.Status, s.Reason)
+	if len(s.Failures) > 0 {
+		msg += fmt.Sprintf("\n\nThe first failed events in the stack %s:\n%s", s.FailedStackName, strings.Join(s.Failures, "\n"))
+	}
+	if r := s.Condition.Remediation(); r != "" {
+		msg += "\n\n" + r
+	}
+	return msg
+}
+
+type stackEventsDescriber interface {
+	DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error)
+}
+
+type stackStateDescriber interface {
+	stackDescriber
+	stackEventsDescriber
+}
+
+// RecoveryService is the set of CloudFormation APIs required for recovering stacks
+type RecoveryService interface {
+	stackStateDescriber
+	ContinueUpdateRollback(input *cloudformation.ContinueUpdateRollbackInput) (*cloudformation.ContinueUpdateRollbackOutput, error)
+	DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error)
+}
+
+// DescribeStackState returns the current state of the stack, including the first failure when it has failed
+func (c *Provisioner) DescribeStackState(cfSvc RecoveryService) (*StackState, error) {
+	cfSvc = NewRetryingRecoveryService(cfSvc, DefaultRetryPolicy)
+	stack, err := describeStack(cfSvc, c.stackName)
+	if err != nil {
+		return nil, err
+	}
+	return stackStateOf(cfSvc, stack)
+}
+
+// RecoverStackAndWait continues rolling back the stack stuck in UPDATE_ROLLBACK_FAILED while skipping the resources,
+// or deletes the stack which has never been created successfully. Resources in nested stacks are specified as `NestedStackName.ResourceLogicalID`
+func (c *Provisioner) RecoverStackAndWait(cfSvc RecoveryService, state *StackState, skipResources []string) error {
+	cfSvc = NewRetryingRecoveryService(cfSvc, DefaultRetryPolicy)
+	switch state.Condition {
+	case StackConditionUpdateRollbackFailed:
+		input := &cloudformation.ContinueUpdateRollbackInput{
+			StackName:       aws.String(state.StackID),
+			ResourcesToSkip: aws.StringSlice(skipResources),
+		}
+		if c.roleARN != "" {
+			input = input.SetRoleARN(c.roleARN)
+		}
+		if _, err := cfSvc.ContinueUpdateRollback(input); err != nil {
+			return fmt.Errorf("failed to continue rolling back %s: %v", state.StackName, err)
+		}
+		return waitUntilStackGetsRecovered(cfSvc, state.StackID, cloudformation.StackStatusUpdateRollbackComplete)
+	case StackConditionNeverCreated:
+		if len(skipResources) > 0 {
+			return errors.New("resources can't be skipped when deleting a stack which has never been created")
+		}
+		input := &cloudformation.DeleteStackInput{StackName: aws.String(state.StackID)}
+		if c.roleARN != "" {
+			input = input.SetRoleARN(c.roleARN)
+		}
+		if _, err := cfSvc.DeleteStack(input); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", state.StackName, err)
+		}
+		return waitUntilStackGetsRecovered(cfSvc, state.StackID, cloudformation.StackStatusDeleteComplete)
+	default:
+		return fmt.Errorf("stack %s in %s can't be recovered by kube-aws", state.StackName, state.Status)
+	}
+}
+
+func waitUntilStackGetsRecovered(cfSvc stackStateDescriber, stackID string, expectedStatus string) error {
+	stack, err := waitUntilStackSettles(cfSvc, stackID)
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(stack.StackStatus) == expectedStatus {
+		return nil
+	}
+	state, err := stackStateOf(cfSvc, stack)
+	if err != nil {
+		return err
+	}
+	return errors.New(state.String())
+}
+
+// waitUntilStackSettles waits until no operation is in progress for the stack and returns it
+func waitUntilStackSettles(cfSvc stackDescriber, stackID string) (*cloudformation.Stack, error) {
+	for {
+		stack, err := describeStack(cfSvc, stackID)
+		if err != nil {
+			return nil, err
+		}
+		if ClassifyStackStatus(aws.StringValue(stack.StackStatus)) != StackConditionInProgress {
+			return stack, nil
+		}
+		time.Sleep(stackPollInterval)
+	}
+}
+
+func describeStack(cfSvc stackDescriber, stackName string) (*cloudformation.Stack, error) {
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Stacks) == 0 {
+		return nil, fmt.Errorf("stack not found")
+	}
+	return resp.Stacks[0], nil
+}
+
+func stackStateOf(cfSvc stackEventsDescriber, stack *cloudformation.Stack) (*StackState, error) {
+	state := &StackState{
+		StackID:   aws.StringValue(stack.StackId),
+		StackName: aws.StringValue(stack.StackName),
+		Status:    aws.StringValue(stack.StackStatus),
+		Reason:    aws.StringValue(stack.StackStatusReason),
+	}
+	state.Condition = ClassifyStackStatus(state.Status)
+
+	if state.Condition == StackConditionInProgress || state.Status == cloudformation.StackStatusCreateComplete || state.Status == cloudformation.StackStatusUpdateComplete {
+		return state, nil
+	}
+
+	name, failures, err := firstFailure(cfSvc, state.StackID, state.StackName)
+	if err != nil {
+		return nil, err
+	}
+	state.FailedStackName = name
+	state.Failures = failures
+	return state, nil
+}
+
+// firstFailure returns the name of the innermost stack which failed first in the last operation on the stack, following failed nested stacks,
+// and the failed events in it, oldest first
+func firstFailure(cfSvc stackEventsDescriber, stackID string, stackName string) (string, []string, error) {
+	events, err := lastOperationEvents(cfSvc, stackID, stackName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	failed := []*cloudformation.StackEvent{}
+	for i := len(events) - 1; i >= 0; i-- {
+		if len(StackEventErrMsgs(events[i:i+1])) > 0 {
+			failed = append(failed, events[i])
+		}
+	}
+	if len(failed) == 0 {
+		return stackName, nil, nil
+	}
+
+	first := failed[0]
+	nestedID := aws.StringValue(first.PhysicalResourceId)
+	if aws.StringValue(first.ResourceType) == "AWS::CloudFormation::Stack" && nestedID != "" && nestedID != stackID {
+		name, failures, err := firstFailure(cfSvc, nestedID, nestedStackName(nestedID))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(failures) > 0 {
+			return name, failures, nil
+		}
+	}
+
+	return stackName, StackEventErrMsgs(failed), nil
+}
+
+// lastOperationEvents returns the events of the stack since the last create or update operation started, newest first
+func lastOperationEvents(cfSvc stackEventsDescriber, stackID string, stackName string) ([]*cloudformation.StackEvent, error) {
+	events := []*cloudformation.StackEvent{}
+	input := &cloudformation.DescribeStackEventsInput{StackName: aws.String(stackID)}
+	for {
+		out, err := cfSvc.DescribeStackEvents(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe events of %s: %v", stackName, err)
+		}
+		for _, e := range out.StackEvents {
+			events = append(events, e)
+			status := aws.StringValue(e.ResourceStatus)
+			if aws.StringValue(e.PhysicalResourceId) == stackID &&
+				(status == cloudformation.ResourceStatusCreateInProgress || status == cloudformation.ResourceStatusUpdateInProgress) {
+				return events, nil
+			}
+		}
+		if out.NextToken == nil {
+			return events, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// nestedStackName returns the name of a stack from its ID, e.g. `arn:aws:cloudformation:us-west-2:123456789012:stack/mycluster-Controlplane-ABC/guid`
+func nestedStackName(stackID string) string {
+	parts := strings.Split(stackID, "/")
+	if len(parts) == 3 {
+		return parts[1]
+	}
+	return stackID
+}
@@ -0,0 +1,189 @@
+package cfnstack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+const (
+	rootStackID         = "arn:aws:cloudformation:us-west-2:123456789012:stack/mycluster/1"
+	controlPlaneStackID = "arn:aws:cloudformation:us-west-2:123456789012:stack/mycluster-Controlplane-ABC/2"
+)
+
+type dummyRecoveryService struct {
+	// statuses are returned in order by DescribeStacks. The last one is returned repeatedly
+	statuses        []string
+	events          map[string][]*cloudformation.StackEvent
+	continued       []cloudformation.ContinueUpdateRollbackInput
+	deleted         []cloudformation.DeleteStackInput
+	describedEvents []string
+}
+
+func (s *dummyRecoveryService) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	status := s.statuses[0]
+	if len(s.statuses) > 1 {
+		s.statuses = s.statuses[1:]
+	}
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{
+				StackId:           aws.String(rootStackID),
+				StackName:         aws.String("mycluster"),
+				StackStatus:       aws.String(status),
+				StackStatusReason: aws.String("reason"),
+			},
+		},
+	}, nil
+}
+
+func (s *dummyRecoveryService) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	s.describedEvents = append(s.describedEvents, *input.StackName)
+	return &cloudformation.DescribeStackEventsOutput{StackEvents: s.events[*input.StackName]}, nil
+}
+
+func (s *dummyRecoveryService) ContinueUpdateRollback(input *cloudformation.ContinueUpdateRollbackInput) (*cloudformation.ContinueUpdateRollbackOutput, error) {
+	s.continued = append(s.continued, *input)
+	return &cloudformation.ContinueUpdateRollbackOutput{}, nil
+}
+
+func (s *dummyRecoveryService) DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	s.deleted = append(s.deleted, *input)
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func stackEvent(physicalID, logicalID, resourceType, status, reason string) *cloudformation.StackEvent {
+	e := &cloudformation.StackEvent{
+		PhysicalResourceId: aws.String(physicalID),
+		LogicalResourceId:  aws.String(logicalID),
+		ResourceType:       aws.String(resourceType),
+		ResourceStatus:     aws.String(status),
+	}
+	if reason != "" {
+		e.ResourceStatusReason = aws.String(reason)
+	}
+	return e
+}
+
+// failedUpdateEvents are the events of the root stack whose update failed in the control-plane stack, newest first
+var failedUpdateEvents = map[string][]*cloudformation.StackEvent{
+	rootStackID: {
+		stackEvent(rootStackID, "mycluster", "AWS::CloudFormation::Stack", "UPDATE_ROLLBACK_FAILED", "The following resource(s) failed to update: [Controlplane]"),
+		stackEvent(controlPlaneStackID, "Controlplane", "AWS::CloudFormation::Stack", "UPDATE_FAILED", "Embedded stack was not successfully updated"),
+		stackEvent("mycluster-Pool1-DEF", "Pool1", "AWS::CloudFormation::Stack", "UPDATE_FAILED", "Resource update cancelled"),
+		stackEvent(rootStackID, "mycluster", "AWS::CloudFormation::Stack", "UPDATE_IN_PROGRESS", "User Initiated"),
+		// Events of the previous operation must be ignored
+		stackEvent("old", "Old", "AWS::EC2::Instance", "CREATE_FAILED", "old failure"),
+	},
+	controlPlaneStackID: {
+		stackEvent("lc-2", "ControllersLC", "AWS::AutoScaling::LaunchConfiguration", "DELETE_FAILED", "rollback failure"),
+		stackEvent("asg", "Controllers", "AWS::AutoScaling::AutoScalingGroup", "UPDATE_FAILED", "Received 0 SUCCESS signal(s) out of 1"),
+		stackEvent("lc-2", "ControllersLC", "AWS::AutoScaling::LaunchConfiguration", "CREATE_COMPLETE", ""),
+		stackEvent(controlPlaneStackID, "mycluster-Controlplane-ABC", "AWS::CloudFormation::Stack", "UPDATE_IN_PROGRESS", ""),
+	},
+}
+
+func TestClassifyStackStatus(t *testing.T) {
+	testCases := map[string]StackCondition{
+		"CREATE_COMPLETE":                              StackConditionReady,
+		"UPDATE_ROLLBACK_COMPLETE":                     StackConditionReady,
+		"UPDATE_ROLLBACK_FAILED":                       StackConditionUpdateRollbackFailed,
+		"ROLLBACK_COMPLETE":                            StackConditionNeverCreated,
+		"CREATE_FAILED":                                StackConditionNeverCreated,
+		"DELETE_FAILED":                                StackConditionDeleteFailed,
+		"DELETE_COMPLETE":                              StackConditionDeleted,
+		"UPDATE_ROLLBACK_COMPLETE_CLEANUP_IN_PROGRESS": StackConditionInProgress,
+	}
+	for status, expected := range testCases {
+		if actual := ClassifyStackStatus(status); actual != expected {
+			t.Errorf("unexpected condition for %s: expected %s but was %s", status, expected, actual)
+		}
+	}
+}
+
+func TestDescribeStackState(t *testing.T) {
+	svc := &dummyRecoveryService{statuses: []string{"UPDATE_ROLLBACK_FAILED"}, events: failedUpdateEvents}
+	p := &Provisioner{stackName: "mycluster"}
+
+	state, err := p.DescribeStackState(svc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if state.Condition != StackConditionUpdateRollbackFailed || state.FailedStackName != "mycluster-Controlplane-ABC" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+
+	expected := []string{
+		"UPDATE_FAILED AWS::AutoScaling::AutoScalingGroup Controllers Received 0 SUCCESS signal(s) out of 1",
+		"DELETE_FAILED AWS::AutoScaling::LaunchConfiguration ControllersLC rollback failure",
+	}
+	if !reflect.DeepEqual(state.Failures, expected) {
+		t.Errorf("unexpected failures: expected %v but was %v", expected, state.Failures)
+	}
+
+	if msg := state.String(); !strings.Contains(msg, "kube-aws recover") || !strings.Contains(msg, expected[0]) {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
+
+func TestRecoverStackAndWait(t *testing.T) {
+	stackPollInterval = 0
+
+	t.Run("ContinueUpdateRollback", func(t *testing.T) {
+		svc := &dummyRecoveryService{
+			statuses: []string{"UPDATE_ROLLBACK_IN_PROGRESS", "UPDATE_ROLLBACK_COMPLETE"},
+			events:   failedUpdateEvents,
+		}
+		p := &Provisioner{stackName: "mycluster", roleARN: "arn:aws:iam::123456789012:role/cfn"}
+		state := &StackState{StackID: rootStackID, StackName: "mycluster", Condition: StackConditionUpdateRollbackFailed}
+
+		if err := p.RecoverStackAndWait(svc, state, []string{"Controlplane.Controllers"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(svc.continued) != 1 || *svc.continued[0].StackName != rootStackID || *svc.continued[0].RoleARN != p.roleARN ||
+			!reflect.DeepEqual(aws.StringValueSlice(svc.continued[0].ResourcesToSkip), []string{"Controlplane.Controllers"}) {
+			t.Errorf("unexpected continue-update-rollback: %+v", svc.continued)
+		}
+	})
+
+	t.Run("RollbackFailedAgain", func(t *testing.T) {
+		svc := &dummyRecoveryService{statuses: []string{"UPDATE_ROLLBACK_FAILED"}, events: failedUpdateEvents}
+		p := &Provisioner{stackName: "mycluster"}
+		state := &StackState{StackID: rootStackID, StackName: "mycluster", Condition: StackConditionUpdateRollbackFailed}
+
+		err := p.RecoverStackAndWait(svc, state, nil)
+		if err == nil || !strings.Contains(err.Error(), "rollback failure") {
+			t.Errorf("expected an error describing the failure but was %v", err)
+		}
+	})
+
+	t.Run("DeleteNeverCreated", func(t *testing.T) {
+		svc := &dummyRecoveryService{statuses: []string{"DELETE_IN_PROGRESS", "DELETE_COMPLETE"}}
+		p := &Provisioner{stackName: "mycluster"}
+		state := &StackState{StackID: rootStackID, StackName: "mycluster", Condition: StackConditionNeverCreated}
+
+		if err := p.RecoverStackAndWait(svc, state, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(svc.deleted) != 1 || *svc.deleted[0].StackName != rootStackID {
+			t.Errorf("unexpected deletion: %+v", svc.deleted)
+		}
+
+		if err := p.RecoverStackAndWait(svc, state, []string{"Foo"}); err == nil {
+			t.Errorf("expected an error for skipping resources of a stack to be deleted")
+		}
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		p := &Provisioner{stackName: "mycluster"}
+		state := &StackState{StackName: "mycluster", Status: "UPDATE_COMPLETE", Condition: StackConditionReady}
+		if err := p.RecoverStackAndWait(&dummyRecoveryService{}, state, nil); err == nil {
+			t.Errorf("expected an error for a stack with nothing to recover")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package cfnstack
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var subVariablePattern = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// LogicalIDsReferencedBy returns the sorted logical ids of resources and parameters referred from the part of a decoded stack template,
+// e.g. a resource or an output, via `Ref`, `Fn::GetAtt`, `Fn::Sub` or `DependsOn`.
+// Pseudo parameters like `AWS::StackName` are excluded
+func LogicalIDsReferencedBy(node interface{}) []string {
+	ids := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !strings.HasPrefix(id, "AWS::") {
+			ids[id] = true
+		}
+	}
+
+	walkTemplate(node, func(fn string, arg interface{}) {
+		switch fn {
+		case "Ref":
+			if s, ok := arg.(string); ok {
+				add(s)
+			}
+		case "Fn::GetAtt":
+			switch a := arg.(type) {
+			case []interface{}:
+				if len(a) > 0 {
+					if s, ok := a[0].(string); ok {
+						add(s)
+					}
+				}
+			case string:
+				add(strings.SplitN(a, ".", 2)[0])
+			}
+		case "Fn::Sub":
+			var s string
+			vars := map[string]interface{}{}
+			switch a := arg.(type) {
+			case string:
+				s = a
+			case []interface{}:
+				if len(a) > 0 {
+					s, _ = a[0].(string)
+				}
+				if len(a) > 1 {
+					vars, _ = a[1].(map[string]interface{})
+				}
+			}
+			for _, m := range subVariablePattern.FindAllStringSubmatch(s, -1) {
+				// Variables defined in the variable map of `Fn::Sub` aren't references
+				if _, ok := vars[m[1]]; ok {
+					continue
+				}
+				add(strings.SplitN(m[1], ".", 2)[0])
+			}
+		}
+	})
+
+	if m, ok := node.(map[string]interface{}); ok {
+		switch d := m["DependsOn"].(type) {
+		case string:
+			add(d)
+		case []interface{}:
+			for _, v := range d {
+				if s, ok := v.(string); ok {
+					add(s)
+				}
+			}
+		}
+	}
+
+	result := []string{}
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,34 @@
+package cfnstack
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLogicalIDsReferencedBy(t *testing.T) {
+	resource := `{
+  "Type": "AWS::CloudFormation::Stack",
+  "Properties": {
+    "Parameters": {
+      "EtcdStackName": {"Fn::GetAtt": ["Etcd", "Outputs.StackName"]},
+      "NetworkStackName": {"Fn::GetAtt": "Network.Outputs.StackName"},
+      "LogGroup": {"Ref": "CloudWatchLogGroup"},
+      "Region": {"Ref": "AWS::Region"},
+      "Name": {"Fn::Sub": "${AWS::StackName}-${Controlplane.Outputs.StackName}"},
+      "Suffix": {"Fn::Sub": ["${Local}-${Bucket}", {"Local": {"Ref": "Prefix"}}]}
+    }
+  },
+  "DependsOn": ["Controlplane", "Etcd"]
+}`
+	var node interface{}
+	if err := json.Unmarshal([]byte(resource), &node); err != nil {
+		t.Fatalf("failed to parse resource: %v", err)
+	}
+
+	actual := LogicalIDsReferencedBy(node)
+	expected := []string{"Bucket", "CloudWatchLogGroup", "Controlplane", "Etcd", "Network", "Prefix"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,247 @@
+package cfnstack
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/kubernetes-incubator/kube-aws/progress"
+)
+
+// RetryPolicy determines how calls to AWS APIs are retried on throttling and transient errors.
+// Delays grow exponentially from BaseDelay up to MaxDelay, and each delay is randomized between zero and the grown delay so that
+// concurrent callers like the goroutines streaming events of nested stacks don't retry in lockstep
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used by Provisioner, configurable from the CLI
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 8,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// retrySleep is replaced in tests to not actually wait
+var retrySleep = time.Sleep
+
+var throttlingErrorCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestThrottled":                       true,
+	"RequestThrottledException":              true,
+	"RequestLimitExceeded":                   true,
+	"TooManyRequestsException":               true,
+	"SlowDown":                               true,
+	"ProvisionedThroughputExceededException": true,
+}
+
+var transientErrorCodes = map[string]bool{
+	"InternalFailure":    true,
+	"InternalError":      true,
+	"ServiceUnavailable": true,
+	"RequestTimeout":     true,
+}
+
+// IsThrottlingError returns true when the error means the request was rejected due to a rate limit of an AWS API
+func IsThrottlingError(err error) bool {
+	if e, ok := err.(awserr.Error); ok {
+		return throttlingErrorCodes[e.Code()]
+	}
+	return false
+}
+
+// isTransientError returns true when the error is likely to be resolved by retrying, including throttling
+func isTransientError(err error) bool {
+	if IsThrottlingError(err) {
+		return true
+	}
+	if e, ok := err.(awserr.RequestFailure); ok && e.StatusCode() >= 500 {
+		return true
+	}
+	if e, ok := err.(awserr.Error); ok {
+		return transientErrorCodes[e.Code()]
+	}
+	return false
+}
+
+// delay returns the randomized delay before the retry-th retry, counting from 0
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 0; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func (p RetryPolicy) do(op string, retryable func(error) bool, f func() error) error {
+	for retry := 0; ; retry++ {
+		err := f()
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if retry >= p.MaxRetries {
+			return fmt.Errorf("%s failed after %d retries: %v", op, retry, err)
+		}
+		d := p.delay(retry)
+		progress.Printf("%s failed due to \"%v\". retrying in %v (%d/%d)\n", op, err, d, retry+1, p.MaxRetries)
+		retrySleep(d)
+	}
+}
+
+// Read retries f on any transient error
+func (p RetryPolicy) Read(op string, f func() error) error {
+	return p.do(op, isTransientError, f)
+}
+
+// Write retries f only on throttling, as the request might have been processed on the other transient errors
+func (p RetryPolicy) Write(op string, f func() error) error {
+	return p.do(op, IsThrottlingError, f)
+}
+
+// NewRetryingCRUDService returns a CRUDService which retries calls to svc according to the policy
+func NewRetryingCRUDService(svc CRUDService, policy RetryPolicy) CRUDService {
+	if r, ok := svc.(retryingCRUDService); ok {
+		svc = r.svc
+	}
+	return retryingCRUDService{svc: svc, policy: policy}
+}
+
+type retryingCRUDService struct {
+	svc    CRUDService
+	policy RetryPolicy
+}
+
+func (s retryingCRUDService) CreateStack(input *cloudformation.CreateStackInput) (out *cloudformation.CreateStackOutput, err error) {
+	err = s.policy.Write("CreateStack", func() error {
+		out, err = s.svc.CreateStack(input)
+		return err
+	})
+	return
+}
+
+func (s retryingCRUDService) UpdateStack(input *cloudformation.UpdateStackInput) (out *cloudformation.UpdateStackOutput, err error) {
+	err = s.policy.Write("UpdateStack", func() error {
+		out, err = s.svc.UpdateStack(input)
+		return err
+	})
+	return
+}
+
+func (s retryingCRUDService) DescribeStacks(input *cloudformation.DescribeStacksInput) (out *cloudformation.DescribeStacksOutput, err error) {
+	err = s.policy.Read("DescribeStacks", func() error {
+		out, err = s.svc.DescribeStacks(input)
+		return err
+	})
+	return
+}
+
+func (s retryingCRUDService) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (out *cloudformation.DescribeStackEventsOutput, err error) {
+	err = s.policy.Read("DescribeStackEvents", func() error {
+		out, err = s.svc.DescribeStackEvents(input)
+		return err
+	})
+	return
+}
+
+func (s retryingCRUDService) EstimateTemplateCost(input *cloudformation.EstimateTemplateCostInput) (out *cloudformation.EstimateTemplateCostOutput, err error) {
+	err = s.policy.Read("EstimateTemplateCost", func() error {
+		out, err = s.svc.EstimateTemplateCost(input)
+		return err
+	})
+	return
+}
+
+// NewRetryingRecoveryService returns a RecoveryService which retries calls to svc according to the policy
+func NewRetryingRecoveryService(svc RecoveryService, policy RetryPolicy) RecoveryService {
+	if r, ok := svc.(retryingRecoveryService); ok {
+		svc = r.svc
+	}
+	return retryingRecoveryService{svc: svc, policy: policy}
+}
+
+type retryingRecoveryService struct {
+	svc    RecoveryService
+	policy RetryPolicy
+}
+
+func (s retryingRecoveryService) DescribeStacks(input *cloudformation.DescribeStacksInput) (out *cloudformation.DescribeStacksOutput, err error) {
+	err = s.policy.Read("DescribeStacks", func() error {
+		out, err = s.svc.DescribeStacks(input)
+		return err
+	})
+	return
+}
+
+func (s retryingRecoveryService) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (out *cloudformation.DescribeStackEventsOutput, err error) {
+	err = s.policy.Read("DescribeStackEvents", func() error {
+		out, err = s.svc.DescribeStackEvents(input)
+		return err
+	})
+	return
+}
+
+func (s retryingRecoveryService) ContinueUpdateRollback(input *cloudformation.ContinueUpdateRollbackInput) (out *cloudformation.ContinueUpdateRollbackOutput, err error) {
+	err = s.policy.Write("ContinueUpdateRollback", func() error {
+		out, err = s.svc.ContinueUpdateRollback(input)
+		return err
+	})
+	return
+}
+
+func (s retryingRecoveryService) DeleteStack(input *cloudformation.DeleteStackInput) (out *cloudformation.DeleteStackOutput, err error) {
+	err = s.policy.Write("DeleteStack", func() error {
+		out, err = s.svc.DeleteStack(input)
+		return err
+	})
+	return
+}
+
+// NewRetryingS3ObjectPutterService returns a S3ObjectPutterService which retries calls to svc according to the policy.
+// Putting an object is idempotent, so that it is retried on any transient error
+func NewRetryingS3ObjectPutterService(svc S3ObjectPutterService, policy RetryPolicy) S3ObjectPutterService {
+	if r, ok := svc.(retryingS3ObjectPutterService); ok {
+		svc = r.svc
+	}
+	return retryingS3ObjectPutterService{svc: svc, policy: policy}
+}
+
+type retryingS3ObjectPutterService struct {
+	svc    S3ObjectPutterService
+	policy RetryPolicy
+}
+
+func (s retryingS3ObjectPutterService) HeadObject(input *s3.HeadObjectInput) (out *s3.HeadObjectOutput, err error) {
+	err = s.policy.Read("HeadObject", func() error {
+		out, err = s.svc.HeadObject(input)
+		return err
+	})
+	return
+}
+
+func (s retryingS3ObjectPutterService) PutObject(input *s3.PutObjectInput) (out *s3.PutObjectOutput, err error) {
+	err = s.policy.Read("PutObject", func() error {
+		// Rewind the body consumed by the previous attempt
+		if input.Body != nil {
+			if _, err := input.Body.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		out, err = s.svc.PutObject(input)
+		return err
+	})
+	return
+}
@@ -0,0 +1,168 @@
+package cfnstack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/progress"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+// withRetryPolicy replaces the default retry policy and records delays instead of sleeping while running f
+func withRetryPolicy(policy RetryPolicy, f func(delays *[]time.Duration)) {
+	origPolicy, origSleep, origInterval := DefaultRetryPolicy, retrySleep, stackPollInterval
+	defer func() { DefaultRetryPolicy, retrySleep, stackPollInterval = origPolicy, origSleep, origInterval }()
+
+	delays := []time.Duration{}
+	DefaultRetryPolicy = policy
+	retrySleep = func(d time.Duration) { delays = append(delays, d) }
+	stackPollInterval = 0
+	f(&delays)
+}
+
+var testRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 250 * time.Millisecond}
+
+func TestIsThrottlingError(t *testing.T) {
+	testCases := []struct {
+		err        error
+		throttling bool
+		transient  bool
+	}{
+		{awserr.New("Throttling", "Rate exceeded", nil), true, true},
+		{awserr.New("RequestLimitExceeded", "Request limit exceeded", nil), true, true},
+		{awserr.New("SlowDown", "Please reduce your request rate", nil), true, true},
+		{awserr.New("InternalFailure", "", nil), false, true},
+		{awserr.NewRequestFailure(awserr.New("ServiceError", "", nil), 503, "id"), false, true},
+		{awserr.NewRequestFailure(awserr.New("ValidationError", "", nil), 400, "id"), false, false},
+		{awserr.New("AlreadyExistsException", "", nil), false, false},
+		{nil, false, false},
+	}
+
+	for _, tc := range testCases {
+		if actual := IsThrottlingError(tc.err); actual != tc.throttling {
+			t.Errorf("expected IsThrottlingError(%v) to be %v but was %v", tc.err, tc.throttling, actual)
+		}
+		if actual := isTransientError(tc.err); actual != tc.transient {
+			t.Errorf("expected isTransientError(%v) to be %v but was %v", tc.err, tc.transient, actual)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	for retry, max := range []time.Duration{100, 200, 250, 250, 250} {
+		max *= time.Millisecond
+		for i := 0; i < 100; i++ {
+			if d := testRetryPolicy.delay(retry); d < 0 || d > max {
+				t.Fatalf("expected delay before retry %d to be in [0, %v] but was %v", retry, max, d)
+			}
+		}
+	}
+
+	if d := (RetryPolicy{MaxRetries: 1}).delay(3); d != 0 {
+		t.Errorf("expected no delay without base delay but was %v", d)
+	}
+}
+
+func TestCreateStackAtURLAndWaitRetriesOnThrottling(t *testing.T) {
+	withRetryPolicy(testRetryPolicy, func(delays *[]time.Duration) {
+		throttler := &helper.Throttler{Count: 3}
+		cfSvc := &helper.DummyCloudformationService{
+			StackStatus: cloudformation.StackStatusCreateComplete,
+			Throttler:   throttler,
+		}
+
+		out := new(bytes.Buffer)
+		defer progress.SetWriter(progress.SetWriter(out))
+
+		p := NewProvisioner("test-cluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+		if err := p.CreateStackAtURLAndWait(cfSvc, "https://example.com/stack.json"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Retries are reported as progress, one per line
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "CreateStack failed due to") || !strings.HasSuffix(lines[2], "(3/3)") {
+			t.Errorf("unexpected progress output: %q", out.String())
+		}
+
+		// 3 throttled calls to CreateStack, followed by successful CreateStack and DescribeStacks
+		if throttler.Calls != 5 {
+			t.Errorf("expected 5 calls but was %d", throttler.Calls)
+		}
+		if len(*delays) != 3 {
+			t.Errorf("expected 3 retries but was %v", *delays)
+		}
+	})
+}
+
+func TestUpdateStackAtURLAndWaitGivesUpAfterMaxRetries(t *testing.T) {
+	withRetryPolicy(testRetryPolicy, func(delays *[]time.Duration) {
+		throttler := &helper.Throttler{Count: 10, Code: "RequestLimitExceeded"}
+		cfSvc := &helper.DummyCloudformationService{
+			StackStatus: cloudformation.StackStatusUpdateComplete,
+			Throttler:   throttler,
+		}
+
+		p := NewProvisioner("test-cluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+		_, err := p.UpdateStackAtURLAndWait(cfSvc, "https://example.com/stack.json")
+		if err == nil || !strings.Contains(err.Error(), "UpdateStack failed after 3 retries") {
+			t.Errorf("expected an error after exhausting retries but was %v", err)
+		}
+		if throttler.Calls != 4 {
+			t.Errorf("expected 4 calls but was %d", throttler.Calls)
+		}
+	})
+}
+
+func TestRetryingCRUDServiceRetriesOnlyReadsOnTransientErrors(t *testing.T) {
+	withRetryPolicy(testRetryPolicy, func(delays *[]time.Duration) {
+		throttler := &helper.Throttler{Count: 1, Code: "InternalFailure"}
+		cfSvc := NewRetryingCRUDService(&helper.DummyCloudformationService{Throttler: throttler}, testRetryPolicy)
+
+		if _, err := cfSvc.CreateStack(&cloudformation.CreateStackInput{}); err == nil {
+			t.Errorf("expected CreateStack not to be retried on InternalFailure")
+		}
+
+		throttler.Calls = 0
+		if _, err := cfSvc.DescribeStackEvents(&cloudformation.DescribeStackEventsInput{}); err != nil {
+			t.Errorf("expected DescribeStackEvents to be retried on InternalFailure but was %v", err)
+		}
+		if throttler.Calls != 2 {
+			t.Errorf("expected 2 calls but was %d", throttler.Calls)
+		}
+	})
+}
+
+func TestUploadAssetsRetriesOnThrottling(t *testing.T) {
+	withRetryPolicy(testRetryPolicy, func(delays *[]time.Duration) {
+		builder := NewAssetsBuilder("test-cluster", "s3://mybucket/mydir", model.RegionForName("us-west-1"))
+		asset, err := builder.Add("stack.json", `{"Resources":{}}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		throttler := &helper.Throttler{Count: 2, Code: "SlowDown"}
+		s3Svc := helper.DummyS3ObjectPutterService{
+			ExpectedBucket:        asset.Bucket,
+			ExpectedKey:           asset.Key,
+			ExpectedBody:          asset.Content,
+			ExpectedContentType:   "application/json",
+			ExpectedContentLength: int64(len(asset.Content)),
+			Throttler:             throttler,
+		}
+
+		p := NewProvisioner("test-cluster", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "{}", nil)
+		// The body consumed by the throttled requests must be rewound to be uploaded in full
+		if err := p.UploadAssets(s3Svc, builder.Build()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if throttler.Calls != 3 {
+			t.Errorf("expected 3 calls but was %d", throttler.Calls)
+		}
+	})
+}
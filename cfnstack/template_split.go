@@ -0,0 +1,638 @@
+package cfnstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// EXTRAS_STACK_NAME is the logical ID of the nested stack holding the resources moved out of an oversized stack template
+const EXTRAS_STACK_NAME = "Extras"
+
+// EXTRAS_TEMPLATE_ASSET_NAME is the name of the asset for the template of the extras stack, suffixed with its fingerprint
+const EXTRAS_TEMPLATE_ASSET_NAME = "extras"
+
+// maxTemplateURLLength is the maximum length of the TemplateURL of a nested stack.
+// It is used as a placeholder while measuring the size of the parent template, before the extras template is uploaded
+const maxTemplateURLLength = 1024
+
+// splittableResourceTypes are the types of resources which are moved into the extras stack when they are referenced by no other resources
+var splittableResourceTypes = map[string]bool{
+	"AWS::IAM::Policy":               true,
+	"AWS::IAM::ManagedPolicy":        true,
+	"AWS::EC2::SecurityGroupIngress": true,
+	"AWS::EC2::SecurityGroupEgress":  true,
+}
+
+// stackScopedPseudoParameters are pseudo parameters evaluated differently in a nested stack.
+// They are passed from the parent stack under the names in the values
+var stackScopedPseudoParameters = map[string]string{
+	"AWS::StackName": "ParentStackName",
+	"AWS::StackId":   "ParentStackId",
+}
+
+// SplitTemplate returns body as-is when it is within limit bytes.
+// Otherwise, IAM policies, security group rules and the resources named in extraResources are moved into a nested stack named
+// EXTRAS_STACK_NAME so that the template fits in the limit. Parameters and attributes of the remaining resources referenced by the moved ones
+// are passed to the nested stack via its parameters, and outputs referencing the moved resources are read from the outputs of the nested stack.
+// Logical IDs of the moved resources are kept as-is in the nested stack.
+// addExtras is called with the template of the nested stack and returns the URL to the template after adding it to the assets
+func SplitTemplate(body string, limit int, extraResources []string, addExtras func(string) (string, error)) (string, error) {
+	if len(body) <= limit {
+		return body, nil
+	}
+
+	tmpl := map[string]interface{}{}
+	d := json.NewDecoder(strings.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&tmpl); err != nil {
+		return "", fmt.Errorf("failed to parse stack template: %v", err)
+	}
+
+	split, err := splitTemplate(tmpl, extraResources)
+	if err != nil {
+		return "", err
+	}
+
+	placeholder, err := split.parentTemplate(strings.Repeat("x", maxTemplateURLLength))
+	if err != nil {
+		return "", err
+	}
+	if len(placeholder) > limit {
+		return "", fmt.Errorf("stack template is %d bytes even after moving %d resource(s) into the nested stack %s, which exceeds the limit of %d bytes", len(placeholder), len(split.moved), EXTRAS_STACK_NAME, limit)
+	}
+
+	extras, err := marshalTemplate(split.extras)
+	if err != nil {
+		return "", err
+	}
+	if len(extras) > limit {
+		return "", fmt.Errorf("template of the nested stack %s is %d bytes, which exceeds the limit of %d bytes", EXTRAS_STACK_NAME, len(extras), limit)
+	}
+
+	url, err := addExtras(extras)
+	if err != nil {
+		return "", fmt.Errorf("failed to add template of the nested stack %s: %v", EXTRAS_STACK_NAME, err)
+	}
+
+	return split.parentTemplate(url)
+}
+
+// MovedResourcesCheckService is the CloudFormation API required for checking split templates against the deployed stacks
+type MovedResourcesCheckService interface {
+	DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error)
+}
+
+// CheckMovedResources returns an error when updating the deployed stack `stackID` to the template `filename` of `stackName` in the assets
+// moves any of its existing resources into the nested stack EXTRAS_STACK_NAME, or back out of it.
+// CloudFormation creates a moved resource before deleting the original, so that the update fails on duplicate security group rules,
+// and IAM policies are removed during the cleanup. Resources added by the update can be placed in either stack
+func CheckMovedResources(cfSvc MovedResourcesCheckService, stackID string, assets Assets, stackName string, filename string) error {
+	parent, err := assets.FindAssetByStackAndFileName(stackName, filename)
+	if err != nil {
+		return err
+	}
+	parentResources, err := templateResourceNames(parent.Content)
+	if err != nil {
+		return err
+	}
+	extrasResources := map[string]bool{}
+	for id, a := range assets.AsMap() {
+		if id.StackName == stackName && strings.HasPrefix(id.Filename, EXTRAS_TEMPLATE_ASSET_NAME+"-") {
+			if extrasResources, err = templateResourceNames(a.Content); err != nil {
+				return err
+			}
+		}
+	}
+
+	deployedParent, err := deployedResources(cfSvc, stackID)
+	if err != nil {
+		return err
+	}
+	deployedExtras := map[string]string{}
+	if extrasID := deployedParent[EXTRAS_STACK_NAME]; extrasID != "" {
+		if deployedExtras, err = deployedResources(cfSvc, extrasID); err != nil {
+			return err
+		}
+	}
+
+	moved := []string{}
+	for name := range extrasResources {
+		if _, ok := deployedParent[name]; ok {
+			moved = append(moved, name)
+		}
+	}
+	for name := range parentResources {
+		if _, ok := deployedExtras[name]; ok && name != EXTRAS_STACK_NAME {
+			moved = append(moved, name)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	sort.Strings(moved)
+	return fmt.Errorf("refusing to update the stack %s, as it moves the existing resource(s) %s between the stack and its nested stack %s to keep the template within %d bytes. "+
+		"CloudFormation creates the moved resources before deleting the originals, which fails on duplicate security group rules and removes IAM policies meanwhile. "+
+		"Reduce the resources in the stack, e.g. those appended by plugins, or recreate the cluster",
+		stackName, strings.Join(moved, ", "), EXTRAS_STACK_NAME, CFN_TEMPLATE_SIZE_LIMIT)
+}
+
+// deployedResources returns the physical IDs of the resources in the deployed stack by their logical IDs
+func deployedResources(cfSvc MovedResourcesCheckService, stackID string) (map[string]string, error) {
+	out, err := cfSvc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{StackName: aws.String(stackID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe resources of %s: %v", stackID, err)
+	}
+	resources := map[string]string{}
+	for _, r := range out.StackResources {
+		resources[aws.StringValue(r.LogicalResourceId)] = aws.StringValue(r.PhysicalResourceId)
+	}
+	return resources, nil
+}
+
+// templateResourceNames returns the logical IDs of the resources in the template
+func templateResourceNames(body string) (map[string]bool, error) {
+	tmpl := struct {
+		Resources map[string]interface{}
+	}{}
+	if err := json.Unmarshal([]byte(body), &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse stack template: %v", err)
+	}
+	names := map[string]bool{}
+	for name := range tmpl.Resources {
+		names[name] = true
+	}
+	return names, nil
+}
+
+// templateSplit is the result of splitting a template. The parent template lacks the nested stack until its template URL is known
+type templateSplit struct {
+	parent          map[string]interface{}
+	parentResources map[string]interface{}
+	extras          map[string]interface{}
+	moved           []string
+	params          map[string]interface{}
+	dependsOn       []string
+}
+
+func (s templateSplit) parentTemplate(templateURL string) (string, error) {
+	props := map[string]interface{}{
+		"TemplateURL": templateURL,
+	}
+	if len(s.params) > 0 {
+		props["Parameters"] = s.params
+	}
+	stack := map[string]interface{}{
+		"Type":       "AWS::CloudFormation::Stack",
+		"Properties": props,
+	}
+	if len(s.dependsOn) > 0 {
+		stack["DependsOn"] = s.dependsOn
+	}
+	s.parentResources[EXTRAS_STACK_NAME] = stack
+	return marshalTemplate(s.parent)
+}
+
+func splitTemplate(tmpl map[string]interface{}, extraResources []string) (*templateSplit, error) {
+	resources, ok := tmpl["Resources"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("stack template has no resources")
+	}
+	if _, exists := resources[EXTRAS_STACK_NAME]; exists {
+		return nil, fmt.Errorf("stack template is too large to be split, as the resource %s already exists", EXTRAS_STACK_NAME)
+	}
+	parameters, _ := tmpl["Parameters"].(map[string]interface{})
+	outputs, _ := tmpl["Outputs"].(map[string]interface{})
+
+	isExtra := map[string]bool{}
+	for _, n := range extraResources {
+		isExtra[n] = true
+	}
+
+	// Candidates are the splittable resources which don't depend on conditions only available in the parent stack
+	movable := map[string]bool{}
+	for name, r := range resources {
+		res, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		typ, _ := res["Type"].(string)
+		if !splittableResourceTypes[typ] && !isExtra[name] {
+			continue
+		}
+		if _, conditional := res["Condition"]; conditional || containsKey(res, "Fn::If") {
+			continue
+		}
+		movable[name] = true
+	}
+
+	// Resources referenced by the remaining ones must stay in the parent stack, which may in turn make other candidates immovable
+	for changed := true; changed; {
+		changed = false
+		for name, r := range resources {
+			if movable[name] {
+				continue
+			}
+			for _, ref := range LogicalIDsReferencedBy(r) {
+				if movable[ref] {
+					delete(movable, ref)
+					changed = true
+				}
+			}
+		}
+		for _, o := range outputs {
+			out, ok := o.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			_, conditional := out["Condition"]
+			if !conditional && !containsKey(out, "Fn::If") {
+				continue
+			}
+			for _, ref := range LogicalIDsReferencedBy(out) {
+				if movable[ref] {
+					delete(movable, ref)
+					changed = true
+				}
+			}
+		}
+	}
+
+	if len(movable) == 0 {
+		return nil, fmt.Errorf("stack template exceeds the size limit but has no resources which can be moved into a nested stack")
+	}
+
+	moved := []string{}
+	for name := range movable {
+		moved = append(moved, name)
+	}
+	sort.Strings(moved)
+
+	extrasResources := map[string]interface{}{}
+	refs := templateRefs{}
+	dependsOn := map[string]bool{}
+	for _, name := range moved {
+		res := resources[name].(map[string]interface{})
+		// Dependencies on the resources remaining in the parent stack become the dependencies of the nested stack
+		deps := []string{}
+		for _, dep := range dependencies(res) {
+			if movable[dep] {
+				deps = append(deps, dep)
+			} else {
+				dependsOn[dep] = true
+			}
+		}
+		delete(res, "DependsOn")
+		if len(deps) > 0 {
+			res["DependsOn"] = deps
+		}
+		refs.merge(collectRefs(res))
+		extrasResources[name] = res
+		delete(resources, name)
+	}
+
+	// Outputs referencing the moved resources are evaluated in the nested stack
+	extrasOutputs := map[string]interface{}{}
+	for name, o := range outputs {
+		out, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value := out["Value"]
+		referencesMoved := false
+		for _, ref := range LogicalIDsReferencedBy(value) {
+			if movable[ref] {
+				referencesMoved = true
+			}
+		}
+		if !referencesMoved {
+			continue
+		}
+		refs.merge(collectRefs(value))
+		extrasOutputs[name] = map[string]interface{}{"Value": value}
+		out["Value"] = map[string]interface{}{"Fn::GetAtt": []interface{}{EXTRAS_STACK_NAME, "Outputs." + name}}
+	}
+
+	extrasParams := map[string]interface{}{}
+	params := map[string]interface{}{}
+	addParam := func(name string, typ string, value interface{}) error {
+		if _, exists := extrasParams[name]; exists {
+			return fmt.Errorf("failed to split stack template: parameter %s of the nested stack %s is defined twice", name, EXTRAS_STACK_NAME)
+		}
+		extrasParams[name] = map[string]interface{}{"Type": typ}
+		params[name] = value
+		return nil
+	}
+
+	renames := map[string]string{}
+	for _, ref := range refs.sortedRefs() {
+		if movable[ref] {
+			continue
+		}
+		if alias, ok := stackScopedPseudoParameters[ref]; ok {
+			if err := addParam(alias, "String", map[string]interface{}{"Ref": ref}); err != nil {
+				return nil, err
+			}
+			renames[ref] = alias
+			continue
+		}
+		if strings.HasPrefix(ref, "AWS::") {
+			continue
+		}
+		if p, ok := parameters[ref].(map[string]interface{}); ok {
+			typ, _ := p["Type"].(string)
+			var value interface{} = map[string]interface{}{"Ref": ref}
+			if strings.HasPrefix(typ, "List<") || typ == "CommaDelimitedList" {
+				value = map[string]interface{}{"Fn::Join": []interface{}{",", value}}
+			}
+			if err := addParam(ref, typ, value); err != nil {
+				return nil, err
+			}
+			if noEcho, ok := p["NoEcho"]; ok {
+				extrasParams[ref].(map[string]interface{})["NoEcho"] = noEcho
+			}
+			continue
+		}
+		if _, ok := resources[ref]; ok {
+			if err := addParam(ref, "String", map[string]interface{}{"Ref": ref}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, fmt.Errorf("failed to split stack template: %s is referenced but not defined", ref)
+	}
+	for _, att := range refs.sortedAtts() {
+		if movable[att[0]] {
+			continue
+		}
+		name := attParamName(att[0], att[1])
+		if err := addParam(name, "String", map[string]interface{}{"Fn::GetAtt": []interface{}{att[0], att[1]}}); err != nil {
+			return nil, err
+		}
+		renames[att[0]+"."+att[1]] = name
+	}
+
+	for name, r := range extrasResources {
+		extrasResources[name] = rewriteRefs(r, renames, movable)
+	}
+	for name, o := range extrasOutputs {
+		extrasOutputs[name] = rewriteRefs(o, renames, movable)
+	}
+
+	extras := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "Resources moved out of the parent stack to keep its template within the size limit",
+		"Resources":                extrasResources,
+	}
+	if len(extrasParams) > 0 {
+		extras["Parameters"] = extrasParams
+	}
+	if len(extrasOutputs) > 0 {
+		extras["Outputs"] = extrasOutputs
+	}
+	if mappings, ok := tmpl["Mappings"]; ok && containsKey(extrasResources, "Fn::FindInMap") {
+		extras["Mappings"] = mappings
+	}
+
+	deps := []string{}
+	for dep := range dependsOn {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	return &templateSplit{
+		parent:          tmpl,
+		parentResources: resources,
+		extras:          extras,
+		moved:           moved,
+		params:          params,
+		dependsOn:       deps,
+	}, nil
+}
+
+// templateRefs are the names referenced via Ref or Fn::Sub, and the attributes referenced via Fn::GetAtt or Fn::Sub
+type templateRefs struct {
+	refs map[string]bool
+	atts map[[2]string]bool
+}
+
+func (r *templateRefs) addRef(name string) {
+	if r.refs == nil {
+		r.refs = map[string]bool{}
+	}
+	r.refs[name] = true
+}
+
+func (r *templateRefs) addAtt(name string, att string) {
+	if r.atts == nil {
+		r.atts = map[[2]string]bool{}
+	}
+	r.atts[[2]string{name, att}] = true
+}
+
+func (r *templateRefs) merge(other templateRefs) {
+	for n := range other.refs {
+		r.addRef(n)
+	}
+	for a := range other.atts {
+		r.addAtt(a[0], a[1])
+	}
+}
+
+func (r templateRefs) sortedRefs() []string {
+	refs := []string{}
+	for n := range r.refs {
+		refs = append(refs, n)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func (r templateRefs) sortedAtts() [][2]string {
+	atts := [][2]string{}
+	for a := range r.atts {
+		atts = append(atts, a)
+	}
+	sort.Slice(atts, func(i, j int) bool {
+		return atts[i][0]+"."+atts[i][1] < atts[j][0]+"."+atts[j][1]
+	})
+	return atts
+}
+
+func collectRefs(node interface{}) templateRefs {
+	refs := templateRefs{}
+	walkTemplate(node, func(fn string, arg interface{}) {
+		switch fn {
+		case "Ref":
+			if name, ok := arg.(string); ok {
+				refs.addRef(name)
+			}
+		case "Fn::GetAtt":
+			if name, att, ok := getAtt(arg); ok {
+				refs.addAtt(name, att)
+			}
+		case "Fn::Sub":
+			str, vars := sub(arg)
+			for _, m := range subVariablePattern.FindAllStringSubmatch(str, -1) {
+				if vars[m[1]] {
+					continue
+				}
+				if i := strings.Index(m[1], "."); i >= 0 {
+					refs.addAtt(m[1][:i], m[1][i+1:])
+				} else {
+					refs.addRef(m[1])
+				}
+			}
+		}
+	})
+	return refs
+}
+
+// rewriteRefs renames the references to the pseudo parameters and attributes passed as parameters of the nested stack
+func rewriteRefs(v interface{}, renames map[string]string, moved map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			switch k {
+			case "Ref":
+				if name, ok := e.(string); ok {
+					if alias, ok := renames[name]; ok {
+						v[k] = alias
+					}
+					continue
+				}
+			case "Fn::GetAtt":
+				if name, att, ok := getAtt(e); ok && !moved[name] {
+					if alias, ok := renames[name+"."+att]; ok {
+						delete(v, k)
+						v["Ref"] = alias
+					}
+					continue
+				}
+			case "Fn::Sub":
+				rewrite := func(str string) string {
+					return subVariablePattern.ReplaceAllStringFunc(str, func(m string) string {
+						name := m[2 : len(m)-1]
+						if alias, ok := renames[name]; ok {
+							return "${" + alias + "}"
+						}
+						return m
+					})
+				}
+				switch s := e.(type) {
+				case string:
+					v[k] = rewrite(s)
+				case []interface{}:
+					if len(s) == 2 {
+						if str, ok := s[0].(string); ok {
+							s[0] = rewrite(str)
+						}
+						s[1] = rewriteRefs(s[1], renames, moved)
+					}
+				}
+				continue
+			}
+			v[k] = rewriteRefs(e, renames, moved)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = rewriteRefs(e, renames, moved)
+		}
+	}
+	return v
+}
+
+func getAtt(v interface{}) (string, string, bool) {
+	switch v := v.(type) {
+	case string:
+		if i := strings.Index(v, "."); i > 0 {
+			return v[:i], v[i+1:], true
+		}
+	case []interface{}:
+		if len(v) == 2 {
+			name, ok1 := v[0].(string)
+			att, ok2 := v[1].(string)
+			if ok1 && ok2 {
+				return name, att, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+func sub(v interface{}) (string, map[string]bool) {
+	vars := map[string]bool{}
+	switch v := v.(type) {
+	case string:
+		return v, vars
+	case []interface{}:
+		if len(v) == 2 {
+			str, _ := v[0].(string)
+			if m, ok := v[1].(map[string]interface{}); ok {
+				for k := range m {
+					vars[k] = true
+				}
+			}
+			return str, vars
+		}
+	}
+	return "", vars
+}
+
+func dependencies(res map[string]interface{}) []string {
+	return stringOrList(res["DependsOn"])
+}
+
+func stringOrList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := []string{}
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func containsKey(v interface{}, key string) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if k == key || containsKey(e, key) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if containsKey(e, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attParamName returns the name of the parameter for the attribute of the resource, e.g. `ControllerSecurityGroupGroupId` for `ControllerSecurityGroup.GroupId`
+func attParamName(name string, att string) string {
+	return name + strings.NewReplacer(".", "", "-", "", "_", "").Replace(att)
+}
+
+func marshalTemplate(tmpl map[string]interface{}) (string, error) {
+	buf := new(bytes.Buffer)
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(tmpl); err != nil {
+		return "", fmt.Errorf("failed to marshal stack template: %v", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
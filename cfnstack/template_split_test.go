@@ -0,0 +1,278 @@
+package cfnstack
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+var oversizedTemplate = `{
+  "AWSTemplateFormatVersion": "2010-09-09",
+  "Parameters": {
+    "NetworkStackName": {"Type": "String"},
+    "Subnets": {"Type": "List<AWS::EC2::Subnet::Id>"}
+  },
+  "Conditions": {"HasFoo": {"Fn::Equals": [{"Ref": "NetworkStackName"}, "foo"]}},
+  "Resources": {
+    "IAMRoleController": {"Type": "AWS::IAM::Role", "Properties": {"Path": "/"}},
+    "SecurityGroupController": {"Type": "AWS::EC2::SecurityGroup", "Properties": {"GroupDescription": {"Ref": "AWS::StackName"}}},
+    "IAMPolicyLarge": {
+      "Type": "AWS::IAM::Policy",
+      "Properties": {
+        "PolicyName": {"Fn::Sub": "${AWS::StackName}-large"},
+        "Roles": [{"Ref": "IAMRoleController"}],
+        "PolicyDocument": {"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "` + strings.Repeat("a", 1200) + `"}]}
+      },
+      "DependsOn": ["SecurityGroupController", "SecurityGroupIngressSelf"]
+    },
+    "SecurityGroupIngressSelf": {
+      "Type": "AWS::EC2::SecurityGroupIngress",
+      "Properties": {
+        "GroupId": {"Fn::GetAtt": ["SecurityGroupController", "GroupId"]},
+        "SourceSecurityGroupId": {"Fn::Sub": "${SecurityGroupController.GroupId}"},
+        "Description": {"Fn::Join": [",", {"Ref": "Subnets"}]}
+      }
+    },
+    "SecurityGroupIngressConditional": {
+      "Type": "AWS::EC2::SecurityGroupIngress",
+      "Condition": "HasFoo",
+      "Properties": {"GroupId": {"Ref": "SecurityGroupController"}}
+    },
+    "IAMManagedPolicyReferenced": {"Type": "AWS::IAM::ManagedPolicy", "Properties": {"PolicyDocument": {}}},
+    "IAMRoleWorker": {"Type": "AWS::IAM::Role", "Properties": {"ManagedPolicyArns": [{"Ref": "IAMManagedPolicyReferenced"}]}},
+    "PluginTopic": {"Type": "AWS::SNS::Topic", "Properties": {"DisplayName": {"Ref": "NetworkStackName"}}}
+  },
+  "Outputs": {
+    "PluginTopicArn": {
+      "Value": {"Ref": "PluginTopic"},
+      "Export": {"Name": {"Fn::Sub": "${AWS::StackName}-PluginTopicArn"}}
+    },
+    "StackName": {"Value": {"Ref": "AWS::StackName"}}
+  }
+}`
+
+func TestSplitTemplate(t *testing.T) {
+	t.Run("WithinLimit", func(t *testing.T) {
+		body, err := SplitTemplate(oversizedTemplate, len(oversizedTemplate), nil, func(string) (string, error) {
+			t.Fatal("the template shouldn't be split")
+			return "", nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if body != oversizedTemplate {
+			t.Errorf("expected the template to be unchanged but was: %s", body)
+		}
+	})
+
+	t.Run("Oversized", func(t *testing.T) {
+		var extras string
+		body, err := SplitTemplate(oversizedTemplate, 2500, []string{"PluginTopic"}, func(e string) (string, error) {
+			extras = e
+			return "https://s3.amazonaws.com/mybucket/control-plane/extras-abc", nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(body) > 2500 {
+			t.Errorf("expected the template to be within the limit but was %d bytes", len(body))
+		}
+
+		for name, tmpl := range map[string]string{"parent": body, "extras": extras} {
+			if err := ValidateTemplateLocally(tmpl); err != nil {
+				t.Errorf("expected the %s template to be valid but it wasn't: %v\n%s", name, err, tmpl)
+			}
+		}
+
+		parent := parseTemplate(t, body)
+		nested := parseTemplate(t, extras)
+
+		expectedParentResources := []string{"Extras", "IAMManagedPolicyReferenced", "IAMRoleController", "IAMRoleWorker", "SecurityGroupController", "SecurityGroupIngressConditional"}
+		if actual := keys(parent["Resources"]); !reflect.DeepEqual(actual, expectedParentResources) {
+			t.Errorf("unexpected resources in the parent template: %v", actual)
+		}
+		expectedExtrasResources := []string{"IAMPolicyLarge", "PluginTopic", "SecurityGroupIngressSelf"}
+		if actual := keys(nested["Resources"]); !reflect.DeepEqual(actual, expectedExtrasResources) {
+			t.Errorf("unexpected resources in the extras template: %v", actual)
+		}
+
+		expectedStack := `{"DependsOn":["SecurityGroupController"],"Properties":{"Parameters":{"IAMRoleController":{"Ref":"IAMRoleController"},"NetworkStackName":{"Ref":"NetworkStackName"},"ParentStackName":{"Ref":"AWS::StackName"},"SecurityGroupControllerGroupId":{"Fn::GetAtt":["SecurityGroupController","GroupId"]},"Subnets":{"Fn::Join":[",",{"Ref":"Subnets"}]}},"TemplateURL":"https://s3.amazonaws.com/mybucket/control-plane/extras-abc"},"Type":"AWS::CloudFormation::Stack"}`
+		if actual := toJSON(t, parent["Resources"].(map[string]interface{})["Extras"]); actual != expectedStack {
+			t.Errorf("unexpected extras stack:\nexpected: %s\nactual:   %s", expectedStack, actual)
+		}
+
+		expectedParams := []string{"IAMRoleController", "NetworkStackName", "ParentStackName", "SecurityGroupControllerGroupId", "Subnets"}
+		if actual := keys(nested["Parameters"]); !reflect.DeepEqual(actual, expectedParams) {
+			t.Errorf("unexpected parameters in the extras template: %v", actual)
+		}
+
+		expectedPolicy := `{"DependsOn":["SecurityGroupIngressSelf"],"Properties":{"PolicyDocument":{"Statement":[{"Action":"s3:GetObject","Effect":"Allow","Resource":"` + strings.Repeat("a", 1200) + `"}]},"PolicyName":{"Fn::Sub":"${ParentStackName}-large"},"Roles":[{"Ref":"IAMRoleController"}]},"Type":"AWS::IAM::Policy"}`
+		if actual := toJSON(t, nested["Resources"].(map[string]interface{})["IAMPolicyLarge"]); actual != expectedPolicy {
+			t.Errorf("unexpected policy:\nexpected: %s\nactual:   %s", expectedPolicy, actual)
+		}
+
+		expectedIngress := `{"Properties":{"Description":{"Fn::Join":[",",{"Ref":"Subnets"}]},"GroupId":{"Ref":"SecurityGroupControllerGroupId"},"SourceSecurityGroupId":{"Fn::Sub":"${SecurityGroupControllerGroupId}"}},"Type":"AWS::EC2::SecurityGroupIngress"}`
+		if actual := toJSON(t, nested["Resources"].(map[string]interface{})["SecurityGroupIngressSelf"]); actual != expectedIngress {
+			t.Errorf("unexpected ingress:\nexpected: %s\nactual:   %s", expectedIngress, actual)
+		}
+
+		expectedOutput := `{"Export":{"Name":{"Fn::Sub":"${AWS::StackName}-PluginTopicArn"}},"Value":{"Fn::GetAtt":["Extras","Outputs.PluginTopicArn"]}}`
+		if actual := toJSON(t, parent["Outputs"].(map[string]interface{})["PluginTopicArn"]); actual != expectedOutput {
+			t.Errorf("unexpected output:\nexpected: %s\nactual:   %s", expectedOutput, actual)
+		}
+		if actual := toJSON(t, nested["Outputs"]); actual != `{"PluginTopicArn":{"Value":{"Ref":"PluginTopic"}}}` {
+			t.Errorf("unexpected outputs of the extras template: %s", actual)
+		}
+	})
+
+	errorCases := []struct {
+		context  string
+		template string
+		limit    int
+		expected string
+	}{
+		{
+			context:  "NothingMovable",
+			template: `{"Resources":{"Role":{"Type":"AWS::IAM::Role","Properties":{"Path":"/"}}}}`,
+			limit:    10,
+			expected: "has no resources which can be moved",
+		},
+		{
+			context:  "StillOversized",
+			template: oversizedTemplate,
+			limit:    1000,
+			expected: "even after moving 2 resource(s)",
+		},
+		{
+			context:  "ExtrasAlreadyExists",
+			template: `{"Resources":{"Extras":{"Type":"AWS::SNS::Topic"}}}`,
+			limit:    10,
+			expected: "the resource Extras already exists",
+		},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.context, func(t *testing.T) {
+			_, err := SplitTemplate(tc.template, tc.limit, nil, func(string) (string, error) {
+				return "https://example.com/extras", nil
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error containing %q but was %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckMovedResources(t *testing.T) {
+	buildAssets := func(limit int) Assets {
+		defaultLimit := CFN_TEMPLATE_SIZE_LIMIT
+		CFN_TEMPLATE_SIZE_LIMIT = limit
+		defer func() { CFN_TEMPLATE_SIZE_LIMIT = defaultLimit }()
+
+		b := NewAssetsBuilder("control-plane", "s3://mybucket/mydir", model.RegionForName("us-west-1"))
+		if err := b.AddStackTemplate("stack.json", oversizedTemplate, map[string]interface{}{"PluginTopic": nil}); err != nil {
+			t.Fatalf("failed to add stack template: %v", err)
+		}
+		return b.Build()
+	}
+	split := buildAssets(2500)
+	unsplit := buildAssets(len(oversizedTemplate))
+
+	stackResources := func(logicalIDs ...string) []*cloudformation.StackResource {
+		resources := []*cloudformation.StackResource{}
+		for _, id := range logicalIDs {
+			physicalID := "physical-" + id
+			if id == EXTRAS_STACK_NAME {
+				physicalID = "extras-stack-id"
+			}
+			resources = append(resources, &cloudformation.StackResource{LogicalResourceId: aws.String(id), PhysicalResourceId: aws.String(physicalID)})
+		}
+		return resources
+	}
+	unsplitResources := stackResources("IAMRoleController", "SecurityGroupController", "IAMPolicyLarge", "SecurityGroupIngressSelf", "IAMManagedPolicyReferenced", "IAMRoleWorker")
+
+	testCases := []struct {
+		context   string
+		assets    Assets
+		resources map[string][]*cloudformation.StackResource
+		expected  string
+	}{
+		{
+			context:   "SplitOnCreate",
+			assets:    split,
+			resources: map[string][]*cloudformation.StackResource{},
+		},
+		{
+			context:   "Unsplit",
+			assets:    unsplit,
+			resources: map[string][]*cloudformation.StackResource{"stack-id": unsplitResources},
+		},
+		{
+			context: "AlreadySplit",
+			assets:  split,
+			resources: map[string][]*cloudformation.StackResource{
+				"stack-id":        stackResources("IAMRoleController", "SecurityGroupController", "IAMManagedPolicyReferenced", "IAMRoleWorker", "Extras"),
+				"extras-stack-id": stackResources("IAMPolicyLarge", "SecurityGroupIngressSelf"),
+			},
+		},
+		{
+			context:   "MovingExistingResources",
+			assets:    split,
+			resources: map[string][]*cloudformation.StackResource{"stack-id": unsplitResources},
+			expected:  "moves the existing resource(s) IAMPolicyLarge, SecurityGroupIngressSelf between the stack and its nested stack Extras",
+		},
+		{
+			context: "MovingBackExistingResources",
+			assets:  unsplit,
+			resources: map[string][]*cloudformation.StackResource{
+				"stack-id":        stackResources("IAMRoleController", "SecurityGroupController", "IAMManagedPolicyReferenced", "IAMRoleWorker", "Extras"),
+				"extras-stack-id": stackResources("IAMPolicyLarge", "PluginTopic", "SecurityGroupIngressSelf"),
+			},
+			expected: "moves the existing resource(s) IAMPolicyLarge, PluginTopic, SecurityGroupIngressSelf between",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.context, func(t *testing.T) {
+			cfSvc := dummyAssetsGCCloudFormationService{resources: tc.resources}
+			err := CheckMovedResources(cfSvc, "stack-id", tc.assets, "control-plane", "stack.json")
+			if tc.expected == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.expected != "" && (err == nil || !strings.Contains(err.Error(), tc.expected)) {
+				t.Errorf("expected error containing %q but was %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func parseTemplate(t *testing.T, body string) map[string]interface{} {
+	tmpl := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(body), &tmpl); err != nil {
+		t.Fatalf("failed to parse template: %v\n%s", err, body)
+	}
+	return tmpl
+}
+
+func keys(v interface{}) []string {
+	ks := []string{}
+	for k := range v.(map[string]interface{}) {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal %v: %v", v, err)
+	}
+	return string(bytes)
+}
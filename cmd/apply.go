@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdApply = &cobra.Command{
+		Use:          "apply PLAN_FILE",
+		Short:        "Update an existing Kubernetes cluster by applying the plan saved with \"kube-aws update --plan-out\"",
+		Long:         ``,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCmdApply,
+		SilenceUsage: true,
+	}
+
+	applyOpts = struct {
+		awsDebug, force bool
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdApply)
+	cmdApply.Flags().BoolVar(&applyOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdApply.Flags().BoolVar(&applyOpts.force, "force", false, "Don't ask for confirmation")
+}
+
+func runCmdApply(_ *cobra.Command, args []string) error {
+	plan, err := root.PlanFromFile(args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to read plan: %v", err)
+	}
+
+	if !applyOpts.force && !updateConfirmation() {
+		fmt.Println("Operation cancelled")
+		return nil
+	}
+
+	opts := root.NewOptions(false, false)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	cluster, err := root.ClusterFromFile(configPath, opts, applyOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	if err := printCallerIdentity(cluster); err != nil {
+		return err
+	}
+
+	if err := cluster.Apply(plan); err != nil {
+		return fmt.Errorf("Error applying plan: %v", err)
+	}
+
+	info, err := cluster.Info()
+	if err != nil {
+		return fmt.Errorf("Failed fetching cluster info: %v", err)
+	}
+
+	successMsg :=
+		`Success! Your AWS resources are being updated:
+%s
+`
+	fmt.Printf(successMsg, info.String())
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdAssets = &cobra.Command{
+		Use:          "assets",
+		Short:        "Manage the assets of the cluster uploaded to S3",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdAssetsGC = &cobra.Command{
+		Use:   "gc",
+		Short: "Delete assets no longer referenced by the cluster stacks from S3",
+		Long: `Deletes the assets under "<s3URI>/kube-aws/clusters/<clusterName>/exported/stacks/" which are referenced by none of the currently deployed templates of the cluster stacks.
+Only assets named with the fingerprints of their contents, like userdata, are deleted, as the others are overwritten on every upload.
+The latest generations of each asset are kept even when unreferenced, so that the stacks can still be rolled back to them.`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdAssetsGC,
+		SilenceUsage: true,
+	}
+
+	assetsGCOpts = struct {
+		awsDebug, dryRun, force bool
+		keep                    int
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdAssets)
+	cmdAssets.AddCommand(cmdAssetsGC)
+	cmdAssetsGC.Flags().BoolVar(&assetsGCOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdAssetsGC.Flags().BoolVar(&assetsGCOpts.dryRun, "dry-run", false, "Only list the assets to be deleted")
+	cmdAssetsGC.Flags().BoolVar(&assetsGCOpts.force, "force", false, "Don't ask for confirmation")
+	cmdAssetsGC.Flags().IntVar(&assetsGCOpts.keep, "keep", 3, "Number of the latest unreferenced generations of each asset to keep")
+}
+
+func runCmdAssetsGC(_ *cobra.Command, _ []string) error {
+	opts := root.NewOptions(false, false)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	cluster, err := root.ClusterFromFile(configPath, opts, assetsGCOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	garbage, err := cluster.GarbageAssets(assetsGCOpts.keep)
+	if err != nil {
+		return fmt.Errorf("Failed to find unreferenced assets: %v", err)
+	}
+
+	if len(garbage.Objects) == 0 {
+		fmt.Println("No assets to delete")
+		return nil
+	}
+
+	fmt.Print(garbage.String())
+
+	if assetsGCOpts.dryRun {
+		return nil
+	}
+
+	if err := printCallerIdentity(cluster); err != nil {
+		return err
+	}
+
+	if !assetsGCOpts.force && !assetsGCConfirmation(len(garbage.Objects)) {
+		fmt.Println("Operation cancelled")
+		return nil
+	}
+
+	if err := cluster.DeleteGarbageAssets(garbage); err != nil {
+		return fmt.Errorf("Failed to delete assets: %v", err)
+	}
+
+	fmt.Printf("Deleted %d asset(s)\n", len(garbage.Objects))
+	return nil
+}
+
+func assetsGCConfirmation(count int) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("\nThis operation will delete the %d asset(s) above. Are you sure? [y,n]: ", count)
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+// awsCredentialsOpts overrides the awsCredentials in cluster.yaml
+var awsCredentialsOpts = model.AWSCredentials{}
+
+func init() {
+	creds := &awsCredentialsOpts
+	RootCmd.PersistentFlags().StringVar(&creds.Profile, "profile", "", "Profile in the AWS shared config and credentials files to call AWS APIs with. Overrides awsCredentials.profile in cluster.yaml and AWS_PROFILE")
+	RootCmd.PersistentFlags().StringVar(&creds.RoleARN, "role-arn", "", "ARN of the IAM role to assume with the credentials of the profile before calling AWS APIs. Overrides awsCredentials.roleArn in cluster.yaml")
+	RootCmd.PersistentFlags().StringVar(&creds.ExternalID, "external-id", "", "External ID to assume the role with. Overrides awsCredentials.externalId in cluster.yaml")
+	RootCmd.PersistentFlags().StringVar(&creds.SessionDuration, "session-duration", "", "Duration of the session of the assumed role like 1h, between 15m and 12h. Overrides awsCredentials.sessionDuration in cluster.yaml")
+}
+
+type callerIdentityProvider interface {
+	CallerIdentity() (*awsconn.CallerIdentity, error)
+}
+
+// printCallerIdentity prints who the AWS resources are about to be modified as, so that a wrong account or role can be noticed before any change
+func printCallerIdentity(p callerIdentityProvider) error {
+	identity, err := p.CallerIdentity()
+	if err != nil {
+		return fmt.Errorf("Failed to resolve AWS credentials: %v", err)
+	}
+	fmt.Printf("Calling AWS APIs as %s\n", identity.String())
+	return nil
+}
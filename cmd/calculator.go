@@ -5,51 +5,109 @@ import (
 	"strings"
 
 	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/pricing"
 	"github.com/spf13/cobra"
 )
 
-//TODO this is a first step to calculate the stack cost
-//this command could scrap aws to print the total cost, rather just showing the link
-
 var (
 	cmdCalculator = &cobra.Command{
-		Use:          "calculator",
-		Short:        "Discover the monthly cost of your cluster",
-		Long:         ``,
+		Use:   "calculator",
+		Short: "Discover the monthly cost of your cluster",
+		Long: `Estimates the monthly cost of the cluster per stack from a price table, without calling AWS APIs.
+The cost is broken down by EC2 instances, EBS volumes, NAT gateways, load balancers and EIPs, and shown for
+auto-scaling groups at their min size, desired capacity and max size.`,
 		RunE:         runCmdCalculator,
 		SilenceUsage: true,
 	}
 
 	calculatorOpts = struct {
-		awsDebug bool
+		awsDebug, urls bool
+		priceFile      string
 	}{}
 )
 
 func init() {
 	RootCmd.AddCommand(cmdCalculator)
 	cmdCalculator.Flags().BoolVar(&calculatorOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdCalculator.Flags().StringVar(&calculatorOpts.priceFile, "price-file", "", "Path to a JSON price table overriding prices in the bundled one")
+	cmdCalculator.Flags().BoolVar(&calculatorOpts.urls, "urls", false, "Also print links to the AWS Simple Monthly Calculator. Requires uploading the assets to S3 and calling CloudFormation")
 }
 
 func runCmdCalculator(_ *cobra.Command, _ []string) error {
+	structured, err := structuredOutput()
+	if err != nil {
+		return err
+	}
+
+	var estimate *root.CostEstimate
+	run := func() error {
+		var err error
+		estimate, err = estimateCost()
+		return err
+	}
+	if structured {
+		err = withProgressToStderr(run)
+	} else {
+		err = run()
+	}
+	if err != nil {
+		return err
+	}
+
+	if structured {
+		return printStructured(estimate)
+	}
+
+	fmt.Print(estimate.String())
+
+	if len(estimate.URLs) > 0 {
+		fmt.Printf("\nTo estimate your monthly cost with the AWS Simple Monthly Calculator, open the links below\n%v\n", strings.Join(estimate.URLs, "\n"))
+	}
+
+	return nil
+}
+
+func estimateCost() (*root.CostEstimate, error) {
+	prices, err := pricing.DefaultPriceTable()
+	if err != nil {
+		return nil, err
+	}
+	if calculatorOpts.priceFile != "" {
+		override, err := pricing.PriceTableFromFile(calculatorOpts.priceFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read price file: %v", err)
+		}
+		prices = prices.Merge(override)
+	}
+
+	estimate, err := root.EstimateMonthlyCostFromFile(configPath, prices)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to estimate cost: %v", err)
+	}
+
+	if !calculatorOpts.urls {
+		return estimate, nil
+	}
 
 	opts := root.NewOptions(false, false)
+	opts.AWSCredentials = awsCredentialsOpts
 
 	cluster, err := root.ClusterFromFile(configPath, opts, calculatorOpts.awsDebug)
 	if err != nil {
-		return fmt.Errorf("Failed to initialize cluster driver: %v", err)
+		return nil, fmt.Errorf("Failed to initialize cluster driver: %v", err)
 	}
 
 	if _, err := cluster.ValidateStack(); err != nil {
-		return fmt.Errorf("Error validating cluster: %v", err)
+		return nil, fmt.Errorf("Error validating cluster: %v", err)
 	}
 
 	urls, err := cluster.EstimateCost()
 
 	if err != nil {
-		return fmt.Errorf("%v", err)
+		return nil, fmt.Errorf("%v", err)
 	}
 
-	fmt.Printf("To estimate your monthly cost, open the links below\n%v", strings.Join(urls, "\n"))
+	estimate.URLs = urls
 
-	return nil
+	return estimate, nil
 }
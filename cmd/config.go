@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdConfig = &cobra.Command{
+		Use:          "config",
+		Short:        "Manage cluster.yaml",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdConfigMigrate = &cobra.Command{
+		Use:          "migrate",
+		Short:        "Rewrite deprecated and renamed keys in cluster.yaml",
+		Long:         ``,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdConfigMigrate,
+		SilenceUsage: true,
+	}
+
+	configMigrateOpts = struct {
+		dryRun bool
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdConfig)
+	cmdConfig.AddCommand(cmdConfigMigrate)
+	cmdConfigMigrate.Flags().BoolVar(&configMigrateOpts.dryRun, "dry-run", false, "Print the diff of cluster.yaml without rewriting it")
+}
+
+func runCmdConfigMigrate(_ *cobra.Command, _ []string) error {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	r, err := config.MigrateConfig(data)
+	if err != nil {
+		return fmt.Errorf("Failed to migrate %s: %v", configPath, err)
+	}
+
+	if len(r.Applied) == 0 {
+		fmt.Printf("%s is up to date\n", configPath)
+		return nil
+	}
+
+	fmt.Println("Migrations to be applied:")
+	for _, m := range r.Applied {
+		fmt.Printf("  %d. %s\n", m.Version, m.Description)
+		if m.Note != "" {
+			fmt.Printf("     NOTE: %s\n", m.Note)
+		}
+	}
+	fmt.Println("")
+
+	if configMigrateOpts.dryRun {
+		fmt.Print(r.Diff(configPath))
+		return nil
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", configPath, time.Now().Format("20060102150405"))
+	if err := ioutil.WriteFile(backupPath, r.Original, info.Mode()); err != nil {
+		return fmt.Errorf("Failed to back up %s: %v", configPath, err)
+	}
+
+	if err := ioutil.WriteFile(configPath, r.Migrated, info.Mode()); err != nil {
+		return fmt.Errorf("Failed to write %s: %v", configPath, err)
+	}
+
+	fmt.Printf("Migrated %s. The original is saved to %s\n", configPath, backupPath)
+	return nil
+}
@@ -20,36 +20,55 @@ var (
 		SilenceUsage: true,
 	}
 	destroyOpts = root.DestroyOptions{}
+
+	destroyTargets []string
 )
 
 func init() {
 	RootCmd.AddCommand(cmdDestroy)
 	cmdDestroy.Flags().BoolVar(&destroyOpts.AwsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
 	cmdDestroy.Flags().BoolVar(&destroyOpts.Force, "force", false, "Don't ask for confirmation")
+	cmdDestroy.Flags().StringSliceVar(&destroyTargets, "targets", root.AllOperationTargetsAsStringSlice(), "Destroy nothing but specified sub-stacks.  Specify `all` or any combination of `etcd`, `control-plane`, and node pool names. Defaults to `all`")
 }
 
 func runCmdDestroy(_ *cobra.Command, _ []string) error {
-	if !destroyOpts.Force && !destroyConfirmation() {
-		fmt.Printf("Operation Cancelled")
-		return nil
-	}
+	destroyOpts.Targets = root.OperationTargetsFromStringSlice(destroyTargets)
+	destroyOpts.AWSCredentials = awsCredentialsOpts
 
 	c, err := root.ClusterDestroyerFromFile(configPath, destroyOpts)
 	if err != nil {
 		return fmt.Errorf("Error parsing config: %v", err)
 	}
 
+	if err := printCallerIdentity(c); err != nil {
+		return err
+	}
+
+	if !destroyOpts.Force && !destroyConfirmation() {
+		fmt.Printf("Operation Cancelled")
+		return nil
+	}
+
 	if err := c.Destroy(); err != nil {
 		return fmt.Errorf("Failed destroying cluster: %v", err)
 	}
 
+	if !destroyOpts.Targets.IsAll() {
+		fmt.Printf("Destroyed %s. Remove them from %s too, or the next \"kube-aws update\" will recreate them\n", destroyOpts.Targets.String(), configPath)
+		return nil
+	}
+
 	fmt.Println("CloudFormation stack is being destroyed. This will take several minutes")
 	return nil
 }
 
 func destroyConfirmation() bool {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("This operation will destroy the cluster. Are you sure? [y,n]: ")
+	if destroyOpts.Targets.IsAll() {
+		fmt.Print("This operation will destroy the cluster. Are you sure? [y,n]: ")
+	} else {
+		fmt.Printf("This operation will destroy %s. Are you sure? [y,n]: ", destroyOpts.Targets.String())
+	}
 	text, _ := reader.ReadString('\n')
 	text = strings.TrimSuffix(strings.ToLower(text), "\n")
 
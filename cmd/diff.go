@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDiff = &cobra.Command{
+		Use:   "diff",
+		Short: "Preview changes to an existing Kubernetes cluster",
+		Long: `Uploads the assets and creates CloudFormation change sets for the root stack and each of the nested stacks
+to show which resources would be added, modified, removed or replaced by "kube-aws update".
+The change sets are deleted afterwards, so nothing is changed in the cluster.`,
+		RunE:         runCmdDiff,
+		SilenceUsage: true,
+	}
+
+	diffOpts = struct {
+		awsDebug, prettyPrint bool
+		targets               []string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdDiff)
+	cmdDiff.Flags().BoolVar(&diffOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdDiff.Flags().BoolVar(&diffOpts.prettyPrint, "pretty-print", false, "Pretty print the resulting CloudFormation")
+	cmdDiff.Flags().StringSliceVar(&diffOpts.targets, "targets", root.AllOperationTargetsAsStringSlice(), "Preview changes to nothing but specified sub-stacks.  Specify `all` or any combination of `etcd`, `control-plane`, and node pool names. Defaults to `all`")
+}
+
+func runCmdDiff(_ *cobra.Command, _ []string) error {
+	opts := root.NewOptions(diffOpts.prettyPrint, false)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	cluster, err := root.ClusterFromFile(configPath, opts, diffOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	targets := root.OperationTargetsFromStringSlice(diffOpts.targets)
+
+	changeSets, err := cluster.Diff(targets)
+	if err != nil {
+		return fmt.Errorf("Error previewing changes: %v", err)
+	}
+
+	fmt.Printf("\n%s", changeSets.String())
+
+	return nil
+}
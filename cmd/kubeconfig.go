@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdKubeconfig = &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Generate a kubeconfig for the cluster",
+		Long: `Generates a kubeconfig for one of the API endpoints of the cluster.
+By default it authenticates with the admin client cert in the credentials directory.
+Specify --user to issue a new client cert signed by the cluster CA, or --oidc to authenticate via the OIDC provider in experimental.oidc`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdKubeconfig,
+		SilenceUsage: true,
+	}
+
+	kubeconfigOpts = struct {
+		root.KubeconfigOptions
+		outputFile string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdKubeconfig)
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.APIEndpointName, "api-endpoint", "", "Name of the API endpoint in apiEndpoints. Defaults to adminAPIEndpointName or the only API endpoint")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.User, "user", "", "Issue a client cert for this username, signed by the cluster CA")
+	cmdKubeconfig.Flags().StringSliceVar(&kubeconfigOpts.Groups, "groups", nil, "Groups of the user specified via --user")
+	cmdKubeconfig.Flags().BoolVar(&kubeconfigOpts.OIDC, "oidc", false, "Authenticate via the OIDC provider configured in experimental.oidc")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.OIDCClientSecret, "oidc-client-secret", "", "Client secret of the OIDC client specified via experimental.oidc.clientId")
+	cmdKubeconfig.Flags().StringSliceVar(&kubeconfigOpts.OIDCExtraScopes, "oidc-extra-scopes", nil, "Scopes requested in addition to openid, e.g. groups and email for the claims in experimental.oidc")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.OIDCCAPath, "oidc-ca-path", "", "path to pem-encoded CA x509 certificate of the OIDC provider. Embedded with --embed-certs")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.CACertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.CAKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA or ECDSA key, used for issuing a client cert for --user")
+	cmdKubeconfig.Flags().IntVar(&kubeconfigOpts.Days, "days", 0, "Validity in days of the client cert issued for --user. Defaults to tlsCertDurationDays")
+	cmdKubeconfig.Flags().BoolVar(&kubeconfigOpts.EmbedCerts, "embed-certs", false, "Embed certs in the kubeconfig instead of referring to files in the credentials directory")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.outputFile, "output-file", "", "Write the kubeconfig to this file instead of stdout")
+}
+
+func runCmdKubeconfig(_ *cobra.Command, _ []string) error {
+	var data []byte
+	err := withProgressToStderr(func() error {
+		var err error
+		data, err = root.KubeconfigFromFile(configPath, kubeconfigOpts.KubeconfigOptions)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to generate kubeconfig: %v", err)
+	}
+
+	if kubeconfigOpts.outputFile == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+
+	// The kubeconfig may contain client keys
+	if err := ioutil.WriteFile(kubeconfigOpts.outputFile, data, 0600); err != nil {
+		return fmt.Errorf("Failed to write kubeconfig: %v", err)
+	}
+	fmt.Printf("Wrote kubeconfig to %s\n", kubeconfigOpts.outputFile)
+	return nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdLogs = &cobra.Command{
+		Use:   "logs",
+		Short: "Print journald logs of the cluster nodes",
+		Long: `Prints journald logs sent from the cluster nodes to the CloudWatch Logs group named after the cluster.
+Requires cloudWatchLogging.enabled in cluster.yaml. A message repeated within cloudWatchLogging.localStreaming.interval is printed only once`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdLogs,
+		SilenceUsage: true,
+	}
+
+	logsOpts = struct {
+		root.LogsOptions
+		awsDebug bool
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdLogs)
+	cmdLogs.Flags().StringSliceVar(&logsOpts.Hosts, "host", nil, "Only print logs from the nodes with these hostnames or instance IDs")
+	cmdLogs.Flags().StringSliceVar(&logsOpts.Units, "unit", nil, "Only print logs from these systemd units. \".service\" is appended to a unit without a suffix")
+	cmdLogs.Flags().StringSliceVar(&logsOpts.NodePools, "node-pool", nil, "Only print logs from the nodes in these node pools")
+	cmdLogs.Flags().DurationVar(&logsOpts.Since, "since", 10*time.Minute, "Print logs newer than this relative duration, e.g. 30s, 5m or 2h")
+	cmdLogs.Flags().BoolVarP(&logsOpts.Follow, "follow", "f", false, "Keep printing new logs until interrupted")
+	cmdLogs.Flags().BoolVar(&logsOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+}
+
+func runCmdLogs(_ *cobra.Command, _ []string) error {
+	q := make(chan struct{})
+	if logsOpts.Follow {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		defer signal.Stop(sig)
+		go func() {
+			<-sig
+			close(q)
+		}()
+	}
+
+	if err := root.StreamLogsFromFile(configPath, logsOpts.LogsOptions, awsCredentialsOpts, logsOpts.awsDebug, os.Stdout, q); err != nil {
+		return fmt.Errorf("Failed to print logs: %v", err)
+	}
+	return nil
+}
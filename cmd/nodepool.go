@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNodePool = &cobra.Command{
+		Use:          "nodepool",
+		Short:        "Manage node pools in cluster.yaml",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdNodePoolAdd = &cobra.Command{
+		Use:          "add NAME",
+		Short:        "Add a node pool to cluster.yaml",
+		Long:         ``,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCmdNodePoolAdd,
+		SilenceUsage: true,
+	}
+
+	cmdNodePoolRemove = &cobra.Command{
+		Use:          "remove NAME",
+		Short:        "Remove a node pool from cluster.yaml",
+		Long:         ``,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCmdNodePoolRemove,
+		SilenceUsage: true,
+	}
+
+	cmdNodePoolList = &cobra.Command{
+		Use:          "list",
+		Short:        "List node pools in cluster.yaml",
+		Long:         ``,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdNodePoolList,
+		SilenceUsage: true,
+	}
+
+	nodePoolAddOpts = config.NodePoolOptions{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdNodePool)
+	cmdNodePool.AddCommand(cmdNodePoolAdd)
+	cmdNodePool.AddCommand(cmdNodePoolRemove)
+	cmdNodePool.AddCommand(cmdNodePoolList)
+
+	cmdNodePoolAdd.Flags().StringVar(&nodePoolAddOpts.InstanceType, "instance-type", "", "Instance type for worker nodes. Defaults to `workerInstanceType` in cluster.yaml")
+	cmdNodePoolAdd.Flags().IntVar(&nodePoolAddOpts.Count, "count", 0, "Number of worker nodes, or the target capacity of the spot fleet with --spot-fleet")
+	cmdNodePoolAdd.Flags().IntVar(&nodePoolAddOpts.MinSize, "min-size", 0, "Minimum size of the auto scaling group. Requires --max-size")
+	cmdNodePoolAdd.Flags().IntVar(&nodePoolAddOpts.MaxSize, "max-size", 0, "Maximum size of the auto scaling group")
+	cmdNodePoolAdd.Flags().StringSliceVar(&nodePoolAddOpts.Subnets, "subnets", []string{}, "Names of the subnets defined under the top-level `subnets` in cluster.yaml to deploy worker nodes to")
+	cmdNodePoolAdd.Flags().BoolVar(&nodePoolAddOpts.SpotFleet, "spot-fleet", false, "Launch worker nodes with a spot fleet instead of an auto scaling group")
+	cmdNodePoolAdd.Flags().StringVar(&nodePoolAddOpts.SpotPrice, "spot-price", "", "Maximum hourly price to pay for a spot instance")
+}
+
+func runCmdNodePoolAdd(_ *cobra.Command, args []string) error {
+	nodePoolAddOpts.Name = args[0]
+	return editConfigFile(func(data []byte) ([]byte, error) {
+		return config.AddNodePool(data, nodePoolAddOpts)
+	}, fmt.Sprintf("Added node pool %s to %s. Run \"kube-aws update\" to create it", args[0], configPath))
+}
+
+func runCmdNodePoolRemove(_ *cobra.Command, args []string) error {
+	return editConfigFile(func(data []byte) ([]byte, error) {
+		return config.RemoveNodePool(data, args[0])
+	}, fmt.Sprintf("Removed node pool %s from %s. Run \"kube-aws update\" to delete it", args[0], configPath))
+}
+
+func runCmdNodePoolList(_ *cobra.Command, _ []string) error {
+	structured, err := structuredOutput()
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	pools, err := config.ListNodePools(data)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	if structured {
+		return printStructured(pools)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tINSTANCE TYPES\tSIZE\tSUBNETS")
+	for _, p := range pools {
+		kind, size := "asg", fmt.Sprintf("%d", p.Count)
+		if p.SpotFleet {
+			kind = "spot-fleet"
+		} else if p.MaxSize != 0 {
+			size = fmt.Sprintf("%d-%d", p.MinSize, p.MaxSize)
+		}
+		subnets := "default"
+		if len(p.Subnets) > 0 {
+			subnets = strings.Join(p.Subnets, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, kind, strings.Join(p.InstanceTypes, ","), size, subnets)
+	}
+	return w.Flush()
+}
+
+// editConfigFile rewrites cluster.yaml with the result of edit, keeping the file mode
+func editConfigFile(edit func([]byte) ([]byte, error), successMsg string) error {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	edited, err := edit(data)
+	if err != nil {
+		return fmt.Errorf("Failed to edit %s: %v", configPath, err)
+	}
+
+	if err := ioutil.WriteFile(configPath, edited, info.Mode()); err != nil {
+		return fmt.Errorf("Failed to write %s: %v", configPath, err)
+	}
+
+	fmt.Println(successMsg)
+	return nil
+}
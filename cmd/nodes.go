@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNodes = &cobra.Command{
+		Use:   "nodes",
+		Short: "List EC2 instances in the cluster",
+		Long: `Lists every EC2 instance in the cluster by role and node pool, discovered by the cluster's stack names and tags.
+Instances still running userdata older than the one in their current launch configuration or spot fleet are flagged as outdated.`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdNodes,
+		SilenceUsage: true,
+	}
+
+	nodesOpts = struct {
+		awsDebug, outdated bool
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdNodes)
+	cmdNodes.Flags().BoolVar(&nodesOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdNodes.Flags().BoolVar(&nodesOpts.outdated, "outdated", false, "List only the instances running outdated userdata")
+}
+
+func runCmdNodes(_ *cobra.Command, _ []string) error {
+	structured, err := structuredOutput()
+	if err != nil {
+		return err
+	}
+
+	var nodes root.Nodes
+	run := func() error {
+		var err error
+		nodes, err = root.NodesFromFile(configPath, awsCredentialsOpts, nodesOpts.awsDebug)
+		return err
+	}
+	if structured {
+		err = withProgressToStderr(run)
+	} else {
+		err = run()
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to list nodes: %v", err)
+	}
+
+	if nodesOpts.outdated {
+		nodes = nodes.Outdated()
+	}
+
+	if structured {
+		return printStructured(nodes)
+	}
+
+	fmt.Print(nodes.String())
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/progress"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+	outputFormatYAML = "yaml"
+)
+
+var outputFormat = outputFormatText
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputFormatText, "Output format of status, validate, show certificates, calculator, nodepool list and nodes. One of: \"text\", \"json\" and \"yaml\"")
+}
+
+func structuredOutput() (bool, error) {
+	switch outputFormat {
+	case outputFormatText:
+		return false, nil
+	case outputFormatJSON, outputFormatYAML:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported output format \"%s\". It must be one of: %s, %s, %s", outputFormat, outputFormatText, outputFormatJSON, outputFormatYAML)
+	}
+}
+
+// printStructured writes v to stdout in the output format.
+// YAML is produced from the JSON representation so that both formats share the same schema defined by the json tags
+func printStructured(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %v", err)
+	}
+
+	if outputFormat == outputFormatYAML {
+		var obj interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return fmt.Errorf("failed to marshal output: %v", err)
+		}
+		if data, err = yaml.Marshal(obj); err != nil {
+			return fmt.Errorf("failed to marshal output: %v", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
+
+// withProgressToStderr runs f while printing the progress to stderr,
+// so that progress messages don't corrupt the structured output
+func withProgressToStderr(f func() error) error {
+	stdout := progress.SetWriter(os.Stderr)
+	defer progress.SetWriter(stdout)
+	return f()
+}
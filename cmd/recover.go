@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/cfnstack"
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdRecover = &cobra.Command{
+		Use:   "recover",
+		Short: "Recover the cluster stack stuck after a failed operation",
+		Long: `Describes the state of the cluster stack along with the first failure in it or its nested stacks, and recovers it if possible.
+A stack stuck in UPDATE_ROLLBACK_FAILED is rolled back again, optionally skipping resources which can't be rolled back.
+A stack which has never been created successfully, e.g. in ROLLBACK_COMPLETE, is deleted so that it can be created again with "kube-aws up".`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdRecover,
+		SilenceUsage: true,
+	}
+
+	recoverOpts = struct {
+		awsDebug, force bool
+		skipResources   []string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdRecover)
+	cmdRecover.Flags().BoolVar(&recoverOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdRecover.Flags().BoolVar(&recoverOpts.force, "force", false, "Don't ask for confirmation")
+	cmdRecover.Flags().StringSliceVar(&recoverOpts.skipResources, "skip-resources", nil, "Logical IDs of resources to skip while continuing the rollback. Specify resources in nested stacks as `NestedStackLogicalID.ResourceLogicalID`, e.g. Controlplane.ControllersLC for the launch configuration of controllers")
+}
+
+func runCmdRecover(_ *cobra.Command, _ []string) error {
+	opts := root.NewOptions(false, false)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	cluster, err := root.ClusterFromFile(configPath, opts, recoverOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	state, err := cluster.StackState()
+	if err != nil {
+		return fmt.Errorf("Failed to describe the cluster stack: %v", err)
+	}
+
+	fmt.Println(state.String())
+
+	var action string
+	switch state.Condition {
+	case cfnstack.StackConditionUpdateRollbackFailed:
+		action = "continue rolling back the last update"
+		if len(recoverOpts.skipResources) > 0 {
+			action += fmt.Sprintf(" while skipping %s", strings.Join(recoverOpts.skipResources, ", "))
+		}
+	case cfnstack.StackConditionNeverCreated:
+		action = "delete the stack which has never been created"
+	case cfnstack.StackConditionInProgress:
+		fmt.Println("\nAn operation is still in progress. Run this command again once it completes")
+		return nil
+	default:
+		fmt.Println("\nNothing to recover")
+		return nil
+	}
+
+	if err := printCallerIdentity(cluster); err != nil {
+		return err
+	}
+
+	if !recoverOpts.force && !recoverConfirmation(action) {
+		fmt.Println("Operation cancelled")
+		return nil
+	}
+
+	if err := cluster.Recover(state, recoverOpts.skipResources); err != nil {
+		return fmt.Errorf("Failed to recover the cluster stack: %v", err)
+	}
+
+	if state.Condition == cfnstack.StackConditionNeverCreated {
+		fmt.Println("The stack has been deleted. Run \"kube-aws up\" to create the cluster again")
+		return nil
+	}
+
+	fmt.Println("The update has been rolled back. Fix the cause of the failure and run \"kube-aws update\" again")
+	return nil
+}
+
+func recoverConfirmation(action string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("\nThis operation will %s. Are you sure? [y,n]: ", action)
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
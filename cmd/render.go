@@ -28,6 +28,10 @@ var (
 
 	renderCredentialsOpts = config.CredentialsOptions{}
 
+	renderStackOpts = struct {
+		outputDir string
+	}{}
+
 	cmdRenderStack = &cobra.Command{
 		Use:          "stack",
 		Short:        "Render CloudFormation stack template and coreos-cloudinit userdata",
@@ -44,9 +48,13 @@ func init() {
 	cmdRender.AddCommand(cmdRenderStack)
 
 	cmdRenderCredentials.Flags().BoolVar(&renderCredentialsOpts.GenerateCA, "generate-ca", false, "if generating credentials, generate root CA key and cert. NOT RECOMMENDED FOR PRODUCTION USE- use '-ca-key-path' and '-ca-cert-path' options to provide your own certificate authority assets")
-	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA key")
-	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA or ECDSA key")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate. For an intermediate CA, the certificates of the CAs up to the root CA follow it")
 	cmdRenderCredentials.Flags().BoolVar(&renderCredentialsOpts.KIAM, "kiam", true, "generate TLS assets for kiam")
+	cmdRenderCredentials.Flags().BoolVar(&renderCredentialsOpts.CertificateRequests, "csr", false, "write keys and certificate signing requests for an external CA to sign, instead of issuing certificates")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.SignedCertificatesDir, "import-signed-certs-dir", "", "import the certificates signed for the requests written with '--csr' from this directory. '--ca-cert-path' is the chain of the CA which signed them")
+
+	cmdRenderStack.Flags().StringVar(&renderStackOpts.outputDir, "output-dir", "", "Also render every stack template and userdata part into this directory, mirroring the layout of the S3 keys, without calling AWS APIs. Credentials are rendered unencrypted even when kmsKeyArn is set")
 }
 func runCmdRender(_ *cobra.Command, args []string) error {
 	fmt.Println("WARNING: 'kube-aws render' is deprecated. See 'kube-aws render --help' for usage")
@@ -72,6 +80,22 @@ func runCmdRenderStack(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	if renderStackOpts.outputDir != "" {
+		opts := root.NewOptions(false, false)
+		opts.AWSCredentials = awsCredentialsOpts
+		opts.Offline = true
+
+		cluster, err := root.ClusterFromFile(configPath, opts, false)
+		if err != nil {
+			return fmt.Errorf("Failed to initialize cluster driver: %v", err)
+		}
+		assets, err := cluster.RenderAssetsOffline(renderStackOpts.outputDir)
+		if err != nil {
+			return fmt.Errorf("Failed to render assets: %v", err)
+		}
+		fmt.Printf("Rendered %d assets to %s\n", len(assets), renderStackOpts.outputDir)
+	}
+
 	successMsg :=
 		`Success! Stack rendered to ./stack-templates.
 
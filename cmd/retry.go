@@ -0,0 +1,12 @@
+package cmd
+
+import (
+	"github.com/kubernetes-incubator/kube-aws/cfnstack"
+)
+
+func init() {
+	policy := &cfnstack.DefaultRetryPolicy
+	RootCmd.PersistentFlags().IntVar(&policy.MaxRetries, "aws-max-retries", policy.MaxRetries, "Maximum number of retries of CloudFormation and S3 API calls throttled or failed transiently")
+	RootCmd.PersistentFlags().DurationVar(&policy.BaseDelay, "aws-retry-base-delay", policy.BaseDelay, "Delay before the first retry of a throttled AWS API call, doubled on each retry and randomized to spread retries")
+	RootCmd.PersistentFlags().DurationVar(&policy.MaxDelay, "aws-retry-max-delay", policy.MaxDelay, "Maximum delay between retries of a throttled AWS API call")
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdRotate = &cobra.Command{
+		Use:          "rotate",
+		Short:        "Rotate the credentials of the cluster",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdRotateCertificates = &cobra.Command{
+		Use:   "certificates",
+		Short: "Re-issue the TLS certificates from the existing CA and roll them out",
+		Long: `Re-issues the TLS certificates in credentials/ with new keys from the existing CA, and encrypts them with KMS.
+The cluster is then updated in stages, etcd first, then the control plane and then node pools, so that the nodes are replaced with the new certificates one component after another.
+The service account key and the CA are kept as is.`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdRotateCertificates,
+		SilenceUsage: true,
+	}
+
+	cmdRotateCA = &cobra.Command{
+		Use:   "ca",
+		Short: "Rotate the CA in phases without rebuilding the cluster",
+		Long: `Rotates the CA in credentials/ in three phases, each of which is run by running this command once and rolled out by updating the cluster in stages.
+Phase 1 distributes ca.pem bundling the old and new CAs to all the nodes. Run "kube-aws kubeconfig" afterwards so that kubeconfigs trust both.
+Phase 2 re-issues every certificate from the new CA.
+Phase 3 removes the old CA from ca.pem.
+The progress is recorded in credentials/ca-rotation.json so that an interrupted phase is resumed by running this command again.`,
+		Args:         cobra.NoArgs,
+		RunE:         runCmdRotateCA,
+		SilenceUsage: true,
+	}
+
+	rotateCAOpts = struct {
+		awsDebug, force bool
+		config.CARotationOptions
+	}{}
+
+	rotateCertificatesOpts = struct {
+		awsDebug, force, skipUpdate bool
+		config.CertificateRotationOptions
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdRotate)
+	cmdRotate.AddCommand(cmdRotateCertificates)
+	cmdRotateCertificates.Flags().BoolVar(&rotateCertificatesOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdRotateCertificates.Flags().BoolVar(&rotateCertificatesOpts.force, "force", false, "Don't ask for confirmation")
+	cmdRotateCertificates.Flags().BoolVar(&rotateCertificatesOpts.skipUpdate, "skip-update", false, "Only re-issue the certificates without updating the cluster. Run \"kube-aws update\" later to roll them out")
+	cmdRotateCertificates.Flags().StringVar(&rotateCertificatesOpts.CaKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA or ECDSA key")
+	cmdRotateCertificates.Flags().StringVar(&rotateCertificatesOpts.CaCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate")
+	cmdRotateCertificates.Flags().StringSliceVar(&rotateCertificatesOpts.Certificates, "certificates", nil, fmt.Sprintf("Certificates to re-issue. Any combination of %s. Defaults to all the certificates in use", strings.Join(config.RotatableCertificates, ", ")))
+
+	cmdRotate.AddCommand(cmdRotateCA)
+	cmdRotateCA.Flags().BoolVar(&rotateCAOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdRotateCA.Flags().BoolVar(&rotateCAOpts.force, "force", false, "Don't ask for confirmation")
+	cmdRotateCA.Flags().StringVar(&rotateCAOpts.NewCAKeyPath, "new-ca-key-path", "", "path to pem-encoded RSA or ECDSA key of the new CA. A new CA is generated when omitted. Only used by phase 1")
+	cmdRotateCA.Flags().StringVar(&rotateCAOpts.NewCACertPath, "new-ca-cert-path", "", "path to pem-encoded x509 certificate of the new CA. Only used by phase 1")
+}
+
+func runCmdRotateCertificates(_ *cobra.Command, _ []string) error {
+	opts := root.NewOptions(false, false)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	rotator, err := root.CertificateRotatorFromFile(configPath, opts, rotateCertificatesOpts.CertificateRotationOptions, rotateCertificatesOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	if !rotateCertificatesOpts.skipUpdate {
+		if err := printCallerIdentity(rotator); err != nil {
+			return err
+		}
+	}
+
+	if !rotateCertificatesOpts.force && !rotateCertificatesConfirmation(rotator.Certificates()) {
+		fmt.Println("Operation cancelled")
+		return nil
+	}
+
+	written, err := rotator.Rotate()
+	if err != nil {
+		return fmt.Errorf("Failed to rotate certificates: %v", err)
+	}
+	for _, f := range written {
+		fmt.Printf("Wrote %s\n", f)
+	}
+
+	if rotateCertificatesOpts.skipUpdate {
+		fmt.Println("Certificates have been re-issued. Run \"kube-aws update\" to roll them out")
+		return nil
+	}
+
+	if err := rotator.UpdateInStages(); err != nil {
+		return fmt.Errorf("Error updating cluster: %v", err)
+	}
+
+	fmt.Println("Success! The certificates have been rotated. Run \"kube-aws kubeconfig\" again if your kubeconfig embeds the previous admin certificate")
+	return nil
+}
+
+func rotateCertificatesConfirmation(certificates []string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("This operation will re-issue the %s certificates", strings.Join(certificates, ", "))
+	if !rotateCertificatesOpts.skipUpdate {
+		fmt.Print(" and replace the nodes of the cluster")
+	}
+	fmt.Print(". Are you sure? [y,n]: ")
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
+
+func runCmdRotateCA(_ *cobra.Command, _ []string) error {
+	opts := root.NewOptions(false, false)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	rotator, err := root.CARotatorFromFile(configPath, opts, rotateCAOpts.CARotationOptions, rotateCAOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	if err := printCallerIdentity(rotator); err != nil {
+		return err
+	}
+
+	current := rotator.State()
+	next := config.CARotationState{Phase: current.NextPhase()}
+	if next.Phase > config.CARotationPhaseUntrust {
+		return fmt.Errorf("Unexpected state of the CA rotation in %s: %+v", config.CARotationStateFileName, current)
+	}
+	resuming := next.Phase == current.Phase
+
+	if !rotateCAOpts.force && !rotateCAConfirmation(next, resuming) {
+		fmt.Println("Operation cancelled")
+		return nil
+	}
+
+	state, err := rotator.Start()
+	if err != nil {
+		return fmt.Errorf("Failed to start phase %d of the CA rotation: %v", next.Phase, err)
+	}
+
+	if err := rotator.UpdateInStages(); err != nil {
+		return fmt.Errorf("Error updating cluster: %v. Run \"kube-aws rotate ca\" again to resume phase %d", err, state.Phase)
+	}
+
+	switch state.Phase {
+	case config.CARotationPhaseTrust:
+		fmt.Println("Success! The cluster trusts both the old and new CAs. Run \"kube-aws kubeconfig\" again, and then \"kube-aws rotate ca\" to proceed to phase 2")
+	case config.CARotationPhaseReissue:
+		fmt.Println("Success! Every certificate has been re-issued from the new CA. Run \"kube-aws kubeconfig\" again, and then \"kube-aws rotate ca\" to proceed to phase 3")
+	default:
+		fmt.Println("Success! The CA has been rotated. Run \"kube-aws kubeconfig\" again so that your kubeconfig doesn't trust the old CA")
+	}
+	return nil
+}
+
+func rotateCAConfirmation(next config.CARotationState, resuming bool) bool {
+	reader := bufio.NewReader(os.Stdin)
+	if resuming {
+		fmt.Printf("Phase %d of the CA rotation, which will %s, has been started but not rolled out yet. ", next.Phase, next.Description())
+		fmt.Print("This operation will resume it and replace the nodes of the cluster. Are you sure? [y,n]: ")
+	} else {
+		fmt.Printf("This operation will start phase %d of 3 of the CA rotation, which will %s, and replace the nodes of the cluster. Are you sure? [y,n]: ", next.Phase, next.Description())
+	}
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdSchema = &cobra.Command{
+		Use:   "schema cluster|plugin",
+		Short: "Print the JSON Schema for cluster.yaml or plugin.yaml",
+		Long: `Prints the JSON Schema generated from the configuration structs of kube-aws.
+Save it to a file and point your editor to it for completion and validation of cluster.yaml and plugin.yaml`,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCmdSchema,
+		SilenceUsage: true,
+	}
+
+	schemaDocuments = map[string]struct {
+		title string
+		v     interface{}
+	}{
+		"cluster": {"cluster.yaml", config.UnmarshalledConfig{}},
+		"plugin":  {"plugin.yaml", pluginmodel.Plugin{}},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdSchema)
+}
+
+func runCmdSchema(_ *cobra.Command, args []string) error {
+	doc, ok := schemaDocuments[args[0]]
+	if !ok {
+		names := []string{}
+		for n := range schemaDocuments {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown document \"%s\". It must be one of: %s", args[0], strings.Join(names, ", "))
+	}
+
+	data, err := schema.Generate(doc.v, doc.title).JSON()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
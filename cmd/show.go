@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
 	"github.com/kubernetes-incubator/kube-aws/core/root"
 	"github.com/kubernetes-incubator/kube-aws/tlscerts"
 	"github.com/spf13/cobra"
+	"os"
 	"sort"
 )
 
@@ -20,35 +22,76 @@ var (
 		Use:   "certificates",
 		Short: "Show info about certificates",
 		Long: `Loads all certificates from credentials directory and prints certificate
-Issuer, Validity, Subject and DNS Names fields`,
+Issuer, Validity, Subject and DNS Names fields, followed by days until expiry and problems found in them:
+certificates not signed by the CA in ca.pem, and the apiserver certificate missing SANs required by cluster.yaml`,
 		RunE:         runCmdShowCertificates,
 		SilenceUsage: true,
 	}
+
+	showCertificatesOpts = struct {
+		warnDays int
+	}{}
 )
 
 func init() {
 	RootCmd.AddCommand(cmdShow)
 	cmdShow.AddCommand(cmdShowCertificates)
+	cmdShowCertificates.Flags().IntVar(&showCertificatesOpts.warnDays, "warn-days", 0, "Report certificates expiring in less than this many days as problems")
 }
 
 func runCmdShowCertificates(_ *cobra.Command, _ []string) error {
+	structured, err := structuredOutput()
+	if err != nil {
+		return err
+	}
+
 	certs, err := root.LoadCertificates()
 	if err != nil {
 		return err
 	}
 
+	health := map[string][]config.CertificateHealth{}
+	report, err := root.CredentialsHealthFromFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: cannot check health of certificates: %v\n", err)
+	} else {
+		for _, h := range report.Certificates {
+			health[h.File] = append(health[h.File], h)
+		}
+	}
+
 	keys := sortedKeys(certs)
+
+	if structured {
+		files := []root.CertificateFile{}
+		for _, k := range keys {
+			files = append(files, root.CertificateFile{File: k, Certificates: certs[k], Health: health[k]})
+		}
+		return printStructured(files)
+	}
+
 	for _, k := range keys {
 		cert := certs[k]
 		fmt.Printf("--- %s ---\n", k)
 		for _, v := range cert {
 			fmt.Println(v)
 		}
+		for _, h := range health[k] {
+			printCertificateHealth(h)
+		}
 		fmt.Println("")
 	}
 	return nil
 }
 
+func printCertificateHealth(h config.CertificateHealth) {
+	fmt.Printf("Days Until Expiry (CN=%s): %d\n", h.CommonName, h.DaysUntilExpiry)
+	problems := config.CredentialsHealthReport{Certificates: []config.CertificateHealth{h}}.Problems(showCertificatesOpts.warnDays)
+	for _, p := range problems {
+		fmt.Printf("PROBLEM: %s\n", p)
+	}
+}
+
 func sortedKeys(m map[string]tlscerts.Certificates) []string {
 
 	var keys []string
@@ -22,7 +22,12 @@ func init() {
 }
 
 func runCmdStatus(_ *cobra.Command, _ []string) error {
-	describer, err := root.ClusterDescriberFromFile(configPath)
+	structured, err := structuredOutput()
+	if err != nil {
+		return err
+	}
+
+	describer, err := root.ClusterDescriberFromFile(configPath, awsCredentialsOpts)
 	if err != nil {
 		return fmt.Errorf("Failed to read cluster config: %v", err)
 	}
@@ -32,6 +37,10 @@ func runCmdStatus(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("Failed fetching cluster info: %v", err)
 	}
 
+	if structured {
+		return printStructured(info)
+	}
+
 	fmt.Print(info.String())
 	return nil
 }
@@ -31,6 +31,7 @@ func init() {
 
 func runCmdUp(_ *cobra.Command, _ []string) error {
 	opts := root.NewOptions(upOpts.prettyPrint, upOpts.skipWait)
+	opts.AWSCredentials = awsCredentialsOpts
 
 	cluster, err := root.ClusterFromFile(configPath, opts, upOpts.awsDebug)
 	if err != nil {
@@ -48,6 +49,10 @@ func runCmdUp(_ *cobra.Command, _ []string) error {
 		return nil
 	}
 
+	if err := printCallerIdentity(cluster); err != nil {
+		return err
+	}
+
 	fmt.Println("Creating AWS resources. Please wait. It may take a few minutes.")
 	if err := cluster.Create(); err != nil {
 		return fmt.Errorf("Error creating cluster: %v", err)
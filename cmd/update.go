@@ -23,6 +23,7 @@ var (
 		awsDebug, prettyPrint, skipWait bool
 		force                           bool
 		targets                         []string
+		planOut                         string
 	}{}
 )
 
@@ -32,22 +33,32 @@ func init() {
 	cmdUpdate.Flags().BoolVar(&updateOpts.prettyPrint, "pretty-print", false, "Pretty print the resulting CloudFormation")
 	cmdUpdate.Flags().BoolVar(&updateOpts.skipWait, "skip-wait", false, "Don't wait the resources finish")
 	cmdUpdate.Flags().BoolVar(&updateOpts.force, "force", false, "Don't ask for confirmation")
+	cmdUpdate.Flags().StringVar(&updateOpts.planOut, "plan-out", "", "Don't update the cluster, instead save the update plan to the specified file. Apply it later with \"kube-aws apply\"")
 	cmdUpdate.Flags().StringSliceVar(&updateOpts.targets, "targets", root.AllOperationTargetsAsStringSlice(), "Update nothing but specified sub-stacks.  Specify `all` or any combination of `etcd`, `control-plane`, and node pool names. Defaults to `all`")
 }
 
 func runCmdUpdate(_ *cobra.Command, _ []string) error {
+	if updateOpts.planOut != "" {
+		return runCmdUpdatePlan()
+	}
+
 	if !updateOpts.force && !updateConfirmation() {
 		fmt.Println("Operation cancelled")
 		return nil
 	}
 
 	opts := root.NewOptions(updateOpts.prettyPrint, updateOpts.skipWait)
+	opts.AWSCredentials = awsCredentialsOpts
 
 	cluster, err := root.ClusterFromFile(configPath, opts, updateOpts.awsDebug)
 	if err != nil {
 		return fmt.Errorf("Failed to read cluster config: %v", err)
 	}
 
+	if err := printCallerIdentity(cluster); err != nil {
+		return err
+	}
+
 	targets := root.OperationTargetsFromStringSlice(updateOpts.targets)
 
 	if _, err := cluster.ValidateStack(targets); err != nil {
@@ -76,6 +87,40 @@ func runCmdUpdate(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+func runCmdUpdatePlan() error {
+	opts := root.NewOptions(updateOpts.prettyPrint, updateOpts.skipWait)
+	opts.AWSCredentials = awsCredentialsOpts
+
+	cluster, err := root.ClusterFromFile(configPath, opts, updateOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	targets := root.OperationTargetsFromStringSlice(updateOpts.targets)
+
+	// The stack templates are validated while planning, so that they are uploaded only once
+	plan, changeSets, err := cluster.Plan(targets)
+	if err != nil {
+		return fmt.Errorf("Error planning update: %v", err)
+	}
+
+	if err := plan.WriteToFile(updateOpts.planOut); err != nil {
+		return err
+	}
+
+	successMsg :=
+		`%s
+Success! The update plan has been saved to %s.
+
+Review the changes above and then run "kube-aws apply %s" to update the cluster.
+Only the change set for the root stack is executed, which updates the nested stacks as well.
+The change sets for the nested stacks are previews of the changes made to them, and are deleted after the update.
+`
+	fmt.Printf(successMsg, changeSets.String(), updateOpts.planOut, updateOpts.planOut)
+
+	return nil
+}
+
 func updateConfirmation() bool {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Print("This operation will update the cluster. Are you sure? [y,n]: ")
@@ -18,8 +18,10 @@ var (
 	}
 
 	validateOpts = struct {
-		awsDebug, skipWait bool
-		targets            []string
+		awsDebug, skipWait, offline bool
+		outputDir                   string
+		targets                     []string
+		warnDays                    int
 	}{}
 )
 
@@ -36,21 +38,83 @@ func init() {
 		"targets",
 		root.AllOperationTargetsAsStringSlice(),
 		"Validate nothing but specified sub-stacks. Specify `all` or any combination of `etcd`, `control-plane`, and node pool names. Defaults to `all`")
+	cmdValidate.Flags().BoolVar(
+		&validateOpts.offline,
+		"offline",
+		false,
+		"Render the assets into the output directory and validate them locally, without uploading them to S3 or calling CloudFormation",
+	)
+	cmdValidate.Flags().StringVar(
+		&validateOpts.outputDir,
+		"output-dir",
+		"rendered",
+		"Directory to render the assets into when validating offline",
+	)
+	cmdValidate.Flags().IntVar(
+		&validateOpts.warnDays,
+		"warn-days",
+		0,
+		"Fail when any certificate in the credentials directory expires in less than this many days. Expired certificates always fail the validation",
+	)
 }
 
 func runCmdValidate(_ *cobra.Command, _ []string) error {
-	opts := root.NewOptions(validateOpts.awsDebug, validateOpts.skipWait)
+	structured, err := structuredOutput()
+	if err != nil {
+		return err
+	}
 
-	cluster, err := root.ClusterFromFile(configPath, opts, validateOpts.awsDebug)
+	targets := root.OperationTargetsFromStringSlice(validateOpts.targets)
+
+	if structured {
+		var report string
+		var problems []string
+		err := withProgressToStderr(func() error {
+			var err error
+			if problems, err = credentialsProblems(); err != nil {
+				return err
+			}
+			if report, err = validateStack(targets); err != nil {
+				return err
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("Found %d problems in credentials", len(problems))
+			}
+			return nil
+		})
+		r := root.ValidationReport{
+			Valid:               err == nil,
+			Offline:             validateOpts.offline,
+			Targets:             targets,
+			Report:              report,
+			CredentialsProblems: problems,
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if perr := printStructured(r); perr != nil {
+			return perr
+		}
+		return err
+	}
+
+	fmt.Printf("Validating credentials...\n")
+
+	problems, err := credentialsProblems()
 	if err != nil {
-		return fmt.Errorf("Failed to initialize cluster driver: %v", err)
+		return err
 	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+		return fmt.Errorf("Found %d problems in credentials. Run \"kube-aws show certificates\" for details", len(problems))
+	}
+	fmt.Printf("credentials are valid.\n\n")
 
 	fmt.Printf("Validating UserData and stack template...\n")
 
-	targets := root.OperationTargetsFromStringSlice(validateOpts.targets)
-
-	report, err := cluster.ValidateStack(targets)
+	report, err := validateStack(targets)
 	if report != "" {
 		fmt.Fprintf(os.Stderr, "Validation Report: %s\n", report)
 	}
@@ -63,3 +127,27 @@ func runCmdValidate(_ *cobra.Command, _ []string) error {
 
 	return nil
 }
+
+func validateStack(targets root.OperationTargets) (string, error) {
+	opts := root.NewOptions(validateOpts.awsDebug, validateOpts.skipWait)
+	opts.AWSCredentials = awsCredentialsOpts
+	opts.Offline = validateOpts.offline
+
+	cluster, err := root.ClusterFromFile(configPath, opts, validateOpts.awsDebug)
+	if err != nil {
+		return "", fmt.Errorf("Failed to initialize cluster driver: %v", err)
+	}
+
+	if validateOpts.offline {
+		return cluster.ValidateStackOffline(validateOpts.outputDir, targets)
+	}
+	return cluster.ValidateStack(targets)
+}
+
+func credentialsProblems() ([]string, error) {
+	report, err := root.CredentialsHealthFromFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to check credentials: %v", err)
+	}
+	return report.Problems(validateOpts.warnDays), nil
+}
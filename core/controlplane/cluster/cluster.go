@@ -2,7 +2,6 @@ package cluster
 
 import (
 	"fmt"
-	"net"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -12,14 +11,15 @@ import (
 	"github.com/aws/aws-sdk-go/service/route53"
 
 	"errors"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/cfnstack"
 	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
 	"github.com/kubernetes-incubator/kube-aws/gzipcompressor"
 	"github.com/kubernetes-incubator/kube-aws/model"
 	"github.com/kubernetes-incubator/kube-aws/naming"
-	"github.com/kubernetes-incubator/kube-aws/netutil"
 	"github.com/kubernetes-incubator/kube-aws/plugin/clusterextension"
 	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 	"github.com/kubernetes-incubator/kube-aws/tlscerts"
 )
 
@@ -28,16 +28,18 @@ var VERSION = "UNKNOWN"
 
 const STACK_TEMPLATE_FILENAME = "stack.json"
 
-func newClusterRef(cfg *config.Cluster, session *session.Session) *ClusterRef {
+func newClusterRef(cfg *config.Cluster, clients *awsconn.ServiceClients) *ClusterRef {
 	return &ClusterRef{
 		Cluster: cfg,
-		session: session,
+		session: clients.Session,
+		clients: clients,
 	}
 }
 
 type ClusterRef struct {
 	*config.Cluster
 	session *session.Session
+	clients *awsconn.ServiceClients
 }
 
 type Cluster struct {
@@ -61,7 +63,7 @@ func (c *ClusterRef) validateExistingVPCState(ec2Svc ec2Service) error {
 
 	// TODO kube-aws should de-reference the vpc id from the stack output and continue validating with it
 	if c.VPC.IDFromStackOutput != "" {
-		fmt.Printf("kube-aws doesn't support validating the vpc referenced by the stack output `%s`. Skipped validation of existing vpc state. The cluster creation may fail afterwards if the VPC isn't configured properly.", c.VPC.IDFromStackOutput)
+		progress.Printf("kube-aws doesn't support validating the vpc referenced by the stack output `%s`. Skipped validation of existing vpc state. The cluster creation may fail afterwards if the VPC isn't configured properly.", c.VPC.IDFromStackOutput)
 		return nil
 	}
 
@@ -119,7 +121,7 @@ func (c *ClusterRef) validateExistingVPCState(ec2Svc ec2Service) error {
 	return nil
 }
 
-func NewCluster(cfgRef *config.Cluster, opts config.StackTemplateOptions, plugins []*pluginmodel.Plugin, session *session.Session) (*Cluster, error) {
+func NewCluster(cfgRef *config.Cluster, opts config.StackTemplateOptions, plugins []*pluginmodel.Plugin, clients *awsconn.ServiceClients) (*Cluster, error) {
 	cfg := &config.Cluster{}
 	*cfg = *cfgRef
 
@@ -132,11 +134,11 @@ func NewCluster(cfgRef *config.Cluster, opts config.StackTemplateOptions, plugin
 	cfg.VPC = cfg.VPC.ImportFromNetworkStack()
 	cfg.SetDefaults()
 
-	clusterRef := newClusterRef(cfg, session)
+	clusterRef := newClusterRef(cfg, clients)
 	// TODO Do this in a cleaner way e.g. in config.go
 	clusterRef.KubeResourcesAutosave.S3Path = model.NewS3Folders(cfg.DeploymentSettings.S3URI, clusterRef.ClusterName).ClusterBackups().Path()
 
-	stackConfig, err := clusterRef.StackConfig(config.ControlPlaneStackName, opts, session, plugins)
+	stackConfig, err := clusterRef.StackConfig(config.ControlPlaneStackName, opts, clients.Session, plugins)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +199,9 @@ func (c *Cluster) buildAssets() (cfnstack.Assets, error) {
 		return nil, fmt.Errorf("failed to render control-plane template: %v", err)
 	}
 
-	assets.Add(STACK_TEMPLATE_FILENAME, stackTemplate)
+	if err = assets.AddStackTemplate(STACK_TEMPLATE_FILENAME, stackTemplate, c.StackConfig.ExtraCfnResources); err != nil {
+		return nil, fmt.Errorf("failed to add control-plane template: %v", err)
+	}
 
 	return assets.Build(), nil
 }
@@ -221,7 +225,12 @@ func (c *Cluster) ValidateStack() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get template url : %v", err)
 	}
-	return c.stackProvisioner().ValidateStackAtURL(templateURL)
+	return c.stackProvisioner().ValidateStackAtURL(c.clients.CloudFormation, templateURL)
+}
+
+// ValidateCerts validates the TLS assets against the cluster settings without calling AWS APIs
+func (c *Cluster) ValidateCerts() error {
+	return c.validateCertsAgainstSettings()
 }
 
 func (c *Cluster) stackProvisioner() *cfnstack.Provisioner {
@@ -248,7 +257,7 @@ func (c *Cluster) stackProvisioner() *cfnstack.Provisioner {
 }
 
 func (c *Cluster) Validate() error {
-	ec2Svc := ec2.New(c.session)
+	ec2Svc := c.clients.EC2
 	if c.KeyName != "" {
 		if err := c.validateKeyPair(ec2Svc); err != nil {
 			return err
@@ -263,7 +272,7 @@ func (c *Cluster) Validate() error {
 		return err
 	}
 
-	if err := c.validateDNSConfig(route53.New(c.session)); err != nil {
+	if err := c.validateDNSConfig(c.clients.Route53); err != nil {
 		return err
 	}
 
@@ -280,7 +289,7 @@ func (c *Cluster) String() string {
 }
 
 func (c *ClusterRef) Destroy() error {
-	return cfnstack.NewDestroyer(config.ControlPlaneStackName, c.session, c.CloudFormation.RoleARN).Destroy()
+	return cfnstack.NewDestroyer(config.ControlPlaneStackName, c.clients.CloudFormation, c.CloudFormation.RoleARN).Destroy()
 }
 
 func (c *ClusterRef) validateKeyPair(ec2Svc ec2Service) error {
@@ -386,7 +395,7 @@ func (c *ClusterRef) validateControllerRootVolume(ec2Svc ec2Service) error {
 }
 
 // validateCertsAgainstSettings cross checks that our api server cert is compatible with our cluster settings: -
-// - It must include the externalDNS name for the api servers.
+// - It must include the externalDNS names for the api servers.
 // - It must include the IPAddress of the first IP in the chosen ServiceCIDR.
 func (c Cluster) validateCertsAgainstSettings() error {
 	apiServerPEM, err := gzipcompressor.DecompressString(c.AssetsConfig.APIServerCert)
@@ -403,22 +412,15 @@ func (c Cluster) validateCertsAgainstSettings() error {
 		return errors.New("no api server certs contain Subject CommonName 'kube-apiserver'")
 	}
 
-	// Check DNS Names
-	for _, apiEndPoint := range c.KubeClusterSettings.APIEndpointConfigs {
-		if !kubeAPIServerCert.ContainsDNSName(apiEndPoint.DNSName) {
-			return fmt.Errorf("the apiserver cert does not contain the external dns name %s, please regenerate or resolve", apiEndPoint.DNSName)
-		}
-	}
-
-	// Check IP SANS
-	_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
+	dnsNames, ips, err := c.MissingAPIServerSANs(kubeAPIServerCert)
 	if err != nil {
-		return fmt.Errorf("invalid serviceCIDR: %v", err)
+		return err
 	}
-	kubernetesServiceIPAddr := netutil.IncrementIP(serviceNet.IP)
-
-	if !kubeAPIServerCert.ContainsIPAddress(kubernetesServiceIPAddr) {
-		return fmt.Errorf("the api server cert does not contain the kubernetes service ip address %v, please regenerate or resolve", kubernetesServiceIPAddr)
+	if len(dnsNames) > 0 {
+		return fmt.Errorf("the apiserver cert does not contain the external dns names %s, please regenerate or resolve", strings.Join(dnsNames, ", "))
+	}
+	if len(ips) > 0 {
+		return fmt.Errorf("the api server cert does not contain the kubernetes service ip address %s, please regenerate or resolve", strings.Join(ips, ", "))
 	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/cfnstack"
 	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
 	"github.com/kubernetes-incubator/kube-aws/model"
@@ -437,6 +438,52 @@ hostedZoneId: /hostedzone/staging_id_5 #non-existent hostedZoneId
 
 }
 
+func TestValidateAgainstFakeAWS(t *testing.T) {
+	configBody := defaultConfigValues(t, `
+amiId: ami-12345678
+createRecordSet: true
+recordSetTTL: 60
+hostedZoneId: staging_id_1
+`)
+	clusterConfig, err := config.ClusterFromBytesWithEncryptService([]byte(configBody), helper.DummyEncryptService{})
+	if err != nil {
+		t.Fatalf("could not get valid cluster config: %v", err)
+	}
+
+	helper.WithDummyCredentials(func(dummyAssetsDir string) {
+		fake := helper.NewFakeAWS("us-west-1", "test-bucket")
+		fake.Route53.HostedZones = []*route53.HostedZone{
+			{Id: aws.String("/hostedzone/staging_id_1"), Name: aws.String("staging.core-os.net.")},
+		}
+
+		stackTemplateOptions := config.StackTemplateOptions{
+			AssetsDir:             dummyAssetsDir,
+			ControllerTmplFile:    "../config/templates/cloud-config-controller",
+			EtcdTmplFile:          "../config/templates/cloud-config-etcd",
+			StackTemplateTmplFile: "../config/templates/stack-template.json",
+			S3URI:                 "s3://test-bucket/foo/bar",
+		}
+		cluster, err := NewCluster(clusterConfig, stackTemplateOptions, []*pluginmodel.Plugin{}, fake.ServiceClients())
+		if err != nil {
+			t.Fatalf("failed to initialize cluster: %v", err)
+		}
+
+		if err := cluster.Validate(); err == nil || !strings.Contains(err.Error(), "Key test-key-name does not exist") {
+			t.Errorf("expected an error for the missing key pair but was %v", err)
+		}
+
+		fake.EC2.KeyPairs = []string{"test-key-name"}
+		if err := cluster.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		fake.Route53.RecordSets["staging_id_1"] = []*route53.ResourceRecordSet{{Name: aws.String("test.staging.core-os.net.")}}
+		if err := cluster.Validate(); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("expected an error for the existing record set but was %v", err)
+		}
+	})
+}
+
 func TestStackTags(t *testing.T) {
 	testCases := []struct {
 		expectedTags []*cloudformation.Tag
@@ -489,7 +536,7 @@ stackTags:
 				S3URI: "s3://test-bucket/foo/bar",
 			}
 
-			cluster, err := NewCluster(clusterConfig, stackTemplateOptions, []*pluginmodel.Plugin{}, nil)
+			cluster, err := NewCluster(clusterConfig, stackTemplateOptions, []*pluginmodel.Plugin{}, &awsconn.ServiceClients{})
 			if !assert.NoError(t, err) {
 				return
 			}
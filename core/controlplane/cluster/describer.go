@@ -5,10 +5,10 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
 )
 
 type ClusterDescriber interface {
@@ -18,16 +18,16 @@ type ClusterDescriber interface {
 type clusterDescriberImpl struct {
 	clusterName             string
 	elbResourceLogicalNames []string
-	session                 *session.Session
+	clients                 *awsconn.ServiceClients
 	stackName               string
 }
 
-func NewClusterDescriber(clusterName string, stackName string, elbResourceLogicalNames []string, session *session.Session) ClusterDescriber {
+func NewClusterDescriber(clusterName string, stackName string, elbResourceLogicalNames []string, clients *awsconn.ServiceClients) ClusterDescriber {
 	return clusterDescriberImpl{
 		clusterName:             clusterName,
 		elbResourceLogicalNames: elbResourceLogicalNames,
 		stackName:               stackName,
-		session:                 session,
+		clients:                 clients,
 	}
 }
 
@@ -41,7 +41,7 @@ func (c clusterDescriberImpl) Info() (*Info, error) {
 	elbv2Names := []string{}
 
 	{
-		cfSvc := cloudformation.New(c.session)
+		cfSvc := c.clients.CloudFormation
 		for _, lb := range c.elbResourceLogicalNames {
 			resp, err := cfSvc.DescribeStackResource(
 				&cloudformation.DescribeStackResourceInput{
@@ -65,8 +65,8 @@ func (c clusterDescriberImpl) Info() (*Info, error) {
 		}
 	}
 
-	elbSvc := elb.New(c.session)
-	elbv2Svc := elbv2.New(c.session)
+	elbSvc := c.clients.ELB
+	elbv2Svc := c.clients.ELBV2
 
 	var info Info
 	{
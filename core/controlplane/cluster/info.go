@@ -8,8 +8,8 @@ import (
 )
 
 type Info struct {
-	Name            string
-	ControllerHosts []string
+	Name            string   `json:"name"`
+	ControllerHosts []string `json:"controllerHosts"`
 }
 
 func (c *Info) String() string {
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// ReadTLSCAChain reads the key of the CA signing the certificates and the chain of certificates from it up to the root CA.
+// The cert file has either a self-signed CA alone, or an intermediate CA with every CA issuing it, so that the root CA key can be kept out of kube-aws.
+// The chain is returned in order from the signing CA to the root CA, which is how it is distributed as ca.pem
+func ReadTLSCAChain(caKeyPath, caCertPath string) (crypto.Signer, []*x509.Certificate, error) {
+	caKey, caCert, err := ReadTLSCA(caKeyPath, caCertPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	certs, err := readCertificatesPEM(caCertPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := verifyCAChain(caCert, certs, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ca cert file %s: %v", caCertPath, err)
+	}
+	return caKey, chain, nil
+}
+
+// verifyCAChain orders the certificates into the chain from the signing CA to the self-signed root CA, and verifies that the chain is valid at now.
+// Every certificate must be part of the chain
+func verifyCAChain(signing *x509.Certificate, certs []*x509.Certificate, now time.Time) ([]*x509.Certificate, error) {
+	if !signing.IsCA || signing.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, fmt.Errorf("the certificate of the ca key (CN=%s) is not allowed to sign certificates", signing.Subject.CommonName)
+	}
+
+	chain := []*x509.Certificate{signing}
+	remaining := []*x509.Certificate{}
+	for _, cert := range certs {
+		if !cert.Equal(signing) {
+			remaining = append(remaining, cert)
+		}
+	}
+
+	current := signing
+	for !isSelfSigned(current) {
+		found := -1
+		for i, cert := range remaining {
+			if current.CheckSignatureFrom(cert) == nil {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return nil, fmt.Errorf("the issuer of CN=%s is missing. The chain must end with the self-signed root CA", current.Subject.CommonName)
+		}
+		current = remaining[found]
+		chain = append(chain, current)
+		remaining = append(remaining[:found], remaining[found+1:]...)
+	}
+
+	if len(remaining) > 0 {
+		return nil, fmt.Errorf("CN=%s is not part of the chain from CN=%s to the root CA", remaining[0].Subject.CommonName, signing.Subject.CommonName)
+	}
+
+	if len(chain) > 1 {
+		opts := x509.VerifyOptions{
+			Roots:         x509.NewCertPool(),
+			Intermediates: x509.NewCertPool(),
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		opts.Roots.AddCert(chain[len(chain)-1])
+		for _, cert := range chain[1 : len(chain)-1] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := signing.Verify(opts); err != nil {
+			return nil, fmt.Errorf("failed to verify the chain from CN=%s: %v", signing.Subject.CommonName, err)
+		}
+	} else if now.After(signing.NotAfter) || now.Before(signing.NotBefore) {
+		return nil, fmt.Errorf("CN=%s is valid only from %s to %s", signing.Subject.CommonName, signing.NotBefore, signing.NotAfter)
+	}
+
+	return chain, nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
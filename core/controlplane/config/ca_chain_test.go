@@ -0,0 +1,163 @@
+package config
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func newTestIntermediateCA(t *testing.T, commonName string, isCA bool, caCert *x509.Certificate, caKey crypto.Signer) (crypto.Signer, *x509.Certificate) {
+	key, err := tlsutil.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             caCert.NotBefore,
+		NotAfter:              caCert.NotAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		t.Fatalf("failed to create %s cert: %v", commonName, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse %s cert: %v", commonName, err)
+	}
+	return key, cert
+}
+
+func TestReadTLSCAChain(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "ca-chain")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootKey, rootCert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating tls ca: %v", err)
+	}
+	intermediateKey, intermediateCert := newTestIntermediateCA(t, "kube-intermediate-ca", true, rootCert, rootKey)
+	_, otherCert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating tls ca: %v", err)
+	}
+	leafKey, leafCert := newTestIntermediateCA(t, "not-a-ca", false, intermediateCert, intermediateKey)
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	intermediateKeyPath := write("intermediate-key.pem", tlsutil.EncodePrivateKeyPEM(intermediateKey))
+
+	t.Run("ValidChain", func(t *testing.T) {
+		for _, certs := range [][]*x509.Certificate{
+			{intermediateCert, rootCert},
+			{rootCert, intermediateCert},
+		} {
+			key, chain, err := ReadTLSCAChain(intermediateKeyPath, write("chain.pem", encodeCertificatesPEM(certs)))
+			if err != nil {
+				t.Fatalf("failed to read ca chain: %v", err)
+			}
+			if !tlsutil.PublicKeysEqual(key.Public(), intermediateKey.Public()) {
+				t.Errorf("expected the intermediate ca key")
+			}
+			if len(chain) != 2 || !chain[0].Equal(intermediateCert) || !chain[1].Equal(rootCert) {
+				t.Errorf("expected the chain to be ordered from the intermediate to the root ca: %d certs", len(chain))
+			}
+		}
+	})
+
+	t.Run("SelfSignedCA", func(t *testing.T) {
+		rootKeyPath := write("root-key.pem", tlsutil.EncodePrivateKeyPEM(rootKey))
+		_, chain, err := ReadTLSCAChain(rootKeyPath, write("root.pem", tlsutil.EncodeCertificatePEM(rootCert)))
+		if err != nil {
+			t.Fatalf("failed to read ca chain: %v", err)
+		}
+		if len(chain) != 1 || !chain[0].Equal(rootCert) {
+			t.Errorf("expected the chain to have the root ca alone: %d certs", len(chain))
+		}
+	})
+
+	t.Run("InvalidChain", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			keyPath string
+			certs   []*x509.Certificate
+			err     string
+		}{
+			{"MissingRoot", intermediateKeyPath, []*x509.Certificate{intermediateCert}, "the issuer of CN=kube-intermediate-ca is missing"},
+			{"UnrelatedCA", intermediateKeyPath, []*x509.Certificate{intermediateCert, rootCert, otherCert}, "CN=kube-ca is not part of the chain"},
+			{"NotCA", write("leaf-key.pem", tlsutil.EncodePrivateKeyPEM(leafKey)), []*x509.Certificate{leafCert, intermediateCert, rootCert}, "not allowed to sign certificates"},
+		}
+		for _, tc := range testCases {
+			_, _, err := ReadTLSCAChain(tc.keyPath, write(tc.name+".pem", encodeCertificatesPEM(tc.certs)))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected an error containing %q but was %v", tc.name, tc.err, err)
+			}
+		}
+	})
+
+	t.Run("NewAssetsOnDisk", func(t *testing.T) {
+		credentialsDir := filepath.Join(dir, "credentials")
+		if err := os.Mkdir(credentialsDir, 0700); err != nil {
+			t.Fatalf("failed to create credentials dir: %v", err)
+		}
+		opts := CredentialsOptions{
+			CaKeyPath:  intermediateKeyPath,
+			CaCertPath: write("chain.pem", encodeCertificatesPEM([]*x509.Certificate{intermediateCert, rootCert})),
+			KIAM:       true,
+		}
+		if _, err := cluster.NewAssetsOnDisk(credentialsDir, opts); err != nil {
+			t.Fatalf("failed to render credentials: %v", err)
+		}
+
+		caCerts, err := readCertificatesPEM(filepath.Join(credentialsDir, "ca.pem"))
+		if err != nil {
+			t.Fatalf("failed to read ca.pem: %v", err)
+		}
+		if len(caCerts) != 2 || !caCerts[0].Equal(intermediateCert) || !caCerts[1].Equal(rootCert) {
+			t.Errorf("expected ca.pem to be the chain from the intermediate to the root ca: %d certs", len(caCerts))
+		}
+
+		roots := x509.NewCertPool()
+		roots.AddCert(rootCert)
+		intermediates := x509.NewCertPool()
+		intermediates.AddCert(intermediateCert)
+		for _, name := range []string{"apiserver", "worker", "etcd-client", "kiam-server"} {
+			cert, err := tlsutil.DecodeCertificatePEM(mustReadFile(t, filepath.Join(credentialsDir, name+".pem")))
+			if err != nil {
+				t.Fatalf("failed to parse %s cert: %v", name, err)
+			}
+			if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+				t.Errorf("expected %s cert to chain up to the root ca: %v", name, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(credentialsDir, "ca-key.pem")); !os.IsNotExist(err) {
+			t.Errorf("expected the intermediate ca key not to be written: %v", err)
+		}
+	})
+}
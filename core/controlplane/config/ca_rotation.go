@@ -0,0 +1,340 @@
+package config
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kubernetes-incubator/kube-aws/progress"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+const (
+	// CARotationStateFileName is the file in the assets directory recording the progress of a CA rotation
+	CARotationStateFileName = "ca-rotation.json"
+
+	// CARotationPhaseTrust distributes the bundle of the old and new CAs so that certificates issued by either are trusted
+	CARotationPhaseTrust = 1
+	// CARotationPhaseReissue re-issues every certificate from the new CA
+	CARotationPhaseReissue = 2
+	// CARotationPhaseUntrust removes the old CA from the bundle
+	CARotationPhaseUntrust = 3
+
+	oldCACertFileName = "ca-old.pem"
+	oldCAKeyFileName  = "ca-old-key.pem"
+	newCACertFileName = "ca-new.pem"
+	newCAKeyFileName  = "ca-new-key.pem"
+)
+
+var caRotationPhaseDescriptions = map[int]string{
+	CARotationPhaseTrust:   "distribute the bundle of the old and new CAs",
+	CARotationPhaseReissue: "re-issue every certificate from the new CA",
+	CARotationPhaseUntrust: "remove the old CA from the bundle",
+}
+
+// CARotationState is the progress of a CA rotation. Each phase is started by writing the credentials and completed once the cluster is updated with them,
+// so that a phase interrupted in between is resumed by updating the cluster again
+type CARotationState struct {
+	Phase   int  `json:"phase"`
+	Updated bool `json:"updated"`
+	// WorkerCAMaterialized is true when worker-ca.pem has been replaced from a symlink to ca.pem with a file, so that the cluster signing cert doesn't become a bundle
+	WorkerCAMaterialized bool `json:"workerCAMaterialized,omitempty"`
+}
+
+type CARotationOptions struct {
+	// NewCAKeyPath and NewCACertPath are the new CA to rotate to. A new CA is generated when omitted
+	NewCAKeyPath  string
+	NewCACertPath string
+}
+
+// Description returns what the phase does
+func (s CARotationState) Description() string {
+	return caRotationPhaseDescriptions[s.Phase]
+}
+
+// NextPhase returns the phase to be started or resumed
+func (s CARotationState) NextPhase() int {
+	if s.Phase > 0 && !s.Updated {
+		return s.Phase
+	}
+	return s.Phase + 1
+}
+
+// ReadCARotationState returns the progress of the CA rotation in the assets directory, which is the zero state when no rotation is in progress
+func ReadCARotationState(dir string) (*CARotationState, error) {
+	path := filepath.Join(dir, CARotationStateFileName)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &CARotationState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	state := &CARotationState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return state, nil
+}
+
+func (s *CARotationState) save(dir string) error {
+	path := filepath.Join(dir, CARotationStateFileName)
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// StartCARotationPhase writes the credentials for the next phase of the CA rotation in the assets directory and records it.
+// It writes nothing when resuming a phase whose credentials have already been written
+func (c *Cluster) StartCARotationPhase(dir string, o CARotationOptions) (*CARotationState, error) {
+	if !c.ManageCertificates {
+		return nil, fmt.Errorf("the CA can not be rotated by kube-aws as manageCertificates is false")
+	}
+
+	state, err := ReadCARotationState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	phase := state.NextPhase()
+	if phase == state.Phase {
+		return state, nil
+	}
+
+	switch phase {
+	case CARotationPhaseTrust:
+		err = c.trustNewCA(dir, state, o)
+	case CARotationPhaseReissue:
+		err = c.reissueFromNewCA(dir, state)
+	case CARotationPhaseUntrust:
+		err = c.untrustOldCA(dir, state)
+	default:
+		err = fmt.Errorf("unexpected phase %d in %s", phase, CARotationStateFileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %v", caRotationPhaseDescriptions[phase], err)
+	}
+
+	state.Phase = phase
+	state.Updated = false
+	if err := state.save(dir); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// CompleteCARotationPhase records that the cluster has been updated with the credentials of the current phase.
+// The record is removed once the last phase is completed
+func CompleteCARotationPhase(dir string, state *CARotationState) error {
+	if state.Phase == CARotationPhaseUntrust {
+		path := filepath.Join(dir, CARotationStateFileName)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", path, err)
+		}
+		state.Updated = true
+		return nil
+	}
+	state.Updated = true
+	return state.save(dir)
+}
+
+func (c *Cluster) trustNewCA(dir string, state *CARotationState, o CARotationOptions) error {
+	caPath := filepath.Join(dir, "ca.pem")
+	currentCerts, err := readCertificatesPEM(caPath)
+	if err != nil {
+		return err
+	}
+
+	var newCACert *x509.Certificate
+	var newCAKeyPEM []byte
+	if o.NewCAKeyPath != "" || o.NewCACertPath != "" {
+		newCAKey, cert, err := ReadTLSCA(o.NewCAKeyPath, o.NewCACertPath)
+		if err != nil {
+			return err
+		}
+		newCACert, newCAKeyPEM = cert, tlsutil.EncodePrivateKeyPEM(newCAKey)
+	} else {
+		newCAKey, cert, err := c.NewTLSCA()
+		if err != nil {
+			return fmt.Errorf("failed generating new CA: %v", err)
+		}
+		newCACert, newCAKeyPEM = cert, tlsutil.EncodePrivateKeyPEM(newCAKey)
+	}
+	for _, cert := range currentCerts {
+		if cert.Equal(newCACert) {
+			return fmt.Errorf("the new CA is already in %s", caPath)
+		}
+	}
+
+	// Keep the old CA for the last phase, and the cluster signing cert of the old CA until the second phase
+	files := map[string][]byte{
+		oldCACertFileName: encodeCertificatesPEM(currentCerts),
+		newCACertFileName: tlsutil.EncodeCertificatePEM(newCACert),
+		newCAKeyFileName:  newCAKeyPEM,
+	}
+	if oldCAKey, err := ioutil.ReadFile(filepath.Join(dir, "ca-key.pem")); err == nil {
+		files[oldCAKeyFileName] = oldCAKey
+	}
+	for name, data := range files {
+		if err := writeCredentialFile(dir, name, data); err != nil {
+			return err
+		}
+	}
+
+	if state.WorkerCAMaterialized, err = materializeSymlink(dir, "worker-ca.pem"); err != nil {
+		return err
+	}
+
+	return writeCredentialFile(dir, "ca.pem", encodeCertificatesPEM(append(currentCerts, newCACert)))
+}
+
+func (c *Cluster) reissueFromNewCA(dir string, state *CARotationState) error {
+	newCAKey, err := ioutil.ReadFile(filepath.Join(dir, newCAKeyFileName))
+	if err != nil {
+		return fmt.Errorf("failed to read the new CA key: %v", err)
+	}
+	newCACert, err := ioutil.ReadFile(filepath.Join(dir, newCACertFileName))
+	if err != nil {
+		return fmt.Errorf("failed to read the new CA cert: %v", err)
+	}
+
+	// The bundle in ca.pem is kept until the last phase, whereas the cluster signing cert and key are switched to the new CA
+	// unless they are of a worker CA separated from the CA
+	if err := writeCredentialFile(dir, "ca-key.pem", newCAKey); err != nil {
+		return err
+	}
+	if err := invalidateEncryptedCredentialCache(filepath.Join(dir, "ca-key.pem")); err != nil {
+		return err
+	}
+	if state.WorkerCAMaterialized {
+		if err := writeCredentialFile(dir, "worker-ca.pem", newCACert); err != nil {
+			return err
+		}
+		if err := invalidateEncryptedCredentialCache(filepath.Join(dir, "worker-ca-key.pem")); err != nil {
+			return err
+		}
+	}
+
+	_, err = c.RotateCertificates(dir, CertificateRotationOptions{
+		CaKeyPath:  filepath.Join(dir, newCAKeyFileName),
+		CaCertPath: filepath.Join(dir, newCACertFileName),
+	})
+	return err
+}
+
+func (c *Cluster) untrustOldCA(dir string, state *CARotationState) error {
+	oldCerts, err := readCertificatesPEM(filepath.Join(dir, oldCACertFileName))
+	if err != nil {
+		return err
+	}
+	bundle, err := readCertificatesPEM(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return err
+	}
+
+	remaining := []*x509.Certificate{}
+	for _, cert := range bundle {
+		old := false
+		for _, o := range oldCerts {
+			old = old || cert.Equal(o)
+		}
+		if !old {
+			remaining = append(remaining, cert)
+		}
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("no certificate would be left in ca.pem")
+	}
+	if err := writeCredentialFile(dir, "ca.pem", encodeCertificatesPEM(remaining)); err != nil {
+		return err
+	}
+
+	if state.WorkerCAMaterialized {
+		workerCAPath := filepath.Join(dir, "worker-ca.pem")
+		if err := os.Remove(workerCAPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %v", workerCAPath, err)
+		}
+		if err := os.Symlink("ca.pem", workerCAPath); err != nil {
+			return fmt.Errorf("failed to link %s to ca.pem: %v", workerCAPath, err)
+		}
+		state.WorkerCAMaterialized = false
+	}
+
+	for _, name := range []string{oldCACertFileName, oldCAKeyFileName, newCACertFileName, newCAKeyFileName} {
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// materializeSymlink replaces the symlink with a file having the same content, so that the target can be changed without affecting it.
+// It returns false when the file isn't a symlink
+func materializeSymlink(dir string, name string) (bool, error) {
+	path := filepath.Join(dir, name)
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) || (err == nil && info.Mode()&os.ModeSymlink == 0) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return false, fmt.Errorf("failed to remove %s: %v", path, err)
+	}
+	return true, writeCredentialFile(dir, name, data)
+}
+
+// CACertificateOf returns the certificate of the CA key from the bundle, which has both the old and new CAs while the CA is being rotated
+func CACertificateOf(bundle []*x509.Certificate, key crypto.Signer) (*x509.Certificate, error) {
+	for _, cert := range bundle {
+		if tlsutil.PublicKeysEqual(key.Public(), cert.PublicKey) {
+			return cert, nil
+		}
+	}
+	return nil, fmt.Errorf("none of the %d certificates is of the CA key", len(bundle))
+}
+
+func writeCredentialFile(dir string, name string, data []byte) error {
+	path := filepath.Join(dir, name)
+	progress.Printf("INFO: Writing %d bytes to %s\n", len(data), path)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+func readCertificatesPEM(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	certs, err := tlsutil.DecodeCertificatesPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return certs, nil
+}
+
+func encodeCertificatesPEM(certs []*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		buf.Write(tlsutil.EncodeCertificatePEM(cert))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func TestCARotation(t *testing.T) {
+	helper.WithDummyCredentials(func(dir string) {
+		cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+		if err != nil {
+			t.Fatalf("failed generating config: %v", err)
+		}
+
+		read := func(name string) []byte {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", name, err)
+			}
+			return b
+		}
+
+		// The dummy CA key isn't an RSA key which the certificates can be issued with
+		oldCAKey, oldCACert, err := cluster.NewTLSCA()
+		if err != nil {
+			t.Fatalf("failed generating tls ca: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "ca-key.pem"), tlsutil.EncodePrivateKeyPEM(oldCAKey), 0600); err != nil {
+			t.Fatalf("failed to write ca key: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "ca.pem"), tlsutil.EncodeCertificatePEM(oldCACert), 0600); err != nil {
+			t.Fatalf("failed to write ca cert: %v", err)
+		}
+
+		newCAKey, newCACert, err := cluster.NewTLSCA()
+		if err != nil {
+			t.Fatalf("failed generating tls ca: %v", err)
+		}
+		caDir, err := ioutil.TempDir("", "rotation-ca")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(caDir)
+		opts := CARotationOptions{
+			NewCAKeyPath:  filepath.Join(caDir, "ca-key.pem"),
+			NewCACertPath: filepath.Join(caDir, "ca.pem"),
+		}
+		if err := ioutil.WriteFile(opts.NewCAKeyPath, tlsutil.EncodePrivateKeyPEM(newCAKey), 0600); err != nil {
+			t.Fatalf("failed to write new ca key: %v", err)
+		}
+		if err := ioutil.WriteFile(opts.NewCACertPath, tlsutil.EncodeCertificatePEM(newCACert), 0600); err != nil {
+			t.Fatalf("failed to write new ca cert: %v", err)
+		}
+
+		bundle := func() map[string]bool {
+			certs, err := tlsutil.DecodeCertificatesPEM(read("ca.pem"))
+			if err != nil {
+				t.Fatalf("failed to parse ca.pem: %v", err)
+			}
+			b := map[string]bool{}
+			for _, cert := range certs {
+				b[string(cert.Raw)] = true
+			}
+			return b
+		}
+
+		t.Run("Trust", func(t *testing.T) {
+			state, err := cluster.StartCARotationPhase(dir, opts)
+			if err != nil {
+				t.Fatalf("failed to start phase 1: %v", err)
+			}
+			if state.Phase != CARotationPhaseTrust || state.Updated || !state.WorkerCAMaterialized {
+				t.Errorf("unexpected state after starting phase 1: %+v", state)
+			}
+			b := bundle()
+			if len(b) != 2 || !b[string(oldCACert.Raw)] || !b[string(newCACert.Raw)] {
+				t.Errorf("expected ca.pem to bundle the old and new CAs but had %d certs", len(b))
+			}
+			info, err := os.Lstat(filepath.Join(dir, "worker-ca.pem"))
+			if err != nil {
+				t.Fatalf("failed to stat worker-ca.pem: %v", err)
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				t.Errorf("expected worker-ca.pem not to be linked to the bundle")
+			}
+			workerCA, err := tlsutil.DecodeCertificatePEM(read("worker-ca.pem"))
+			if err != nil || !workerCA.Equal(oldCACert) {
+				t.Errorf("expected worker-ca.pem to be kept as the old CA: %v", err)
+			}
+
+			resumed, err := cluster.StartCARotationPhase(dir, opts)
+			if err != nil {
+				t.Fatalf("failed to resume phase 1: %v", err)
+			}
+			if resumed.Phase != CARotationPhaseTrust || len(bundle()) != 2 {
+				t.Errorf("expected phase 1 to be resumed without writing the bundle again: %+v", resumed)
+			}
+
+			if err := CompleteCARotationPhase(dir, resumed); err != nil {
+				t.Fatalf("failed to complete phase 1: %v", err)
+			}
+			recorded, err := ReadCARotationState(dir)
+			if err != nil {
+				t.Fatalf("failed to read state: %v", err)
+			}
+			if recorded.Phase != CARotationPhaseTrust || !recorded.Updated || recorded.NextPhase() != CARotationPhaseReissue {
+				t.Errorf("unexpected recorded state after phase 1: %+v", recorded)
+			}
+		})
+
+		t.Run("Reissue", func(t *testing.T) {
+			state, err := cluster.StartCARotationPhase(dir, opts)
+			if err != nil {
+				t.Fatalf("failed to start phase 2: %v", err)
+			}
+			if state.Phase != CARotationPhaseReissue {
+				t.Errorf("unexpected state after starting phase 2: %+v", state)
+			}
+			if len(bundle()) != 2 {
+				t.Errorf("expected ca.pem to still bundle the old and new CAs")
+			}
+			for _, name := range []string{"apiserver", "etcd", "etcd-client", "worker", "admin", "kube-controller-manager", "kube-scheduler"} {
+				cert, err := tlsutil.DecodeCertificatePEM(read(name + ".pem"))
+				if err != nil {
+					t.Errorf("failed to parse re-issued %s cert: %v", name, err)
+					continue
+				}
+				if err := cert.CheckSignatureFrom(newCACert); err != nil {
+					t.Errorf("re-issued %s cert must be signed by the new CA: %v", name, err)
+				}
+			}
+			for _, name := range []string{"ca-key.pem", "worker-ca-key.pem"} {
+				key, err := tlsutil.DecodePrivateKeyPEM(read(name))
+				if err != nil || !tlsutil.PublicKeysEqual(key.Public(), newCAKey.Public()) {
+					t.Errorf("expected %s to be the new CA key: %v", name, err)
+				}
+			}
+			workerCA, err := tlsutil.DecodeCertificatePEM(read("worker-ca.pem"))
+			if err != nil || !workerCA.Equal(newCACert) {
+				t.Errorf("expected worker-ca.pem to be the new CA: %v", err)
+			}
+			if err := CompleteCARotationPhase(dir, state); err != nil {
+				t.Fatalf("failed to complete phase 2: %v", err)
+			}
+		})
+
+		t.Run("Untrust", func(t *testing.T) {
+			state, err := cluster.StartCARotationPhase(dir, opts)
+			if err != nil {
+				t.Fatalf("failed to start phase 3: %v", err)
+			}
+			if state.Phase != CARotationPhaseUntrust || state.WorkerCAMaterialized {
+				t.Errorf("unexpected state after starting phase 3: %+v", state)
+			}
+			b := bundle()
+			if len(b) != 1 || !b[string(newCACert.Raw)] {
+				t.Errorf("expected ca.pem to have only the new CA but had %d certs", len(b))
+			}
+			if target, err := os.Readlink(filepath.Join(dir, "worker-ca.pem")); err != nil || target != "ca.pem" {
+				t.Errorf("expected worker-ca.pem to be linked to ca.pem again: %s, %v", target, err)
+			}
+			for _, name := range []string{"ca-old.pem", "ca-old-key.pem", "ca-new.pem", "ca-new-key.pem"} {
+				if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed: %v", name, err)
+				}
+			}
+
+			if err := CompleteCARotationPhase(dir, state); err != nil {
+				t.Fatalf("failed to complete phase 3: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, CARotationStateFileName)); !os.IsNotExist(err) {
+				t.Errorf("expected %s to be removed after the last phase: %v", CARotationStateFileName, err)
+			}
+			recorded, err := ReadCARotationState(dir)
+			if err != nil || recorded.NextPhase() != CARotationPhaseTrust {
+				t.Errorf("expected another rotation to start from phase 1: %+v, %v", recorded, err)
+			}
+		})
+	})
+}
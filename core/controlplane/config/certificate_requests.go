@@ -0,0 +1,193 @@
+package config
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/progress"
+	"github.com/kubernetes-incubator/kube-aws/tlscerts"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+// validateExternalCA returns an error when the cluster can't work with the certificates signed by an external CA, whose key is never given to kube-aws
+func (c *Cluster) validateExternalCA() error {
+	if !c.ManageCertificates {
+		return fmt.Errorf("certificates can not be signed by an external CA as manageCertificates is false")
+	}
+	if c.Experimental.TLSBootstrap.Enabled {
+		return fmt.Errorf("kubelet TLS bootstrapping requires the CA key on controller nodes, which is not available when the certificates are signed by an external CA. Render the credentials with the key of an intermediate CA instead")
+	}
+	return nil
+}
+
+// WriteCertificateRequests generates the key of every certificate and writes it with the CSR named like `apiserver.csr` to the assets directory,
+// so that the certificates can be signed by an external CA. It returns the paths to the CSRs written
+func (c *Cluster) WriteCertificateRequests(dir string, o CredentialsOptions) ([]string, error) {
+	if err := c.validateExternalCA(); err != nil {
+		return nil, err
+	}
+
+	written := []string{}
+	for _, name := range tlsCertificateNames(o.KIAM) {
+		spec, err := c.tlsCertificateSpec(name)
+		if err != nil {
+			return nil, err
+		}
+		key, err := c.NewTLSKey(name)
+		if err != nil {
+			return nil, err
+		}
+		csr, err := tlsutil.NewCertificateRequest(spec.ClientCertConfig, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the certificate request of %s: %v", name, err)
+		}
+		if err := writeCredentialFile(dir, name+"-key.pem", tlsutil.EncodePrivateKeyPEM(key)); err != nil {
+			return nil, err
+		}
+		if err := writeCredentialFile(dir, name+".csr", tlsutil.EncodeCertificateRequestPEM(csr)); err != nil {
+			return nil, err
+		}
+		written = append(written, filepath.Join(dir, name+".csr"))
+	}
+	return written, nil
+}
+
+// ImportSignedCertificates imports the certificates signed by an external CA for the CSRs written by WriteCertificateRequests, and writes the rest of the assets with the CA chain as ca.pem.
+// Each certificate named like `apiserver.pem` in o.SignedCertificatesDir must be of the key in the assets directory, have the subject, SANs and usages requested,
+// and be issued by the first CA of the chain in o.CaCertPath
+func (c *Cluster) ImportSignedCertificates(dir string, o CredentialsOptions) (*RawAssetsOnDisk, error) {
+	if err := c.validateExternalCA(); err != nil {
+		return nil, err
+	}
+
+	progress.Println("Importing signed certificates...")
+	caCerts, err := readCertificatesPEM(o.CaCertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := tlsCertificateNames(o.KIAM)
+	keys := map[string]crypto.Signer{}
+	certs := map[string]*x509.Certificate{}
+	for _, name := range names {
+		if keys[name], certs[name], err = readSignedCertificate(dir, o.SignedCertificatesDir, name); err != nil {
+			return nil, err
+		}
+	}
+
+	// The CA signing the certificates is found from the apiserver cert, so that the chain to be distributed can be ordered from it
+	var signing *x509.Certificate
+	for _, cert := range caCerts {
+		if certs[names[0]].CheckSignatureFrom(cert) == nil {
+			signing = cert
+			break
+		}
+	}
+	if signing == nil {
+		return nil, fmt.Errorf("%s.pem is not signed by any CA in %s", names[0], o.CaCertPath)
+	}
+	now := time.Now()
+	caChain, err := verifyCAChain(signing, caCerts, now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ca cert file %s: %v", o.CaCertPath, err)
+	}
+	progress.Printf("-> Verifying the certificates against the chain of %d CAs from %s\n", len(caChain), signing.Subject.CommonName)
+
+	for _, name := range names {
+		spec, err := c.tlsCertificateSpec(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignedCertificate(certs[name], spec, caChain, now); err != nil {
+			return nil, fmt.Errorf("invalid certificate %s.pem: %v", name, err)
+		}
+	}
+
+	assets, err := newRawAssetsOnMemory(caChain, nil, keys, certs, o.KIAM)
+	if err != nil {
+		return nil, fmt.Errorf("Error generating default assets: %v", err)
+	}
+	return c.writeAssets(dir, assets, false, o.KIAM)
+}
+
+// readSignedCertificate reads the key written with the CSR from the assets directory, and the certificate signed for it from signedDir
+func readSignedCertificate(dir string, signedDir string, name string) (crypto.Signer, *x509.Certificate, error) {
+	keyPath := filepath.Join(dir, name+"-key.pem")
+	keyBytes, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s, which is written with the certificate request: %v", keyPath, err)
+	}
+	key, err := tlsutil.DecodePrivateKeyPEM(keyBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %v", keyPath, err)
+	}
+
+	// The certificate may be followed by the chain of the CA
+	certs, err := readCertificatesPEM(filepath.Join(signedDir, name+".pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !tlsutil.PublicKeysEqual(key.Public(), certs[0].PublicKey) {
+		return nil, nil, fmt.Errorf("the certificate in %s is not of the key in %s", filepath.Join(signedDir, name+".pem"), keyPath)
+	}
+	return key, certs[0], nil
+}
+
+// verifySignedCertificate verifies that the certificate is issued as requested with the spec, and chains up to the root CA of the chain
+func verifySignedCertificate(cert *x509.Certificate, spec *tlsCertificateSpec, caChain []*x509.Certificate, now time.Time) error {
+	if cert.Subject.CommonName != spec.CommonName {
+		return fmt.Errorf("the common name must be %s but was %s", spec.CommonName, cert.Subject.CommonName)
+	}
+	for _, o := range spec.Organization {
+		if !containsString(cert.Subject.Organization, o) {
+			return fmt.Errorf("the organization %s is missing", o)
+		}
+	}
+
+	c := tlscerts.Certificate{DNSNames: cert.DNSNames, IPAddresses: cert.IPAddresses}
+	for _, name := range spec.DNSNames {
+		if !c.ContainsDNSName(name) {
+			return fmt.Errorf("the dns name %s is missing", name)
+		}
+	}
+	for _, ip := range spec.IPAddresses {
+		if !c.ContainsIPAddress(net.ParseIP(ip)) {
+			return fmt.Errorf("the ip address %s is missing", ip)
+		}
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+	}
+	opts.Roots.AddCert(caChain[len(caChain)-1])
+	for _, ca := range caChain[:len(caChain)-1] {
+		opts.Intermediates.AddCert(ca)
+	}
+	if err := cert.CheckSignatureFrom(caChain[0]); err != nil {
+		return fmt.Errorf("not signed by %s: %v", caChain[0].Subject.CommonName, err)
+	}
+	// A certificate used as both the client and server is verified for each usage, as any one of the usages satisfies the verification
+	for _, usage := range spec.usage.extKeyUsages() {
+		opts.KeyUsages = []x509.ExtKeyUsage{usage}
+		if _, err := cert.Verify(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func TestCertificateRequests(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "certificate-requests")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	credentialsDir := filepath.Join(dir, "credentials")
+	signedDir := filepath.Join(dir, "signed")
+	for _, d := range []string{credentialsDir, signedDir} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			t.Fatalf("failed to create %s: %v", d, err)
+		}
+	}
+
+	rootKey, rootCert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating tls ca: %v", err)
+	}
+	intermediateKey, intermediateCert := newTestIntermediateCA(t, "kube-intermediate-ca", true, rootCert, rootKey)
+	chainPath := filepath.Join(dir, "chain.pem")
+	if err := ioutil.WriteFile(chainPath, encodeCertificatesPEM([]*x509.Certificate{intermediateCert, rootCert}), 0600); err != nil {
+		t.Fatalf("failed to write chain: %v", err)
+	}
+
+	// sign plays the external CA, issuing the certificate as requested with the usages
+	sign := func(name string, usages []x509.ExtKeyUsage, caCert *x509.Certificate, caKey crypto.Signer) {
+		csr, err := tlsutil.DecodeCertificateRequestPEM(mustReadFile(t, filepath.Join(credentialsDir, name+".csr")))
+		if err != nil {
+			t.Fatalf("failed to parse %s csr: %v", name, err)
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Fatalf("invalid signature of %s csr: %v", name, err)
+		}
+		tmpl := x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			IPAddresses:  csr.IPAddresses,
+			NotBefore:    caCert.NotBefore,
+			NotAfter:     time.Now().Add(24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  usages,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &tmpl, caCert, csr.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("failed to sign %s csr: %v", name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(signedDir, name+".pem"), tlsutil.EncodeCertificatePEM(&x509.Certificate{Raw: der}), 0600); err != nil {
+			t.Fatalf("failed to write %s cert: %v", name, err)
+		}
+	}
+	signAll := func() {
+		for _, name := range tlsCertificateNames(true) {
+			spec, err := cluster.tlsCertificateSpec(name)
+			if err != nil {
+				t.Fatalf("failed to get the spec of %s: %v", name, err)
+			}
+			sign(name, spec.usage.extKeyUsages(), intermediateCert, intermediateKey)
+		}
+	}
+
+	opts := CredentialsOptions{
+		CaCertPath:            chainPath,
+		KIAM:                  true,
+		SignedCertificatesDir: signedDir,
+	}
+
+	t.Run("WriteCertificateRequests", func(t *testing.T) {
+		csrs, err := cluster.WriteCertificateRequests(credentialsDir, opts)
+		if err != nil {
+			t.Fatalf("failed to write certificate requests: %v", err)
+		}
+		if len(csrs) != 9 {
+			t.Errorf("expected 9 certificate requests but was %d", len(csrs))
+		}
+
+		admin, err := tlsutil.DecodeCertificateRequestPEM(mustReadFile(t, filepath.Join(credentialsDir, "admin.csr")))
+		if err != nil {
+			t.Fatalf("failed to parse admin csr: %v", err)
+		}
+		if admin.Subject.CommonName != "kube-admin" || strings.Join(admin.Subject.Organization, ",") != "system:masters" {
+			t.Errorf("unexpected subject of admin csr: %v", admin.Subject)
+		}
+		apiserver, err := tlsutil.DecodeCertificateRequestPEM(mustReadFile(t, filepath.Join(credentialsDir, "apiserver.csr")))
+		if err != nil {
+			t.Fatalf("failed to parse apiserver csr: %v", err)
+		}
+		if !containsString(apiserver.DNSNames, "test.staging.core-os.net") || len(apiserver.IPAddresses) != 2 {
+			t.Errorf("unexpected SANs of apiserver csr: %v %v", apiserver.DNSNames, apiserver.IPAddresses)
+		}
+	})
+
+	t.Run("ImportSignedCertificates", func(t *testing.T) {
+		signAll()
+		if _, err := cluster.ImportSignedCertificates(credentialsDir, opts); err != nil {
+			t.Fatalf("failed to import signed certificates: %v", err)
+		}
+
+		caCerts, err := readCertificatesPEM(filepath.Join(credentialsDir, "ca.pem"))
+		if err != nil {
+			t.Fatalf("failed to read ca.pem: %v", err)
+		}
+		if len(caCerts) != 2 || !caCerts[0].Equal(intermediateCert) || !caCerts[1].Equal(rootCert) {
+			t.Errorf("expected ca.pem to be the chain from the intermediate to the root ca: %d certs", len(caCerts))
+		}
+		if target, err := os.Readlink(filepath.Join(credentialsDir, "kiam-ca.pem")); err == nil {
+			t.Errorf("expected kiam-ca.pem to be written rather than linked to %s", target)
+		}
+		for _, name := range tlsCertificateNames(true) {
+			key, err := tlsutil.DecodePrivateKeyPEM(mustReadFile(t, filepath.Join(credentialsDir, name+"-key.pem")))
+			if err != nil {
+				t.Fatalf("failed to parse %s key: %v", name, err)
+			}
+			cert, err := tlsutil.DecodeCertificatePEM(mustReadFile(t, filepath.Join(credentialsDir, name+".pem")))
+			if err != nil {
+				t.Fatalf("failed to parse %s cert: %v", name, err)
+			}
+			if !tlsutil.PublicKeysEqual(key.Public(), cert.PublicKey) {
+				t.Errorf("expected %s cert to be of the key written with the csr", name)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(credentialsDir, "ca-key.pem")); !os.IsNotExist(err) {
+			t.Errorf("expected no ca key to be written: %v", err)
+		}
+	})
+
+	t.Run("InvalidCertificates", func(t *testing.T) {
+		otherKey, otherCert, err := cluster.NewTLSCA()
+		if err != nil {
+			t.Fatalf("failed generating tls ca: %v", err)
+		}
+
+		testCases := []struct {
+			name   string
+			resign func()
+			err    string
+		}{
+			{
+				"MissingUsage",
+				func() {
+					sign("kiam-server", []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, intermediateCert, intermediateKey)
+				},
+				"invalid certificate kiam-server.pem",
+			},
+			{
+				"UnrelatedCA",
+				func() { sign("worker", []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, otherCert, otherKey) },
+				"invalid certificate worker.pem: not signed by kube-intermediate-ca",
+			},
+			{
+				"UnrelatedCAForAll",
+				func() { sign("apiserver", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, otherCert, otherKey) },
+				"apiserver.pem is not signed by any CA",
+			},
+		}
+		for _, tc := range testCases {
+			signAll()
+			tc.resign()
+			_, err := cluster.ImportSignedCertificates(credentialsDir, opts)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected an error containing %q but was %v", tc.name, tc.err, err)
+			}
+		}
+
+		signAll()
+		if err := ioutil.WriteFile(filepath.Join(credentialsDir, "admin-key.pem"), tlsutil.EncodePrivateKeyPEM(otherKey), 0600); err != nil {
+			t.Fatalf("failed to overwrite admin key: %v", err)
+		}
+		if _, err := cluster.ImportSignedCertificates(credentialsDir, opts); err == nil || !strings.Contains(err.Error(), "is not of the key in") {
+			t.Errorf("expected an error for the certificate of another key but was %v", err)
+		}
+	})
+
+	t.Run("TLSBootstrap", func(t *testing.T) {
+		bootstrapped := *cluster
+		bootstrapped.Experimental.TLSBootstrap.Enabled = true
+		if _, err := bootstrapped.WriteCertificateRequests(credentialsDir, opts); err == nil || !strings.Contains(err.Error(), "kubelet TLS bootstrapping requires the CA key") {
+			t.Errorf("expected an error for TLS bootstrapping but was %v", err)
+		}
+	})
+}
+
+func TestCredentialsOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		opts  CredentialsOptions
+		valid bool
+	}{
+		{CredentialsOptions{GenerateCA: true}, true},
+		{CredentialsOptions{CertificateRequests: true}, true},
+		{CredentialsOptions{SignedCertificatesDir: "signed"}, true},
+		{CredentialsOptions{GenerateCA: true, CertificateRequests: true}, false},
+		{CredentialsOptions{GenerateCA: true, SignedCertificatesDir: "signed"}, false},
+		{CredentialsOptions{CertificateRequests: true, SignedCertificatesDir: "signed"}, false},
+	}
+	for _, tc := range testCases {
+		if err := tc.opts.Validate(); (err == nil) != tc.valid {
+			t.Errorf("unexpected result of validating %+v: %v", tc.opts, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+// RotatableCertificates are the names of the certificates which can be re-issued from the existing CA by RotateCertificates.
+// Each is stored as `<name>.pem` along with its key `<name>-key.pem` in the assets directory
+var RotatableCertificates = []string{
+	"apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+	"worker",
+	"admin",
+	"etcd",
+	"etcd-client",
+	"kiam-server",
+	"kiam-agent",
+}
+
+type CertificateRotationOptions struct {
+	CaKeyPath  string
+	CaCertPath string
+	// Certificates are the names of the certificates to be re-issued. All the certificates in use are re-issued when empty
+	Certificates []string
+}
+
+// CertificatesToRotate returns the names of the certificates to be re-issued, or an error for a certificate unknown or not in use
+func (c *Cluster) CertificatesToRotate(o CertificateRotationOptions) ([]string, error) {
+	kiamEnabled := c.Experimental.KIAMSupport.Enabled
+	inUse := func(name string) bool {
+		return kiamEnabled || !strings.HasPrefix(name, "kiam-")
+	}
+
+	if len(o.Certificates) == 0 {
+		names := []string{}
+		for _, name := range RotatableCertificates {
+			if inUse(name) {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	for _, name := range o.Certificates {
+		known := false
+		for _, n := range RotatableCertificates {
+			known = known || n == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown certificate %q. It must be one of: %s", name, strings.Join(RotatableCertificates, ", "))
+		}
+		if !inUse(name) {
+			return nil, fmt.Errorf("certificate %q can not be rotated as experimental.kiamSupport is disabled", name)
+		}
+	}
+	return o.Certificates, nil
+}
+
+// RotateCertificates re-issues the certificates from the existing CA with new keys, and writes them to the assets directory.
+// The .enc and .fingerprint caches of the files written are removed so that they are encrypted again on the next render or update.
+// It returns the paths to the files written
+func (c *Cluster) RotateCertificates(dir string, o CertificateRotationOptions) ([]string, error) {
+	if !c.ManageCertificates {
+		return nil, fmt.Errorf("certificates can not be rotated by kube-aws as manageCertificates is false")
+	}
+
+	names, err := c.CertificatesToRotate(o)
+	if err != nil {
+		return nil, err
+	}
+
+	caKey, caCert, err := ReadTLSCA(o.CaKeyPath, o.CaCertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	written := []string{}
+	for _, name := range names {
+		key, err := c.NewTLSKey(name)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := c.NewTLSCertificate(name, key, caCert, caKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue %s certificate: %v", name, err)
+		}
+
+		files := []struct {
+			name string
+			data []byte
+		}{
+			{name + ".pem", tlsutil.EncodeCertificatePEM(cert)},
+			{name + "-key.pem", tlsutil.EncodePrivateKeyPEM(key)},
+		}
+		for _, f := range files {
+			path := filepath.Join(dir, f.name)
+			if err := ioutil.WriteFile(path, f.data, 0600); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %v", path, err)
+			}
+			if err := invalidateEncryptedCredentialCache(path); err != nil {
+				return nil, err
+			}
+			written = append(written, path)
+		}
+	}
+
+	return written, nil
+}
+
+// invalidateEncryptedCredentialCache removes the encrypted cache and the fingerprint of the credential file, if any
+func invalidateEncryptedCredentialCache(rawCredFilePath string) error {
+	for _, path := range []string{cacheFilePath(rawCredFilePath), fingerprintFilePath(rawCredFilePath)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", path, err)
+		}
+	}
+	return nil
+}
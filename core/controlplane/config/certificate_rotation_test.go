@@ -0,0 +1,121 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func TestRotateCertificates(t *testing.T) {
+	helper.WithDummyCredentials(func(dir string) {
+		cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+		if err != nil {
+			t.Fatalf("failed generating config: %v", err)
+		}
+
+		kmsConfig := NewKMSConfig("keyarn", &dummyEncryptService{}, nil)
+		if _, err := ReadOrCreateCompactAssets(dir, true, true, false, kmsConfig); err != nil {
+			t.Fatalf("failed to encrypt assets: %v", err)
+		}
+
+		read := func(name string) []byte {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", name, err)
+			}
+			return b
+		}
+		originalWorkerKey := read("worker-key.pem")
+
+		// The dummy CA key isn't an RSA key which the certificates can be issued with
+		caDir, err := ioutil.TempDir("", "rotation-ca")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(caDir)
+		caKey, caCert, err := cluster.NewTLSCA()
+		if err != nil {
+			t.Fatalf("failed generating tls ca: %v", err)
+		}
+		opts := CertificateRotationOptions{
+			CaKeyPath:    filepath.Join(caDir, "ca-key.pem"),
+			CaCertPath:   filepath.Join(caDir, "ca.pem"),
+			Certificates: []string{"apiserver", "etcd"},
+		}
+		if err := ioutil.WriteFile(opts.CaKeyPath, tlsutil.EncodePrivateKeyPEM(caKey), 0600); err != nil {
+			t.Fatalf("failed to write ca key: %v", err)
+		}
+		if err := ioutil.WriteFile(opts.CaCertPath, tlsutil.EncodeCertificatePEM(caCert), 0600); err != nil {
+			t.Fatalf("failed to write ca cert: %v", err)
+		}
+		written, err := cluster.RotateCertificates(dir, opts)
+		if err != nil {
+			t.Fatalf("failed to rotate certificates: %v", err)
+		}
+		if len(written) != 4 {
+			t.Errorf("expected the certs and keys of apiserver and etcd to be written but were: %v", written)
+		}
+
+		for name, commonName := range map[string]string{"apiserver": "kube-apiserver", "etcd": "kube-etcd"} {
+			cert, err := tlsutil.DecodeCertificatePEM(read(name + ".pem"))
+			if err != nil {
+				t.Errorf("failed to parse rotated %s cert: %v", name, err)
+				continue
+			}
+			if cert.Subject.CommonName != commonName {
+				t.Errorf("unexpected common name of %s cert: %s", name, cert.Subject.CommonName)
+			}
+			if err := cert.CheckSignatureFrom(caCert); err != nil {
+				t.Errorf("rotated %s cert must be signed by the existing CA: %v", name, err)
+			}
+			key, err := tlsutil.DecodePrivateKeyPEM(read(name + "-key.pem"))
+			if err != nil {
+				t.Errorf("failed to parse rotated %s key: %v", name, err)
+				continue
+			}
+			if !tlsutil.PublicKeysEqual(key.Public(), cert.PublicKey) {
+				t.Errorf("rotated %s cert must be issued for the rotated key", name)
+			}
+			for _, cache := range []string{name + "-key.pem.enc", name + "-key.pem.fingerprint"} {
+				if _, err := os.Stat(filepath.Join(dir, cache)); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed but was not: %v", cache, err)
+				}
+			}
+		}
+
+		if string(read("worker-key.pem")) != string(originalWorkerKey) {
+			t.Error("worker key must not change as it is not rotated")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "worker-key.pem.enc")); err != nil {
+			t.Errorf("expected the cache of worker key to be kept: %v", err)
+		}
+
+		if _, err := cluster.RotateCertificates(dir, CertificateRotationOptions{CaKeyPath: opts.CaKeyPath, CaCertPath: opts.CaCertPath, Certificates: []string{"ca"}}); err == nil || !strings.Contains(err.Error(), `unknown certificate "ca"`) {
+			t.Errorf("expected an error for an unknown certificate but was %v", err)
+		}
+		if _, err := cluster.RotateCertificates(dir, CertificateRotationOptions{CaKeyPath: opts.CaKeyPath, CaCertPath: opts.CaCertPath, Certificates: []string{"kiam-agent"}}); err == nil || !strings.Contains(err.Error(), "kiamSupport is disabled") {
+			t.Errorf("expected an error for a kiam certificate while kiam is disabled but was %v", err)
+		}
+	})
+}
+
+func TestCertificatesToRotate(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+
+	names, err := cluster.CertificatesToRotate(CertificateRotationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "apiserver,kube-controller-manager,kube-scheduler,worker,admin,etcd,etcd-client"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("expected all the certificates but kiam ones to be rotated by default: expected %s but was %v", expected, names)
+	}
+}
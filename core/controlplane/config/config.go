@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -24,6 +25,7 @@ import (
 	"github.com/kubernetes-incubator/kube-aws/netutil"
 	"github.com/kubernetes-incubator/kube-aws/node"
 	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 )
 
 const (
@@ -326,6 +328,12 @@ func (c *Cluster) Load() error {
 
 	c.ConsumeDeprecatedKeys()
 
+	awsEndpoints, err := c.AWSEndpoints.WithEnvOverrides(os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("invalid cluster: %v", err)
+	}
+	c.AWSEndpoints = awsEndpoints
+
 	if err := c.validate(); err != nil {
 		return fmt.Errorf("invalid cluster: %v", err)
 	}
@@ -340,12 +348,12 @@ func (c *Cluster) Load() error {
 func (c *Cluster) ConsumeDeprecatedKeys() {
 	// TODO Remove in v0.9.9-rc.1
 	if c.DeprecatedVPCID != "" {
-		fmt.Println("WARN: vpcId is deprecated and will be removed in v0.9.9. Please use vpc.id instead")
+		progress.Println("WARN: vpcId is deprecated and will be removed in v0.9.9. Please use vpc.id instead, or run \"kube-aws config migrate\"")
 		c.VPC.ID = c.DeprecatedVPCID
 	}
 
 	if c.DeprecatedInternetGatewayID != "" {
-		fmt.Println("WARN: internetGatewayId is deprecated and will be removed in v0.9.9. Please use internetGateway.id instead")
+		progress.Println("WARN: internetGatewayId is deprecated and will be removed in v0.9.9. Please use internetGateway.id instead, or run \"kube-aws config migrate\"")
 		c.InternetGateway.ID = c.DeprecatedInternetGatewayID
 	}
 }
@@ -472,11 +480,14 @@ type DeploymentSettings struct {
 	CloudFormation                        model.CloudFormation  `yaml:"cloudformation,omitempty"`
 	ClusterName                           string                `yaml:"clusterName,omitempty"`
 	S3URI                                 string                `yaml:"s3URI,omitempty"`
+	S3Assets                              model.S3Assets        `yaml:"s3Assets,omitempty"`
+	AWSEndpoints                          model.AWSEndpoints    `yaml:"awsEndpoints,omitempty"`
+	AWSCredentials                        model.AWSCredentials  `yaml:"awsCredentials,omitempty"`
 	DisableContainerLinuxAutomaticUpdates string                `yaml:"disableContainerLinuxAutomaticUpdates,omitempty"`
 	KeyName                               string                `yaml:"keyName,omitempty"`
 	Region                                model.Region          `yaml:",inline"`
 	AvailabilityZone                      string                `yaml:"availabilityZone,omitempty"`
-	ReleaseChannel                        string                `yaml:"releaseChannel,omitempty"`
+	ReleaseChannel                        string                `yaml:"releaseChannel,omitempty" enum:"alpha,beta,stable"`
 	AmiId                                 string                `yaml:"amiId,omitempty"`
 	DeprecatedVPCID                       string                `yaml:"vpcId,omitempty"`
 	VPC                                   model.VPC             `yaml:"vpc,omitempty"`
@@ -486,7 +497,7 @@ type DeploymentSettings struct {
 	VPCCIDR                 string            `yaml:"vpcCIDR,omitempty"`
 	InstanceCIDR            string            `yaml:"instanceCIDR,omitempty"`
 	K8sVer                  string            `yaml:"kubernetesVersion,omitempty"`
-	ContainerRuntime        string            `yaml:"containerRuntime,omitempty"`
+	ContainerRuntime        string            `yaml:"containerRuntime,omitempty" enum:"docker,rkt"`
 	KMSKeyARN               string            `yaml:"kmsKeyArn,omitempty"`
 	StackTags               map[string]string `yaml:"stackTags,omitempty"`
 	Subnets                 model.Subnets     `yaml:"subnets,omitempty"`
@@ -538,12 +549,12 @@ type DeploymentSettings struct {
 type DefaultWorkerSettings struct {
 	WorkerCreateTimeout    string   `yaml:"workerCreateTimeout,omitempty"`
 	WorkerInstanceType     string   `yaml:"workerInstanceType,omitempty"`
-	WorkerRootVolumeType   string   `yaml:"workerRootVolumeType,omitempty"`
+	WorkerRootVolumeType   string   `yaml:"workerRootVolumeType,omitempty" enum:"standard,gp2,io1"`
 	WorkerRootVolumeIOPS   int      `yaml:"workerRootVolumeIOPS,omitempty"`
 	WorkerRootVolumeSize   int      `yaml:"workerRootVolumeSize,omitempty"`
 	WorkerSpotPrice        string   `yaml:"workerSpotPrice,omitempty"`
 	WorkerSecurityGroupIds []string `yaml:"workerSecurityGroupIds,omitempty"`
-	WorkerTenancy          string   `yaml:"workerTenancy,omitempty"`
+	WorkerTenancy          string   `yaml:"workerTenancy,omitempty" enum:"default,dedicated"`
 	WorkerTopologyPrivate  bool     `yaml:"workerTopologyPrivate,omitempty"`
 }
 
@@ -570,13 +581,14 @@ type Cluster struct {
 	ControllerSettings     `yaml:",inline"`
 	EtcdSettings           `yaml:",inline"`
 	FlannelSettings        `yaml:",inline"`
-	AdminAPIEndpointName   string              `yaml:"adminAPIEndpointName,omitempty"`
-	ServiceCIDR            string              `yaml:"serviceCIDR,omitempty"`
-	RecordSetTTL           int                 `yaml:"recordSetTTL,omitempty"`
-	TLSCADurationDays      int                 `yaml:"tlsCADurationDays,omitempty"`
-	TLSCertDurationDays    int                 `yaml:"tlsCertDurationDays,omitempty"`
-	HostedZoneID           string              `yaml:"hostedZoneId,omitempty"`
-	PluginConfigs          model.PluginConfigs `yaml:"kubeAwsPlugins,omitempty"`
+	AdminAPIEndpointName   string                 `yaml:"adminAPIEndpointName,omitempty"`
+	ServiceCIDR            string                 `yaml:"serviceCIDR,omitempty"`
+	RecordSetTTL           int                    `yaml:"recordSetTTL,omitempty"`
+	TLSCADurationDays      int                    `yaml:"tlsCADurationDays,omitempty"`
+	TLSCertDurationDays    int                    `yaml:"tlsCertDurationDays,omitempty"`
+	TLSKeyAlgorithms       model.TLSKeyAlgorithms `yaml:"tlsKeyAlgorithms,omitempty"`
+	HostedZoneID           string                 `yaml:"hostedZoneId,omitempty"`
+	PluginConfigs          model.PluginConfigs    `yaml:"kubeAwsPlugins,omitempty"`
 	ProvidedEncryptService EncryptService
 	// SSHAccessAllowedSourceCIDRs is network ranges of sources you'd like SSH accesses to be allowed from, in CIDR notation
 	SSHAccessAllowedSourceCIDRs model.CIDRRanges       `yaml:"sshAccessAllowedSourceCIDRs,omitempty"`
@@ -774,7 +786,7 @@ type Networking struct {
 
 type SelfHosting struct {
 	Enabled         bool        `yaml:"enabled"`
-	Type            string      `yaml:"type"`
+	Type            string      `yaml:"type" enum:"canal,flannel"`
 	Typha           bool        `yaml:"typha"`
 	CalicoNodeImage model.Image `yaml:"calicoNodeImage"`
 	CalicoCniImage  model.Image `yaml:"calicoCniImage"`
@@ -1000,6 +1012,8 @@ type StackTemplateOptions struct {
 	S3URI                 string
 	PrettyPrint           bool
 	SkipWait              bool
+	// Offline skips encrypting the compact assets with KMS, so that they can be rendered without AWS credentials
+	Offline bool
 }
 
 func (c Cluster) StackConfig(stackName string, opts StackTemplateOptions, session *session.Session, extra ...[]*pluginmodel.Plugin) (*StackConfig, error) {
@@ -1020,7 +1034,7 @@ func (c Cluster) StackConfig(stackName string, opts StackTemplateOptions, sessio
 
 	var compactAssets *CompactAssets
 
-	if c.AssetsEncryptionEnabled() {
+	if c.AssetsEncryptionEnabled() && !opts.Offline {
 		kmsConfig := NewKMSConfig(c.KMSKeyARN, c.ProvidedEncryptService, session)
 		compactAssets, err = ReadOrCreateCompactAssets(opts.AssetsDir, c.ManageCertificates, c.Experimental.TLSBootstrap.Enabled, c.Experimental.KIAMSupport.Enabled, kmsConfig)
 		if err != nil {
@@ -1091,7 +1105,7 @@ func (c Config) VPCLogicalName() (string, error) {
 }
 
 func (c Config) VPCID() (string, error) {
-	fmt.Println("WARN: .VPCID in stack template is deprecated and will be removed in v0.9.9. Please use .VPC.ID instead")
+	progress.Println("WARN: .VPCID in stack template is deprecated and will be removed in v0.9.9. Please use .VPC.ID instead")
 	if !c.VPC.HasIdentifier() {
 		return "", fmt.Errorf("[BUG] .VPCID should not be called in stack template when vpc.id(FromStackOutput) is specified. Use .VPCManaged instead.")
 	}
@@ -1181,6 +1195,10 @@ func (c Cluster) validate() error {
 		return fmt.Errorf("clusterName(=%s) is malformed. It must consist only of alphanumeric characters, colons, or hyphens", c.ClusterName)
 	}
 
+	if err := c.TLSKeyAlgorithms.Validate(); err != nil {
+		return err
+	}
+
 	var dnsServiceIPAddr net.IP
 
 	if kubeClusterValidationResult, err := c.KubeClusterSettings.Validate(); err != nil {
@@ -1255,7 +1273,7 @@ func (c Cluster) validate() error {
 	}
 
 	if c.Controller.InstanceType == "t2.micro" || c.Etcd.InstanceType == "t2.micro" || c.Controller.InstanceType == "t2.nano" || c.Etcd.InstanceType == "t2.nano" {
-		fmt.Println(`WARNING: instance types "t2.nano" and "t2.micro" are not recommended. See https://github.com/kubernetes-incubator/kube-aws/issues/258 for more information`)
+		progress.Println(`WARNING: instance types "t2.nano" and "t2.micro" are not recommended. See https://github.com/kubernetes-incubator/kube-aws/issues/258 for more information`)
 	}
 
 	if len(c.Controller.IAMConfig.Role.Name) > 0 {
@@ -1332,6 +1350,12 @@ func (c DeploymentSettings) Validate() (*DeploymentValidationResult, error) {
 	if c.S3URI == "" {
 		return nil, errors.New("s3URI must be set")
 	}
+	if err := c.S3Assets.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.AWSEndpoints.Validate(); err != nil {
+		return nil, err
+	}
 	if c.KMSKeyARN == "" && c.AssetsEncryptionEnabled() {
 		return nil, errors.New("kmsKeyArn must be set")
 	}
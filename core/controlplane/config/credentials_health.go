@@ -0,0 +1,164 @@
+package config
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/netutil"
+	"github.com/kubernetes-incubator/kube-aws/tlscerts"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+// CertificateHealth is the result of checking a certificate in the credentials directory
+type CertificateHealth struct {
+	File            string    `json:"file"`
+	CommonName      string    `json:"commonName"`
+	NotAfter        time.Time `json:"notAfter"`
+	DaysUntilExpiry int       `json:"daysUntilExpiry"`
+	// SignedByCA is true when the certificate is signed by one of the CAs in ca.pem, including the CAs themselves
+	SignedByCA bool `json:"signedByCA"`
+	// MissingDNSNames and MissingIPAddresses are the SANs expected from cluster.yaml but missing in the apiserver cert
+	MissingDNSNames    []string `json:"missingDNSNames,omitempty"`
+	MissingIPAddresses []string `json:"missingIPAddresses,omitempty"`
+}
+
+// CredentialsHealthReport is the health of all the certificates in the credentials directory
+type CredentialsHealthReport struct {
+	Certificates []CertificateHealth `json:"certificates"`
+}
+
+// Problems returns a description of each problem found in the certificates.
+// Certificates expiring in less than warnDays are considered problems too, unless warnDays is zero
+func (r CredentialsHealthReport) Problems(warnDays int) []string {
+	problems := []string{}
+	for _, h := range r.Certificates {
+		name := fmt.Sprintf("%s (CN=%s)", h.File, h.CommonName)
+		if h.DaysUntilExpiry < 0 {
+			problems = append(problems, fmt.Sprintf("%s expired on %s", name, h.NotAfter.Format(tlscerts.ValidityFormat)))
+		} else if warnDays > 0 && h.DaysUntilExpiry < warnDays {
+			problems = append(problems, fmt.Sprintf("%s expires in %d days, which is less than %d days", name, h.DaysUntilExpiry, warnDays))
+		}
+		if !h.SignedByCA {
+			problems = append(problems, fmt.Sprintf("%s is not signed by the CA in ca.pem", name))
+		}
+		if len(h.MissingDNSNames) > 0 {
+			problems = append(problems, fmt.Sprintf("%s does not contain the external dns names %s", name, strings.Join(h.MissingDNSNames, ", ")))
+		}
+		if len(h.MissingIPAddresses) > 0 {
+			problems = append(problems, fmt.Sprintf("%s does not contain the kubernetes service ip address %s", name, strings.Join(h.MissingIPAddresses, ", ")))
+		}
+	}
+	return problems
+}
+
+// MissingAPIServerSANs returns the external DNS names and the kubernetes service IP derived from serviceCIDR which are missing in the apiserver cert
+func (c Cluster) MissingAPIServerSANs(cert tlscerts.Certificate) ([]string, []string, error) {
+	dnsNames := []string{}
+	for _, name := range c.ExternalDNSNames() {
+		if !cert.ContainsDNSName(name) {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+
+	_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid serviceCIDR: %v", err)
+	}
+	ips := []string{}
+	if kubernetesServiceIPAddr := netutil.IncrementIP(serviceNet.IP); !cert.ContainsIPAddress(kubernetesServiceIPAddr) {
+		ips = append(ips, kubernetesServiceIPAddr.String())
+	}
+	return dnsNames, ips, nil
+}
+
+// CheckCredentialsHealth checks every certificate in the credentials directory for its expiry and whether it is signed by the CA in ca.pem.
+// The SANs of the apiserver cert are checked against the cluster settings unless cluster is nil
+func CheckCredentialsHealth(dir string, cluster *Cluster, now time.Time) (*CredentialsHealthReport, error) {
+	cas, err := readCertificatesPEM(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", dir, err)
+	}
+	names := []string{}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".pem") {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+
+	report := &CredentialsHealthReport{Certificates: []CertificateHealth{}}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			// A dangling symlink
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		if !tlsutil.IsCertificatePEM(data) {
+			continue
+		}
+		certs, err := tlsutil.DecodeCertificatesPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		for _, cert := range certs {
+			h, err := checkCertificateHealth(name, cert, cas, cluster, now)
+			if err != nil {
+				return nil, err
+			}
+			report.Certificates = append(report.Certificates, *h)
+		}
+	}
+	return report, nil
+}
+
+func checkCertificateHealth(file string, cert *x509.Certificate, cas []*x509.Certificate, cluster *Cluster, now time.Time) (*CertificateHealth, error) {
+	h := &CertificateHealth{
+		File:            file,
+		CommonName:      cert.Subject.CommonName,
+		NotAfter:        cert.NotAfter,
+		DaysUntilExpiry: daysUntil(now, cert.NotAfter),
+	}
+
+	for _, ca := range cas {
+		if cert.Equal(ca) || cert.CheckSignatureFrom(ca) == nil {
+			h.SignedByCA = true
+			break
+		}
+	}
+
+	if cluster != nil && cert.Subject.CommonName == "kube-apiserver" {
+		var err error
+		h.MissingDNSNames, h.MissingIPAddresses, err = cluster.MissingAPIServerSANs(tlscerts.Certificate{
+			DNSNames:    cert.DNSNames,
+			IPAddresses: cert.IPAddresses,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// daysUntil returns the whole days left until t, which is negative once t has passed
+func daysUntil(now time.Time, t time.Time) int {
+	d := t.Sub(now)
+	if d < 0 {
+		return -int((-d).Hours()/24) - 1
+	}
+	return int(d.Hours() / 24)
+}
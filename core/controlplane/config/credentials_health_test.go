@@ -0,0 +1,165 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func TestCheckCredentialsHealth(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "credentials-health")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, data []byte) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	caKey, caCert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating tls ca: %v", err)
+	}
+	write("ca.pem", tlsutil.EncodeCertificatePEM(caCert))
+	write("ca-key.pem", tlsutil.EncodePrivateKeyPEM(caKey))
+	if err := os.Symlink("ca.pem", filepath.Join(dir, "worker-ca.pem")); err != nil {
+		t.Fatalf("failed to link worker-ca.pem: %v", err)
+	}
+
+	otherCAKey, otherCACert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating tls ca: %v", err)
+	}
+
+	issue := func(name string, signer string) {
+		key, err := tlsutil.NewPrivateKey()
+		if err != nil {
+			t.Fatalf("failed generating key: %v", err)
+		}
+		signerKey, signerCert := caKey, caCert
+		if signer == "other" {
+			signerKey, signerCert = otherCAKey, otherCACert
+		}
+		cert, err := cluster.NewTLSCertificate(name, key, signerCert, signerKey)
+		if err != nil {
+			t.Fatalf("failed generating %s cert: %v", name, err)
+		}
+		write(name+".pem", tlsutil.EncodeCertificatePEM(cert))
+		write(name+"-key.pem", tlsutil.EncodePrivateKeyPEM(key))
+	}
+	issue("apiserver", "ca")
+	issue("worker", "other")
+
+	t.Run("Healthy", func(t *testing.T) {
+		report, err := CheckCredentialsHealth(dir, cluster, time.Now())
+		if err != nil {
+			t.Fatalf("failed to check credentials: %v", err)
+		}
+
+		files := []string{}
+		for _, h := range report.Certificates {
+			files = append(files, h.File)
+		}
+		if strings.Join(files, ",") != "apiserver.pem,ca.pem,worker-ca.pem,worker.pem" {
+			t.Errorf("unexpected certificates checked: %v", files)
+		}
+
+		for _, h := range report.Certificates {
+			if h.DaysUntilExpiry < 300 {
+				t.Errorf("unexpected days until expiry of %s: %d", h.File, h.DaysUntilExpiry)
+			}
+			if h.SignedByCA != (h.File != "worker.pem") {
+				t.Errorf("unexpected signedByCA of %s: %v", h.File, h.SignedByCA)
+			}
+			if len(h.MissingDNSNames) > 0 || len(h.MissingIPAddresses) > 0 {
+				t.Errorf("unexpected missing SANs of %s: %v %v", h.File, h.MissingDNSNames, h.MissingIPAddresses)
+			}
+		}
+
+		problems := report.Problems(0)
+		if len(problems) != 1 || !strings.Contains(problems[0], "worker.pem (CN=kube-worker) is not signed by the CA") {
+			t.Errorf("unexpected problems: %v", problems)
+		}
+	})
+
+	t.Run("Expiring", func(t *testing.T) {
+		apiserver, err := tlsutil.DecodeCertificatePEM(mustReadFile(t, filepath.Join(dir, "apiserver.pem")))
+		if err != nil {
+			t.Fatalf("failed to parse apiserver cert: %v", err)
+		}
+		report, err := CheckCredentialsHealth(dir, cluster, apiserver.NotAfter.Add(-10*24*time.Hour-time.Hour))
+		if err != nil {
+			t.Fatalf("failed to check credentials: %v", err)
+		}
+		for _, h := range report.Certificates {
+			if h.File == "apiserver.pem" && h.DaysUntilExpiry != 10 {
+				t.Errorf("expected apiserver cert to expire in 10 days but was %d", h.DaysUntilExpiry)
+			}
+		}
+		if problems := report.Problems(0); len(problems) != 1 {
+			t.Errorf("expected no expiry problem without warn days: %v", problems)
+		}
+		if problems := strings.Join(report.Problems(30), "\n"); !strings.Contains(problems, "apiserver.pem (CN=kube-apiserver) expires in 10 days") {
+			t.Errorf("expected the apiserver cert to be reported as expiring: %s", problems)
+		}
+
+		expired, err := CheckCredentialsHealth(dir, cluster, apiserver.NotAfter.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("failed to check credentials: %v", err)
+		}
+		if problems := strings.Join(expired.Problems(0), "\n"); !strings.Contains(problems, "apiserver.pem (CN=kube-apiserver) expired on") {
+			t.Errorf("expected the apiserver cert to be reported as expired: %s", problems)
+		}
+	})
+
+	t.Run("MissingSANs", func(t *testing.T) {
+		changed := *cluster
+		changed.ExternalDNSName = "new.example.com"
+		changed.ServiceCIDR = "10.9.0.0/24"
+
+		report, err := CheckCredentialsHealth(dir, &changed, time.Now())
+		if err != nil {
+			t.Fatalf("failed to check credentials: %v", err)
+		}
+		for _, h := range report.Certificates {
+			if h.File != "apiserver.pem" {
+				continue
+			}
+			if strings.Join(h.MissingDNSNames, ",") != "new.example.com" {
+				t.Errorf("unexpected missing dns names: %v", h.MissingDNSNames)
+			}
+			if strings.Join(h.MissingIPAddresses, ",") != "10.9.0.1" {
+				t.Errorf("unexpected missing ip addresses: %v", h.MissingIPAddresses)
+			}
+		}
+
+		withoutCluster, err := CheckCredentialsHealth(dir, nil, time.Now())
+		if err != nil {
+			t.Fatalf("failed to check credentials: %v", err)
+		}
+		if problems := withoutCluster.Problems(0); len(problems) != 1 {
+			t.Errorf("expected SANs not to be checked without the cluster: %v", problems)
+		}
+	})
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return b
+}
@@ -1,8 +1,8 @@
 package config
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
@@ -17,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/kubernetes-incubator/kube-aws/gzipcompressor"
 	"github.com/kubernetes-incubator/kube-aws/netutil"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 	"github.com/kubernetes-incubator/kube-aws/tlscerts"
 	"github.com/kubernetes-incubator/kube-aws/tlsutil"
 )
@@ -159,8 +160,8 @@ type CompactAssets struct {
 	EncryptionConfig string
 }
 
-func (c *Cluster) NewTLSCA() (*rsa.PrivateKey, *x509.Certificate, error) {
-	caKey, err := tlsutil.NewPrivateKey()
+func (c *Cluster) NewTLSCA() (crypto.Signer, *x509.Certificate, error) {
+	caKey, err := c.NewTLSKey("ca")
 	if err != nil {
 		return nil, nil, err
 	}
@@ -181,64 +182,112 @@ func (c *Cluster) NewTLSCA() (*rsa.PrivateKey, *x509.Certificate, error) {
 	return caKey, caCert, nil
 }
 
+// NewTLSKey generates the private key for the certificate named like `ca` or `etcd-client` with the algorithm specified in tlsKeyAlgorithms
+func (c *Cluster) NewTLSKey(name string) (crypto.Signer, error) {
+	return tlsutil.NewPrivateKeyWithAlgorithm(c.TLSKeyAlgorithms.For(name))
+}
+
+// ReadTLSCA reads the PEM encoded key and certificate of an existing CA. The key is either an RSA or ECDSA key
+func ReadTLSCA(caKeyPath, caCertPath string) (crypto.Signer, *x509.Certificate, error) {
+	caKeyBytes, err := ioutil.ReadFile(caKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed reading ca key file %s : %v", caKeyPath, err)
+	}
+	caKey, err := tlsutil.DecodePrivateKeyPEM(caKeyBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing ca key: %v", err)
+	}
+	caCertBytes, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed reading ca cert file %s : %v", caCertPath, err)
+	}
+	caCerts, err := tlsutil.DecodeCertificatesPEM(caCertBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing ca cert: %v", err)
+	}
+	caCert, err := CACertificateOf(caCerts, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing ca cert file %s : %v", caCertPath, err)
+	}
+	return caKey, caCert, nil
+}
+
 type CredentialsOptions struct {
 	GenerateCA bool
 	CaKeyPath  string
+	// CaCertPath is the cert of the CA key. When the CA key is of an intermediate CA, the certs of the CAs issuing it up to the root CA follow
 	CaCertPath string
 	// KIAM is set to true when you want kube-aws to render TLS assets for uswitch/kiam
 	KIAM bool
+	// CertificateRequests is set to true when you want kube-aws to write keys and CSRs for an external CA to sign, instead of issuing certificates
+	CertificateRequests bool
+	// SignedCertificatesDir is the directory of the certificates signed by an external CA for the CSRs. They are imported with the CA chain in CaCertPath
+	SignedCertificatesDir string
+}
+
+// Validate returns an error when the options conflict with each other
+func (o CredentialsOptions) Validate() error {
+	externallySigned := o.CertificateRequests || o.SignedCertificatesDir != ""
+	if o.GenerateCA && externallySigned {
+		return fmt.Errorf("a CA can not be generated for the certificates signed by an external CA")
+	}
+	if o.CertificateRequests && o.SignedCertificatesDir != "" {
+		return fmt.Errorf("certificate requests can not be written while importing signed certificates")
+	}
+	return nil
 }
 
 func (c *Cluster) NewAssetsOnDisk(dir string, o CredentialsOptions) (*RawAssetsOnDisk, error) {
-	fmt.Println("Generating credentials...")
-	var caKey *rsa.PrivateKey
-	var caCert *x509.Certificate
+	progress.Println("Generating credentials...")
+	var caKey crypto.Signer
+	var caChain []*x509.Certificate
 	if o.GenerateCA {
+		var caCert *x509.Certificate
 		var err error
 		caKey, caCert, err = c.NewTLSCA()
 		if err != nil {
 			return nil, fmt.Errorf("failed generating cluster CA: %v", err)
 		}
-		fmt.Printf("-> Generating new TLS CA\n")
+		caChain = []*x509.Certificate{caCert}
+		progress.Printf("-> Generating new TLS CA\n")
 	} else {
-		fmt.Printf("-> Parsing existing TLS CA\n")
-		if caKeyBytes, err := ioutil.ReadFile(o.CaKeyPath); err != nil {
-			return nil, fmt.Errorf("failed reading ca key file %s : %v", o.CaKeyPath, err)
-		} else {
-			if caKey, err = tlsutil.DecodePrivateKeyPEM(caKeyBytes); err != nil {
-				return nil, fmt.Errorf("failed parsing ca key: %v", err)
-			}
+		progress.Printf("-> Parsing existing TLS CA\n")
+		var err error
+		if caKey, caChain, err = ReadTLSCAChain(o.CaKeyPath, o.CaCertPath); err != nil {
+			return nil, err
 		}
-		if caCertBytes, err := ioutil.ReadFile(o.CaCertPath); err != nil {
-			return nil, fmt.Errorf("failed reading ca cert file %s : %v", o.CaCertPath, err)
-		} else {
-			if caCert, err = tlsutil.DecodeCertificatePEM(caCertBytes); err != nil {
-				return nil, fmt.Errorf("failed parsing ca cert: %v", err)
-			}
+		if len(caChain) > 1 {
+			progress.Printf("--> Issuing certificates from the intermediate CA %s, which is distributed with its chain of %d CAs up to the root CA\n", caChain[0].Subject.CommonName, len(caChain)-1)
 		}
 	}
 
-	fmt.Println("-> Generating new assets")
-	assets, err := c.NewAssetsOnMemory(caKey, caCert, o.KIAM)
+	progress.Println("-> Generating new assets")
+	assets, err := c.newAssetsFromCAChain(caKey, caChain, o.KIAM)
 	if err != nil {
 		return nil, fmt.Errorf("Error generating default assets: %v", err)
 	}
 
+	return c.writeAssets(dir, assets, o.GenerateCA, o.KIAM)
+}
+
+// writeAssets writes the assets to the directory and reads them back for verification.
+// The CA key is written too when it is generated or required on controller nodes
+func (c *Cluster) writeAssets(dir string, assets *RawAssetsOnMemory, caGenerated bool, kiamEnabled bool) (*RawAssetsOnDisk, error) {
 	tlsBootstrappingEnabled := c.Experimental.TLSBootstrap.Enabled
 	certsManagedByKubeAws := c.ManageCertificates
 	caKeyRequiredOnController := certsManagedByKubeAws && tlsBootstrappingEnabled
 
-	fmt.Printf("--> Summarizing the configuration\n    Kubelet TLS bootstrapping enabled=%v, TLS certificates managed by kube-aws=%v, CA key required on controller nodes=%v\n", tlsBootstrappingEnabled, certsManagedByKubeAws, caKeyRequiredOnController)
+	progress.Printf("--> Summarizing the configuration\n    Kubelet TLS bootstrapping enabled=%v, TLS certificates managed by kube-aws=%v, CA key required on controller nodes=%v\n", tlsBootstrappingEnabled, certsManagedByKubeAws, caKeyRequiredOnController)
 
-	fmt.Println("--> Writing to the storage")
-	alsoWriteCAKey := o.GenerateCA || caKeyRequiredOnController
-	if err := assets.WriteToDir(dir, alsoWriteCAKey, o.KIAM); err != nil {
+	progress.Println("--> Writing to the storage")
+	alsoWriteCAKey := caGenerated || caKeyRequiredOnController
+	if err := assets.WriteToDir(dir, alsoWriteCAKey, kiamEnabled); err != nil {
 		return nil, fmt.Errorf("Error creating assets: %v", err)
 	}
 
 	{
-		fmt.Println("--> Verifying the result")
-		verified, err := ReadRawAssets(dir, certsManagedByKubeAws, tlsBootstrappingEnabled, o.KIAM)
+		progress.Println("--> Verifying the result")
+		verified, err := ReadRawAssets(dir, certsManagedByKubeAws, tlsBootstrappingEnabled, kiamEnabled)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed verifying the result: %v", err)
@@ -248,111 +297,47 @@ func (c *Cluster) NewAssetsOnDisk(dir string, o CredentialsOptions) (*RawAssetsO
 	}
 }
 
-func (c *Cluster) NewAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certificate, kiamEnabled bool) (*RawAssetsOnMemory, error) {
-	// Convert from days to time.Duration
-	certDuration := time.Duration(c.TLSCertDurationDays) * 24 * time.Hour
+func (c *Cluster) NewAssetsOnMemory(caKey crypto.Signer, caCert *x509.Certificate, kiamEnabled bool) (*RawAssetsOnMemory, error) {
+	return c.newAssetsFromCAChain(caKey, []*x509.Certificate{caCert}, kiamEnabled)
+}
+
+// newAssetsFromCAChain issues the certificates with the key of the first CA in the chain.
+// Nodes are given the whole chain as ca.pem to verify the certificates issued from an intermediate CA
+func (c *Cluster) newAssetsFromCAChain(caKey crypto.Signer, caChain []*x509.Certificate, kiamEnabled bool) (*RawAssetsOnMemory, error) {
+	names := tlsCertificateNames(kiamEnabled)
 
 	// Generate keys for the various components.
-	keys := make([]*rsa.PrivateKey, 10)
+	keys := map[string]crypto.Signer{}
 	var err error
-	for i := range keys {
-		if keys[i], err = tlsutil.NewPrivateKey(); err != nil {
+	for _, name := range names {
+		if keys[name], err = c.NewTLSKey(name); err != nil {
 			return nil, err
 		}
 	}
-	apiServerKey, kubeControllerManagerKey, kubeSchedulerKey, workerKey, adminKey, etcdKey, etcdClientKey, kiamAgentKey, kiamServerKey, serviceAccountKey := keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8], keys[9]
-
-	// Compute kubernetesServiceIP from serviceCIDR
-	_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
-	if err != nil {
-		return nil, fmt.Errorf("invalid serviceCIDR: %v", err)
-	}
-	kubernetesServiceIPAddr := netutil.IncrementIP(serviceNet.IP)
-
-	apiServerConfig := tlsutil.ServerCertConfig{
-		CommonName: "kube-apiserver",
-		DNSNames: append(
-			[]string{
-				"kubernetes",
-				"kubernetes.default",
-				"kubernetes.default.svc",
-				"kubernetes.default.svc.cluster.local",
-			},
-			c.ExternalDNSNames()...,
-		),
-		IPAddresses: []string{
-			kubernetesServiceIPAddr.String(),
-
-			// Also allows control plane components to reach the apiserver via HTTPS at localhost
-			"127.0.0.1",
-		},
-		Duration: certDuration,
-	}
-	apiServerCert, err := tlsutil.NewSignedServerCertificate(apiServerConfig, apiServerKey, caCert, caKey)
-	if err != nil {
-		return nil, err
-	}
-
-	etcdConfig := tlsutil.ServerCertConfig{
-		CommonName: "kube-etcd",
-		DNSNames:   c.EtcdCluster().DNSNames(),
-		// etcd https client/peer interfaces are not exposed externally
-		// but anyway we'll make it valid for the same duration as other certs just because it is easy to implement.
-		Duration: certDuration,
-	}
 
-	etcdCert, err := tlsutil.NewSignedServerCertificate(etcdConfig, etcdKey, caCert, caKey)
-	if err != nil {
-		return nil, err
-	}
-
-	workerConfig := tlsutil.ClientCertConfig{
-		CommonName: "kube-worker",
-		DNSNames: []string{
-			fmt.Sprintf("*.%s.compute.internal", c.Region),
-			"*.ec2.internal",
-		},
-		Duration: certDuration,
-	}
-	workerCert, err := tlsutil.NewSignedClientCertificate(workerConfig, workerKey, caCert, caKey)
-	if err != nil {
-		return nil, err
-	}
-
-	etcdClientConfig := tlsutil.ClientCertConfig{
-		CommonName: "kube-etcd-client",
-		Duration:   certDuration,
-	}
-
-	etcdClientCert, err := tlsutil.NewSignedClientCertificate(etcdClientConfig, etcdClientKey, caCert, caKey)
-	if err != nil {
-		return nil, err
+	certs := map[string]*x509.Certificate{}
+	for _, name := range names {
+		if certs[name], err = c.NewTLSCertificate(name, keys[name], caChain[0], caKey); err != nil {
+			return nil, err
+		}
 	}
 
-	adminConfig := tlsutil.ClientCertConfig{
-		CommonName:   "kube-admin",
-		Organization: []string{"system:masters"},
-		Duration:     certDuration,
-	}
-	adminCert, err := tlsutil.NewSignedClientCertificate(adminConfig, adminKey, caCert, caKey)
-	if err != nil {
-		return nil, err
-	}
+	return newRawAssetsOnMemory(caChain, caKey, keys, certs, kiamEnabled)
+}
 
-	kubeControllerManagerConfig := tlsutil.ClientCertConfig{
-		CommonName: "system:kube-controller-manager",
-		Duration:   certDuration,
-	}
-	kubeControllerManagerCert, err := tlsutil.NewSignedClientCertificate(kubeControllerManagerConfig, kubeControllerManagerKey, caCert, caKey)
-	if err != nil {
-		return nil, err
+// tlsCertificateNames returns the names of the certificates issued for the cluster, other than the CA
+func tlsCertificateNames(kiamEnabled bool) []string {
+	names := []string{"apiserver", "kube-controller-manager", "kube-scheduler", "worker", "admin", "etcd", "etcd-client"}
+	if kiamEnabled {
+		names = append(names, "kiam-agent", "kiam-server")
 	}
+	return names
+}
 
-	kubeSchedulerConfig := tlsutil.ClientCertConfig{
-		CommonName: "system:kube-scheduler",
-		Duration:   certDuration,
-	}
-	kubeSchedulerCert, err := tlsutil.NewSignedClientCertificate(kubeSchedulerConfig, kubeSchedulerKey, caCert, caKey)
+// newRawAssetsOnMemory encodes the keys and certificates issued from the CA chain, and generates the rest of the assets.
+// The CA key is omitted when it is nil, that is when the certificates are signed by an external CA
+func newRawAssetsOnMemory(caChain []*x509.Certificate, caKey crypto.Signer, keys map[string]crypto.Signer, certs map[string]*x509.Certificate, kiamEnabled bool) (*RawAssetsOnMemory, error) {
+	serviceAccountKey, err := tlsutil.NewPrivateKey()
 	if err != nil {
 		return nil, err
 	}
@@ -369,64 +354,207 @@ func (c *Cluster) NewAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certific
 	}
 
 	r := &RawAssetsOnMemory{
-		CACert:                    tlsutil.EncodeCertificatePEM(caCert),
-		APIServerCert:             tlsutil.EncodeCertificatePEM(apiServerCert),
-		KubeControllerManagerCert: tlsutil.EncodeCertificatePEM(kubeControllerManagerCert),
-		KubeSchedulerCert:         tlsutil.EncodeCertificatePEM(kubeSchedulerCert),
-		WorkerCert:                tlsutil.EncodeCertificatePEM(workerCert),
-		AdminCert:                 tlsutil.EncodeCertificatePEM(adminCert),
-		EtcdCert:                  tlsutil.EncodeCertificatePEM(etcdCert),
-		EtcdClientCert:            tlsutil.EncodeCertificatePEM(etcdClientCert),
-		CAKey:                     tlsutil.EncodePrivateKeyPEM(caKey),
-		APIServerKey:              tlsutil.EncodePrivateKeyPEM(apiServerKey),
-		KubeControllerManagerKey:  tlsutil.EncodePrivateKeyPEM(kubeControllerManagerKey),
-		KubeSchedulerKey:          tlsutil.EncodePrivateKeyPEM(kubeSchedulerKey),
-		WorkerKey:                 tlsutil.EncodePrivateKeyPEM(workerKey),
-		AdminKey:                  tlsutil.EncodePrivateKeyPEM(adminKey),
-		EtcdKey:                   tlsutil.EncodePrivateKeyPEM(etcdKey),
-		EtcdClientKey:             tlsutil.EncodePrivateKeyPEM(etcdClientKey),
+		CACert:                    encodeCertificatesPEM(caChain),
+		APIServerCert:             tlsutil.EncodeCertificatePEM(certs["apiserver"]),
+		KubeControllerManagerCert: tlsutil.EncodeCertificatePEM(certs["kube-controller-manager"]),
+		KubeSchedulerCert:         tlsutil.EncodeCertificatePEM(certs["kube-scheduler"]),
+		WorkerCert:                tlsutil.EncodeCertificatePEM(certs["worker"]),
+		AdminCert:                 tlsutil.EncodeCertificatePEM(certs["admin"]),
+		EtcdCert:                  tlsutil.EncodeCertificatePEM(certs["etcd"]),
+		EtcdClientCert:            tlsutil.EncodeCertificatePEM(certs["etcd-client"]),
+		APIServerKey:              tlsutil.EncodePrivateKeyPEM(keys["apiserver"]),
+		KubeControllerManagerKey:  tlsutil.EncodePrivateKeyPEM(keys["kube-controller-manager"]),
+		KubeSchedulerKey:          tlsutil.EncodePrivateKeyPEM(keys["kube-scheduler"]),
+		WorkerKey:                 tlsutil.EncodePrivateKeyPEM(keys["worker"]),
+		AdminKey:                  tlsutil.EncodePrivateKeyPEM(keys["admin"]),
+		EtcdKey:                   tlsutil.EncodePrivateKeyPEM(keys["etcd"]),
+		EtcdClientKey:             tlsutil.EncodePrivateKeyPEM(keys["etcd-client"]),
 		ServiceAccountKey:         tlsutil.EncodePrivateKeyPEM(serviceAccountKey),
 
 		AuthTokens:        []byte(authTokens),
 		TLSBootstrapToken: []byte(tlsBootstrapToken),
 		EncryptionConfig:  []byte(encryptionConfig),
 	}
+	if caKey != nil {
+		r.CAKey = tlsutil.EncodePrivateKeyPEM(caKey)
+	}
 
 	if kiamEnabled {
-		// See https://github.com/uswitch/kiam/blob/master/docs/agent.json
-		agentConfig := tlsutil.ClientCertConfig{
-			CommonName: "Kiam Agent",
-			Duration:   certDuration,
-		}
-		kiamAgentCert, err := tlsutil.NewSignedClientCertificate(agentConfig, kiamAgentKey, caCert, caKey)
+		r.KIAMCACert = r.CACert
+		r.KIAMAgentCert = tlsutil.EncodeCertificatePEM(certs["kiam-agent"])
+		r.KIAMAgentKey = tlsutil.EncodePrivateKeyPEM(keys["kiam-agent"])
+		r.KIAMServerCert = tlsutil.EncodeCertificatePEM(certs["kiam-server"])
+		r.KIAMServerKey = tlsutil.EncodePrivateKeyPEM(keys["kiam-server"])
+	}
+
+	return r, nil
+}
+
+// tlsCertificateUsage is what the certificate is used for, which is either as the server, client or both
+type tlsCertificateUsage int
+
+const (
+	serverCertificate tlsCertificateUsage = iota
+	clientCertificate
+	clientAndServerCertificate
+)
+
+// extKeyUsages returns the extended key usages the certificate must have
+func (u tlsCertificateUsage) extKeyUsages() []x509.ExtKeyUsage {
+	switch u {
+	case serverCertificate:
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	case clientCertificate:
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	return []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+}
+
+// tlsCertificateSpec is the subject, SANs and usage of a certificate issued by kube-aws or requested to an external CA
+type tlsCertificateSpec struct {
+	tlsutil.ClientCertConfig
+	usage tlsCertificateUsage
+}
+
+// tlsCertificateSpec returns the spec of the certificate named like `apiserver` or `etcd-client`
+func (c *Cluster) tlsCertificateSpec(name string) (*tlsCertificateSpec, error) {
+	// Convert from days to time.Duration
+	certDuration := time.Duration(c.TLSCertDurationDays) * 24 * time.Hour
+
+	switch name {
+	case "apiserver":
+		// Compute kubernetesServiceIP from serviceCIDR
+		_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid serviceCIDR: %v", err)
 		}
+		kubernetesServiceIPAddr := netutil.IncrementIP(serviceNet.IP)
+
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "kube-apiserver",
+				DNSNames: append(
+					[]string{
+						"kubernetes",
+						"kubernetes.default",
+						"kubernetes.default.svc",
+						"kubernetes.default.svc.cluster.local",
+					},
+					c.ExternalDNSNames()...,
+				),
+				IPAddresses: []string{
+					kubernetesServiceIPAddr.String(),
+
+					// Also allows control plane components to reach the apiserver via HTTPS at localhost
+					"127.0.0.1",
+				},
+				Duration: certDuration,
+			},
+			usage: serverCertificate,
+		}, nil
+	case "etcd":
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "kube-etcd",
+				DNSNames:   c.EtcdCluster().DNSNames(),
+				// etcd https client/peer interfaces are not exposed externally
+				// but anyway we'll make it valid for the same duration as other certs just because it is easy to implement.
+				Duration: certDuration,
+			},
+			usage: serverCertificate,
+		}, nil
+	case "worker":
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "kube-worker",
+				DNSNames: []string{
+					fmt.Sprintf("*.%s.compute.internal", c.Region),
+					"*.ec2.internal",
+				},
+				Duration: certDuration,
+			},
+			usage: clientCertificate,
+		}, nil
+	case "etcd-client":
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "kube-etcd-client",
+				Duration:   certDuration,
+			},
+			usage: clientCertificate,
+		}, nil
+	case "admin":
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName:   "kube-admin",
+				Organization: []string{"system:masters"},
+				Duration:     certDuration,
+			},
+			usage: clientCertificate,
+		}, nil
+	case "kube-controller-manager":
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "system:kube-controller-manager",
+				Duration:   certDuration,
+			},
+			usage: clientCertificate,
+		}, nil
+	case "kube-scheduler":
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "system:kube-scheduler",
+				Duration:   certDuration,
+			},
+			usage: clientCertificate,
+		}, nil
+	case "kiam-agent":
+		// See https://github.com/uswitch/kiam/blob/master/docs/agent.json
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "Kiam Agent",
+				Duration:   certDuration,
+			},
+			usage: clientCertificate,
+		}, nil
+	case "kiam-server":
 		// See https://github.com/uswitch/kiam/blob/master/docs/server.json
-		serverConfig := tlsutil.ClientCertConfig{
-			CommonName: "Kiam Server",
-			DNSNames: append(
-				[]string{
+		return &tlsCertificateSpec{
+			ClientCertConfig: tlsutil.ClientCertConfig{
+				CommonName: "Kiam Server",
+				DNSNames: []string{
 					"kiam-server:443",
 					"localhost:443",
 					"localhost:9610",
 				},
-			),
-			Duration: certDuration,
-		}
-		kiamServerCert, err := tlsutil.NewSignedKIAMCertificate(serverConfig, kiamServerKey, caCert, caKey)
-		if err != nil {
-			return nil, err
-		}
+				Duration: certDuration,
+			},
+			usage: clientAndServerCertificate,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown certificate: %s", name)
+}
 
-		r.KIAMCACert = tlsutil.EncodeCertificatePEM(caCert)
-		r.KIAMAgentCert = tlsutil.EncodeCertificatePEM(kiamAgentCert)
-		r.KIAMAgentKey = tlsutil.EncodePrivateKeyPEM(kiamAgentKey)
-		r.KIAMServerCert = tlsutil.EncodeCertificatePEM(kiamServerCert)
-		r.KIAMServerKey = tlsutil.EncodePrivateKeyPEM(kiamServerKey)
+// NewTLSCertificate issues the certificate named like `apiserver` or `etcd-client`, which is also the name of its file in the assets directory without `.pem`, from the CA
+func (c *Cluster) NewTLSCertificate(name string, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
+	spec, err := c.tlsCertificateSpec(name)
+	if err != nil {
+		return nil, err
 	}
 
-	return r, nil
+	switch spec.usage {
+	case serverCertificate:
+		serverConfig := tlsutil.ServerCertConfig{
+			CommonName:  spec.CommonName,
+			DNSNames:    spec.DNSNames,
+			IPAddresses: spec.IPAddresses,
+			Duration:    spec.Duration,
+		}
+		return tlsutil.NewSignedServerCertificate(serverConfig, key, caCert, caKey)
+	case clientCertificate:
+		return tlsutil.NewSignedClientCertificate(spec.ClientCertConfig, key, caCert, caKey)
+	}
+	return tlsutil.NewSignedKIAMCertificate(spec.ClientCertConfig, key, caCert, caKey)
 }
 
 func ReadRawAssets(dirname string, manageCertificates bool, caKeyRequiredOnController bool, kiamEnabled bool) (*RawAssetsOnDisk, error) {
@@ -709,20 +837,20 @@ func (r *RawAssetsOnMemory) WriteToDir(dirname string, includeCAKey bool, kiamEn
 				fileExists := lstatErr == nil && !symlinkExists
 
 				if fileExists {
-					fmt.Printf("INFO: Removing a file at %s\n", from)
+					progress.Printf("INFO: Removing a file at %s\n", from)
 					if err := os.Remove(from); err != nil {
 						return err
 					}
 				}
 
 				if symlinkExists {
-					fmt.Printf("INFO: Removing a symlink at %s\n", from)
+					progress.Printf("INFO: Removing a symlink at %s\n", from)
 					if err := os.Remove(from); err != nil {
 						return err
 					}
 				}
 
-				fmt.Printf("INFO: Creating a symlink from %s to %s\n", from, to)
+				progress.Printf("INFO: Creating a symlink from %s to %s\n", from, to)
 				if err := os.Symlink(to, from); err != nil {
 					return err
 				}
@@ -735,7 +863,7 @@ func (r *RawAssetsOnMemory) WriteToDir(dirname string, includeCAKey bool, kiamEn
 				return fmt.Errorf("Not sure what to do for %s", path)
 			}
 		}
-		fmt.Printf("INFO: Writing %d bytes to %s\n", len(asset.data), path)
+		progress.Printf("INFO: Writing %d bytes to %s\n", len(asset.data), path)
 		if err := ioutil.WriteFile(path, asset.data, 0600); err != nil {
 			return err
 		}
@@ -3,11 +3,15 @@ package config
 import (
 	"testing"
 
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -119,6 +123,83 @@ func TestTLSGeneration(t *testing.T) {
 	}
 }
 
+func TestTLSGenerationWithKeyAlgorithms(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+tlsKeyAlgorithms:
+  default: ecdsa-p256
+  ca: ecdsa-p384
+  worker: rsa-4096
+`))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+
+	caKey, caCert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating tls ca: %v", err)
+	}
+	if k, ok := caKey.(*ecdsa.PrivateKey); !ok || k.Curve != elliptic.P384() {
+		t.Errorf("expected the ca key to be an ecdsa-p384 key but was %T", caKey)
+	}
+
+	// A user-supplied CA key is read from the credentials directory
+	dir, err := ioutil.TempDir("", "key-algorithms")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	opts := CredentialsOptions{
+		CaKeyPath:  filepath.Join(dir, "ca-key.pem"),
+		CaCertPath: filepath.Join(dir, "ca.pem"),
+	}
+	if err := ioutil.WriteFile(opts.CaKeyPath, tlsutil.EncodePrivateKeyPEM(caKey), 0600); err != nil {
+		t.Fatalf("failed to write ca key: %v", err)
+	}
+	if err := ioutil.WriteFile(opts.CaCertPath, tlsutil.EncodeCertificatePEM(caCert), 0600); err != nil {
+		t.Fatalf("failed to write ca cert: %v", err)
+	}
+	if _, err := cluster.NewAssetsOnDisk(dir, opts); err != nil {
+		t.Fatalf("failed generating assets from the ecdsa ca: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"apiserver-key.pem":       "EC PRIVATE KEY",
+		"etcd-client-key.pem":     "EC PRIVATE KEY",
+		"worker-key.pem":          "RSA PRIVATE KEY",
+		"service-account-key.pem": "RSA PRIVATE KEY",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if block, _ := pem.Decode(data); block == nil || block.Type != expected {
+			t.Errorf("expected %s to be %s", name, expected)
+		}
+	}
+
+	for _, name := range []string{"apiserver", "worker", "admin", "etcd", "etcd-client"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name+".pem"))
+		if err != nil {
+			t.Fatalf("failed to read %s.pem: %v", name, err)
+		}
+		cert, err := tlsutil.DecodeCertificatePEM(data)
+		if err != nil {
+			t.Fatalf("failed to parse %s cert: %v", name, err)
+		}
+		if err := cert.CheckSignatureFrom(caCert); err != nil {
+			t.Errorf("%s cert must be signed by the ecdsa ca: %v", name, err)
+		}
+	}
+
+	worker, err := ioutil.ReadFile(filepath.Join(dir, "worker-key.pem"))
+	if err != nil {
+		t.Fatalf("failed to read worker key: %v", err)
+	}
+	if key, err := tlsutil.DecodePrivateKeyPEM(worker); err != nil || key.(*rsa.PrivateKey).N.BitLen() != 4096 {
+		t.Errorf("expected the worker key to be an rsa-4096 key: %v", err)
+	}
+}
+
 func TestReadOrCreateCompactAssets(t *testing.T) {
 	helper.WithDummyCredentials(func(dir string) {
 		kmsConfig := NewKMSConfig("keyarn", &dummyEncryptService{}, nil)
@@ -7,12 +7,14 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/cfnstack"
 	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
 	"github.com/kubernetes-incubator/kube-aws/model"
 	"github.com/kubernetes-incubator/kube-aws/naming"
 	"github.com/kubernetes-incubator/kube-aws/plugin/clusterextension"
 	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 )
 
 // VERSION set by build script
@@ -20,16 +22,18 @@ var VERSION = "UNKNOWN"
 
 const STACK_TEMPLATE_FILENAME = "stack.json"
 
-func newClusterRef(cfg *config.Cluster, session *session.Session) *ClusterRef {
+func newClusterRef(cfg *config.Cluster, clients *awsconn.ServiceClients) *ClusterRef {
 	return &ClusterRef{
 		Cluster: cfg,
-		session: session,
+		session: clients.Session,
+		clients: clients,
 	}
 }
 
 type ClusterRef struct {
 	*config.Cluster
 	session *session.Session
+	clients *awsconn.ServiceClients
 }
 
 type Cluster struct {
@@ -53,7 +57,7 @@ func (c *ClusterRef) validateExistingVPCState(ec2Svc ec2Service) error {
 
 	// TODO kube-aws should de-reference the vpc id from the stack output and continue validating with it
 	if c.VPC.IDFromStackOutput != "" {
-		fmt.Printf("kube-aws doesn't support validating the vpc referenced by the stack output `%s`. Skipped validation of existing vpc state. The cluster creation may fail afterwards if the VPC isn't configured properly.", c.VPC.IDFromStackOutput)
+		progress.Printf("kube-aws doesn't support validating the vpc referenced by the stack output `%s`. Skipped validation of existing vpc state. The cluster creation may fail afterwards if the VPC isn't configured properly.", c.VPC.IDFromStackOutput)
 		return nil
 	}
 
@@ -111,7 +115,7 @@ func (c *ClusterRef) validateExistingVPCState(ec2Svc ec2Service) error {
 	return nil
 }
 
-func NewCluster(cfgRef *config.Cluster, opts config.StackTemplateOptions, plugins []*pluginmodel.Plugin, session *session.Session) (*Cluster, error) {
+func NewCluster(cfgRef *config.Cluster, opts config.StackTemplateOptions, plugins []*pluginmodel.Plugin, clients *awsconn.ServiceClients) (*Cluster, error) {
 	cfg := &config.Cluster{}
 	*cfg = *cfgRef
 
@@ -124,11 +128,11 @@ func NewCluster(cfgRef *config.Cluster, opts config.StackTemplateOptions, plugin
 	cfg.VPC = cfg.VPC.ImportFromNetworkStack()
 	cfg.SetDefaults()
 
-	clusterRef := newClusterRef(cfg, session)
+	clusterRef := newClusterRef(cfg, clients)
 	// TODO Do this in a cleaner way e.g. in config.go
 	clusterRef.KubeResourcesAutosave.S3Path = model.NewS3Folders(cfg.DeploymentSettings.S3URI, clusterRef.ClusterName).ClusterBackups().Path()
 
-	stackConfig, err := clusterRef.StackConfig("etcd", opts, session, plugins)
+	stackConfig, err := clusterRef.StackConfig("etcd", opts, clients.Session, plugins)
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +194,9 @@ func (c *Cluster) buildAssets() (cfnstack.Assets, error) {
 		return nil, fmt.Errorf("Error while rendering template: %v", err)
 	}
 
-	assets.Add(STACK_TEMPLATE_FILENAME, stackTemplate)
+	if err = assets.AddStackTemplate(STACK_TEMPLATE_FILENAME, stackTemplate, c.StackConfig.ExtraCfnResources); err != nil {
+		return nil, fmt.Errorf("failed to add etcd template: %v", err)
+	}
 
 	return assets.Build(), nil
 }
@@ -210,7 +216,7 @@ func (c *Cluster) ValidateStack() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get template url : %v", err)
 	}
-	return c.stackProvisioner().ValidateStackAtURL(templateURL)
+	return c.stackProvisioner().ValidateStackAtURL(c.clients.CloudFormation, templateURL)
 }
 
 func (c *Cluster) stackProvisioner() *cfnstack.Provisioner {
@@ -237,7 +243,7 @@ func (c *Cluster) stackProvisioner() *cfnstack.Provisioner {
 }
 
 func (c *Cluster) Validate() error {
-	ec2Svc := ec2.New(c.session)
+	ec2Svc := c.clients.EC2
 
 	if err := c.validateExistingVPCState(ec2Svc); err != nil {
 		return err
@@ -251,5 +257,5 @@ func (c *Cluster) String() string {
 }
 
 func (c *ClusterRef) Destroy() error {
-	return cfnstack.NewDestroyer("etcd", c.session, c.CloudFormation.RoleARN).Destroy()
+	return cfnstack.NewDestroyer("etcd", c.clients.CloudFormation, c.CloudFormation.RoleARN).Destroy()
 }
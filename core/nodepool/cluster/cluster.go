@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/cfnstack"
 	"github.com/kubernetes-incubator/kube-aws/core/nodepool/config"
 	"github.com/kubernetes-incubator/kube-aws/model"
@@ -20,6 +21,7 @@ const STACK_TEMPLATE_FILENAME = "stack.json"
 type ClusterRef struct {
 	config.ProvidedConfig
 	session *session.Session
+	clients *awsconn.ServiceClients
 }
 
 type Cluster struct {
@@ -29,7 +31,9 @@ type Cluster struct {
 }
 
 type Info struct {
-	Name string
+	Name      string `json:"name"`
+	StackName string `json:"stackName"`
+	Status    string `json:"status"`
 }
 
 type ec2DescribeKeyPairsService interface {
@@ -51,20 +55,21 @@ func (c *Info) String() string {
 	return buf.String()
 }
 
-func newClusterRef(cfg *config.ProvidedConfig, session *session.Session) *ClusterRef {
+func newClusterRef(cfg *config.ProvidedConfig, clients *awsconn.ServiceClients) *ClusterRef {
 	return &ClusterRef{
 		ProvidedConfig: *cfg,
-		session:        session,
+		session:        clients.Session,
+		clients:        clients,
 	}
 }
 
-func NewCluster(provided *config.ProvidedConfig, opts config.StackTemplateOptions, plugins []*pluginmodel.Plugin, session *session.Session) (*Cluster, error) {
-	stackConfig, err := provided.StackConfig(opts, session)
+func NewCluster(provided *config.ProvidedConfig, opts config.StackTemplateOptions, plugins []*pluginmodel.Plugin, clients *awsconn.ServiceClients) (*Cluster, error) {
+	stackConfig, err := provided.StackConfig(opts, clients.Session)
 	if err != nil {
 		return nil, err
 	}
 
-	clusterRef := newClusterRef(provided, session)
+	clusterRef := newClusterRef(provided, clients)
 
 	c := &Cluster{
 		StackConfig: stackConfig,
@@ -118,7 +123,9 @@ func (c *Cluster) buildAssets() (cfnstack.Assets, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Error while rendering template : %v", err)
 	}
-	assets.Add(STACK_TEMPLATE_FILENAME, stackTemplate)
+	if err = assets.AddStackTemplate(STACK_TEMPLATE_FILENAME, stackTemplate, c.StackConfig.ExtraCfnResources); err != nil {
+		return nil, fmt.Errorf("failed to add node pool template: %v", err)
+	}
 
 	return assets.Build(), nil
 }
@@ -153,7 +160,7 @@ func (c *Cluster) session() *session.Session {
 
 // ValidateStack validates the CloudFormation stack for this worker node pool already uploaded to S3
 func (c *Cluster) ValidateStack() (string, error) {
-	ec2Svc := ec2.New(c.session())
+	ec2Svc := c.clients.EC2
 	if err := c.validateWorkerRootVolume(ec2Svc); err != nil {
 		return "", err
 	}
@@ -167,7 +174,7 @@ func (c *Cluster) ValidateStack() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get template url : %v", err)
 	}
-	return c.stackProvisioner().ValidateStackAtURL(stackTemplateURL)
+	return c.stackProvisioner().ValidateStackAtURL(c.clients.CloudFormation, stackTemplateURL)
 }
 
 func (c *ClusterRef) validateKeyPair(ec2Svc ec2DescribeKeyPairsService) error {
@@ -242,5 +249,5 @@ func (c *ClusterRef) Info() (*Info, error) {
 }
 
 func (c *ClusterRef) Destroy() error {
-	return cfnstack.NewDestroyer(c.StackName(), c.session, c.CloudFormation.RoleARN).Destroy()
+	return cfnstack.NewDestroyer(c.StackName(), c.clients.CloudFormation, c.CloudFormation.RoleARN).Destroy()
 }
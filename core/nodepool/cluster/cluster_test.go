@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
 	"github.com/kubernetes-incubator/kube-aws/core/nodepool/config"
 	"github.com/kubernetes-incubator/kube-aws/model"
@@ -261,7 +262,7 @@ name: pool1
 			S3URI:                 "s3://test-bucket/foo/bar",
 		}
 
-		cluster, err := NewCluster(clusterConfig, stackTemplateOptions, []*pluginmodel.Plugin{}, nil)
+		cluster, err := NewCluster(clusterConfig, stackTemplateOptions, []*pluginmodel.Plugin{}, &awsconn.ServiceClients{})
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -15,6 +15,7 @@ import (
 	"github.com/kubernetes-incubator/kube-aws/model"
 	"github.com/kubernetes-incubator/kube-aws/model/derived"
 	"github.com/kubernetes-incubator/kube-aws/naming"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 )
 
 type Ref struct {
@@ -62,6 +63,8 @@ type StackTemplateOptions struct {
 	PrettyPrint           bool
 	S3URI                 string
 	SkipWait              bool
+	// Offline skips encrypting the compact assets with KMS, so that they can be rendered without AWS credentials
+	Offline bool
 }
 
 // NestedStackName returns a sanitized name of this node pool which is usable as a valid cloudformation nested stack name
@@ -82,7 +85,7 @@ func (c ProvidedConfig) StackConfig(opts StackTemplateOptions, session *session.
 	}
 
 	tlsBootstrappingEnabled := c.Experimental.TLSBootstrap.Enabled
-	if stackConfig.ComputedConfig.AssetsEncryptionEnabled() {
+	if stackConfig.ComputedConfig.AssetsEncryptionEnabled() && !opts.Offline {
 		kmsConfig := cfg.NewKMSConfig(c.KMSKeyARN, c.ProvidedEncryptService, session)
 		compactAssets, err := cfg.ReadOrCreateCompactAssets(opts.AssetsDir, c.ManageCertificates, tlsBootstrappingEnabled, false, kmsConfig)
 		if err != nil {
@@ -133,7 +136,7 @@ func ClusterFromBytes(data []byte, main *cfg.Config) (*ProvidedConfig, error) {
 }
 
 func (c *ProvidedConfig) ExternalDNSName() string {
-	fmt.Println("WARN: ExternalDNSName is deprecated and will be removed in v0.9.7. Please use APIEndpoint.Name instead")
+	progress.Println("WARN: ExternalDNSName is deprecated and will be removed in v0.9.7. Please use APIEndpoint.Name instead")
 	return c.APIEndpoint.DNSName
 }
 
@@ -231,7 +234,7 @@ define one or more public subnets in cluster.yaml or explicitly reference privat
 	}
 
 	if !apiEndpoint.LoadBalancer.ManageELBRecordSet() {
-		fmt.Printf(`WARN: the worker node pool "%s" is associated to a k8s API endpoint behind the DNS name "%s" managed by YOU!
+		progress.Printf(`WARN: the worker node pool "%s" is associated to a k8s API endpoint behind the DNS name "%s" managed by YOU!
 Please never point the DNS record for it to a different k8s cluster, especially when the name is a "stable" one which is shared among multiple k8s clusters for achieving blue-green deployments of k8s clusters!
 kube-aws can't save users from mistakes like that
 `, c.NodePoolName, apiEndpoint.DNSName)
@@ -110,10 +110,16 @@ func (c DeploymentSettings) WithDefaultsFrom(main cfg.DeploymentSettings) Deploy
 	// * Region
 	// * ContainerRuntime
 	// * KMSKeyARN
+	// * S3Assets
+	// * AWSEndpoints
+	// * AWSCredentials
 	// * ElasticFileSystemID
 	c.Region = main.Region
 	c.ContainerRuntime = main.ContainerRuntime
 	c.KMSKeyARN = main.KMSKeyARN
+	c.S3Assets = main.S3Assets
+	c.AWSEndpoints = main.AWSEndpoints
+	c.AWSCredentials = main.AWSCredentials
 
 	// TODO Allow providing one or more elasticFileSystemId's to be mounted both per-node-pool/cluster-wide
 	// TODO Allow providing elasticFileSystemId to a node pool in managed subnets.
@@ -0,0 +1,92 @@
+package root
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+)
+
+// CARotator rotates the CA of a cluster in phases, each of which is rolled out before the next one is started
+type CARotator interface {
+	CallerIdentity() (*awsconn.CallerIdentity, error)
+	// State returns the progress of the CA rotation recorded in the assets directory
+	State() controlplane.CARotationState
+	// Start writes the credentials for the next phase, or keeps the ones of the phase being resumed, and encrypts them with KMS
+	Start() (*controlplane.CARotationState, error)
+	// UpdateInStages rolls out the credentials of the current phase and records the phase as completed
+	UpdateInStages() error
+}
+
+type caRotatorImpl struct {
+	cfg     *config.Config
+	opts    options
+	clients *awsconn.ServiceClients
+	caOpts  controlplane.CARotationOptions
+	state   *controlplane.CARotationState
+}
+
+func CARotatorFromFile(configPath string, opts options, caOpts controlplane.CARotationOptions, awsDebug bool) (CARotator, error) {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := awsconn.NewSessionFromRegion(cfg.Region, cfg.AWSEndpoints, cfg.AWSCredentials.WithOverrides(opts.AWSCredentials), awsDebug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+	}
+
+	return CARotatorFromConfigWithServiceClients(cfg, opts, caOpts, awsconn.NewServiceClients(session))
+}
+
+// CARotatorFromConfigWithServiceClients returns the rotator calling AWS APIs via the clients
+func CARotatorFromConfigWithServiceClients(cfg *config.Config, opts options, caOpts controlplane.CARotationOptions, clients *awsconn.ServiceClients) (CARotator, error) {
+	if !cfg.ManageCertificates {
+		return nil, fmt.Errorf("the CA can not be rotated by kube-aws as manageCertificates is false")
+	}
+	state, err := controlplane.ReadCARotationState(opts.AssetsDir)
+	if err != nil {
+		return nil, err
+	}
+	return &caRotatorImpl{
+		cfg:     cfg,
+		opts:    opts,
+		clients: clients,
+		caOpts:  caOpts,
+		state:   state,
+	}, nil
+}
+
+func (r *caRotatorImpl) CallerIdentity() (*awsconn.CallerIdentity, error) {
+	return awsconn.GetCallerIdentity(r.clients.STS)
+}
+
+func (r *caRotatorImpl) State() controlplane.CARotationState {
+	return *r.state
+}
+
+func (r *caRotatorImpl) Start() (*controlplane.CARotationState, error) {
+	state, err := r.cfg.StartCARotationPhase(r.opts.AssetsDir, r.caOpts)
+	if err != nil {
+		return nil, err
+	}
+	r.state = state
+
+	if err := encryptAssets(r.cfg, r.opts, r.clients); err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials: %v", err)
+	}
+
+	return state, nil
+}
+
+func (r *caRotatorImpl) UpdateInStages() error {
+	if r.state.Phase == 0 || r.state.Updated {
+		return fmt.Errorf("no phase of the CA rotation has been started")
+	}
+	if err := updateInStages(r.cfg, r.opts, r.clients); err != nil {
+		return err
+	}
+	return controlplane.CompleteCARotationPhase(r.opts.AssetsDir, r.state)
+}
@@ -0,0 +1,143 @@
+package root
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/progress"
+)
+
+// CertificateRotator re-issues the TLS certificates of a cluster and rolls them out
+type CertificateRotator interface {
+	CallerIdentity() (*awsconn.CallerIdentity, error)
+	// Certificates returns the names of the certificates to be re-issued
+	Certificates() []string
+	// Rotate re-issues the certificates from the existing CA, encrypts them with KMS and returns the paths to the files written
+	Rotate() ([]string, error)
+	// UpdateInStages rolls out the certificates by updating etcd first, then the control plane and then node pools.
+	// A stage without any change is skipped
+	UpdateInStages() error
+}
+
+type certificateRotatorImpl struct {
+	cfg          *config.Config
+	opts         options
+	clients      *awsconn.ServiceClients
+	rotationOpts controlplane.CertificateRotationOptions
+	certificates []string
+}
+
+func CertificateRotatorFromFile(configPath string, opts options, rotationOpts controlplane.CertificateRotationOptions, awsDebug bool) (CertificateRotator, error) {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := awsconn.NewSessionFromRegion(cfg.Region, cfg.AWSEndpoints, cfg.AWSCredentials.WithOverrides(opts.AWSCredentials), awsDebug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+	}
+
+	return CertificateRotatorFromConfigWithServiceClients(cfg, opts, rotationOpts, awsconn.NewServiceClients(session))
+}
+
+// CertificateRotatorFromConfigWithServiceClients returns the rotator calling AWS APIs via the clients
+func CertificateRotatorFromConfigWithServiceClients(cfg *config.Config, opts options, rotationOpts controlplane.CertificateRotationOptions, clients *awsconn.ServiceClients) (CertificateRotator, error) {
+	certificates, err := cfg.CertificatesToRotate(rotationOpts)
+	if err != nil {
+		return nil, err
+	}
+	return certificateRotatorImpl{
+		cfg:          cfg,
+		opts:         opts,
+		clients:      clients,
+		rotationOpts: rotationOpts,
+		certificates: certificates,
+	}, nil
+}
+
+func (r certificateRotatorImpl) CallerIdentity() (*awsconn.CallerIdentity, error) {
+	return awsconn.GetCallerIdentity(r.clients.STS)
+}
+
+func (r certificateRotatorImpl) Certificates() []string {
+	return r.certificates
+}
+
+func (r certificateRotatorImpl) Rotate() ([]string, error) {
+	rotationOpts := r.rotationOpts
+	rotationOpts.Certificates = r.certificates
+	written, err := r.cfg.RotateCertificates(r.opts.AssetsDir, rotationOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encryptAssets(r.cfg, r.opts, r.clients); err != nil {
+		return nil, fmt.Errorf("failed to encrypt rotated certificates: %v", err)
+	}
+
+	return written, nil
+}
+
+// encryptAssets encrypts the credentials written in the assets directory with KMS, if the assets encryption is enabled
+func encryptAssets(cfg *config.Config, opts options, clients *awsconn.ServiceClients) error {
+	if !cfg.AssetsEncryptionEnabled() {
+		return nil
+	}
+	kmsConfig := controlplane.NewKMSConfig(cfg.KMSKeyARN, cfg.ProvidedEncryptService, clients.Session)
+	_, err := controlplane.ReadOrCreateEncryptedAssets(opts.AssetsDir, cfg.ManageCertificates, cfg.Experimental.TLSBootstrap.Enabled, cfg.Experimental.KIAMSupport.Enabled, kmsConfig)
+	return err
+}
+
+func (r certificateRotatorImpl) UpdateInStages() error {
+	return updateInStages(r.cfg, r.opts, r.clients)
+}
+
+// updateInStages updates etcd first, then the control plane and then node pools.
+// The cluster is initialized here so that its stack templates embed the credentials written beforehand
+func updateInStages(cfg *config.Config, opts options, clients *awsconn.ServiceClients) error {
+	cluster, err := ClusterFromConfigWithServiceClients(cfg, opts, clients)
+	if err != nil {
+		return err
+	}
+
+	for _, stage := range stages(cfg) {
+		progress.Printf("Updating %s...\n", stage.String())
+		report, err := cluster.Update(stage)
+		if err != nil {
+			if isNoUpdatesError(err) {
+				progress.Printf("No changes to %s. Skipping\n", stage.String())
+				continue
+			}
+			return fmt.Errorf("failed to update %s: %v", stage.String(), err)
+		}
+		if report != "" {
+			progress.Printf("Update stack: %s\n", report)
+		}
+	}
+	return nil
+}
+
+// stages returns the targets updated one after another, so that etcd is replaced before the controllers connecting to it,
+// and the controllers before the workers connecting to them
+func stages(cfg *config.Config) []OperationTargets {
+	targets := []OperationTargets{
+		{OperationTargetEtcd},
+		{OperationTargetControlPlane},
+	}
+	nodePools := OperationTargets{}
+	for _, np := range cfg.NodePools {
+		nodePools = append(nodePools, np.NodePoolName)
+	}
+	if len(nodePools) > 0 {
+		targets = append(targets, nodePools)
+	}
+	return targets
+}
+
+func isNoUpdatesError(err error) bool {
+	return strings.Contains(err.Error(), "No updates are to be performed")
+}
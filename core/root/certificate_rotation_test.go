@@ -0,0 +1,100 @@
+package root
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func TestRotateCertificatesAgainstFakeAWS(t *testing.T) {
+	cfg, err := config.ConfigFromBytesWithEncryptService([]byte(clusterYamlForFakeAWS), []*pluginmodel.Plugin{}, helper.DummyEncryptService{})
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+
+	helper.WithDummyCredentials(func(dir string) {
+		fake := helper.NewFakeAWS("us-west-1", "mybucket")
+		opts := clusterOptionsForFakeAWS(dir)
+
+		cluster, err := ClusterFromConfigWithServiceClients(cfg, opts, fake.ServiceClients())
+		if err != nil {
+			t.Fatalf("failed to initialize cluster: %v", err)
+		}
+		if err := cluster.Create(); err != nil {
+			t.Fatalf("failed to create cluster: %v", err)
+		}
+
+		// The dummy CA key isn't an RSA key which the certificates can be issued with
+		caKey, caCert, err := cfg.NewTLSCA()
+		if err != nil {
+			t.Fatalf("failed generating tls ca: %v", err)
+		}
+		caDir, err := ioutil.TempDir("", "rotation-ca")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(caDir)
+		rotationOpts := controlplane.CertificateRotationOptions{
+			CaKeyPath:    filepath.Join(caDir, "ca-key.pem"),
+			CaCertPath:   filepath.Join(caDir, "ca.pem"),
+			Certificates: []string{"etcd", "etcd-client", "apiserver", "worker"},
+		}
+		if err := ioutil.WriteFile(rotationOpts.CaKeyPath, tlsutil.EncodePrivateKeyPEM(caKey), 0600); err != nil {
+			t.Fatalf("failed to write ca key: %v", err)
+		}
+		if err := ioutil.WriteFile(rotationOpts.CaCertPath, tlsutil.EncodeCertificatePEM(caCert), 0600); err != nil {
+			t.Fatalf("failed to write ca cert: %v", err)
+		}
+
+		rotator, err := CertificateRotatorFromConfigWithServiceClients(cfg, opts, rotationOpts, fake.ServiceClients())
+		if err != nil {
+			t.Fatalf("failed to initialize certificate rotator: %v", err)
+		}
+
+		written, err := rotator.Rotate()
+		if err != nil {
+			t.Fatalf("failed to rotate certificates: %v", err)
+		}
+		if len(written) != 8 {
+			t.Errorf("expected 4 certs and their keys to be written but were: %v", written)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "etcd-key.pem.enc")); err != nil {
+			t.Errorf("expected the rotated etcd key to be encrypted again: %v", err)
+		}
+
+		if err := rotator.UpdateInStages(); err != nil {
+			t.Fatalf("failed to update cluster in stages: %v", err)
+		}
+
+		statuses := map[string]string{}
+		for _, name := range fake.CloudFormation.StackNames() {
+			resp, err := fake.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(name)})
+			if err != nil {
+				t.Fatalf("failed to describe stack %s: %v", name, err)
+			}
+			statuses[name] = aws.StringValue(resp.Stacks[0].StackStatus)
+		}
+		for prefix, expected := range map[string]string{
+			"test-cluster-Network-":      cloudformation.StackStatusCreateComplete,
+			"test-cluster-Etcd-":         cloudformation.StackStatusUpdateComplete,
+			"test-cluster-Controlplane-": cloudformation.StackStatusUpdateComplete,
+			"test-cluster-Pool1-":        cloudformation.StackStatusUpdateComplete,
+		} {
+			for name, status := range statuses {
+				if strings.HasPrefix(name, prefix) && status != expected {
+					t.Errorf("unexpected status of %s after rotation: expected %s but was %s", name, expected, status)
+				}
+			}
+		}
+	})
+}
@@ -6,14 +6,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/cfnstack"
 	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/cluster"
@@ -29,6 +28,7 @@ import (
 	"github.com/kubernetes-incubator/kube-aws/naming"
 	"github.com/kubernetes-incubator/kube-aws/plugin/clusterextension"
 	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 	"github.com/tidwall/sjson"
 )
 
@@ -46,7 +46,7 @@ func (c clusterImpl) Export() error {
 
 	for _, asset := range assets.AsMap() {
 		path := filepath.Join("exported", "stacks", asset.Path)
-		fmt.Printf("Exporting %s\n", path)
+		progress.Printf("Exporting %s\n", path)
 		dir := filepath.Dir(path)
 		if err := os.MkdirAll(dir, 0700); err != nil {
 			return fmt.Errorf("failed to create directory \"%s\": %v", dir, err)
@@ -55,7 +55,7 @@ func (c clusterImpl) Export() error {
 			return fmt.Errorf("Error writing %s : %v", path, err)
 		}
 		if strings.HasSuffix(path, "stack.json") && c.controlPlane.KMSKeyARN == "" {
-			fmt.Printf("BEWARE: %s contains your TLS secrets!\n", path)
+			progress.Printf("BEWARE: %s contains your TLS secrets!\n", path)
 		}
 	}
 	return nil
@@ -63,7 +63,7 @@ func (c clusterImpl) Export() error {
 
 func (c clusterImpl) EstimateCost() ([]string, error) {
 
-	cfSvc := cloudformation.New(c.session)
+	cfSvc := c.clients.CloudFormation
 	var urls []string
 
 	controlPlaneTemplate, err := c.controlPlane.RenderStackTemplateAsString()
@@ -106,13 +106,23 @@ func (c clusterImpl) EstimateCost() ([]string, error) {
 }
 
 type Cluster interface {
+	Apply(*Plan) error
 	Assets() (cfnstack.Assets, error)
+	CallerIdentity() (*awsconn.CallerIdentity, error)
 	Create() error
+	DeleteGarbageAssets(*cfnstack.GarbageAssets) error
+	Diff(OperationTargets) (cfnstack.ChangeSets, error)
 	Export() error
 	EstimateCost() ([]string, error)
+	GarbageAssets(int) (*cfnstack.GarbageAssets, error)
 	Info() (*Info, error)
+	Plan(OperationTargets) (*Plan, cfnstack.ChangeSets, error)
+	Recover(*cfnstack.StackState, []string) error
+	RenderAssetsOffline(string, ...OperationTargets) ([]model.Asset, error)
+	StackState() (*cfnstack.StackState, error)
 	Update(OperationTargets) (string, error)
 	ValidateStack(...OperationTargets) (string, error)
+	ValidateStackOffline(string, ...OperationTargets) (string, error)
 	ValidateTemplates() error
 	ControlPlane() *controlplane.Cluster
 	Etcd() *etcd.Cluster
@@ -130,11 +140,15 @@ func ClusterFromFile(configPath string, opts options, awsDebug bool) (Cluster, e
 }
 
 func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster, error) {
-	session, err := awsconn.NewSessionFromRegion(cfg.Region, awsDebug)
+	session, err := awsconn.NewSessionFromRegion(cfg.Region, cfg.AWSEndpoints, cfg.AWSCredentials.WithOverrides(opts.AWSCredentials), awsDebug)
 	if err != nil {
 		return nil, fmt.Errorf("failed to establish aws session: %v", err)
 	}
+	return ClusterFromConfigWithServiceClients(cfg, opts, awsconn.NewServiceClients(session))
+}
 
+// ClusterFromConfigWithServiceClients returns the cluster calling AWS APIs via the clients instead of the ones created from a new AWS session
+func ClusterFromConfigWithServiceClients(cfg *config.Config, opts options, clients *awsconn.ServiceClients) (Cluster, error) {
 	plugins := cfg.Plugins
 
 	stackTemplateOpts := controlplane_cfg.StackTemplateOptions{
@@ -145,25 +159,26 @@ func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster
 		PrettyPrint:           opts.PrettyPrint,
 		S3URI:                 cfg.DeploymentSettings.S3URI,
 		SkipWait:              opts.SkipWait,
+		Offline:               opts.Offline,
 	}
 
 	netOpts := stackTemplateOpts
 	netOpts.StackTemplateTmplFile = opts.NetworkStackTemplateTmplFile
-	net, err := network.NewCluster(cfg.Cluster, netOpts, plugins, session)
+	net, err := network.NewCluster(cfg.Cluster, netOpts, plugins, clients)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initizlie network stack: %v", err)
 	}
 
 	cpOpts := stackTemplateOpts
 	cpOpts.StackTemplateTmplFile = opts.ControlPlaneStackTemplateTmplFile
-	cp, err := controlplane.NewCluster(cfg.Cluster, cpOpts, plugins, session)
+	cp, err := controlplane.NewCluster(cfg.Cluster, cpOpts, plugins, clients)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize control-plane stack: %v", err)
 	}
 
 	etcdOpts := stackTemplateOpts
 	etcdOpts.StackTemplateTmplFile = opts.EtcdStackTemplateTmplFile
-	etcd, err := etcd.NewCluster(cfg.Cluster, etcdOpts, plugins, session)
+	etcd, err := etcd.NewCluster(cfg.Cluster, etcdOpts, plugins, clients)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize etcd stack: %v", err)
 	}
@@ -177,8 +192,9 @@ func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster
 			PrettyPrint:           opts.PrettyPrint,
 			S3URI:                 cfg.DeploymentSettings.S3URI,
 			SkipWait:              opts.SkipWait,
+			Offline:               opts.Offline,
 		}
-		np, err := nodepool.NewCluster(c, npOpts, plugins, session)
+		np, err := nodepool.NewCluster(c, npOpts, plugins, clients)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load node pool #%d: %v", i, err)
 		}
@@ -197,7 +213,7 @@ func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster
 		etcd:              etcd,
 		network:           net,
 		nodePools:         nodePools,
-		session:           session,
+		clients:           clients,
 		ExtraCfnResources: extra.Resources,
 	}
 
@@ -210,7 +226,7 @@ type clusterImpl struct {
 	network           *network.Cluster
 	nodePools         []*nodepool.Cluster
 	opts              options
-	session           *session.Session
+	clients           *awsconn.ServiceClients
 	ExtraCfnResources map[string]interface{}
 }
 
@@ -253,7 +269,7 @@ func (c clusterImpl) operationTargetsFromUserInput(opts []OperationTargets) Oper
 }
 
 func (c clusterImpl) Create() error {
-	cfSvc := cloudformation.New(c.session)
+	cfSvc := c.clients.CloudFormation
 
 	assets, err := c.generateAssets(c.allOperationTargets())
 	if err != nil {
@@ -270,16 +286,10 @@ func (c clusterImpl) Create() error {
 		return err
 	}
 
-	q := make(chan struct{}, 1)
-	defer func() { q <- struct{}{} }()
+	q := make(chan struct{})
+	defer close(q)
 
-	if c.controlPlane.CloudWatchLogging.Enabled && c.controlPlane.CloudWatchLogging.LocalStreaming.Enabled {
-		go streamJournaldLogs(c, q)
-	}
-
-	if c.controlPlane.CloudFormationStreaming {
-		go streamStackEvents(c, cfSvc, q)
-	}
+	c.startStreaming(cfSvc, q)
 
 	return c.stackProvisioner().CreateStackAtURLAndWait(cfSvc, stackTemplateURL)
 }
@@ -291,12 +301,17 @@ func (c clusterImpl) Info() (*Info, error) {
 		return nil, err
 	}
 
-	describer := NewClusterDescriber(c.controlPlane.ClusterName, c.stackName(), cpConfig, c.session)
+	nodePoolNames := []string{}
+	for _, np := range c.nodePools {
+		nodePoolNames = append(nodePoolNames, np.NodePoolName)
+	}
+
+	describer := NewClusterDescriber(c.controlPlane.ClusterName, c.stackName(), cpConfig, nodePoolNames, c.clients)
 	return describer.Info()
 }
 
 func (c clusterImpl) generateAssets(targets OperationTargets) (cfnstack.Assets, error) {
-	fmt.Printf("generating assets for %s\n", targets.String())
+	progress.Printf("generating assets for %s\n", targets.String())
 	var netAssets cfnstack.Assets
 	if targets.IncludeNetwork() {
 		netAssets = c.network.Assets()
@@ -328,11 +343,7 @@ func (c clusterImpl) generateAssets(targets OperationTargets) (cfnstack.Assets,
 
 	nestedStacksAssets := netAssets.Merge(cpAssets).Merge(etcdAssets).Merge(wAssets)
 
-	s3URI := fmt.Sprintf("%s/kube-aws/clusters/%s/exported/stacks",
-		strings.TrimSuffix(c.s3URI(), "/"),
-		c.controlPlane.ClusterName,
-	)
-	rootStackAssetsBuilder := cfnstack.NewAssetsBuilder(c.stackName(), s3URI, c.controlPlane.Region)
+	rootStackAssetsBuilder := cfnstack.NewAssetsBuilder(c.stackName(), rootStackAssetsS3URI(c.s3URI(), c.controlPlane.ClusterName), c.controlPlane.Region)
 
 	var stackTemplate string
 	// Do not update the root stack but update either controlplane or worker stack(s) only when specified so
@@ -348,7 +359,7 @@ func (c clusterImpl) generateAssets(targets OperationTargets) (cfnstack.Assets,
 		stackTemplate = renderedTemplate
 	} else {
 		for _, target := range targets {
-			fmt.Printf("updating template url of %s\n", target)
+			progress.Printf("updating template url of %s\n", target)
 
 			rootStackTemplate, err := c.getCurrentRootStackTemplate()
 			if err != nil {
@@ -371,20 +382,29 @@ func (c clusterImpl) generateAssets(targets OperationTargets) (cfnstack.Assets,
 			}
 		}
 	}
-	rootStackAssetsBuilder.Add(REMOTE_STACK_TEMPLATE_FILENAME, stackTemplate)
+	if err := rootStackAssetsBuilder.AddStackTemplate(REMOTE_STACK_TEMPLATE_FILENAME, stackTemplate, c.ExtraCfnResources); err != nil {
+		return nil, fmt.Errorf("failed to add root stack template: %v", err)
+	}
 
 	rootStackAssets := rootStackAssetsBuilder.Build()
 
 	return nestedStacksAssets.Merge(rootStackAssets), nil
 }
 
+func rootStackAssetsS3URI(s3URI string, clusterName string) string {
+	return fmt.Sprintf("%s/kube-aws/clusters/%s/exported/stacks",
+		strings.TrimSuffix(s3URI, "/"),
+		clusterName,
+	)
+}
+
 func (c clusterImpl) setNestedStackTemplateURL(template, stack string, url string) (string, error) {
 	path := fmt.Sprintf("Resources.%s.Properties.TemplateURL", naming.FromStackToCfnResource(stack))
 	return sjson.Set(template, path, url)
 }
 
 func (c clusterImpl) getCurrentRootStackTemplate() (string, error) {
-	cfnSvc := cloudformation.New(c.session)
+	cfnSvc := c.clients.CloudFormation
 	byRootStackName := &cloudformation.GetTemplateInput{StackName: aws.String(c.stackName())}
 	output, err := cfnSvc.GetTemplate(byRootStackName)
 	if err != nil {
@@ -394,7 +414,7 @@ func (c clusterImpl) getCurrentRootStackTemplate() (string, error) {
 }
 
 func (c clusterImpl) uploadAssets(assets cfnstack.Assets) error {
-	s3Svc := s3.New(c.session)
+	s3Svc := c.clients.S3
 	err := c.stackProvisioner().UploadAssets(s3Svc, assets)
 	if err != nil {
 		return fmt.Errorf("failed to upload assets: %v", err)
@@ -412,6 +432,11 @@ func (c clusterImpl) extractRootStackTemplateURL(assets cfnstack.Assets) (string
 	return asset.URL()
 }
 
+// CallerIdentity returns the AWS account and the IAM user or role the cluster is created and updated as
+func (c clusterImpl) CallerIdentity() (*awsconn.CallerIdentity, error) {
+	return awsconn.GetCallerIdentity(c.clients.STS)
+}
+
 func (c clusterImpl) Assets() (cfnstack.Assets, error) {
 	return c.generateAssets(c.allOperationTargets())
 }
@@ -437,8 +462,7 @@ func (c clusterImpl) renderTemplateAsString() (string, error) {
 	return template, nil
 }
 
-func (c clusterImpl) stackProvisioner() *cfnstack.Provisioner {
-	stackPolicyBody := `{
+const rootStackPolicyBody = `{
   "Statement" : [
     {
        "Effect" : "Allow",
@@ -448,15 +472,17 @@ func (c clusterImpl) stackProvisioner() *cfnstack.Provisioner {
      }
   ]
 }`
+
+func (c clusterImpl) stackProvisioner() *cfnstack.Provisioner {
 	return cfnstack.NewProvisioner(
 		c.stackName(),
 		c.tags(),
 		c.s3URI(),
 		c.controlPlane.Region,
-		stackPolicyBody,
-		c.session,
+		rootStackPolicyBody,
+		c.clients.Session,
 		c.controlPlane.CloudFormation.RoleARN,
-	)
+	).WithS3Assets(c.controlPlane.S3Assets)
 }
 
 func (c clusterImpl) stackName() string {
@@ -468,13 +494,17 @@ func (c clusterImpl) tags() map[string]string {
 }
 
 func (c clusterImpl) Update(targets OperationTargets) (string, error) {
-	cfSvc := cloudformation.New(c.session)
+	cfSvc := c.clients.CloudFormation
 
 	assets, err := c.generateAssets(c.operationTargetsFromUserInput([]OperationTargets{targets}))
 	if err != nil {
 		return "", err
 	}
 
+	if err := c.checkMovedResources(assets); err != nil {
+		return "", err
+	}
+
 	err = c.uploadAssets(assets)
 	if err != nil {
 		return "", err
@@ -485,18 +515,141 @@ func (c clusterImpl) Update(targets OperationTargets) (string, error) {
 		return "", err
 	}
 
-	q := make(chan struct{}, 1)
-	defer func() { q <- struct{}{} }()
+	q := make(chan struct{})
+	defer close(q)
 
-	if c.controlPlane.CloudWatchLogging.Enabled && c.controlPlane.CloudWatchLogging.LocalStreaming.Enabled {
-		go streamJournaldLogs(c, q)
+	c.startStreaming(cfSvc, q)
+
+	return c.stackProvisioner().UpdateStackAtURLAndWait(cfSvc, templateUrl)
+}
+
+// Diff previews an update by creating change sets for the root stack and each targeted nested stack.
+// The change sets are deleted once their changes are described so that nothing is modified in the cluster.
+func (c clusterImpl) Diff(targets OperationTargets) (cfnstack.ChangeSets, error) {
+	cfSvc := c.clients.CloudFormation
+
+	changeSetName := fmt.Sprintf("kube-aws-diff-%d", time.Now().Unix())
+
+	_, changeSets, err := c.createChangeSets(cfSvc, c.operationTargetsFromUserInput([]OperationTargets{targets}), changeSetName)
+	if err != nil {
+		return nil, err
 	}
 
-	if c.controlPlane.CloudFormationStreaming {
-		go streamStackEvents(c, cfSvc, q)
+	if err := c.stackProvisioner().DeleteChangeSets(cfSvc, changeSets); err != nil {
+		return nil, err
 	}
 
-	return c.stackProvisioner().UpdateStackAtURLAndWait(cfSvc, templateUrl)
+	return changeSets, nil
+}
+
+func (c clusterImpl) createChangeSets(cfSvc cfnstack.ChangeSetService, targets OperationTargets, changeSetName string) (cfnstack.Assets, cfnstack.ChangeSets, error) {
+	assets, err := c.generateAndUploadAssets(targets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changeSets, err := c.createChangeSetsForAssets(cfSvc, assets, targets, changeSetName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return assets, changeSets, nil
+}
+
+func (c clusterImpl) generateAndUploadAssets(targets OperationTargets) (cfnstack.Assets, error) {
+	assets, err := c.generateAssets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload all the assets including stack templates and cloud-configs for all the stacks
+	if err := c.uploadAssets(assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// createChangeSetsForAssets creates change sets for the root stack and the targeted nested stacks with the stack templates already uploaded
+func (c clusterImpl) createChangeSetsForAssets(cfSvc cfnstack.ChangeSetService, assets cfnstack.Assets, targets OperationTargets, changeSetName string) (cfnstack.ChangeSets, error) {
+	if err := c.checkMovedResources(assets); err != nil {
+		return nil, err
+	}
+
+	rootStackTemplate, err := assets.FindAssetByStackAndFileName(c.stackName(), REMOTE_STACK_TEMPLATE_FILENAME)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find root stack template: %v", err)
+	}
+	rootStackTemplateURL, err := rootStackTemplate.URL()
+	if err != nil {
+		return nil, err
+	}
+
+	nestedStackTemplateURLs := map[string]string{}
+	for _, target := range targets {
+		a, err := assets.FindAssetByStackAndFileName(target, REMOTE_STACK_TEMPLATE_FILENAME)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find assets for stack %s: %v", target, err)
+		}
+		nestedStackTemplateURLs[target], err = a.URL()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate %s stack template url: %v", target, err)
+		}
+	}
+
+	rootChangeSet, err := c.stackProvisioner().CreateChangeSetAtURLAndWait(cfSvc, changeSetName, rootStackTemplateURL)
+	if err != nil {
+		return nil, err
+	}
+
+	nestedChangeSets, err := c.stackProvisioner().CreateNestedChangeSetsAtURLsAndWait(cfSvc, changeSetName, nestedStackTemplateURLs, rootStackTemplate.Content)
+	changeSets := append(cfnstack.ChangeSets{rootChangeSet}, nestedChangeSets...)
+	if err != nil {
+		// Don't leave the change sets created so far, which are never executed
+		if deleteErr := c.stackProvisioner().DeleteChangeSets(cfSvc, changeSets); deleteErr != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %v\n", deleteErr)
+		}
+		return nil, err
+	}
+
+	return changeSets, nil
+}
+
+// checkMovedResources refuses to update the deployed stacks when splitting their oversized templates moves any existing resource.
+// See cfnstack.CheckMovedResources for details
+func (c clusterImpl) checkMovedResources(assets cfnstack.Assets) error {
+	cfSvc := c.clients.CloudFormation
+
+	resources, err := cfSvc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{StackName: aws.String(c.stackName())})
+	if err != nil {
+		return fmt.Errorf("failed to describe resources of stack %s: %v", c.stackName(), err)
+	}
+	physicalIDs := map[string]string{}
+	for _, r := range resources.StackResources {
+		physicalIDs[aws.StringValue(r.LogicalResourceId)] = aws.StringValue(r.PhysicalResourceId)
+	}
+
+	stackIDs := map[string]string{c.stackName(): c.stackName()}
+	for id := range assets.AsMap() {
+		if id.Filename != REMOTE_STACK_TEMPLATE_FILENAME || id.StackName == c.stackName() {
+			continue
+		}
+		// Nested stacks added by the update have nothing to be moved
+		if stackID := physicalIDs[naming.FromStackToCfnResource(id.StackName)]; stackID != "" {
+			stackIDs[id.StackName] = stackID
+		}
+	}
+
+	names := []string{}
+	for name := range stackIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := cfnstack.CheckMovedResources(cfSvc, stackIDs[name], assets, name, REMOTE_STACK_TEMPLATE_FILENAME); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c clusterImpl) ValidateTemplates() error {
@@ -523,27 +676,26 @@ func (c clusterImpl) ValidateTemplates() error {
 
 // ValidateStack validates all the CloudFormation stack templates already uploaded to S3
 func (c clusterImpl) ValidateStack(opts ...OperationTargets) (string, error) {
-	reports := []string{}
-
 	targets := c.operationTargetsFromUserInput(opts)
 
-	assets, err := c.generateAssets(c.operationTargetsFromUserInput([]OperationTargets{targets}))
+	assets, err := c.generateAndUploadAssets(c.operationTargetsFromUserInput([]OperationTargets{targets}))
 	if err != nil {
 		return "", err
 	}
 
-	// Upload all the assets including stack templates and cloud-configs for all the stacks
-	err = c.uploadAssets(assets)
-	if err != nil {
-		return "", err
-	}
+	return c.validateUploadedStacks(assets)
+}
+
+// validateUploadedStacks validates the CloudFormation stack templates of the assets which are already uploaded to S3
+func (c clusterImpl) validateUploadedStacks(assets cfnstack.Assets) (string, error) {
+	reports := []string{}
 
 	rootStackTemplateURL, err := c.extractRootStackTemplateURL(assets)
 	if err != nil {
 		return "", err
 	}
 
-	r, err := c.stackProvisioner().ValidateStackAtURL(rootStackTemplateURL)
+	r, err := c.stackProvisioner().ValidateStackAtURL(c.clients.CloudFormation, rootStackTemplateURL)
 	if err != nil {
 		return "", err
 	}
@@ -579,16 +731,59 @@ func (c clusterImpl) ValidateStack(opts ...OperationTargets) (string, error) {
 	return strings.Join(reports, "\n"), nil
 }
 
+// StackState returns the state of the root stack, including the first failure found in it or its nested stacks
+func (c clusterImpl) StackState() (*cfnstack.StackState, error) {
+	return c.stackProvisioner().DescribeStackState(c.clients.CloudFormation)
+}
+
+// Recover continues rolling back the root stack stuck in UPDATE_ROLLBACK_FAILED while skipping the resources,
+// or deletes the root stack which has never been created successfully
+func (c clusterImpl) Recover(state *cfnstack.StackState, skipResources []string) error {
+	cfSvc := c.clients.CloudFormation
+
+	q := make(chan struct{})
+	defer close(q)
+
+	if c.controlPlane.CloudFormationStreaming {
+		go streamStackEvents(c, cfSvc, q)
+	}
+
+	return c.stackProvisioner().RecoverStackAndWait(cfSvc, state, skipResources)
+}
+
+// GarbageAssets returns the assets of the cluster in S3 which are no longer referenced by the deployed stacks,
+// except the latest keep generations of each asset
+func (c clusterImpl) GarbageAssets(keep int) (*cfnstack.GarbageAssets, error) {
+	s3URI := rootStackAssetsS3URI(c.s3URI(), c.controlPlane.ClusterName)
+	return cfnstack.FindGarbageAssets(c.clients.CloudFormation, c.clients.S3, c.stackName(), s3URI, keep)
+}
+
+// DeleteGarbageAssets deletes the assets returned by GarbageAssets
+func (c clusterImpl) DeleteGarbageAssets(garbage *cfnstack.GarbageAssets) error {
+	return cfnstack.DeleteGarbageAssets(c.clients.S3, garbage)
+}
+
+// startStreaming streams journald logs and stack events while the cluster is created or updated, until q is closed
+func (c clusterImpl) startStreaming(cfSvc cfnstack.StackEventsStreamingService, q chan struct{}) {
+	if c.controlPlane.CloudWatchLogging.Enabled && c.controlPlane.CloudWatchLogging.LocalStreaming.Enabled {
+		go streamJournaldLogs(c, q)
+	}
+
+	if c.controlPlane.CloudFormationStreaming {
+		go streamStackEvents(c, cfSvc, q)
+	}
+}
+
 func streamJournaldLogs(c clusterImpl, q chan struct{}) error {
-	fmt.Printf("Streaming filtered Journald logs for log group '%s'...\nNOTE: Due to high initial entropy, '.service' failures may occur during the early stages of booting.\n", c.controlPlane.ClusterName)
-	cwlSvc := cloudwatchlogs.New(c.session)
+	progress.Printf("Streaming filtered Journald logs for log group '%s'...\nNOTE: Due to high initial entropy, '.service' failures may occur during the early stages of booting.\n", c.controlPlane.ClusterName)
+	cwlSvc := c.clients.CloudWatchLogs
 	s := time.Now().Unix() * 1E3
 	t := s
 	in := cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &c.controlPlane.ClusterName,
 		FilterPattern: &c.controlPlane.CloudWatchLogging.LocalStreaming.Filter,
 		StartTime:     &s}
-	ms := make(map[string]int64)
+	dedup := newMessageDeduplicator(c.controlPlane.CloudWatchLogging.LocalStreaming.Interval())
 
 	for {
 		select {
@@ -602,13 +797,12 @@ func streamJournaldLogs(c clusterImpl, q chan struct{}) error {
 			if len(out.Events) > 1 {
 				s = *out.Events[len(out.Events)-1].Timestamp
 				for _, event := range out.Events {
-					if *event.Timestamp > ms[*event.Message]+c.controlPlane.CloudWatchLogging.LocalStreaming.Interval() {
-						ms[*event.Message] = *event.Timestamp
+					if dedup.allow(*event.Message, *event.Timestamp) {
 						res := model.SystemdMessageResponse{}
 						json.Unmarshal([]byte(*event.Message), &res)
 						s := int(((*event.Timestamp) - t) / 1E3)
 						d := fmt.Sprintf("+%.2d:%.2d:%.2d", s/3600, (s/60)%60, s%60)
-						fmt.Printf("%s\t%s: \"%s\"\n", d, res.Hostname, res.Message)
+						progress.Printf("%s\t%s: \"%s\"\n", d, res.Hostname, res.Message)
 					}
 				}
 			}
@@ -622,7 +816,7 @@ func streamJournaldLogs(c clusterImpl, q chan struct{}) error {
 }
 
 // streamStackEvents streams all the events from the root, the control-plane, and worker node pool stacks using StreamEventsNested
-func streamStackEvents(c clusterImpl, cfSvc *cloudformation.CloudFormation, q chan struct{}) error {
-	fmt.Printf("Streaming CloudFormation events for the cluster '%s'...\n", c.controlPlane.ClusterName)
+func streamStackEvents(c clusterImpl, cfSvc cfnstack.StackEventsStreamingService, q chan struct{}) error {
+	progress.Printf("Streaming CloudFormation events for the cluster '%s'...\n", c.controlPlane.ClusterName)
 	return c.stackProvisioner().StreamEventsNested(q, cfSvc, c.controlPlane.ClusterName, c.controlPlane.ClusterName, time.Now())
 }
@@ -0,0 +1,327 @@
+package root
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	"github.com/kubernetes-incubator/kube-aws/cfnstack"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+const clusterYamlForFakeAWS = `clusterName: test-cluster
+externalDNSName: test.example.com
+keyName: test-key-name
+s3URI: s3://mybucket/mydir
+region: us-west-1
+amiId: ami-12345678
+kmsKeyArn: "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxxxxxxxxxxxxxxxxxx"
+availabilityZone: us-west-1c
+hostedZoneId: hostedzone-xxxx
+worker:
+  nodePools:
+  - name: pool1
+`
+
+func clusterOptionsForFakeAWS(assetsDir string) options {
+	opts := NewOptions(false, false)
+	opts.AssetsDir = assetsDir
+	opts.ControllerTmplFile = "../controlplane/config/templates/cloud-config-controller"
+	opts.WorkerTmplFile = "../nodepool/config/templates/cloud-config-worker"
+	opts.EtcdTmplFile = "../etcd/config/templates/cloud-config-etcd"
+	opts.RootStackTemplateTmplFile = "config/templates/stack-template.json"
+	opts.NodePoolStackTemplateTmplFile = "../nodepool/config/templates/stack-template.json"
+	opts.ControlPlaneStackTemplateTmplFile = "../controlplane/config/templates/stack-template.json"
+	opts.NetworkStackTemplateTmplFile = "../network/config/templates/stack-template.json"
+	opts.EtcdStackTemplateTmplFile = "../etcd/config/templates/stack-template.json"
+	return opts
+}
+
+func TestClusterLifecycleAgainstFakeAWS(t *testing.T) {
+	cfg, err := config.ConfigFromBytesWithEncryptService([]byte(clusterYamlForFakeAWS), []*pluginmodel.Plugin{}, helper.DummyEncryptService{})
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+
+	helper.WithDummyCredentials(func(dir string) {
+		fake := helper.NewFakeAWS("us-west-1", "mybucket")
+
+		cluster, err := ClusterFromConfigWithServiceClients(cfg, clusterOptionsForFakeAWS(dir), fake.ServiceClients())
+		if err != nil {
+			t.Fatalf("failed to initialize cluster: %v", err)
+		}
+
+		describeRootStack := func() *cloudformation.Stack {
+			resp, err := fake.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String("test-cluster")})
+			if err != nil {
+				t.Fatalf("failed to describe the root stack: %v", err)
+			}
+			return resp.Stacks[0]
+		}
+
+		identity, err := cluster.CallerIdentity()
+		if err != nil {
+			t.Fatalf("failed to get caller identity: %v", err)
+		}
+		if identity.Account != "123456789012" || identity.ARN != "arn:aws:iam::123456789012:user/kube-aws" {
+			t.Errorf("unexpected caller identity: %+v", identity)
+		}
+
+		if err := cluster.Create(); err != nil {
+			t.Fatalf("failed to create cluster: %v", err)
+		}
+
+		if status := aws.StringValue(describeRootStack().StackStatus); status != cloudformation.StackStatusCreateComplete {
+			t.Errorf("unexpected status of the root stack after creation: %s", status)
+		}
+
+		stacks := fake.CloudFormation.StackNames()
+		if len(stacks) != 5 {
+			t.Errorf("expected the root stack and 4 nested stacks but were: %v", stacks)
+		}
+		for _, prefix := range []string{"test-cluster-Network-", "test-cluster-Etcd-", "test-cluster-Controlplane-", "test-cluster-Pool1-"} {
+			found := false
+			for _, s := range stacks {
+				found = found || strings.HasPrefix(s, prefix)
+			}
+			if !found {
+				t.Errorf("expected a nested stack named %s* but there were: %v", prefix, stacks)
+			}
+		}
+
+		uploaded := fake.S3.Keys("mybucket")
+		for _, suffix := range []string{
+			"/exported/stacks/test-cluster/stack.json",
+			"/exported/stacks/control-plane/stack.json",
+			"/exported/stacks/pool1/stack.json",
+		} {
+			found := false
+			for _, k := range uploaded {
+				found = found || strings.HasSuffix(k, suffix)
+			}
+			if !found {
+				t.Errorf("expected an asset ending with %s to be uploaded but there were: %v", suffix, uploaded)
+			}
+		}
+
+		info, err := cluster.Info()
+		if err != nil {
+			t.Fatalf("failed to describe cluster: %v", err)
+		}
+		if len(info.ControlPlane.ControllerHosts) != 1 || !strings.HasSuffix(info.ControlPlane.ControllerHosts[0], ".us-west-1.elb.amazonaws.com") {
+			t.Errorf("unexpected controller hosts: %v", info.ControlPlane.ControllerHosts)
+		}
+		if len(info.NodePools) != 1 || info.NodePools[0].Name != "pool1" || !strings.HasPrefix(info.NodePools[0].StackName, "test-cluster-Pool1-") || info.NodePools[0].Status != cloudformation.StackStatusCreateComplete {
+			t.Errorf("unexpected node pools: %+v", info.NodePools)
+		}
+
+		t.Run("UpdateWithoutChanges", func(t *testing.T) {
+			_, err := cluster.Update(OperationTargetsFromStringSlice(AllOperationTargetsAsStringSlice()))
+			if err == nil || !strings.Contains(err.Error(), "No updates are to be performed") {
+				t.Errorf("expected the update to be rejected for having no changes but was: %v", err)
+			}
+		})
+
+		t.Run("UpdateRolledBack", func(t *testing.T) {
+			cfg.Controller.InstanceType = "m4.large"
+			updated, err := ClusterFromConfigWithServiceClients(cfg, clusterOptionsForFakeAWS(dir), fake.ServiceClients())
+			if err != nil {
+				t.Fatalf("failed to initialize cluster: %v", err)
+			}
+
+			fake.CloudFormation.FailingResources = map[string]string{"Controlplane.ControllersLC": "The requested configuration is currently not supported"}
+			defer func() { fake.CloudFormation.FailingResources = nil }()
+
+			_, err = updated.Update(OperationTargetsFromStringSlice(AllOperationTargetsAsStringSlice()))
+			if err == nil || !strings.Contains(err.Error(), "The requested configuration is currently not supported") {
+				t.Errorf("expected the update to fail with the reason of the failed resource but was: %v", err)
+			}
+			if status := aws.StringValue(describeRootStack().StackStatus); status != cloudformation.StackStatusUpdateRollbackComplete {
+				t.Errorf("unexpected status of the root stack after the failed update: %s", status)
+			}
+		})
+
+		t.Run("Update", func(t *testing.T) {
+			cfg.Controller.InstanceType = "m4.large"
+			updated, err := ClusterFromConfigWithServiceClients(cfg, clusterOptionsForFakeAWS(dir), fake.ServiceClients())
+			if err != nil {
+				t.Fatalf("failed to initialize cluster: %v", err)
+			}
+			if _, err := updated.Update(OperationTargetsFromStringSlice(AllOperationTargetsAsStringSlice())); err != nil {
+				t.Fatalf("failed to update cluster: %v", err)
+			}
+			if status := aws.StringValue(describeRootStack().StackStatus); status != cloudformation.StackStatusUpdateComplete {
+				t.Errorf("unexpected status of the root stack after the update: %s", status)
+			}
+		})
+
+		t.Run("ValidateStack", func(t *testing.T) {
+			// The certificates are validated against the settings before the templates, which the dummy credentials fail
+			credentialsDir, err := ioutil.TempDir("", "kube-aws-credentials")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(credentialsDir)
+			if _, err := cfg.NewAssetsOnDisk(credentialsDir, controlplane.CredentialsOptions{GenerateCA: true}); err != nil {
+				t.Fatalf("failed to generate credentials: %v", err)
+			}
+
+			fake.EC2.KeyPairs = []string{"test-key-name"}
+
+			validated, err := ClusterFromConfigWithServiceClients(cfg, clusterOptionsForFakeAWS(credentialsDir), fake.ServiceClients())
+			if err != nil {
+				t.Fatalf("failed to initialize cluster: %v", err)
+			}
+			if _, err := validated.ValidateStack(); err != nil {
+				t.Fatalf("failed to validate stacks: %v", err)
+			}
+			urls := fake.CloudFormation.ValidatedTemplateURLs()
+			for _, suffix := range []string{
+				"/exported/stacks/test-cluster/stack.json",
+				"/exported/stacks/network/stack.json",
+				"/exported/stacks/etcd/stack.json",
+				"/exported/stacks/control-plane/stack.json",
+				"/exported/stacks/pool1/stack.json",
+			} {
+				found := false
+				for _, u := range urls {
+					found = found || strings.HasSuffix(u, suffix)
+				}
+				if !found {
+					t.Errorf("expected a template ending with %s to be validated but there were: %v", suffix, urls)
+				}
+			}
+		})
+
+		rootStackID := aws.StringValue(describeRootStack().StackId)
+
+		destroyer := ClusterDestroyerFromConfigWithServiceClients(cfg, DestroyOptions{}, fake.ServiceClients())
+		if err := destroyer.Destroy(); err != nil {
+			t.Fatalf("failed to destroy cluster: %v", err)
+		}
+
+		resp, err := fake.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(rootStackID)})
+		if err != nil {
+			t.Fatalf("failed to describe the deleted root stack: %v", err)
+		}
+		if status := aws.StringValue(resp.Stacks[0].StackStatus); status != cloudformation.StackStatusDeleteComplete {
+			t.Errorf("unexpected status of the root stack after destruction: %s", status)
+		}
+		if stacks := fake.CloudFormation.StackNames(); len(stacks) != 0 {
+			t.Errorf("expected all the stacks to be deleted but there were: %v", stacks)
+		}
+	})
+}
+
+func TestRenderAssetsOfflineWithoutAWS(t *testing.T) {
+	cfg, err := config.ConfigFromBytes([]byte(clusterYamlForFakeAWS), []*pluginmodel.Plugin{})
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+
+	helper.WithDummyCredentials(func(dir string) {
+		opts := clusterOptionsForFakeAWS(dir)
+		opts.Offline = true
+
+		// No session is given, so that encrypting the credentials with KMS, or calling any other AWS API, fails the test
+		cluster, err := ClusterFromConfigWithServiceClients(cfg, opts, &awsconn.ServiceClients{})
+		if err != nil {
+			t.Fatalf("failed to initialize cluster: %v", err)
+		}
+
+		if _, err := cluster.RenderAssetsOffline(filepath.Join(dir, "rendered")); err != nil {
+			t.Fatalf("failed to render assets offline: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "rendered", "mydir", "kube-aws", "clusters", "test-cluster", "exported", "stacks", "test-cluster", "stack.json")); err != nil {
+			t.Errorf("expected the root stack template to be rendered: %v", err)
+		}
+	})
+}
+
+func TestRenderAssetsSplitsOversizedNetworkTemplate(t *testing.T) {
+	cfg, err := config.ConfigFromBytes([]byte(clusterYamlForFakeAWS), []*pluginmodel.Plugin{})
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+
+	// The network stack template is the largest one for this cluster.yaml
+	limit := cfnstack.CFN_TEMPLATE_SIZE_LIMIT
+	cfnstack.CFN_TEMPLATE_SIZE_LIMIT = 8000
+	defer func() { cfnstack.CFN_TEMPLATE_SIZE_LIMIT = limit }()
+
+	helper.WithDummyCredentials(func(dir string) {
+		opts := clusterOptionsForFakeAWS(dir)
+		opts.Offline = true
+
+		cluster, err := ClusterFromConfigWithServiceClients(cfg, opts, &awsconn.ServiceClients{})
+		if err != nil {
+			t.Fatalf("failed to initialize cluster: %v", err)
+		}
+
+		assets, err := cluster.RenderAssetsOffline(filepath.Join(dir, "rendered"))
+		if err != nil {
+			t.Fatalf("failed to render assets: %v", err)
+		}
+
+		extras := 0
+		for _, a := range assets {
+			if len(a.Content) > cfnstack.CFN_TEMPLATE_SIZE_LIMIT && strings.HasSuffix(a.Key, ".json") {
+				t.Errorf("expected %s to be within the limit but was %d bytes", a.Key, len(a.Content))
+			}
+			if a.ID.StackName == "network" && strings.HasPrefix(a.ID.Filename, cfnstack.EXTRAS_TEMPLATE_ASSET_NAME+"-") {
+				extras++
+			}
+		}
+		if extras != 1 {
+			t.Errorf("expected the network stack template to be split into itself and the extras template but there were %d extras templates", extras)
+		}
+	})
+}
+
+func TestTemplateURLsWithS3EndpointOverridden(t *testing.T) {
+	cfg, err := config.ConfigFromBytes([]byte(clusterYamlForFakeAWS+`awsEndpoints:
+  s3: http://localhost:4566
+  s3ForcePathStyle: true
+`), []*pluginmodel.Plugin{})
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+
+	helper.WithDummyCredentials(func(dir string) {
+		opts := clusterOptionsForFakeAWS(dir)
+		opts.Offline = true
+
+		cluster, err := ClusterFromConfigWithServiceClients(cfg, opts, &awsconn.ServiceClients{})
+		if err != nil {
+			t.Fatalf("failed to initialize cluster: %v", err)
+		}
+
+		assets, err := cluster.Assets()
+		if err != nil {
+			t.Fatalf("failed to generate assets: %v", err)
+		}
+
+		root, err := assets.FindAssetByStackAndFileName("test-cluster", REMOTE_STACK_TEMPLATE_FILENAME)
+		if err != nil {
+			t.Fatalf("failed to find the root stack template: %v", err)
+		}
+		url, err := root.URL()
+		if err != nil {
+			t.Fatalf("failed to get the url of the root stack template: %v", err)
+		}
+		if url != "https://s3.amazonaws.com/mybucket/mydir/kube-aws/clusters/test-cluster/exported/stacks/test-cluster/stack.json" {
+			t.Errorf("unexpected url of the root stack template: %s", url)
+		}
+		if strings.Contains(root.Content, "localhost") || !strings.Contains(root.Content, `"TemplateURL":"https://s3.amazonaws.com/mybucket/mydir/kube-aws/clusters/test-cluster/exported/stacks/control-plane/stack.json"`) {
+			t.Errorf("expected the template urls of the nested stacks to point to the standard S3 endpoint:\n%s", root.Content)
+		}
+	})
+}
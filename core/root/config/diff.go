@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const diffContextLines = 3
+
+type diffLine struct {
+	op   byte
+	text string
+	// a and b are the line numbers in the old and new texts, counting from 0
+	a, b int
+}
+
+// unifiedDiff returns the differences between the lines a and b in the unified format, or an empty string when they are equal
+func unifiedDiff(a, b []string, fromFile, toFile string) string {
+	lines := diffLines(a, b)
+
+	changed := false
+	for _, l := range lines {
+		if l.op != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", fromFile, toFile)
+
+	for start := 0; start < len(lines); {
+		// Find the next change and the range of the hunk around it
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		hunkStart := first - diffContextLines
+		if hunkStart < start {
+			hunkStart = start
+		}
+		hunkEnd, unchanged := first, 0
+		for hunkEnd < len(lines) && unchanged <= 2*diffContextLines {
+			if lines[hunkEnd].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			hunkEnd++
+		}
+		if unchanged > diffContextLines {
+			hunkEnd -= unchanged - diffContextLines
+		}
+
+		aStart, aLen, bStart, bLen := lines[hunkStart].a, 0, lines[hunkStart].b, 0
+		for _, l := range lines[hunkStart:hunkEnd] {
+			if l.op != '+' {
+				aLen++
+			}
+			if l.op != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aStart+1, aLen, bStart+1, bLen)
+		for _, l := range lines[hunkStart:hunkEnd] {
+			fmt.Fprintf(buf, "%c%s\n", l.op, l.text)
+		}
+		start = hunkEnd
+	}
+
+	return buf.String()
+}
+
+// diffLines computes the shortest edit script from a to b via the longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,335 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Migration rewrites deprecated syntax in cluster.yaml into the current one, keeping comments and key ordering
+type Migration struct {
+	// Version is the position of the migration in Migrations
+	Version int
+	// Description is what the migration rewrites
+	Description string
+	// Note is what users should be aware of when the migration is applied, e.g. a behavior change of the cluster
+	Note string
+
+	migrate func(lines []string) ([]string, error)
+}
+
+// MigrationResult is cluster.yaml before and after applying migrations
+type MigrationResult struct {
+	Original []byte
+	Migrated []byte
+	// Applied is the migrations that changed cluster.yaml
+	Applied []Migration
+}
+
+// Migrations are applied to cluster.yaml in order by `kube-aws config migrate`.
+// Append a migration here whenever a key in cluster.yaml is deprecated or renamed
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "`vpcId` is moved to `vpc.id`",
+		migrate:     nestTopLevelKey("vpcId", "vpc", "id"),
+	},
+	{
+		Version:     2,
+		Description: "`internetGatewayId` is moved to `internetGateway.id`",
+		migrate:     nestTopLevelKey("internetGatewayId", "internetGateway", "id"),
+	},
+	{
+		Version:     3,
+		Description: "`workerInstanceType`, `workerCreateTimeout`, `workerTenancy` and `workerRootVolume*` are moved to `worker.nodePools[]`",
+		Note: "These top-level keys have been ignored since node pools were introduced. " +
+			"Node pools without their own settings will now use them, which replaces their nodes on the next `kube-aws update`",
+		migrate: moveWorkerSettingsToNodePools,
+	},
+}
+
+// MigrateConfig applies all the migrations needed for the cluster.yaml in data
+func MigrateConfig(data []byte) (*MigrationResult, error) {
+	r := &MigrationResult{Original: data, Applied: []Migration{}}
+
+	lines := strings.Split(string(data), "\n")
+	for _, m := range Migrations {
+		migrated, err := m.migrate(lines)
+		if err != nil {
+			return nil, fmt.Errorf("migration %d failed: %v", m.Version, err)
+		}
+		if strings.Join(migrated, "\n") != strings.Join(lines, "\n") {
+			r.Applied = append(r.Applied, m)
+		}
+		lines = migrated
+	}
+	r.Migrated = []byte(strings.Join(lines, "\n"))
+
+	if len(r.Applied) > 0 {
+		if _, err := unmarshalWorker(r.Migrated); err != nil {
+			return nil, fmt.Errorf("migrated cluster.yaml is invalid: %v", err)
+		}
+	}
+
+	return r, nil
+}
+
+// Diff returns the unified diff between the original and the migrated cluster.yaml
+func (r MigrationResult) Diff(path string) string {
+	return unifiedDiff(
+		strings.Split(string(r.Original), "\n"),
+		strings.Split(string(r.Migrated), "\n"),
+		path,
+		path+" (migrated)",
+	)
+}
+
+// nestTopLevelKey returns a migration moving the top-level scalar key `old` to `parent.child`
+func nestTopLevelKey(old, parent, child string) func([]string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		top, err := unmarshalTopLevel(lines)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := top[old]
+		if !ok {
+			return lines, nil
+		}
+
+		i := findTopLevelKey(lines, old)
+		if i < 0 {
+			return nil, fmt.Errorf("failed to locate `%s` in cluster.yaml", old)
+		}
+
+		scalar, err := yamlScalar(value)
+		if err != nil {
+			return nil, err
+		}
+
+		p, ok := top[parent]
+		if !ok {
+			result := append([]string{}, lines[:i]...)
+			result = append(result, parent+":", fmt.Sprintf("  %s: %s", child, scalar))
+			return append(result, lines[i+1:]...), nil
+		}
+
+		m, ok := p.(map[interface{}]interface{})
+		if !ok && p != nil {
+			return nil, fmt.Errorf("`%s` must be a map but was %v", parent, p)
+		}
+		if current, ok := m[child]; ok {
+			if fmt.Sprint(current) != fmt.Sprint(value) {
+				return nil, fmt.Errorf("`%s` and `%s.%s` are specified with different values. Remove either of them by hand", old, parent, child)
+			}
+			return append(append([]string{}, lines[:i]...), lines[i+1:]...), nil
+		}
+		if _, ok := m[child+"FromStackOutput"]; ok {
+			return nil, fmt.Errorf("`%s` and `%s.%sFromStackOutput` can't be specified at once. Remove either of them by hand", old, parent, child)
+		}
+
+		j := findTopLevelKey(lines, parent)
+		if j < 0 || !isBlockKeyLine(lines[j]) {
+			return nil, fmt.Errorf("`%s` in flow style is not supported. Rewrite it in block style to migrate `%s`", parent, old)
+		}
+		result := append([]string{}, lines...)
+		result = insertLines(result, j+1, fmt.Sprintf("%s%s: %s", strings.Repeat(" ", childIndentAt(result, j, 2)), child, scalar))
+		if i > j {
+			i++
+		}
+		return append(result[:i], result[i+1:]...), nil
+	}
+}
+
+// workerSettings maps top-level keys for the default worker settings to the keys of node pools
+var workerSettings = []struct {
+	key         string
+	nodePoolKey string
+	// rootVolumeKey is the key under `rootVolume` of node pools, if any
+	rootVolumeKey string
+	// spotFleet is true when the setting applies to spot fleet based node pools too
+	spotFleet bool
+}{
+	{"workerInstanceType", "instanceType", "", false},
+	{"workerCreateTimeout", "createTimeout", "", true},
+	{"workerTenancy", "tenancy", "", false},
+	{"workerRootVolumeType", "rootVolume", "type", true},
+	{"workerRootVolumeSize", "rootVolume", "size", true},
+	{"workerRootVolumeIOPS", "rootVolume", "iops", true},
+}
+
+func moveWorkerSettingsToNodePools(lines []string) ([]string, error) {
+	top, err := unmarshalTopLevel(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := []map[interface{}]interface{}{}
+	if worker, ok := top["worker"].(map[interface{}]interface{}); ok {
+		if ps, ok := worker["nodePools"].([]interface{}); ok {
+			for _, p := range ps {
+				m, _ := p.(map[interface{}]interface{})
+				pools = append(pools, m)
+			}
+		}
+	}
+
+	s, err := parseNodePoolsSection([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	if len(s.items) != len(pools) {
+		return nil, fmt.Errorf("failed to locate node pools in cluster.yaml: found %d items in `worker.nodePools` while expecting %d", len(s.items), len(pools))
+	}
+	result := s.lines
+
+	// Edit from the last node pool so that the line numbers of the preceding ones don't change
+	for i := len(s.items) - 1; i >= 0; i-- {
+		item, pool := s.items[i], pools[i]
+		_, isSpotFleet := pool["spotFleet"]
+
+		keys, rootVolumeKeys := []string{}, []string{}
+		for _, w := range workerSettings {
+			value, ok := top[w.key]
+			if !ok || isSpotFleet && !w.spotFleet {
+				continue
+			}
+			scalar, err := yamlScalar(value)
+			if err != nil {
+				return nil, err
+			}
+			if w.rootVolumeKey == "" {
+				if _, ok := pool[w.nodePoolKey]; !ok {
+					keys = append(keys, fmt.Sprintf("%s: %s", w.nodePoolKey, scalar))
+				}
+				continue
+			}
+			rootVolume, _ := pool["rootVolume"].(map[interface{}]interface{})
+			if _, ok := rootVolume[w.rootVolumeKey]; !ok {
+				rootVolumeKeys = append(rootVolumeKeys, fmt.Sprintf("%s: %s", w.rootVolumeKey, scalar))
+			}
+		}
+
+		indent := strings.Repeat(" ", s.contentIndent)
+		added := []string{}
+		for _, k := range keys {
+			added = append(added, indent+k)
+		}
+		if len(rootVolumeKeys) > 0 {
+			if j := findKeyInRange(result, item.start, item.end, s.contentIndent, "rootVolume"); j >= 0 {
+				if !isBlockKeyLine(result[j]) {
+					return nil, fmt.Errorf("`rootVolume` of node pool at index %d in flow style is not supported. Rewrite it in block style to migrate worker settings", i)
+				}
+				childIndent := strings.Repeat(" ", childIndentAt(result, j, s.contentIndent+2))
+				for k := len(rootVolumeKeys) - 1; k >= 0; k-- {
+					result = insertLines(result, j+1, childIndent+rootVolumeKeys[k])
+				}
+				item.end += len(rootVolumeKeys)
+			} else {
+				added = append(added, indent+"rootVolume:")
+				for _, k := range rootVolumeKeys {
+					added = append(added, indent+"  "+k)
+				}
+			}
+		}
+		result = insertLines(result, item.end, added...)
+	}
+
+	for _, w := range workerSettings {
+		if _, ok := top[w.key]; !ok {
+			continue
+		}
+		i := findTopLevelKey(result, w.key)
+		if i < 0 {
+			return nil, fmt.Errorf("failed to locate `%s` in cluster.yaml", w.key)
+		}
+		result = append(result[:i], result[i+1:]...)
+	}
+
+	return result, nil
+}
+
+func unmarshalTopLevel(lines []string) (map[string]interface{}, error) {
+	top := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &top); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	return top, nil
+}
+
+func yamlScalar(v interface{}) (string, error) {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %v: %v", v, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+var keyLinePattern = regexp.MustCompile(`^([A-Za-z0-9_-]+):(\s|$)`)
+
+// lineKey returns the indentation and the key of a `key: value` line, counting the dash of a sequence item as indentation
+func lineKey(l string) (int, string) {
+	indent := indentOf(l)
+	rest := l[indent:]
+	if strings.HasPrefix(rest, "- ") {
+		trimmed := strings.TrimLeft(rest[1:], " ")
+		indent += len(rest) - len(trimmed)
+		rest = trimmed
+	}
+	m := keyLinePattern.FindStringSubmatch(rest)
+	if m == nil {
+		return indent, ""
+	}
+	return indent, m[1]
+}
+
+func findTopLevelKey(lines []string, key string) int {
+	for i, l := range lines {
+		if indent, k := lineKey(l); indent == 0 && k == key && !strings.HasPrefix(l, "-") {
+			return i
+		}
+	}
+	return -1
+}
+
+// findKeyInRange returns the index of the line for key at the indentation within lines[start:end], or -1 when missing
+func findKeyInRange(lines []string, start, end, indent int, key string) int {
+	for i := start; i < end; i++ {
+		if in, k := lineKey(lines[i]); in == indent && k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBlockKeyLine returns true when the value of the key on the line is a block, e.g. `vpc:` or `vpc: # comment`
+func isBlockKeyLine(l string) bool {
+	_, key := lineKey(l)
+	rest := strings.TrimSpace(l[strings.Index(l, key+":")+len(key)+1:])
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+// childIndentAt returns the indentation of the children of the key on the line i, or defaultIndent when it has none
+func childIndentAt(lines []string, i int, defaultIndent int) int {
+	parent, _ := lineKey(lines[i])
+	for j := i + 1; j < len(lines); j++ {
+		if !isContentLine(lines[j]) {
+			continue
+		}
+		if indent := indentOf(lines[j]); indent > parent {
+			return indent
+		}
+		break
+	}
+	if defaultIndent <= parent {
+		return parent + 2
+	}
+	return defaultIndent
+}
+
+func insertLines(lines []string, i int, inserted ...string) []string {
+	result := append([]string{}, lines[:i]...)
+	result = append(result, inserted...)
+	return append(result, lines[i:]...)
+}
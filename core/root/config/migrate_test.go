@@ -0,0 +1,166 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	testCases := []struct {
+		context  string
+		config   string
+		expected string
+		applied  []int
+	}{
+		{
+			context: "UpToDate",
+			config: `clusterName: test-cluster-name
+vpc:
+  id: vpc-1
+`,
+			expected: `clusterName: test-cluster-name
+vpc:
+  id: vpc-1
+`,
+			applied: []int{},
+		},
+		{
+			context: "VPCIDAndInternetGatewayID",
+			config: `clusterName: test-cluster-name
+# Existing VPC
+vpcId: vpc-1
+internetGateway:
+  # Existing internet gateway
+#  id:
+region: us-west-1
+internetGatewayId: igw-1
+`,
+			expected: `clusterName: test-cluster-name
+# Existing VPC
+vpc:
+  id: vpc-1
+internetGateway:
+  id: igw-1
+  # Existing internet gateway
+#  id:
+region: us-west-1
+`,
+			applied: []int{1, 2},
+		},
+		{
+			context: "VPCIDAlongWithTheSameVPCID",
+			config: `vpcId: vpc-1
+vpc:
+    id: vpc-1
+`,
+			expected: `vpc:
+    id: vpc-1
+`,
+			applied: []int{1},
+		},
+		{
+			context: "WorkerSettings",
+			config: `clusterName: test-cluster-name
+workerInstanceType: m4.large
+worker:
+  nodePools:
+    - name: pool1
+#     # Disk size
+#     rootVolume:
+    - name: pool2
+      rootVolume:
+          size: 50
+      instanceType: c4.large
+    - name: pool3
+      spotFleet:
+        targetCapacity: 2
+# Worker root volume
+workerRootVolumeType: io1
+workerRootVolumeIOPS: 200
+workerTenancy: default
+`,
+			expected: `clusterName: test-cluster-name
+worker:
+  nodePools:
+    - name: pool1
+#     # Disk size
+#     rootVolume:
+      instanceType: m4.large
+      tenancy: default
+      rootVolume:
+        type: io1
+        iops: 200
+    - name: pool2
+      rootVolume:
+          type: io1
+          iops: 200
+          size: 50
+      instanceType: c4.large
+      tenancy: default
+    - name: pool3
+      spotFleet:
+        targetCapacity: 2
+      rootVolume:
+        type: io1
+        iops: 200
+# Worker root volume
+`,
+			applied: []int{3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.context, func(t *testing.T) {
+			r, err := MigrateConfig([]byte(tc.config))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(r.Migrated) != tc.expected {
+				t.Errorf("unexpected migrated cluster.yaml:\n%s", r.Migrated)
+			}
+			applied := []int{}
+			for _, m := range r.Applied {
+				applied = append(applied, m.Version)
+			}
+			if len(applied) != len(tc.applied) {
+				t.Fatalf("expected migrations %v to be applied but was %v", tc.applied, applied)
+			}
+			for i := range applied {
+				if applied[i] != tc.applied[i] {
+					t.Errorf("expected migrations %v to be applied but was %v", tc.applied, applied)
+				}
+			}
+		})
+	}
+
+	t.Run("ConflictingValues", func(t *testing.T) {
+		_, err := MigrateConfig([]byte("vpcId: vpc-1\nvpc:\n  id: vpc-2\n"))
+		if err == nil || !strings.Contains(err.Error(), "`vpcId` and `vpc.id` are specified with different values") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestMigrationResultDiff(t *testing.T) {
+	r, err := MigrateConfig([]byte("a: 1\nb: 2\nc: 3\nd: 4\nvpcId: vpc-1\ne: 5\nf: 6\ng: 7\nh: 8\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `--- cluster.yaml
++++ cluster.yaml (migrated)
+@@ -2,7 +2,8 @@
+ b: 2
+ c: 3
+ d: 4
+-vpcId: vpc-1
++vpc:
++  id: vpc-1
+ e: 5
+ f: 6
+ g: 7
+`
+	if actual := r.Diff("cluster.yaml"); actual != expected {
+		t.Errorf("unexpected diff:\n%s", actual)
+	}
+}
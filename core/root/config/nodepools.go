@@ -0,0 +1,477 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	nodepool "github.com/kubernetes-incubator/kube-aws/core/nodepool/config"
+)
+
+// NodePoolOptions is the set of settings for a node pool added to cluster.yaml by `kube-aws nodepool add`
+type NodePoolOptions struct {
+	Name         string
+	InstanceType string
+	// Count is the number of nodes, or the target capacity when SpotFleet is true.
+	// Omitted from cluster.yaml when zero so that the default applies
+	Count int
+	// MinSize and MaxSize are the bounds of the auto scaling group. Specified only when MaxSize is non-zero
+	MinSize   int
+	MaxSize   int
+	Subnets   []string
+	SpotFleet bool
+	SpotPrice string
+}
+
+// NodePoolSummary is the brief description of a node pool defined in cluster.yaml
+type NodePoolSummary struct {
+	Name          string   `json:"name"`
+	InstanceTypes []string `json:"instanceTypes"`
+	SpotFleet     bool     `json:"spotFleet"`
+	Count         int      `json:"count,omitempty"`
+	MinSize       int      `json:"minSize,omitempty"`
+	MaxSize       int      `json:"maxSize,omitempty"`
+	Subnets       []string `json:"subnets"`
+}
+
+var (
+	workerKeyPattern    = regexp.MustCompile(`^worker:\s*(#.*)?$`)
+	nodePoolsKeyPattern = regexp.MustCompile(`^(\s+)nodePools:\s*(#.*)?$`)
+	emptyNodePools      = regexp.MustCompile(`^(\s+)nodePools:\s*\[\s*\]\s*(#.*)?$`)
+)
+
+// ListNodePools returns the summaries of the node pools defined in the cluster.yaml in data
+func ListNodePools(data []byte) ([]NodePoolSummary, error) {
+	c, err := unmarshalWorker(data)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []NodePoolSummary{}
+	for _, np := range c.Worker.NodePools {
+		s := NodePoolSummary{
+			Name:          np.NodePoolName,
+			InstanceTypes: []string{},
+			SpotFleet:     np.SpotFleet.Enabled(),
+			Subnets:       []string{},
+		}
+		if s.SpotFleet {
+			s.Count = np.SpotFleet.TargetCapacity
+			for _, spec := range np.SpotFleet.LaunchSpecifications {
+				s.InstanceTypes = append(s.InstanceTypes, spec.InstanceType)
+			}
+		} else {
+			s.InstanceTypes = append(s.InstanceTypes, np.InstanceType)
+			if np.AutoScalingGroup.MaxSize != 0 {
+				s.MaxSize = np.AutoScalingGroup.MaxSize
+				if np.AutoScalingGroup.MinSize != nil {
+					s.MinSize = *np.AutoScalingGroup.MinSize
+				}
+			} else {
+				s.Count = np.Count
+			}
+		}
+		for _, subnet := range np.Subnets {
+			s.Subnets = append(s.Subnets, subnet.Name)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
+
+// AddNodePool returns the cluster.yaml in data with the node pool described by opts appended to `worker.nodePools`.
+// The rest of the file including comments and key ordering is kept as is
+func AddNodePool(data []byte, opts NodePoolOptions) ([]byte, error) {
+	c, err := unmarshalWorker(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Name == "" {
+		return nil, errors.New("node pool name must not be empty")
+	}
+	for _, np := range c.Worker.NodePools {
+		if np.NodePoolName == opts.Name {
+			return nil, fmt.Errorf("node pool named %s already exists", opts.Name)
+		}
+	}
+	if err := validateSubnetNames(c, opts.Subnets); err != nil {
+		return nil, err
+	}
+
+	item, err := nodePoolYAML(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseNodePoolsSection(data)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := append([]string{}, s.lines[:s.end]...)
+	if s.worker < 0 {
+		lines = append(lines, "worker:")
+	}
+	if s.nodePools < 0 {
+		lines = append(lines, fmt.Sprintf("%snodePools:", strings.Repeat(" ", s.nodePoolsIndent)))
+	}
+	lines = append(lines, indentItem(item, s.itemIndent, s.contentIndent)...)
+	lines = append(lines, s.lines[s.end:]...)
+
+	result := []byte(strings.Join(lines, "\n"))
+	if err := verifyNodePoolNames(result, append(nodePoolNames(c), opts.Name)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RemoveNodePool returns the cluster.yaml in data with the node pool named name removed from `worker.nodePools`.
+// Comments within the removed node pool are removed altogether, and the rest of the file is kept as is
+func RemoveNodePool(data []byte, name string) ([]byte, error) {
+	c, err := unmarshalWorker(data)
+	if err != nil {
+		return nil, err
+	}
+
+	names := nodePoolNames(c)
+	index := -1
+	remaining := []string{}
+	for i, n := range names {
+		if n == name {
+			index = i
+		} else {
+			remaining = append(remaining, n)
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("no node pool named %s found", name)
+	}
+
+	s, err := parseNodePoolsSection(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.items) != len(names) {
+		return nil, fmt.Errorf("failed to locate node pools in cluster.yaml: found %d items in `worker.nodePools` while expecting %d", len(s.items), len(names))
+	}
+
+	item := s.items[index]
+	lines := append([]string{}, s.lines[:item.start]...)
+	lines = append(lines, s.lines[item.end:]...)
+
+	result := []byte(strings.Join(lines, "\n"))
+	if err := verifyNodePoolNames(result, remaining); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func unmarshalWorker(data []byte) (*UnmarshalledConfig, error) {
+	c := newDefaultUnmarshalledConfig()
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	for i, np := range c.Worker.NodePools {
+		if np == nil {
+			return nil, fmt.Errorf("Empty nodepool definition found at index %d", i)
+		}
+	}
+	return c, nil
+}
+
+func nodePoolNames(c *UnmarshalledConfig) []string {
+	names := []string{}
+	for _, np := range c.Worker.NodePools {
+		names = append(names, np.NodePoolName)
+	}
+	return names
+}
+
+// verifyNodePoolNames guards against a broken cluster.yaml being written, by confirming that the edited file parses and
+// has exactly the expected node pools
+func verifyNodePoolNames(data []byte, expected []string) error {
+	c, err := unmarshalWorker(data)
+	if err != nil {
+		return fmt.Errorf("edited cluster.yaml is invalid: %v", err)
+	}
+	actual := nodePoolNames(c)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		return fmt.Errorf("edited cluster.yaml has unexpected node pools: expected %v but was %v", expected, actual)
+	}
+	return nil
+}
+
+func validateSubnetNames(c *UnmarshalledConfig, names []string) error {
+	defined := map[string]bool{}
+	if len(c.Subnets) == 0 {
+		// Corresponds to the default subnet created by controlplane.Cluster.SetDefaults
+		defined["Subnet0"] = true
+	}
+	for i, s := range c.Subnets {
+		if s.Name == "" {
+			defined[fmt.Sprintf("Subnet%d", i)] = true
+		} else {
+			defined[s.Name] = true
+		}
+	}
+	for _, n := range names {
+		if !defined[n] {
+			return fmt.Errorf("no subnet named %s found under the top-level `subnets` in cluster.yaml", n)
+		}
+	}
+	return nil
+}
+
+// nodePoolYAML renders the node pool as a YAML map and validates it in the same way as node pools loaded from cluster.yaml
+func nodePoolYAML(opts NodePoolOptions) (string, error) {
+	item := yaml.MapSlice{{Key: "name", Value: opts.Name}}
+
+	if len(opts.Subnets) > 0 {
+		subnets := []yaml.MapSlice{}
+		for _, n := range opts.Subnets {
+			subnets = append(subnets, yaml.MapSlice{{Key: "name", Value: n}})
+		}
+		item = append(item, yaml.MapItem{Key: "subnets", Value: subnets})
+	}
+
+	if opts.SpotFleet {
+		if opts.Count <= 0 {
+			return "", errors.New("count must be greater than 0 for a spot fleet based node pool, as it is the target capacity of the spot fleet")
+		}
+		if opts.MinSize != 0 || opts.MaxSize != 0 {
+			return "", errors.New("min size and max size can't be specified for a spot fleet based node pool")
+		}
+		fleet := yaml.MapSlice{{Key: "targetCapacity", Value: opts.Count}}
+		if opts.SpotPrice != "" {
+			fleet = append(fleet, yaml.MapItem{Key: "spotPrice", Value: opts.SpotPrice})
+		}
+		if opts.InstanceType != "" {
+			spec := yaml.MapSlice{{Key: "weightedCapacity", Value: 1}, {Key: "instanceType", Value: opts.InstanceType}}
+			fleet = append(fleet, yaml.MapItem{Key: "launchSpecifications", Value: []yaml.MapSlice{spec}})
+		}
+		item = append(item, yaml.MapItem{Key: "spotFleet", Value: fleet})
+	} else {
+		if opts.InstanceType != "" {
+			item = append(item, yaml.MapItem{Key: "instanceType", Value: opts.InstanceType})
+		}
+		if opts.MaxSize != 0 {
+			if opts.Count != 0 {
+				return "", errors.New("count can't be specified with min size and max size")
+			}
+			if opts.MinSize > opts.MaxSize {
+				return "", fmt.Errorf("min size %d must not be greater than max size %d", opts.MinSize, opts.MaxSize)
+			}
+			asg := yaml.MapSlice{{Key: "minSize", Value: opts.MinSize}, {Key: "maxSize", Value: opts.MaxSize}}
+			item = append(item, yaml.MapItem{Key: "autoScalingGroup", Value: asg})
+		} else if opts.MinSize != 0 {
+			return "", errors.New("max size must be specified with min size")
+		} else if opts.Count != 0 {
+			item = append(item, yaml.MapItem{Key: "count", Value: opts.Count})
+		}
+		if opts.SpotPrice != "" {
+			item = append(item, yaml.MapItem{Key: "spotPrice", Value: opts.SpotPrice})
+		}
+	}
+
+	out, err := yaml.Marshal(item)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal node pool: %v", err)
+	}
+
+	np := &nodepool.ProvidedConfig{}
+	if err := yaml.Unmarshal(out, np); err != nil {
+		return "", err
+	}
+	if err := np.ValidateInputs(); err != nil {
+		return "", fmt.Errorf("invalid node pool: %v", err)
+	}
+	if err := np.WorkerNodePoolConfig.Validate(controlplane.Experimental{}); err != nil {
+		return "", fmt.Errorf("invalid node pool: %v", err)
+	}
+
+	return string(out), nil
+}
+
+// indentItem turns the YAML map in item into the lines of a sequence item, e.g. `    - name: pool1`
+func indentItem(item string, itemIndent, contentIndent int) []string {
+	lines := []string{}
+	for i, l := range strings.Split(strings.TrimRight(item, "\n"), "\n") {
+		if i == 0 {
+			lines = append(lines, strings.Repeat(" ", itemIndent)+"-"+strings.Repeat(" ", contentIndent-itemIndent-1)+l)
+		} else {
+			lines = append(lines, strings.Repeat(" ", contentIndent)+l)
+		}
+	}
+	return lines
+}
+
+// nodePoolsSection is the location of `worker.nodePools` and its items within the lines of cluster.yaml
+type nodePoolsSection struct {
+	lines []string
+	// worker and nodePools are the indices of the lines for the keys, or -1 when missing
+	worker    int
+	nodePools int
+	items     []nodePoolItem
+	// end is the index of the line right after the last item, where a new item is inserted
+	end             int
+	nodePoolsIndent int
+	itemIndent      int
+	contentIndent   int
+}
+
+// nodePoolItem is the range of lines [start, end) for an item in `worker.nodePools`
+type nodePoolItem struct {
+	start, end int
+}
+
+func parseNodePoolsSection(data []byte) (*nodePoolsSection, error) {
+	s := &nodePoolsSection{
+		lines:     strings.Split(string(data), "\n"),
+		worker:    -1,
+		nodePools: -1,
+	}
+	lines := s.lines
+
+	for i, l := range lines {
+		if workerKeyPattern.MatchString(l) {
+			s.worker = i
+			break
+		}
+		if strings.HasPrefix(l, "worker:") {
+			return nil, errors.New("`worker` in flow style is not supported. Rewrite it in block style to edit node pools")
+		}
+	}
+
+	if s.worker < 0 {
+		// Append `worker.nodePools` to the end of the file, in the same indentation as the default cluster.yaml
+		s.end = len(lines)
+		for s.end > 0 && isBlankLine(lines[s.end-1]) {
+			s.end--
+		}
+		s.nodePoolsIndent, s.itemIndent, s.contentIndent = 2, 4, 6
+		return s, nil
+	}
+
+	workerEnd := len(lines)
+	childIndent := -1
+	for i := s.worker + 1; i < len(lines); i++ {
+		l := lines[i]
+		if !isContentLine(l) {
+			continue
+		}
+		if indentOf(l) == 0 {
+			workerEnd = i
+			break
+		}
+		if childIndent < 0 {
+			childIndent = indentOf(l)
+		}
+		if indentOf(l) != childIndent {
+			continue
+		}
+		if m := emptyNodePools.FindStringSubmatch(l); m != nil {
+			lines[i] = m[1] + "nodePools:"
+			l = lines[i]
+		}
+		if nodePoolsKeyPattern.MatchString(l) {
+			s.nodePools = i
+		} else if strings.HasPrefix(strings.TrimSpace(l), "nodePools:") {
+			return nil, errors.New("`worker.nodePools` in flow style is not supported. Rewrite it in block style to edit node pools")
+		}
+	}
+
+	if s.nodePools < 0 {
+		if childIndent < 0 {
+			childIndent = 2
+		}
+		s.end = s.worker + 1
+		s.nodePoolsIndent, s.itemIndent, s.contentIndent = childIndent, childIndent+2, childIndent+4
+		return s, nil
+	}
+
+	s.nodePoolsIndent = indentOf(lines[s.nodePools])
+	s.itemIndent = -1
+	s.end = s.nodePools + 1
+	var current *nodePoolItem
+	for i := s.nodePools + 1; i < workerEnd; i++ {
+		l := lines[i]
+		if isBlankLine(l) {
+			continue
+		}
+		if !isContentLine(l) {
+			if current != nil && commentIndentOf(l) > s.itemIndent {
+				current.end = i + 1
+			}
+			continue
+		}
+		indent := indentOf(l)
+		isItem := strings.HasPrefix(strings.TrimSpace(l), "-")
+		if s.itemIndent < 0 {
+			if !isItem || indent < s.nodePoolsIndent {
+				break
+			}
+			s.itemIndent = indent
+			s.contentIndent = contentIndentOf(lines, i, workerEnd)
+		}
+		if indent == s.itemIndent && isItem {
+			s.items = append(s.items, nodePoolItem{start: i, end: i + 1})
+			current = &s.items[len(s.items)-1]
+			continue
+		}
+		if indent <= s.itemIndent || current == nil {
+			break
+		}
+		current.end = i + 1
+	}
+
+	if s.itemIndent < 0 {
+		s.itemIndent, s.contentIndent = s.nodePoolsIndent+2, s.nodePoolsIndent+4
+	}
+	if len(s.items) > 0 {
+		s.end = s.items[len(s.items)-1].end
+	}
+
+	return s, nil
+}
+
+// contentIndentOf returns the indentation of the keys in the sequence item starting at the line i
+func contentIndentOf(lines []string, i, end int) int {
+	l := lines[i]
+	indent := indentOf(l)
+	rest := strings.TrimPrefix(l[indent:], "-")
+	afterDash := strings.TrimLeft(rest, " ")
+	if afterDash != "" && !strings.HasPrefix(afterDash, "#") {
+		return indent + 1 + len(rest) - len(afterDash)
+	}
+	for j := i + 1; j < end; j++ {
+		if isContentLine(lines[j]) && indentOf(lines[j]) > indent {
+			return indentOf(lines[j])
+		}
+	}
+	return indent + 2
+}
+
+func indentOf(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// commentIndentOf returns the indentation of a comment line.
+// For a commented-out line like `#      subnets:`, it is the indentation the line would have once uncommented
+func commentIndentOf(l string) int {
+	if strings.HasPrefix(l, "#") {
+		return indentOf(l[1:])
+	}
+	return indentOf(l)
+}
+
+func isBlankLine(l string) bool {
+	return strings.TrimSpace(l) == ""
+}
+
+func isContentLine(l string) bool {
+	return !isBlankLine(l) && !strings.HasPrefix(strings.TrimSpace(l), "#")
+}
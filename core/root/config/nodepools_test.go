@@ -0,0 +1,215 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nodePoolsConfig = `# Unique name of Kubernetes cluster
+clusterName: test-cluster-name
+keyName: test-key-name
+region: us-west-1
+subnets:
+- name: public1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.1.0/24
+- name: private1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.2.0/24
+  private: true
+
+worker:
+#  apiEndpointName: versionedPublic
+#
+  nodePools:
+    - # Name of this node pool
+      name: pool1
+#      # Subnet(s) to which worker nodes in this node pool are deployed
+#      subnets:
+#      - name: ManagedPublicSubnet1
+      instanceType: c4.large
+    - name: pool2
+      count: 2
+
+# Maximum time to wait for worker creation
+#workerCreateTimeout: PT15M
+
+kubernetesVersion: v1.9.3
+`
+
+func TestAddNodePool(t *testing.T) {
+	t.Run("ASG", func(t *testing.T) {
+		out, err := AddNodePool([]byte(nodePoolsConfig), NodePoolOptions{
+			Name:         "pool3",
+			InstanceType: "m4.large",
+			MinSize:      1,
+			MaxSize:      3,
+			Subnets:      []string{"private1"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := strings.Replace(nodePoolsConfig, `      count: 2
+`, `      count: 2
+    - name: pool3
+      subnets:
+      - name: private1
+      instanceType: m4.large
+      autoScalingGroup:
+        minSize: 1
+        maxSize: 3
+`, 1)
+		if string(out) != expected {
+			t.Errorf("unexpected cluster.yaml:\n%s", out)
+		}
+	})
+
+	t.Run("SpotFleet", func(t *testing.T) {
+		out, err := AddNodePool([]byte(nodePoolsConfig), NodePoolOptions{
+			Name:         "pool3",
+			InstanceType: "m4.large",
+			Count:        4,
+			SpotFleet:    true,
+			SpotPrice:    "0.1",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		pools, err := ListNodePools(out)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		actual := pools[2]
+		expected := NodePoolSummary{Name: "pool3", InstanceTypes: []string{"m4.large"}, SpotFleet: true, Count: 4, Subnets: []string{}}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected node pool: expected %+v but was %+v", expected, actual)
+		}
+	})
+
+	t.Run("CommentedOutOptionsOfLastNodePool", func(t *testing.T) {
+		config := strings.Replace(nodePoolsConfig, `      count: 2
+`, `      count: 2
+#      # Disk size (GiB) for worker nodes
+#      rootVolume:
+#        size: 30
+`, 1)
+		out, err := AddNodePool([]byte(config), NodePoolOptions{Name: "pool3"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := strings.Replace(config, `#        size: 30
+`, `#        size: 30
+    - name: pool3
+`, 1)
+		if string(out) != expected {
+			t.Errorf("unexpected cluster.yaml:\n%s", out)
+		}
+	})
+
+	t.Run("NoWorker", func(t *testing.T) {
+		config := "clusterName: test-cluster-name\n"
+		out, err := AddNodePool([]byte(config), NodePoolOptions{Name: "pool1", Count: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "clusterName: test-cluster-name\nworker:\n  nodePools:\n    - name: pool1\n      count: 2\n"
+		if string(out) != expected {
+			t.Errorf("unexpected cluster.yaml:\n%s", out)
+		}
+	})
+
+	t.Run("EmptyNodePools", func(t *testing.T) {
+		config := "worker:\n  apiEndpointName: public\n  nodePools: []\n"
+		out, err := AddNodePool([]byte(config), NodePoolOptions{Name: "pool1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "worker:\n  apiEndpointName: public\n  nodePools:\n    - name: pool1\n"
+		if string(out) != expected {
+			t.Errorf("unexpected cluster.yaml:\n%s", out)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		testCases := []struct {
+			opts     NodePoolOptions
+			expected string
+		}{
+			{NodePoolOptions{}, "node pool name must not be empty"},
+			{NodePoolOptions{Name: "pool1"}, "node pool named pool1 already exists"},
+			{NodePoolOptions{Name: "pool3", Subnets: []string{"public2"}}, "no subnet named public2 found"},
+			{NodePoolOptions{Name: "pool3", SpotFleet: true}, "count must be greater than 0"},
+			{NodePoolOptions{Name: "pool3", Count: 2, MaxSize: 3}, "count can't be specified with min size and max size"},
+			{NodePoolOptions{Name: "pool3", MinSize: 4, MaxSize: 3}, "min size 4 must not be greater than max size 3"},
+		}
+
+		for _, tc := range testCases {
+			_, err := AddNodePool([]byte(nodePoolsConfig), tc.opts)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error containing \"%s\" for %+v but was: %v", tc.expected, tc.opts, err)
+			}
+		}
+	})
+}
+
+func TestRemoveNodePool(t *testing.T) {
+	t.Run("First", func(t *testing.T) {
+		out, err := RemoveNodePool([]byte(nodePoolsConfig), "pool1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := strings.Replace(nodePoolsConfig, `    - # Name of this node pool
+      name: pool1
+#      # Subnet(s) to which worker nodes in this node pool are deployed
+#      subnets:
+#      - name: ManagedPublicSubnet1
+      instanceType: c4.large
+`, "", 1)
+		if string(out) != expected {
+			t.Errorf("unexpected cluster.yaml:\n%s", out)
+		}
+	})
+
+	t.Run("Last", func(t *testing.T) {
+		out, err := RemoveNodePool([]byte(nodePoolsConfig), "pool2")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := strings.Replace(nodePoolsConfig, `    - name: pool2
+      count: 2
+`, "", 1)
+		if string(out) != expected {
+			t.Errorf("unexpected cluster.yaml:\n%s", out)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := RemoveNodePool([]byte(nodePoolsConfig), "pool3")
+		if err == nil || err.Error() != "no node pool named pool3 found" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestListNodePools(t *testing.T) {
+	pools, err := ListNodePools([]byte(nodePoolsConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []NodePoolSummary{
+		{Name: "pool1", InstanceTypes: []string{"c4.large"}, Count: 1, Subnets: []string{}},
+		{Name: "pool2", InstanceTypes: []string{"t2.medium"}, Count: 2, Subnets: []string{}},
+	}
+	if !reflect.DeepEqual(pools, expected) {
+		t.Errorf("unexpected node pools: expected %+v but was %+v", expected, pools)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/schema"
+)
+
+func TestClusterSchema(t *testing.T) {
+	s := schema.Generate(UnmarshalledConfig{}, "cluster.yaml")
+
+	if s.AdditionalProperties != false {
+		t.Errorf("expected unknown top-level keys to be rejected, but additionalProperties was %v", s.AdditionalProperties)
+	}
+
+	worker := s.Definitions["core.root.config.Worker"]
+	if worker == nil || worker.Properties["nodePools"].Items.Ref != "#/definitions/core.nodepool.config.ProvidedConfig" {
+		t.Errorf("unexpected schema for worker: %+v", worker)
+	}
+
+	testCases := []struct {
+		definition string
+		property   string
+		expected   []string
+	}{
+		{"model.APIEndpointLB", "type", []string{"classic", "network"}},
+		{"core.controlplane.config.SelfHosting", "type", []string{"canal", "flannel"}},
+		{"model.RootVolume", "type", []string{"standard", "gp2", "io1"}},
+		{"core.nodepool.config.ProvidedConfig", "tenancy", []string{"default", "dedicated"}},
+	}
+
+	for _, tc := range testCases {
+		d, ok := s.Definitions[tc.definition]
+		if !ok {
+			t.Errorf("definition %s not found", tc.definition)
+			continue
+		}
+		p, ok := d.Properties[tc.property]
+		if !ok {
+			t.Errorf("property %s not found in %s", tc.property, tc.definition)
+			continue
+		}
+		if !reflect.DeepEqual(p.Enum, tc.expected) {
+			t.Errorf("unexpected enum for %s.%s: expected %v but was %v", tc.definition, tc.property, tc.expected, p.Enum)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package root
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"text/tabwriter"
+
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	nodepool "github.com/kubernetes-incubator/kube-aws/core/nodepool/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/pricing"
+)
+
+// CostEstimate is the estimated monthly cost of a cluster, broken down by stacks
+type CostEstimate struct {
+	Region   string      `json:"region"`
+	Currency string      `json:"currency"`
+	Stacks   []StackCost `json:"stacks"`
+	Total    CostRange   `json:"total"`
+	// URLs are links to the AWS Simple Monthly Calculator, one for the control plane and each node pool
+	URLs []string `json:"urls,omitempty"`
+}
+
+type StackCost struct {
+	Name  string         `json:"name"`
+	Items []CostLineItem `json:"items"`
+	Total CostRange      `json:"total"`
+}
+
+// CostLineItem is the monthly cost of a kind of resources in a stack
+type CostLineItem struct {
+	Resource string     `json:"resource"`
+	Quantity CountRange `json:"quantity"`
+	// UnitPrice is the monthly price of a single resource
+	UnitPrice float64   `json:"unitPrice"`
+	Monthly   CostRange `json:"monthly"`
+}
+
+// CountRange is the number of resources when an auto-scaling group is at its min size, desired capacity and max size
+type CountRange struct {
+	Min     int `json:"min"`
+	Desired int `json:"desired"`
+	Max     int `json:"max"`
+}
+
+type CostRange struct {
+	Min     float64 `json:"min"`
+	Desired float64 `json:"desired"`
+	Max     float64 `json:"max"`
+}
+
+func fixedCount(n int) CountRange {
+	return CountRange{Min: n, Desired: n, Max: n}
+}
+
+func (r CostRange) add(other CostRange) CostRange {
+	return CostRange{Min: r.Min + other.Min, Desired: r.Desired + other.Desired, Max: r.Max + other.Max}
+}
+
+func (s *StackCost) add(resource string, quantity CountRange, unitPrice float64) {
+	if quantity.Max == 0 {
+		return
+	}
+	item := CostLineItem{
+		Resource:  resource,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+		Monthly: CostRange{
+			Min:     unitPrice * float64(quantity.Min),
+			Desired: unitPrice * float64(quantity.Desired),
+			Max:     unitPrice * float64(quantity.Max),
+		},
+	}
+	s.Items = append(s.Items, item)
+	s.Total = s.Total.add(item.Monthly)
+}
+
+func (e *CostEstimate) String() string {
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Region:\t%s\n", e.Region)
+	fmt.Fprintf(w, "Currency:\t%s\n", e.Currency)
+	fmt.Fprintf(w, "\nSTACK\tRESOURCE\tUNIT PRICE\tQUANTITY(MIN/DESIRED/MAX)\tMONTHLY(MIN/DESIRED/MAX)\n")
+	for _, s := range e.Stacks {
+		for _, i := range s.Items {
+			fmt.Fprintf(w, "%s\t%s\t%.2f\t%d/%d/%d\t%.2f/%.2f/%.2f\n", s.Name, i.Resource, i.UnitPrice, i.Quantity.Min, i.Quantity.Desired, i.Quantity.Max, i.Monthly.Min, i.Monthly.Desired, i.Monthly.Max)
+		}
+		fmt.Fprintf(w, "%s\tSubtotal\t\t\t%.2f/%.2f/%.2f\n", s.Name, s.Total.Min, s.Total.Desired, s.Total.Max)
+	}
+	fmt.Fprintf(w, "Total\t\t\t\t%.2f/%.2f/%.2f\n", e.Total.Min, e.Total.Desired, e.Total.Max)
+
+	w.Flush()
+	return buf.String()
+}
+
+// EstimateMonthlyCostFromFile estimates the monthly cost of the cluster described in cluster.yaml without calling AWS APIs
+func EstimateMonthlyCostFromFile(configPath string, prices pricing.Source) (*CostEstimate, error) {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return estimateMonthlyCost(cfg, prices)
+}
+
+func estimateMonthlyCost(cfg *config.Config, prices pricing.Source) (*CostEstimate, error) {
+	e := &CostEstimate{
+		Region:   cfg.Region.String(),
+		Currency: "USD",
+		Stacks:   []StackCost{},
+	}
+
+	estimators := []func() (StackCost, error){
+		func() (StackCost, error) { return estimateNetworkCost(cfg.Cluster, prices) },
+		func() (StackCost, error) { return estimateControlPlaneCost(cfg.Cluster, prices) },
+		func() (StackCost, error) { return estimateEtcdCost(cfg.Cluster, prices) },
+	}
+	for _, np := range cfg.NodePools {
+		p := np
+		estimators = append(estimators, func() (StackCost, error) { return estimateNodePoolCost(p, cfg.Region.String(), prices) })
+	}
+
+	for _, estimate := range estimators {
+		s, err := estimate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate cost for %s: %v", s.Name, err)
+		}
+		e.Stacks = append(e.Stacks, s)
+		e.Total = e.Total.add(s.Total)
+	}
+
+	return e, nil
+}
+
+func estimateNetworkCost(c *controlplane.Cluster, prices pricing.Source) (StackCost, error) {
+	s := StackCost{Name: OperationTargetNetwork, Items: []CostLineItem{}}
+	region := c.Region.String()
+
+	ngws, eips := 0, 0
+	seen := map[string]bool{}
+	for _, ngw := range c.NATGateways() {
+		if !ngw.ManageNATGateway() || seen[ngw.LogicalName()] {
+			continue
+		}
+		seen[ngw.LogicalName()] = true
+		ngws++
+		if ngw.ManageEIP() {
+			eips++
+		}
+	}
+
+	ngwHourly, err := prices.NATGatewayHourly(region)
+	if err != nil {
+		return s, err
+	}
+	s.add("NAT gateway", fixedCount(ngws), ngwHourly*pricing.HoursPerMonth)
+
+	eipHourly, err := prices.EIPHourly(region)
+	if err != nil {
+		return s, err
+	}
+	s.add("EIP for NAT gateway", fixedCount(eips), eipHourly*pricing.HoursPerMonth)
+
+	return s, nil
+}
+
+func estimateControlPlaneCost(c *controlplane.Cluster, prices pricing.Source) (StackCost, error) {
+	s := StackCost{Name: OperationTargetControlPlane, Items: []CostLineItem{}}
+	region := c.Region.String()
+
+	min, max := c.MinControllerCount(), c.MaxControllerCount()
+	count := CountRange{Min: min, Desired: desiredCount(c.Controller.Count, min, max), Max: max}
+
+	if err := addInstanceCost(&s, region, c.Controller.InstanceType, "", count, prices); err != nil {
+		return s, err
+	}
+	if err := addVolumeCost(&s, region, "root volume", c.Controller.RootVolume.Type, c.Controller.RootVolume.Size, c.Controller.RootVolume.IOPS, count, prices); err != nil {
+		return s, err
+	}
+
+	elbs, nlbs := 0, 0
+	for _, e := range c.APIEndpointConfigs {
+		if !e.LoadBalancer.ManageELB() {
+			continue
+		}
+		if e.LoadBalancer.NetworkLoadBalancer() {
+			nlbs++
+		} else {
+			elbs++
+		}
+	}
+	elbHourly, err := prices.LoadBalancerHourly(region, false)
+	if err != nil {
+		return s, err
+	}
+	s.add("ELB for API endpoint", fixedCount(elbs), elbHourly*pricing.HoursPerMonth)
+
+	nlbHourly, err := prices.LoadBalancerHourly(region, true)
+	if err != nil {
+		return s, err
+	}
+	s.add("NLB for API endpoint", fixedCount(nlbs), nlbHourly*pricing.HoursPerMonth)
+
+	return s, nil
+}
+
+func estimateEtcdCost(c *controlplane.Cluster, prices pricing.Source) (StackCost, error) {
+	s := StackCost{Name: OperationTargetEtcd, Items: []CostLineItem{}}
+	region := c.Region.String()
+	count := fixedCount(c.Etcd.Count)
+
+	if err := addInstanceCost(&s, region, c.Etcd.InstanceType, "", count, prices); err != nil {
+		return s, err
+	}
+	if err := addVolumeCost(&s, region, "root volume", c.Etcd.RootVolume.Type, c.Etcd.RootVolume.Size, c.Etcd.RootVolume.IOPS, count, prices); err != nil {
+		return s, err
+	}
+	if !c.Etcd.DataVolume.Ephemeral {
+		if err := addVolumeCost(&s, region, "data volume", c.Etcd.DataVolume.Type, c.Etcd.DataVolume.Size, c.Etcd.DataVolume.IOPS, count, prices); err != nil {
+			return s, err
+		}
+	}
+	if c.EtcdCluster().NodeShouldHaveEIP() {
+		eipHourly, err := prices.EIPHourly(region)
+		if err != nil {
+			return s, err
+		}
+		s.add("EIP for etcd node", count, eipHourly*pricing.HoursPerMonth)
+	}
+
+	return s, nil
+}
+
+func estimateNodePoolCost(np *nodepool.ProvidedConfig, region string, prices pricing.Source) (StackCost, error) {
+	s := StackCost{Name: np.NodePoolName, Items: []CostLineItem{}}
+
+	var count CountRange
+	if np.SpotFleet.Enabled() {
+		spec, unitPrice, err := cheapestLaunchSpecification(np.SpotFleet)
+		if err != nil {
+			return s, err
+		}
+		// The number of instances required to fulfill the target capacity with the cheapest launch specification
+		count = fixedCount(int(math.Ceil(float64(np.SpotFleet.TargetCapacity) / float64(spec.WeightedCapacity))))
+		resource := fmt.Sprintf("EC2 spot instance %s (weighted capacity %d)", spec.InstanceType, spec.WeightedCapacity)
+		s.add(resource, count, unitPrice*float64(spec.WeightedCapacity)*pricing.HoursPerMonth)
+		if err := addVolumeCost(&s, region, "root volume", spec.RootVolume.Type, spec.RootVolume.Size, spec.RootVolume.IOPS, count, prices); err != nil {
+			return s, err
+		}
+	} else {
+		min, max := np.MinCount(), np.MaxCount()
+		count = CountRange{Min: min, Desired: desiredCount(np.Count, min, max), Max: max}
+		if err := addInstanceCost(&s, region, np.InstanceType, np.SpotPrice, count, prices); err != nil {
+			return s, err
+		}
+		if err := addVolumeCost(&s, region, "root volume", np.RootVolume.Type, np.RootVolume.Size, np.RootVolume.IOPS, count, prices); err != nil {
+			return s, err
+		}
+	}
+
+	for _, v := range np.VolumeMounts {
+		if err := addVolumeCost(&s, region, fmt.Sprintf("volume mounted at %s", v.Path), v.Type, v.Size, v.Iops, count, prices); err != nil {
+			return s, err
+		}
+	}
+
+	return s, nil
+}
+
+// cheapestLaunchSpecification returns the launch specification with the lowest spot price per unit of capacity.
+// A launch specification without its own spot price is priced at the spot price per unit of the fleet
+func cheapestLaunchSpecification(fleet model.SpotFleet) (model.LaunchSpecification, float64, error) {
+	var cheapest model.LaunchSpecification
+	cheapestUnitPrice := math.MaxFloat64
+	for _, spec := range fleet.LaunchSpecifications {
+		if spec.WeightedCapacity <= 0 {
+			return cheapest, 0, fmt.Errorf("invalid weighted capacity %d for %s", spec.WeightedCapacity, spec.InstanceType)
+		}
+		var unitPrice float64
+		if spec.SpotPrice != "" {
+			p, err := strconv.ParseFloat(spec.SpotPrice, 64)
+			if err != nil {
+				return cheapest, 0, fmt.Errorf("invalid spot price \"%s\" for %s: %v", spec.SpotPrice, spec.InstanceType, err)
+			}
+			unitPrice = p / float64(spec.WeightedCapacity)
+		} else {
+			p, err := strconv.ParseFloat(fleet.SpotPrice, 64)
+			if err != nil {
+				return cheapest, 0, fmt.Errorf("invalid spot price \"%s\": %v", fleet.SpotPrice, err)
+			}
+			unitPrice = p
+		}
+		if unitPrice < cheapestUnitPrice {
+			cheapest, cheapestUnitPrice = spec, unitPrice
+		}
+	}
+	if len(fleet.LaunchSpecifications) == 0 {
+		return cheapest, 0, fmt.Errorf("no launch specification found for the spot fleet")
+	}
+	return cheapest, cheapestUnitPrice, nil
+}
+
+// addInstanceCost adds the cost of EC2 instances, priced at the spot price when specified or at the on-demand price otherwise
+func addInstanceCost(s *StackCost, region string, instanceType string, spotPrice string, count CountRange, prices pricing.Source) error {
+	if spotPrice != "" {
+		hourly, err := strconv.ParseFloat(spotPrice, 64)
+		if err != nil {
+			return fmt.Errorf("invalid spot price \"%s\": %v", spotPrice, err)
+		}
+		s.add(fmt.Sprintf("EC2 spot instance %s", instanceType), count, hourly*pricing.HoursPerMonth)
+		return nil
+	}
+
+	hourly, err := prices.InstanceHourly(region, instanceType)
+	if err != nil {
+		return err
+	}
+	s.add(fmt.Sprintf("EC2 instance %s", instanceType), count, hourly*pricing.HoursPerMonth)
+	return nil
+}
+
+func addVolumeCost(s *StackCost, region string, name string, volumeType string, size int, iops int, count CountRange, prices pricing.Source) error {
+	monthly, err := prices.VolumeMonthly(region, volumeType, size, iops)
+	if err != nil {
+		return err
+	}
+	resource := fmt.Sprintf("EBS %s %s %dGB", volumeType, name, size)
+	if iops > 0 {
+		resource = fmt.Sprintf("%s %d IOPS", resource, iops)
+	}
+	s.add(resource, count, monthly)
+	return nil
+}
+
+// desiredCount returns the configured count bounded by the min size and the max size of an auto-scaling group
+func desiredCount(count, min, max int) int {
+	if count < min {
+		return min
+	}
+	if count > max {
+		return max
+	}
+	return count
+}
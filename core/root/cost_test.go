@@ -0,0 +1,131 @@
+package root
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/pricing"
+)
+
+const clusterYamlForCost = `clusterName: test-cluster
+externalDNSName: test.example.com
+keyName: test-key-name
+s3URI: s3://mybucket/mydir
+region: us-east-1
+amiId: ami-12345678
+kmsKeyArn: "arn:aws:kms:us-east-1:xxxxxxxxx:key/xxxxxxxxxxxxxxxxxxx"
+availabilityZone: us-east-1a
+hostedZoneId: hostedzone-xxxx
+controller:
+  count: 2
+  instanceType: m4.large
+etcd:
+  count: 3
+  dataVolume:
+    size: 50
+worker:
+  nodePools:
+  - name: pool1
+    instanceType: c4.large
+    autoScalingGroup:
+      minSize: 1
+      maxSize: 5
+  - name: spotfleet
+    spotFleet:
+      targetCapacity: 4
+      spotPrice: "0.05"
+      launchSpecifications:
+      - weightedCapacity: 1
+        instanceType: c4.large
+      - weightedCapacity: 2
+        instanceType: c4.xlarge
+        spotPrice: "0.08"
+`
+
+const priceTableForCost = `{"regions": {"us-east-1": {
+  "ec2": {"t2.medium": 0.05, "m4.large": 0.1, "c4.large": 0.1},
+  "ebs": {"gp2": {"gbMonth": 0.1}},
+  "natGateway": 0.045,
+  "classicLoadBalancer": 0.025,
+  "networkLoadBalancer": 0.0225,
+  "eip": 0.005
+}}}`
+
+func TestEstimateMonthlyCost(t *testing.T) {
+	cfg, err := config.ConfigFromBytes([]byte(clusterYamlForCost), nil)
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+	prices, err := pricing.PriceTableFromBytes([]byte(priceTableForCost))
+	if err != nil {
+		t.Fatalf("failed to load price table: %v", err)
+	}
+
+	e, err := estimateMonthlyCost(cfg, prices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stacks := map[string]StackCost{}
+	for _, s := range e.Stacks {
+		stacks[s.Name] = s
+	}
+
+	findItem := func(stack string, prefix string) CostLineItem {
+		for _, i := range stacks[stack].Items {
+			if strings.HasPrefix(i.Resource, prefix) {
+				return i
+			}
+		}
+		t.Fatalf("no item starting with \"%s\" found in %s: %+v", prefix, stack, stacks[stack].Items)
+		return CostLineItem{}
+	}
+
+	controllers := findItem("control-plane", "EC2 instance m4.large")
+	if controllers.Quantity != fixedCount(2) || controllers.Monthly.Max != 2*0.1*pricing.HoursPerMonth {
+		t.Errorf("unexpected cost of controllers: %+v", controllers)
+	}
+	elb := findItem("control-plane", "ELB for API endpoint")
+	if elb.Quantity != fixedCount(1) {
+		t.Errorf("unexpected cost of ELBs: %+v", elb)
+	}
+
+	etcdDataVolume := findItem("etcd", "EBS gp2 data volume 50GB")
+	if etcdDataVolume.Quantity != fixedCount(3) || etcdDataVolume.Monthly.Max != 3*50*0.1 {
+		t.Errorf("unexpected cost of etcd data volumes: %+v", etcdDataVolume)
+	}
+
+	workers := findItem("pool1", "EC2 instance c4.large")
+	if workers.Quantity != (CountRange{Min: 1, Desired: 1, Max: 5}) {
+		t.Errorf("unexpected number of workers: %+v", workers)
+	}
+	if workers.Monthly.Max != 5*0.1*pricing.HoursPerMonth {
+		t.Errorf("unexpected cost of workers: %+v", workers)
+	}
+
+	// c4.xlarge costs 0.04 per unit of capacity, which is cheaper than 0.05 of c4.large
+	spot := findItem("spotfleet", "EC2 spot instance c4.xlarge (weighted capacity 2)")
+	if spot.Quantity != fixedCount(2) || spot.UnitPrice != 0.08*pricing.HoursPerMonth {
+		t.Errorf("unexpected cost of spot instances: %+v", spot)
+	}
+	spotRootVolume := findItem("spotfleet", "EBS gp2 root volume 60GB")
+	if spotRootVolume.Quantity != fixedCount(2) {
+		t.Errorf("unexpected cost of root volumes of spot instances: %+v", spotRootVolume)
+	}
+
+	total := 0.0
+	for _, s := range e.Stacks {
+		total += s.Total.Max
+	}
+	if e.Total.Max != total {
+		t.Errorf("expected the total to be the sum of all the stacks(%f) but was %f", total, e.Total.Max)
+	}
+
+	report := e.String()
+	for _, expected := range []string{"Region:", "us-east-1", "pool1", "Subtotal", "Total"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected the report to contain \"%s\" but it didn't:\n%s", expected, report)
+		}
+	}
+}
@@ -4,16 +4,22 @@ import (
 	"fmt"
 	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
 	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 	"github.com/kubernetes-incubator/kube-aws/tlscerts"
 	"github.com/kubernetes-incubator/kube-aws/tlsutil"
 	"io/ioutil"
 	"os"
 	"path"
 	"strings"
+	"time"
 )
 
 func RenderCredentials(configPath string, renderCredentialsOpts config.CredentialsOptions) error {
 
+	if err := renderCredentialsOpts.Validate(); err != nil {
+		return err
+	}
+
 	cluster, err := config.ClusterFromFile(configPath)
 	if err != nil {
 		return err
@@ -23,6 +29,19 @@ func RenderCredentials(configPath string, renderCredentialsOpts config.Credentia
 		return err
 	}
 
+	switch {
+	case renderCredentialsOpts.CertificateRequests:
+		csrs, err := cluster.WriteCertificateRequests(defaults.AssetsDir, renderCredentialsOpts)
+		if err != nil {
+			return err
+		}
+		progress.Printf("Wrote %d certificate requests. Have them signed by your CA, and import the certificates named like apiserver.pem with the chain of the CA\n", len(csrs))
+		return nil
+	case renderCredentialsOpts.SignedCertificatesDir != "":
+		_, err = cluster.ImportSignedCertificates(defaults.AssetsDir, renderCredentialsOpts)
+		return err
+	}
+
 	_, err = cluster.NewAssetsOnDisk(defaults.AssetsDir, renderCredentialsOpts)
 	return err
 }
@@ -45,7 +64,7 @@ func LoadCertificates() (map[string]tlscerts.Certificates, error) {
 		}
 		b, err := ioutil.ReadFile(path.Join(defaults.AssetsDir, f.Name()))
 		if err != nil {
-			fmt.Printf("WARNING: cannot read %q file: %v", f.Name(), err)
+			progress.Printf("WARNING: cannot read %q file: %v", f.Name(), err)
 			continue
 		}
 		if !tlsutil.IsCertificatePEM(b) {
@@ -53,10 +72,27 @@ func LoadCertificates() (map[string]tlscerts.Certificates, error) {
 		}
 		c, err := tlscerts.FromBytes(b)
 		if err != nil {
-			fmt.Printf("WARNING: cannot parse %q file: %v", f.Name(), err)
+			progress.Printf("WARNING: cannot parse %q file: %v", f.Name(), err)
 			continue
 		}
 		certs[f.Name()] = c
 	}
 	return certs, nil
 }
+
+// CredentialsHealthFromFile checks the certificates in the credentials directory for their expiry and issuer.
+// The SANs of the apiserver cert are checked too when the cluster config exists
+func CredentialsHealthFromFile(configPath string) (*config.CredentialsHealthReport, error) {
+	if _, err := os.Stat(defaults.AssetsDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not exist, run 'render credentials' first", defaults.AssetsDir)
+	}
+
+	var cluster *config.Cluster
+	if _, err := os.Stat(configPath); err == nil {
+		if cluster, err = config.ClusterFromFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return config.CheckCredentialsHealth(defaults.AssetsDir, cluster, time.Now())
+}
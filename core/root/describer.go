@@ -1,23 +1,46 @@
 package root
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
+	"text/tabwriter"
+
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/core/controlplane/cluster"
 	cp "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	nodepool "github.com/kubernetes-incubator/kube-aws/core/nodepool/cluster"
 	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/naming"
 	"github.com/kubernetes-incubator/kube-aws/plugin/pluginmodel"
 )
 
 type Info struct {
-	ControlPlane *cluster.Info
+	ControlPlane *cluster.Info    `json:"controlPlane"`
+	NodePools    []*nodepool.Info `json:"nodePools"`
 }
 
 func (i *Info) String() string {
-	return i.ControlPlane.String()
+	if len(i.NodePools) == 0 {
+		return i.ControlPlane.String()
+	}
+
+	names := []string{}
+	for _, np := range i.NodePools {
+		names = append(names, fmt.Sprintf("%s(%s)", np.Name, np.Status))
+	}
+
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 0, '\t', 0)
+
+	fmt.Fprintf(w, "Node Pools:\t%s\n", strings.Join(names, ", "))
+
+	w.Flush()
+	return i.ControlPlane.String() + buf.String()
 }
 
 type ClusterDescriber interface {
@@ -25,19 +48,20 @@ type ClusterDescriber interface {
 }
 
 type clusterDescriberImpl struct {
-	cpConfig    *cp.Config
-	session     *session.Session
-	clusterName string
-	stackName   string
+	cpConfig      *cp.Config
+	clients       *awsconn.ServiceClients
+	clusterName   string
+	stackName     string
+	nodePoolNames []string
 }
 
-func ClusterDescriberFromFile(configPath string) (ClusterDescriber, error) {
+func ClusterDescriberFromFile(configPath string, awsCredentials model.AWSCredentials) (ClusterDescriber, error) {
 	config, err := config.ConfigFromFile(configPath)
 	if err != nil {
 		return nil, err
 	}
 
-	session, err := awsconn.NewSessionFromRegion(config.Region, false)
+	session, err := awsconn.NewSessionFromRegion(config.Region, config.AWSEndpoints, config.AWSCredentials.WithOverrides(awsCredentials), false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to establish aws session: %v", err)
 	}
@@ -47,20 +71,26 @@ func ClusterDescriberFromFile(configPath string) (ClusterDescriber, error) {
 		return nil, err
 	}
 
-	return NewClusterDescriber(config.ClusterName, config.ClusterName, cpConfig, session), nil
+	nodePoolNames := []string{}
+	for _, np := range config.NodePools {
+		nodePoolNames = append(nodePoolNames, np.NodePoolName)
+	}
+
+	return NewClusterDescriber(config.ClusterName, config.ClusterName, cpConfig, nodePoolNames, awsconn.NewServiceClients(session)), nil
 }
 
-func NewClusterDescriber(clusterName string, stackName string, cpConfig *cp.Config, session *session.Session) ClusterDescriber {
+func NewClusterDescriber(clusterName string, stackName string, cpConfig *cp.Config, nodePoolNames []string, clients *awsconn.ServiceClients) ClusterDescriber {
 	return clusterDescriberImpl{
-		clusterName: clusterName,
-		stackName:   stackName,
-		cpConfig:    cpConfig,
-		session:     session,
+		clusterName:   clusterName,
+		stackName:     stackName,
+		cpConfig:      cpConfig,
+		nodePoolNames: nodePoolNames,
+		clients:       clients,
 	}
 }
 
 func (c clusterDescriberImpl) Info() (*Info, error) {
-	cfSvc := cloudformation.New(c.session)
+	cfSvc := c.clients.CloudFormation
 
 	var cpStackName string
 	{
@@ -92,7 +122,7 @@ func (c clusterDescriberImpl) Info() (*Info, error) {
 			return nil, fmt.Errorf("found multiple load balancers with name %s: %v", cpStackName, resp)
 		}
 
-		cpDescriber := cluster.NewClusterDescriber(c.clusterName, cpStackName, c.cpConfig.ManagedELBLogicalNames(), c.session)
+		cpDescriber := cluster.NewClusterDescriber(c.clusterName, cpStackName, c.cpConfig.ManagedELBLogicalNames(), c.clients)
 
 		cpInfo, err := cpDescriber.Info()
 
@@ -103,5 +133,62 @@ func (c clusterDescriberImpl) Info() (*Info, error) {
 		info.ControlPlane = cpInfo
 	}
 
+	nodePools, err := c.nodePoolsInfo()
+	if err != nil {
+		return nil, err
+	}
+	info.NodePools = nodePools
+
 	return &info, nil
 }
+
+// nodePoolsInfo describes the node pool stacks found in the outputs of the root stack, including the ones already removed from cluster.yaml
+func (c clusterDescriberImpl) nodePoolsInfo() ([]*nodepool.Info, error) {
+	cfSvc := c.clients.CloudFormation
+
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(c.stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing stack %s: %v", c.stackName, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return nil, fmt.Errorf("could not find a stack with name %s", c.stackName)
+	}
+
+	names := map[string]string{}
+	for _, name := range c.nodePoolNames {
+		names[naming.FromStackToCfnResource(name)] = name
+	}
+
+	nodePools := []*nodepool.Info{}
+	for _, o := range resp.Stacks[0].Outputs {
+		key := aws.StringValue(o.OutputKey)
+		if !strings.HasPrefix(key, "NodePool") || !strings.HasSuffix(key, "StackName") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "NodePool"), "StackName")
+		if n, ok := names[name]; ok {
+			name = n
+		}
+		stackName := aws.StringValue(o.OutputValue)
+
+		npResp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+			StackName: aws.String(stackName),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing stack %s: %v", stackName, err)
+		}
+		if len(npResp.Stacks) == 0 {
+			return nil, fmt.Errorf("could not find a stack with name %s", stackName)
+		}
+
+		nodePools = append(nodePools, &nodepool.Info{
+			Name:      name,
+			StackName: stackName,
+			Status:    aws.StringValue(npResp.Stacks[0].StackStatus),
+		})
+	}
+
+	return nodePools, nil
+}
@@ -1,24 +1,40 @@
 package root
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/kubernetes-incubator/kube-aws/awsconn"
 	"github.com/kubernetes-incubator/kube-aws/cfnstack"
 	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/naming"
+	"github.com/kubernetes-incubator/kube-aws/progress"
 )
 
 type DestroyOptions struct {
 	AwsDebug bool
 	Force    bool
+	// AWSCredentials overrides the awsCredentials in cluster.yaml, e.g. with the ones given via command-line flags
+	AWSCredentials model.AWSCredentials
+	// Targets are the sub-stacks to be destroyed. The whole cluster is destroyed when empty or `all`
+	Targets OperationTargets
 }
 
 type ClusterDestroyer interface {
+	CallerIdentity() (*awsconn.CallerIdentity, error)
 	Destroy() error
 }
 
 type clusterDestroyerImpl struct {
 	underlying *cfnstack.Destroyer
+	cfg        *config.Config
+	clients    *awsconn.ServiceClients
+	targets    OperationTargets
 }
 
 func ClusterDestroyerFromFile(configPath string, opts DestroyOptions) (ClusterDestroyer, error) {
@@ -27,17 +43,142 @@ func ClusterDestroyerFromFile(configPath string, opts DestroyOptions) (ClusterDe
 		return nil, err
 	}
 
-	session, err := awsconn.NewSessionFromRegion(cfg.Region, opts.AwsDebug)
+	session, err := awsconn.NewSessionFromRegion(cfg.Region, cfg.AWSEndpoints, cfg.AWSCredentials.WithOverrides(opts.AWSCredentials), opts.AwsDebug)
 	if err != nil {
 		return nil, fmt.Errorf("failed to establish aws session: %v", err)
 	}
 
-	cfnDestroyer := cfnstack.NewDestroyer(cfg.RootStackName(), session, cfg.CloudFormation.RoleARN)
+	return ClusterDestroyerFromConfigWithServiceClients(cfg, opts, awsconn.NewServiceClients(session)), nil
+}
+
+// ClusterDestroyerFromConfigWithServiceClients returns the destroyer calling AWS APIs via the clients
+func ClusterDestroyerFromConfigWithServiceClients(cfg *config.Config, opts DestroyOptions, clients *awsconn.ServiceClients) ClusterDestroyer {
+	cfnDestroyer := cfnstack.NewDestroyer(cfg.RootStackName(), clients.CloudFormation, cfg.CloudFormation.RoleARN)
 	return clusterDestroyerImpl{
 		underlying: cfnDestroyer,
-	}, nil
+		cfg:        cfg,
+		clients:    clients,
+		targets:    opts.Targets,
+	}
+}
+
+// CallerIdentity returns the AWS account and the IAM user or role the cluster is destroyed as
+func (d clusterDestroyerImpl) CallerIdentity() (*awsconn.CallerIdentity, error) {
+	return awsconn.GetCallerIdentity(d.clients.STS)
 }
 
 func (d clusterDestroyerImpl) Destroy() error {
-	return d.underlying.Destroy()
+	if len(d.targets) == 0 || d.targets.IsAll() {
+		return d.underlying.Destroy()
+	}
+	return d.destroyNestedStacks()
+}
+
+// destroyNestedStacks removes the targeted nested stacks from the root stack template and then updates the root stack,
+// so that CloudFormation deletes the nested stacks
+func (d clusterDestroyerImpl) destroyNestedStacks() error {
+	stackName := d.cfg.RootStackName()
+	cfSvc := d.clients.CloudFormation
+
+	resp, err := cfSvc.GetTemplate(&cloudformation.GetTemplateInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return fmt.Errorf("failed to get current root stack template: %v", err)
+	}
+
+	template, err := removeNestedStacks(aws.StringValue(resp.TemplateBody), d.targets)
+	if err != nil {
+		return err
+	}
+
+	assetsBuilder := cfnstack.NewAssetsBuilder(stackName, rootStackAssetsS3URI(d.cfg.DeploymentSettings.S3URI, d.cfg.ClusterName), d.cfg.Region)
+	asset, err := assetsBuilder.Add(REMOTE_STACK_TEMPLATE_FILENAME, template)
+	if err != nil {
+		return fmt.Errorf("failed to build root stack template asset: %v", err)
+	}
+	templateURL, err := asset.URL()
+	if err != nil {
+		return fmt.Errorf("failed to locate root stack template url: %v", err)
+	}
+
+	provisioner := cfnstack.NewProvisioner(
+		stackName,
+		d.cfg.StackTags,
+		d.cfg.DeploymentSettings.S3URI,
+		d.cfg.Region,
+		rootStackPolicyBody,
+		d.clients.Session,
+		d.cfg.CloudFormation.RoleARN,
+	).WithS3Assets(d.cfg.S3Assets)
+
+	if err := provisioner.UploadAssets(d.clients.S3, assetsBuilder.Build()); err != nil {
+		return fmt.Errorf("failed to upload root stack template: %v", err)
+	}
+
+	progress.Printf("Destroying %s by updating the stack %s\n", d.targets.String(), stackName)
+
+	if _, err := provisioner.UpdateStackAtURLAndWait(cfSvc, templateURL); err != nil {
+		return fmt.Errorf("failed to update the stack %s: %v", stackName, err)
+	}
+	return nil
+}
+
+// removeNestedStacks removes the nested stacks for the targets and the outputs referring to them from the root stack template.
+// It refuses to remove a nested stack while any of the remaining resources depends on it,
+// e.g. etcd and the control plane can't be removed while node pools exist
+func removeNestedStacks(template string, targets OperationTargets) (string, error) {
+	t := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(template), &t); err != nil {
+		return "", fmt.Errorf("failed to parse root stack template: %v", err)
+	}
+
+	resources, ok := t["Resources"].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("root stack template has no resources")
+	}
+
+	removed := map[string]string{}
+	for _, target := range targets {
+		id := naming.FromStackToCfnResource(target)
+		r, ok := resources[id].(map[string]interface{})
+		if !ok || r["Type"] != "AWS::CloudFormation::Stack" {
+			return "", fmt.Errorf("no sub-stack named %s found in the root stack", target)
+		}
+		removed[id] = target
+		delete(resources, id)
+	}
+
+	dependents := map[string][]string{}
+	for id, r := range resources {
+		for _, ref := range cfnstack.LogicalIDsReferencedBy(r) {
+			if target, ok := removed[ref]; ok {
+				dependents[target] = append(dependents[target], id)
+			}
+		}
+	}
+	if len(dependents) > 0 {
+		msgs := []string{}
+		for target, ids := range dependents {
+			sort.Strings(ids)
+			msgs = append(msgs, fmt.Sprintf("%s is still depended on by %s", target, strings.Join(ids, ", ")))
+		}
+		sort.Strings(msgs)
+		return "", fmt.Errorf("refusing to destroy %s: %s. Destroy the dependents first, or destroy the whole cluster", targets.String(), strings.Join(msgs, "; "))
+	}
+
+	if outputs, ok := t["Outputs"].(map[string]interface{}); ok {
+		for id, o := range outputs {
+			for _, ref := range cfnstack.LogicalIDsReferencedBy(o) {
+				if _, ok := removed[ref]; ok {
+					delete(outputs, id)
+					break
+				}
+			}
+		}
+	}
+
+	result, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal root stack template: %v", err)
+	}
+	return string(result), nil
 }
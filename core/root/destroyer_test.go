@@ -0,0 +1,108 @@
+package root
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const rootStackTemplateForDestroy = `{
+  "Resources": {
+    "Network": {"Type": "AWS::CloudFormation::Stack", "Properties": {"TemplateURL": "https://example.com/network/stack.json"}},
+    "Etcd": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {"Parameters": {"NetworkStackName": {"Fn::GetAtt": ["Network", "Outputs.StackName"]}}}
+    },
+    "Controlplane": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {
+        "Parameters": {
+          "EtcdStackName": {"Fn::GetAtt": ["Etcd", "Outputs.StackName"]},
+          "NetworkStackName": {"Fn::GetAtt": ["Network", "Outputs.StackName"]}
+        }
+      }
+    },
+    "Pool1": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {"Parameters": {"EtcdStackName": {"Fn::GetAtt": ["Etcd", "Outputs.StackName"]}}},
+      "DependsOn": ["Controlplane"]
+    },
+    "Pool2": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {"Parameters": {"EtcdStackName": {"Fn::GetAtt": ["Etcd", "Outputs.StackName"]}}},
+      "DependsOn": ["Controlplane"]
+    }
+  },
+  "Outputs": {
+    "ControlPlaneStackName": {"Value": {"Fn::GetAtt": ["Controlplane", "Outputs.StackName"]}},
+    "NodePoolpool1StackName": {
+      "Value": {"Fn::GetAtt": ["Pool1", "Outputs.StackName"]},
+      "Export": {"Name": {"Fn::Sub": "${AWS::StackName}-NodePoolpool1StackName"}}
+    },
+    "NodePoolpool2StackName": {"Value": {"Fn::GetAtt": ["Pool2", "Outputs.StackName"]}}
+  }
+}`
+
+func TestRemoveNestedStacks(t *testing.T) {
+	t.Run("NodePool", func(t *testing.T) {
+		result, err := removeNestedStacks(rootStackTemplateForDestroy, OperationTargets{"pool1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		parsed := struct {
+			Resources map[string]interface{}
+			Outputs   map[string]interface{}
+		}{}
+		if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+			t.Fatalf("failed to parse the resulting template: %v", err)
+		}
+
+		if _, ok := parsed.Resources["Pool1"]; ok {
+			t.Errorf("expected Pool1 to be removed but it wasn't")
+		}
+		if _, ok := parsed.Outputs["NodePoolpool1StackName"]; ok {
+			t.Errorf("expected the output for pool1 to be removed but it wasn't")
+		}
+		for _, id := range []string{"Network", "Etcd", "Controlplane", "Pool2"} {
+			if _, ok := parsed.Resources[id]; !ok {
+				t.Errorf("expected %s to be kept but it wasn't", id)
+			}
+		}
+		for _, id := range []string{"ControlPlaneStackName", "NodePoolpool2StackName"} {
+			if _, ok := parsed.Outputs[id]; !ok {
+				t.Errorf("expected the output %s to be kept but it wasn't", id)
+			}
+		}
+	})
+
+	t.Run("ControlPlaneWithNodePools", func(t *testing.T) {
+		_, err := removeNestedStacks(rootStackTemplateForDestroy, OperationTargets{"control-plane"})
+		if err == nil {
+			t.Fatal("expected an error but got none")
+		}
+		if !strings.Contains(err.Error(), "control-plane is still depended on by Pool1, Pool2") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("EtcdWithControlPlane", func(t *testing.T) {
+		_, err := removeNestedStacks(rootStackTemplateForDestroy, OperationTargets{"etcd", "pool1", "pool2"})
+		if err == nil {
+			t.Fatal("expected an error but got none")
+		}
+		if !strings.Contains(err.Error(), "etcd is still depended on by Controlplane") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("NonExistent", func(t *testing.T) {
+		_, err := removeNestedStacks(rootStackTemplateForDestroy, OperationTargets{"pool3"})
+		if err == nil {
+			t.Fatal("expected an error but got none")
+		}
+		if !strings.Contains(err.Error(), "no sub-stack named pool3") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
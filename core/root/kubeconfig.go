@@ -0,0 +1,277 @@
+package root
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-yaml/yaml"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+	"github.com/kubernetes-incubator/kube-aws/model"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+// KubeconfigOptions determines the API endpoint and the credentials written in a kubeconfig.
+// The admin client cert in the credentials directory is used unless either User or OIDC is specified
+type KubeconfigOptions struct {
+	// APIEndpointName is the name of the API endpoint in `apiEndpoints`. Defaults to `adminAPIEndpointName`
+	APIEndpointName string
+	// User is the username in the client cert freshly issued and signed by the cluster CA
+	User string
+	// Groups are the groups in the client cert issued for User
+	Groups []string
+	// OIDC is true when the kubeconfig should authenticate users via the OIDC provider in `experimental.oidc`
+	OIDC bool
+	// OIDCClientSecret, OIDCExtraScopes and OIDCCAPath are written to the auth provider along with the issuer url and the client id in `experimental.oidc`.
+	// Tokens are specific to each user, so they're left to `kubectl config set-credentials`
+	OIDCClientSecret string
+	OIDCExtraScopes  []string
+	OIDCCAPath       string
+	// CACertPath and CAKeyPath are the cluster CA used for issuing a client cert for User
+	CACertPath string
+	CAKeyPath  string
+	// Days is the validity of the client cert issued for User. Defaults to `tlsCertDurationDays`
+	Days int
+	// EmbedCerts is true when certs are embedded in the kubeconfig rather than referred by their paths.
+	// Certs are always embedded for User
+	EmbedCerts bool
+}
+
+type kubeconfig struct {
+	APIVersion     string              `yaml:"apiVersion"`
+	Kind           string              `yaml:"kind"`
+	Clusters       []kubeconfigCluster `yaml:"clusters"`
+	Contexts       []kubeconfigContext `yaml:"contexts"`
+	Users          []kubeconfigUser    `yaml:"users"`
+	CurrentContext string              `yaml:"current-context"`
+}
+
+type kubeconfigCluster struct {
+	Cluster struct {
+		CertificateAuthority     string `yaml:"certificate-authority,omitempty"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+		Server                   string `yaml:"server"`
+	} `yaml:"cluster"`
+	Name string `yaml:"name"`
+}
+
+type kubeconfigContext struct {
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		Namespace string `yaml:"namespace"`
+		User      string `yaml:"user"`
+	} `yaml:"context"`
+	Name string `yaml:"name"`
+}
+
+type kubeconfigUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		ClientCertificate     string                  `yaml:"client-certificate,omitempty"`
+		ClientCertificateData string                  `yaml:"client-certificate-data,omitempty"`
+		ClientKey             string                  `yaml:"client-key,omitempty"`
+		ClientKeyData         string                  `yaml:"client-key-data,omitempty"`
+		AuthProvider          *kubeconfigAuthProvider `yaml:"auth-provider,omitempty"`
+	} `yaml:"user"`
+}
+
+type kubeconfigAuthProvider struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config"`
+}
+
+// KubeconfigFromFile returns a kubeconfig for the cluster described in the cluster.yaml at configPath
+func KubeconfigFromFile(configPath string, opts KubeconfigOptions) ([]byte, error) {
+	cluster, err := controlplane.ClusterFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return generateKubeconfigFor(cluster, opts, defaults.AssetsDir)
+}
+
+func generateKubeconfigFor(cluster *controlplane.Cluster, opts KubeconfigOptions, assetsDir string) ([]byte, error) {
+	if opts.User != "" && opts.OIDC {
+		return nil, errors.New("user and OIDC can't be specified at once")
+	}
+
+	endpoint, err := findAPIEndpoint(cluster, opts.APIEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	userName := "admin"
+	if opts.User != "" {
+		userName = opts.User
+	} else if opts.OIDC {
+		userName = "oidc"
+	}
+
+	prefix := fmt.Sprintf("kube-aws-%s", cluster.ClusterName)
+	clusterName := fmt.Sprintf("%s-%s", prefix, endpoint.Name)
+	contextName := fmt.Sprintf("%s-%s-%s", prefix, endpoint.Name, userName)
+
+	c := kubeconfigCluster{Name: clusterName}
+	c.Cluster.Server = fmt.Sprintf("https://%s", endpoint.DNSName)
+
+	caCertPath := filepath.Join(assetsDir, "ca.pem")
+	if opts.User != "" || opts.EmbedCerts {
+		if opts.CACertPath != "" {
+			caCertPath = opts.CACertPath
+		}
+		if c.Cluster.CertificateAuthorityData, err = readBase64(caCertPath); err != nil {
+			return nil, err
+		}
+	} else {
+		c.Cluster.CertificateAuthority = caCertPath
+	}
+
+	u := kubeconfigUser{Name: fmt.Sprintf("%s-%s", prefix, userName)}
+	switch {
+	case opts.User != "":
+		certPEM, keyPEM, err := issueClientCert(cluster, opts)
+		if err != nil {
+			return nil, err
+		}
+		u.User.ClientCertificateData = base64.StdEncoding.EncodeToString(certPEM)
+		u.User.ClientKeyData = base64.StdEncoding.EncodeToString(keyPEM)
+	case opts.OIDC:
+		oidc := cluster.Experimental.Oidc
+		if !oidc.Enabled {
+			return nil, errors.New("OIDC authentication is not enabled. Enable it by turning on `experimental.oidc.enabled` in cluster.yaml")
+		}
+		u.User.AuthProvider = &kubeconfigAuthProvider{
+			Name: "oidc",
+			Config: map[string]string{
+				"idp-issuer-url": oidc.IssuerUrl,
+				"client-id":      oidc.ClientId,
+			},
+		}
+		if opts.OIDCClientSecret != "" {
+			u.User.AuthProvider.Config["client-secret"] = opts.OIDCClientSecret
+		}
+		if len(opts.OIDCExtraScopes) > 0 {
+			u.User.AuthProvider.Config["extra-scopes"] = strings.Join(opts.OIDCExtraScopes, ",")
+		}
+		if opts.OIDCCAPath != "" {
+			if opts.EmbedCerts {
+				if u.User.AuthProvider.Config["idp-certificate-authority-data"], err = readBase64(opts.OIDCCAPath); err != nil {
+					return nil, err
+				}
+			} else {
+				u.User.AuthProvider.Config["idp-certificate-authority"] = opts.OIDCCAPath
+			}
+		}
+	case opts.EmbedCerts:
+		if u.User.ClientCertificateData, err = readBase64(filepath.Join(assetsDir, "admin.pem")); err != nil {
+			return nil, err
+		}
+		if u.User.ClientKeyData, err = readBase64(filepath.Join(assetsDir, "admin-key.pem")); err != nil {
+			return nil, err
+		}
+	default:
+		u.User.ClientCertificate = filepath.Join(assetsDir, "admin.pem")
+		u.User.ClientKey = filepath.Join(assetsDir, "admin-key.pem")
+	}
+
+	ctx := kubeconfigContext{Name: contextName}
+	ctx.Context.Cluster = c.Name
+	ctx.Context.Namespace = "default"
+	ctx.Context.User = u.Name
+
+	data, err := yaml.Marshal(kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Clusters:       []kubeconfigCluster{c},
+		Contexts:       []kubeconfigContext{ctx},
+		Users:          []kubeconfigUser{u},
+		CurrentContext: contextName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal kubeconfig: %v", err)
+	}
+	return data, nil
+}
+
+// findAPIEndpoint returns the API endpoint named name, or the admin API endpoint when name is empty
+func findAPIEndpoint(cluster *controlplane.Cluster, name string) (*model.APIEndpoint, error) {
+	names := []string{}
+	for _, e := range cluster.APIEndpointConfigs {
+		names = append(names, e.Name)
+	}
+
+	if name == "" {
+		name = cluster.AdminAPIEndpointName
+	}
+	if name == "" {
+		if len(cluster.APIEndpointConfigs) != 1 {
+			return nil, fmt.Errorf("API endpoint name must be specified as `adminAPIEndpointName` is empty and there're %d API endpoints. Specify one of: %s", len(names), strings.Join(names, ", "))
+		}
+		return &cluster.APIEndpointConfigs[0], nil
+	}
+
+	for i, e := range cluster.APIEndpointConfigs {
+		if e.Name == name {
+			return &cluster.APIEndpointConfigs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no API endpoint named \"%s\" found. Specify one of: %s", name, strings.Join(names, ", "))
+}
+
+// issueClientCert returns a PEM-encoded client cert and key for the user, signed by the cluster CA
+func issueClientCert(cluster *controlplane.Cluster, opts KubeconfigOptions) ([]byte, []byte, error) {
+	caCertPEM, err := ioutil.ReadFile(opts.CACertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ca cert: %v", err)
+	}
+	caCerts, err := tlsutil.DecodeCertificatesPEM(caCertPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse ca cert: %v", err)
+	}
+
+	caKeyPEM, err := ioutil.ReadFile(opts.CAKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ca key: %v. The CA key is required for issuing client certs for users", err)
+	}
+	caKey, err := tlsutil.DecodePrivateKeyPEM(caKeyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse ca key: %v", err)
+	}
+	// ca.pem bundles the old and new CAs while the CA is being rotated
+	caCert, err := controlplane.CACertificateOf(caCerts, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse ca cert: %v", err)
+	}
+
+	days := opts.Days
+	if days == 0 {
+		days = cluster.TLSCertDurationDays
+	}
+
+	key, err := cluster.NewTLSKey("admin")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate client key: %v", err)
+	}
+	cert, err := tlsutil.NewSignedClientCertificate(tlsutil.ClientCertConfig{
+		CommonName:   opts.User,
+		Organization: opts.Groups,
+		Duration:     time.Duration(days) * 24 * time.Hour,
+	}, key, caCert, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue client cert: %v", err)
+	}
+
+	return tlsutil.EncodeCertificatePEM(cert), tlsutil.EncodePrivateKeyPEM(key), nil
+}
+
+func readBase64(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,214 @@
+package root
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-yaml/yaml"
+	controlplane "github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+const clusterYamlForKubeconfig = `clusterName: test-cluster
+keyName: test-key-name
+s3URI: s3://mybucket/mydir
+region: us-west-1
+kmsKeyArn: "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxxxxxxxxxxxxxxxxxx"
+availabilityZone: us-west-1a
+experimental:
+  oidc:
+    enabled: true
+    issuerUrl: "https://accounts.example.com"
+    clientId: "kubernetes"
+    usernameClaim: "email"
+    groupsClaim: "groups"
+apiEndpoints:
+- name: public
+  dnsName: api.example.com
+  loadBalancer:
+    hostedZone:
+      id: hostedzone-public
+- name: private
+  dnsName: api.internal.example.com
+  loadBalancer:
+    private: true
+    hostedZone:
+      id: hostedzone-private
+`
+
+func writeTestCA(t *testing.T, dir string) {
+	key, err := tlsutil.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate ca key: %v", err)
+	}
+	cert, err := tlsutil.NewSelfSignedCACertificate(tlsutil.CACertConfig{
+		CommonName:   "kube-ca",
+		Organization: "kube-aws",
+		Duration:     24 * time.Hour,
+	}, key)
+	if err != nil {
+		t.Fatalf("failed to generate ca cert: %v", err)
+	}
+	files := map[string][]byte{
+		"ca.pem":     tlsutil.EncodeCertificatePEM(cert),
+		"ca-key.pem": tlsutil.EncodePrivateKeyPEM(key),
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestGenerateKubeconfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-aws-kubeconfig")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestCA(t, dir)
+
+	cluster, err := controlplane.ClusterFromBytes([]byte(clusterYamlForKubeconfig))
+	if err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+
+	t.Run("Admin", func(t *testing.T) {
+		data, err := generateKubeconfigFor(cluster, KubeconfigOptions{APIEndpointName: "private"}, "credentials")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority: credentials/ca.pem
+    server: https://api.internal.example.com
+  name: kube-aws-test-cluster-private
+contexts:
+- context:
+    cluster: kube-aws-test-cluster-private
+    namespace: default
+    user: kube-aws-test-cluster-admin
+  name: kube-aws-test-cluster-private-admin
+users:
+- name: kube-aws-test-cluster-admin
+  user:
+    client-certificate: credentials/admin.pem
+    client-key: credentials/admin-key.pem
+current-context: kube-aws-test-cluster-private-admin
+`
+		if string(data) != expected {
+			t.Errorf("unexpected kubeconfig:\nexpected:\n%s\nactual:\n%s", expected, data)
+		}
+	})
+
+	t.Run("User", func(t *testing.T) {
+		data, err := generateKubeconfigFor(cluster, KubeconfigOptions{
+			APIEndpointName: "public",
+			User:            "alice",
+			Groups:          []string{"dev", "ops"},
+			CACertPath:      filepath.Join(dir, "ca.pem"),
+			CAKeyPath:       filepath.Join(dir, "ca-key.pem"),
+			Days:            7,
+		}, "credentials")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		kc := kubeconfig{}
+		if err := yaml.Unmarshal(data, &kc); err != nil {
+			t.Fatalf("failed to parse kubeconfig: %v", err)
+		}
+		if kc.CurrentContext != "kube-aws-test-cluster-public-alice" {
+			t.Errorf("unexpected current context: %s", kc.CurrentContext)
+		}
+		if kc.Clusters[0].Cluster.Server != "https://api.example.com" || kc.Clusters[0].Cluster.CertificateAuthorityData == "" {
+			t.Errorf("unexpected cluster: %+v", kc.Clusters[0])
+		}
+
+		certPEM, err := base64.StdEncoding.DecodeString(kc.Users[0].User.ClientCertificateData)
+		if err != nil {
+			t.Fatalf("failed to decode client cert: %v", err)
+		}
+		cert, err := tlsutil.DecodeCertificatePEM(certPEM)
+		if err != nil {
+			t.Fatalf("failed to parse client cert: %v", err)
+		}
+		// The organization of the CA is always included, as in the admin cert
+		orgs := append([]string{}, cert.Subject.Organization...)
+		sort.Strings(orgs)
+		if cert.Subject.CommonName != "alice" || !reflect.DeepEqual(orgs, []string{"dev", "kube-aws", "ops"}) {
+			t.Errorf("unexpected subject: %+v", cert.Subject)
+		}
+		// NotBefore is inherited from the CA cert, so the validity is checked against NotAfter, which is computed from the time of issuing
+		if d := cert.NotAfter.Sub(time.Now()); d > 7*24*time.Hour || d < 7*24*time.Hour-time.Minute {
+			t.Errorf("unexpected validity: %v", d)
+		}
+
+		caPEM, _ := ioutil.ReadFile(filepath.Join(dir, "ca.pem"))
+		ca, _ := tlsutil.DecodeCertificatePEM(caPEM)
+		if err := cert.CheckSignatureFrom(ca); err != nil {
+			t.Errorf("client cert isn't signed by the ca: %v", err)
+		}
+	})
+
+	t.Run("OIDC", func(t *testing.T) {
+		data, err := generateKubeconfigFor(cluster, KubeconfigOptions{
+			APIEndpointName:  "public",
+			OIDC:             true,
+			OIDCClientSecret: "secret",
+			OIDCExtraScopes:  []string{"groups", "email"},
+			OIDCCAPath:       filepath.Join(dir, "ca.pem"),
+		}, "credentials")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		kc := kubeconfig{}
+		if err := yaml.Unmarshal(data, &kc); err != nil {
+			t.Fatalf("failed to parse kubeconfig: %v", err)
+		}
+		expected := &kubeconfigAuthProvider{
+			Name: "oidc",
+			Config: map[string]string{
+				"idp-issuer-url":            "https://accounts.example.com",
+				"client-id":                 "kubernetes",
+				"client-secret":             "secret",
+				"extra-scopes":              "groups,email",
+				"idp-certificate-authority": filepath.Join(dir, "ca.pem"),
+			},
+		}
+		if u := kc.Users[0]; u.Name != "kube-aws-test-cluster-oidc" || !reflect.DeepEqual(u.User.AuthProvider, expected) || u.User.ClientCertificate != "" {
+			t.Errorf("unexpected user: %+v", u)
+		}
+	})
+
+	errorCases := []struct {
+		context  string
+		opts     KubeconfigOptions
+		expected string
+	}{
+		{"AmbiguousEndpoint", KubeconfigOptions{}, "API endpoint name must be specified"},
+		{"UnknownEndpoint", KubeconfigOptions{APIEndpointName: "foo"}, "no API endpoint named \"foo\" found. Specify one of: public, private"},
+		{"UserAndOIDC", KubeconfigOptions{APIEndpointName: "public", User: "alice", OIDC: true}, "can't be specified at once"},
+		{"MissingCAKey", KubeconfigOptions{APIEndpointName: "public", User: "alice", CACertPath: filepath.Join(dir, "ca.pem"), CAKeyPath: filepath.Join(dir, "missing.pem")}, "failed to read ca key"},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.context, func(t *testing.T) {
+			_, err := generateKubeconfigFor(cluster, tc.opts, "credentials")
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error containing %q but was %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package root
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+// LogsOptions filters the journald logs sent to CloudWatch Logs from the nodes
+type LogsOptions struct {
+	// Hosts are hostnames or instance IDs of the nodes
+	Hosts []string
+	// Units are systemd units, e.g. `kubelet.service`
+	Units []string
+	// NodePools are names of node pools whose nodes' logs are shown
+	NodePools []string
+	// Since is how far to look back for logs
+	Since time.Duration
+	// Follow is true when new logs are streamed until q is closed
+	Follow bool
+}
+
+// messageDeduplicator suppresses a message printed again within the interval in milliseconds, as configured by `cloudWatchLogging.localStreaming.interval`
+type messageDeduplicator struct {
+	interval int64
+	last     map[string]int64
+}
+
+func newMessageDeduplicator(interval int64) *messageDeduplicator {
+	return &messageDeduplicator{interval: interval, last: map[string]int64{}}
+}
+
+// allow returns true and records the message when it hasn't been seen within the interval before the timestamp
+func (d *messageDeduplicator) allow(message string, timestamp int64) bool {
+	if timestamp <= d.last[message]+d.interval {
+		return false
+	}
+	d.last[message] = timestamp
+	return true
+}
+
+type cloudWatchLogsService interface {
+	FilterLogEvents(*cloudwatchlogs.FilterLogEventsInput) (*cloudwatchlogs.FilterLogEventsOutput, error)
+}
+
+// StreamLogsFromFile prints the journald logs of the cluster described in the cluster.yaml at configPath to w.
+// When following logs, it returns once q is closed
+func StreamLogsFromFile(configPath string, opts LogsOptions, awsCredentials model.AWSCredentials, awsDebug bool, w io.Writer, q <-chan struct{}) error {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.CloudWatchLogging.Enabled {
+		return errors.New("CloudWatch logging is not enabled. Turn on `cloudWatchLogging.enabled` in cluster.yaml to send journald logs to CloudWatch Logs")
+	}
+
+	session, err := awsconn.NewSessionFromRegion(cfg.Region, cfg.AWSEndpoints, cfg.AWSCredentials.WithOverrides(awsCredentials), awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to establish aws session: %v", err)
+	}
+
+	clients := awsconn.NewServiceClients(session)
+
+	var instanceIDs map[string]bool
+	if len(opts.NodePools) > 0 {
+		lister := nodeLister{
+			clusterName: cfg.ClusterName,
+			stackName:   cfg.ClusterName,
+			etcd:        cfg.Etcd,
+			ec2Svc:      clients.EC2,
+			asSvc:       autoscaling.New(session),
+		}
+		if instanceIDs, err = lister.instanceIDsOfNodePools(opts.NodePools); err != nil {
+			return err
+		}
+	}
+
+	tailer := logTailer{
+		svc:           clients.CloudWatchLogs,
+		logGroupName:  cfg.ClusterName,
+		filterPattern: journaldFilterPattern(opts.Hosts, opts.Units),
+		instanceIDs:   instanceIDs,
+		dedup:         newMessageDeduplicator(cfg.CloudWatchLogging.LocalStreaming.Interval()),
+		pollInterval:  time.Second,
+	}
+	return tailer.run(w, time.Now().Add(-opts.Since), opts.Follow, q)
+}
+
+// instanceIDsOfNodePools returns the IDs of the instances in the node pools
+func (l nodeLister) instanceIDsOfNodePools(names []string) (map[string]bool, error) {
+	instances, err := l.describeInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	pools := map[string]bool{}
+	for _, n := range names {
+		pools[n] = true
+	}
+
+	ids := map[string]bool{}
+	for _, i := range instances {
+		if pools[instanceTags(i)[nodePoolNameTagKey]] {
+			ids[aws.StringValue(i.InstanceId)] = true
+		}
+	}
+	return ids, nil
+}
+
+// journaldFilterPattern returns a CloudWatch Logs filter pattern matching the JSON log events sent by journald-cloudwatch-logs
+// from any of the hosts and any of the units. An empty pattern matches everything
+func journaldFilterPattern(hosts, units []string) string {
+	conds := []string{}
+
+	if len(hosts) > 0 {
+		terms := []string{}
+		for _, h := range hosts {
+			terms = append(terms, fmt.Sprintf("$.hostname = %q || $.instanceId = %q", h, h))
+		}
+		conds = append(conds, "("+strings.Join(terms, " || ")+")")
+	}
+
+	if len(units) > 0 {
+		terms := []string{}
+		for _, u := range units {
+			if !strings.Contains(u, ".") {
+				u = u + ".service"
+			}
+			terms = append(terms, fmt.Sprintf("$.systemdUnit = %q", u))
+		}
+		conds = append(conds, "("+strings.Join(terms, " || ")+")")
+	}
+
+	if len(conds) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(conds, " && ") + " }"
+}
+
+type logTailer struct {
+	svc           cloudWatchLogsService
+	logGroupName  string
+	filterPattern string
+	// instanceIDs limits events to the ones sent from the instances. nil means no limit
+	instanceIDs  map[string]bool
+	dedup        *messageDeduplicator
+	pollInterval time.Duration
+}
+
+// run prints the log events since the time to w. When following, it keeps polling for new events until q is closed
+func (t logTailer) run(w io.Writer, since time.Time, follow bool, q <-chan struct{}) error {
+	start := since.UnixNano() / int64(time.Millisecond)
+	// IDs of the events printed at the start time, which are returned again in the next poll
+	seen := map[string]bool{}
+
+	for {
+		last, err := t.poll(w, start, seen)
+		if err != nil {
+			return err
+		}
+		if last > start {
+			start = last
+		}
+
+		if !follow {
+			return nil
+		}
+
+		select {
+		case <-q:
+			return nil
+		case <-time.After(t.pollInterval):
+		}
+	}
+}
+
+// poll prints the events since start and returns the timestamp of the last event
+func (t logTailer) poll(w io.Writer, start int64, seen map[string]bool) (int64, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(t.logGroupName),
+		StartTime:    aws.Int64(start),
+	}
+	if t.filterPattern != "" {
+		input.FilterPattern = aws.String(t.filterPattern)
+	}
+
+	last := start
+	for {
+		out, err := t.svc.FilterLogEvents(input)
+		if err != nil {
+			return 0, fmt.Errorf("failed to filter log events in %s: %v", t.logGroupName, err)
+		}
+
+		for _, e := range out.Events {
+			id, ts := aws.StringValue(e.EventId), aws.Int64Value(e.Timestamp)
+			if seen[id] {
+				continue
+			}
+			if ts > last {
+				last = ts
+				for k := range seen {
+					delete(seen, k)
+				}
+			}
+			seen[id] = true
+
+			msg := model.SystemdMessageResponse{}
+			if err := json.Unmarshal([]byte(aws.StringValue(e.Message)), &msg); err != nil {
+				continue
+			}
+			if t.instanceIDs != nil && !t.instanceIDs[msg.InstanceId] {
+				continue
+			}
+			if !t.dedup.allow(aws.StringValue(e.Message), ts) {
+				continue
+			}
+
+			timestamp := time.Unix(0, ts*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+			fmt.Fprintf(w, "%s\t%s\t%s: \"%s\"\n", timestamp, msg.Hostname, msg.SystemdUnit, msg.Message)
+		}
+
+		if out.NextToken == nil {
+			return last, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
@@ -0,0 +1,144 @@
+package root
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+type fakeCloudWatchLogsService struct {
+	// pages are returned in order, one per call
+	pages  []*cloudwatchlogs.FilterLogEventsOutput
+	inputs []cloudwatchlogs.FilterLogEventsInput
+	// onLastPage is called when the last page is returned
+	onLastPage func()
+}
+
+func (s *fakeCloudWatchLogsService) FilterLogEvents(input *cloudwatchlogs.FilterLogEventsInput) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	s.inputs = append(s.inputs, *input)
+	if len(s.pages) == 0 {
+		return &cloudwatchlogs.FilterLogEventsOutput{}, nil
+	}
+	page := s.pages[0]
+	s.pages = s.pages[1:]
+	if len(s.pages) == 0 && s.onLastPage != nil {
+		s.onLastPage()
+	}
+	return page, nil
+}
+
+func journaldEvent(id string, timestamp int64, instanceID, hostname, unit, message string) *cloudwatchlogs.FilteredLogEvent {
+	return &cloudwatchlogs.FilteredLogEvent{
+		EventId:   aws.String(id),
+		Timestamp: aws.Int64(timestamp),
+		Message:   aws.String(fmt.Sprintf(`{"instanceId":%q,"hostname":%q,"systemdUnit":%q,"message":%q}`, instanceID, hostname, unit, message)),
+	}
+}
+
+func TestJournaldFilterPattern(t *testing.T) {
+	testCases := []struct {
+		hosts, units []string
+		expected     string
+	}{
+		{nil, nil, ""},
+		{[]string{"ip-10-0-0-1"}, nil, `{ ($.hostname = "ip-10-0-0-1" || $.instanceId = "ip-10-0-0-1") }`},
+		{nil, []string{"kubelet", "docker.socket"}, `{ ($.systemdUnit = "kubelet.service" || $.systemdUnit = "docker.socket") }`},
+		{[]string{"i-1"}, []string{"etcd-member.service"}, `{ ($.hostname = "i-1" || $.instanceId = "i-1") && ($.systemdUnit = "etcd-member.service") }`},
+	}
+
+	for _, tc := range testCases {
+		if actual := journaldFilterPattern(tc.hosts, tc.units); actual != tc.expected {
+			t.Errorf("unexpected pattern for hosts=%v units=%v: expected %s but was %s", tc.hosts, tc.units, tc.expected, actual)
+		}
+	}
+}
+
+func TestLogTailer(t *testing.T) {
+	since := time.Unix(1500000000, 0)
+	start := since.Unix() * 1000
+
+	t.Run("Paged", func(t *testing.T) {
+		svc := &fakeCloudWatchLogsService{
+			pages: []*cloudwatchlogs.FilterLogEventsOutput{
+				{
+					Events: []*cloudwatchlogs.FilteredLogEvent{
+						journaldEvent("1", start+1000, "i-1", "host1", "kubelet.service", "failed"),
+						journaldEvent("2", start+2000, "i-2", "host2", "kubelet.service", "started"),
+					},
+					NextToken: aws.String("next"),
+				},
+				{
+					Events: []*cloudwatchlogs.FilteredLogEvent{
+						// Suppressed as the same message was printed within the interval
+						journaldEvent("3", start+30000, "i-1", "host1", "kubelet.service", "failed"),
+						journaldEvent("4", start+70000, "i-1", "host1", "kubelet.service", "failed"),
+					},
+				},
+			},
+		}
+
+		tailer := logTailer{
+			svc:           svc,
+			logGroupName:  "mycluster",
+			filterPattern: `{ ($.systemdUnit = "kubelet.service") }`,
+			instanceIDs:   map[string]bool{"i-1": true},
+			dedup:         newMessageDeduplicator(60000),
+			pollInterval:  time.Millisecond,
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tailer.run(buf, since, false, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "2017-07-14T02:40:01Z\thost1\tkubelet.service: \"failed\"\n" +
+			"2017-07-14T02:41:10Z\thost1\tkubelet.service: \"failed\"\n"
+		if buf.String() != expected {
+			t.Errorf("unexpected output:\nexpected:\n%s\nactual:\n%s", expected, buf.String())
+		}
+
+		if len(svc.inputs) != 2 || *svc.inputs[0].StartTime != start || *svc.inputs[0].FilterPattern != tailer.filterPattern || *svc.inputs[1].NextToken != "next" {
+			t.Errorf("unexpected inputs: %+v", svc.inputs)
+		}
+	})
+
+	t.Run("Follow", func(t *testing.T) {
+		q := make(chan struct{})
+		svc := &fakeCloudWatchLogsService{
+			pages: []*cloudwatchlogs.FilterLogEventsOutput{
+				{Events: []*cloudwatchlogs.FilteredLogEvent{journaldEvent("1", start+1000, "i-1", "host1", "kubelet.service", "a")}},
+				// The event at the last timestamp is returned again when polling from there
+				{Events: []*cloudwatchlogs.FilteredLogEvent{
+					journaldEvent("1", start+1000, "i-1", "host1", "kubelet.service", "a"),
+					journaldEvent("2", start+1000, "i-1", "host1", "kubelet.service", "b"),
+				}},
+			},
+			onLastPage: func() { close(q) },
+		}
+
+		tailer := logTailer{
+			svc:          svc,
+			logGroupName: "mycluster",
+			dedup:        newMessageDeduplicator(0),
+			pollInterval: time.Millisecond,
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tailer.run(buf, since, true, q); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "2017-07-14T02:40:01Z\thost1\tkubelet.service: \"a\"\n" +
+			"2017-07-14T02:40:01Z\thost1\tkubelet.service: \"b\"\n"
+		if buf.String() != expected {
+			t.Errorf("unexpected output:\nexpected:\n%s\nactual:\n%s", expected, buf.String())
+		}
+		if len(svc.inputs) != 2 || *svc.inputs[1].StartTime != start+1000 || svc.inputs[1].FilterPattern != nil {
+			t.Errorf("unexpected inputs: %+v", svc.inputs)
+		}
+	})
+}
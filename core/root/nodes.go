@@ -0,0 +1,436 @@
+package root
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/fingerprint"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+const (
+	NodeRoleController = "controller"
+	NodeRoleEtcd       = "etcd"
+	NodeRoleWorker     = "worker"
+
+	nodePoolNameTagKey    = "kube-aws:node-pool:name"
+	roleTagKey            = "kube-aws:role"
+	masterRoleTagKey      = "kubernetes.io/role/master"
+	stackNameTagKey       = "aws:cloudformation:stack-name"
+	asgNameTagKey         = "aws:autoscaling:groupName"
+	spotFleetRequestIDKey = "aws:ec2spot:fleet-request-id"
+
+	// fingerprintLength is the number of hex digits of a userdata fingerprint shown to users
+	fingerprintLength = 12
+)
+
+// Node is an EC2 instance in the cluster
+type Node struct {
+	InstanceID string `json:"instanceId"`
+	Role       string `json:"role"`
+	// NodePool is the name of the node pool for a worker, or the name of the etcd member for an etcd node
+	NodePool         string `json:"nodePool,omitempty"`
+	AdvertisedFQDN   string `json:"advertisedFQDN,omitempty"`
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceType     string `json:"instanceType"`
+	PrivateIP        string `json:"privateIP,omitempty"`
+	PublicIP         string `json:"publicIP,omitempty"`
+	Lifecycle        string `json:"lifecycle"`
+	State            string `json:"state"`
+	// Fingerprint is the fingerprint of the userdata the instance is running
+	Fingerprint string `json:"fingerprint"`
+	// Outdated is true when the instance's userdata differs from the one in the current launch configuration or spot fleet
+	Outdated bool `json:"outdated"`
+}
+
+type Nodes []Node
+
+func (n Nodes) String() string {
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 1, ' ', 0)
+
+	fmt.Fprintln(w, "INSTANCE ID\tROLE\tPOOL\tAZ\tINSTANCE TYPE\tPRIVATE IP\tPUBLIC IP\tLIFECYCLE\tSTATE\tFINGERPRINT\tOUTDATED")
+	for _, node := range n {
+		outdated := ""
+		if node.Outdated {
+			outdated = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			node.InstanceID, node.Role, orDash(node.NodePool), node.AvailabilityZone, node.InstanceType,
+			orDash(node.PrivateIP), orDash(node.PublicIP), node.Lifecycle, node.State, orDash(node.Fingerprint), outdated)
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// Outdated returns the nodes still running outdated userdata
+func (n Nodes) Outdated() Nodes {
+	outdated := Nodes{}
+	for _, node := range n {
+		if node.Outdated {
+			outdated = append(outdated, node)
+		}
+	}
+	return outdated
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type nodesEC2Service interface {
+	DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error)
+	DescribeInstanceAttribute(*ec2.DescribeInstanceAttributeInput) (*ec2.DescribeInstanceAttributeOutput, error)
+	DescribeVolumes(*ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error)
+	DescribeSpotFleetRequests(*ec2.DescribeSpotFleetRequestsInput) (*ec2.DescribeSpotFleetRequestsOutput, error)
+}
+
+type nodesAutoScalingService interface {
+	DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+	DescribeLaunchConfigurations(*autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error)
+}
+
+// NodesFromFile lists the EC2 instances in the cluster described in the cluster.yaml at configPath
+func NodesFromFile(configPath string, awsCredentials model.AWSCredentials, awsDebug bool) (Nodes, error) {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := awsconn.NewSessionFromRegion(cfg.Region, cfg.AWSEndpoints, cfg.AWSCredentials.WithOverrides(awsCredentials), awsDebug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+	}
+
+	lister := nodeLister{
+		clusterName: cfg.ClusterName,
+		stackName:   cfg.ClusterName,
+		etcd:        cfg.Etcd,
+		ec2Svc:      awsconn.NewServiceClients(session).EC2,
+		asSvc:       autoscaling.New(session),
+	}
+	return lister.list()
+}
+
+type nodeLister struct {
+	clusterName string
+	stackName   string
+	etcd        model.Etcd
+	ec2Svc      nodesEC2Service
+	asSvc       nodesAutoScalingService
+}
+
+func (l nodeLister) list() (Nodes, error) {
+	instances, err := l.describeInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	etcdVolumes, err := l.describeEtcdVolumes(instances)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := l.expectedFingerprints(instances)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := Nodes{}
+	for _, i := range instances {
+		tags := instanceTags(i)
+
+		node := Node{
+			InstanceID:       aws.StringValue(i.InstanceId),
+			AvailabilityZone: aws.StringValue(i.Placement.AvailabilityZone),
+			InstanceType:     aws.StringValue(i.InstanceType),
+			PrivateIP:        aws.StringValue(i.PrivateIpAddress),
+			PublicIP:         aws.StringValue(i.PublicIpAddress),
+			Lifecycle:        "on-demand",
+			State:            aws.StringValue(i.State.Name),
+		}
+		if aws.StringValue(i.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot {
+			node.Lifecycle = ec2.InstanceLifecycleTypeSpot
+		}
+
+		switch {
+		case tags[roleTagKey] == NodeRoleEtcd:
+			node.Role = NodeRoleEtcd
+			for _, m := range i.BlockDeviceMappings {
+				if m.Ebs == nil {
+					continue
+				}
+				if v, ok := etcdVolumes[aws.StringValue(m.Ebs.VolumeId)]; ok {
+					node.NodePool = v[l.etcd.NameTagKey()]
+					node.AdvertisedFQDN = v[l.etcd.AdvertisedFQDNTagKey()]
+				}
+			}
+		case hasKey(tags, masterRoleTagKey):
+			node.Role = NodeRoleController
+		case hasKey(tags, nodePoolNameTagKey):
+			node.Role = NodeRoleWorker
+			node.NodePool = tags[nodePoolNameTagKey]
+		default:
+			continue
+		}
+
+		fp, err := l.userDataFingerprint(node.InstanceID)
+		if err != nil {
+			return nil, err
+		}
+		node.Fingerprint = fp
+
+		if fps, ok := expected[launchSourceOf(tags)]; ok && fp != "" && !fps[fp] {
+			node.Outdated = true
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.Role != b.Role {
+			return nodeRoleOrder(a.Role) < nodeRoleOrder(b.Role)
+		}
+		if a.NodePool != b.NodePool {
+			return a.NodePool < b.NodePool
+		}
+		return a.InstanceID < b.InstanceID
+	})
+
+	return nodes, nil
+}
+
+func nodeRoleOrder(role string) int {
+	switch role {
+	case NodeRoleEtcd:
+		return 0
+	case NodeRoleController:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// describeInstances returns the non-terminated instances tagged with the cluster name and created by the cluster's stacks.
+// Spot fleet instances aren't tagged with stack names but tag themselves with the cluster name on boot
+func (l nodeLister) describeInstances() ([]*ec2.Instance, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", l.clusterName))},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"}),
+			},
+		},
+	}
+
+	instances := []*ec2.Instance{}
+	for {
+		out, err := l.ec2Svc.DescribeInstances(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %v", err)
+		}
+		for _, r := range out.Reservations {
+			for _, i := range r.Instances {
+				stack, ok := instanceTags(i)[stackNameTagKey]
+				if ok && stack != l.stackName && !strings.HasPrefix(stack, l.stackName+"-") {
+					continue
+				}
+				instances = append(instances, i)
+			}
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	return instances, nil
+}
+
+// describeEtcdVolumes returns the tags of the etcd data volumes attached to the instances, keyed by volume ID
+func (l nodeLister) describeEtcdVolumes(instances []*ec2.Instance) (map[string]map[string]string, error) {
+	ids := []*string{}
+	for _, i := range instances {
+		if instanceTags(i)[roleTagKey] != NodeRoleEtcd {
+			continue
+		}
+		for _, m := range i.BlockDeviceMappings {
+			if m.Ebs != nil {
+				ids = append(ids, m.Ebs.VolumeId)
+			}
+		}
+	}
+
+	volumes := map[string]map[string]string{}
+	if len(ids) == 0 {
+		return volumes, nil
+	}
+
+	out, err := l.ec2Svc.DescribeVolumes(&ec2.DescribeVolumesInput{
+		VolumeIds: ids,
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(l.etcd.NameTagKey())},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe etcd volumes: %v", err)
+	}
+	for _, v := range out.Volumes {
+		tags := map[string]string{}
+		for _, t := range v.Tags {
+			tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+		volumes[aws.StringValue(v.VolumeId)] = tags
+	}
+	return volumes, nil
+}
+
+// expectedFingerprints returns the fingerprints of the userdata in the current launch configurations of auto-scaling groups
+// and the launch specifications of spot fleets, keyed by the source returned by launchSourceOf
+func (l nodeLister) expectedFingerprints(instances []*ec2.Instance) (map[string]map[string]bool, error) {
+	asgNames := map[string]bool{}
+	fleetIDs := map[string]bool{}
+	for _, i := range instances {
+		tags := instanceTags(i)
+		if n, ok := tags[asgNameTagKey]; ok {
+			asgNames[n] = true
+		} else if id, ok := tags[spotFleetRequestIDKey]; ok {
+			fleetIDs[id] = true
+		}
+	}
+
+	expected := map[string]map[string]bool{}
+
+	if len(asgNames) > 0 {
+		lcNames := map[string][]string{}
+		input := &autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: aws.StringSlice(sortedKeys(asgNames))}
+		for {
+			out, err := l.asSvc.DescribeAutoScalingGroups(input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe auto-scaling groups: %v", err)
+			}
+			for _, g := range out.AutoScalingGroups {
+				lc := aws.StringValue(g.LaunchConfigurationName)
+				lcNames[lc] = append(lcNames[lc], aws.StringValue(g.AutoScalingGroupName))
+			}
+			if out.NextToken == nil {
+				break
+			}
+			input.NextToken = out.NextToken
+		}
+
+		names := []string{}
+		for n := range lcNames {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		lcInput := &autoscaling.DescribeLaunchConfigurationsInput{LaunchConfigurationNames: aws.StringSlice(names)}
+		for {
+			out, err := l.asSvc.DescribeLaunchConfigurations(lcInput)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe launch configurations: %v", err)
+			}
+			for _, lc := range out.LaunchConfigurations {
+				for _, g := range lcNames[aws.StringValue(lc.LaunchConfigurationName)] {
+					expected["asg:"+g] = map[string]bool{userDataFingerprint(aws.StringValue(lc.UserData)): true}
+				}
+			}
+			if out.NextToken == nil {
+				break
+			}
+			lcInput.NextToken = out.NextToken
+		}
+	}
+
+	if len(fleetIDs) > 0 {
+		out, err := l.ec2Svc.DescribeSpotFleetRequests(&ec2.DescribeSpotFleetRequestsInput{SpotFleetRequestIds: aws.StringSlice(sortedKeys(fleetIDs))})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe spot fleet requests: %v", err)
+		}
+		for _, r := range out.SpotFleetRequestConfigs {
+			fps := map[string]bool{}
+			for _, s := range r.SpotFleetRequestConfig.LaunchSpecifications {
+				fps[userDataFingerprint(aws.StringValue(s.UserData))] = true
+			}
+			expected["fleet:"+aws.StringValue(r.SpotFleetRequestId)] = fps
+		}
+	}
+
+	return expected, nil
+}
+
+func (l nodeLister) userDataFingerprint(instanceID string) (string, error) {
+	out, err := l.ec2Svc.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+		Attribute:  aws.String(ec2.InstanceAttributeNameUserData),
+		InstanceId: aws.String(instanceID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe userdata of %s: %v", instanceID, err)
+	}
+	if out.UserData == nil {
+		return "", nil
+	}
+	return userDataFingerprint(aws.StringValue(out.UserData.Value)), nil
+}
+
+// userDataFingerprint returns the fingerprint of base64-encoded userdata
+func userDataFingerprint(userData string) string {
+	if userData == "" {
+		return ""
+	}
+	return fingerprint.SHA256(userData)[:fingerprintLength]
+}
+
+// launchSourceOf returns the auto-scaling group or the spot fleet which launched the instance with the tags
+func launchSourceOf(tags map[string]string) string {
+	if n, ok := tags[asgNameTagKey]; ok {
+		return "asg:" + n
+	}
+	if id, ok := tags[spotFleetRequestIDKey]; ok {
+		return "fleet:" + id
+	}
+	return ""
+}
+
+func instanceTags(i *ec2.Instance) map[string]string {
+	tags := map[string]string{}
+	for _, t := range i.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags
+}
+
+func hasKey(m map[string]string, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
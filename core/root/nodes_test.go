@@ -0,0 +1,177 @@
+package root
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+type fakeNodesEC2Service struct {
+	instances []*ec2.Instance
+	userData  map[string]string
+	volumes   []*ec2.Volume
+	fleets    []*ec2.SpotFleetRequestConfig
+}
+
+func (s fakeNodesEC2Service) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: s.instances}}}, nil
+}
+
+func (s fakeNodesEC2Service) DescribeInstanceAttribute(input *ec2.DescribeInstanceAttributeInput) (*ec2.DescribeInstanceAttributeOutput, error) {
+	return &ec2.DescribeInstanceAttributeOutput{
+		InstanceId: input.InstanceId,
+		UserData:   &ec2.AttributeValue{Value: aws.String(s.userData[*input.InstanceId])},
+	}, nil
+}
+
+func (s fakeNodesEC2Service) DescribeVolumes(input *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	return &ec2.DescribeVolumesOutput{Volumes: s.volumes}, nil
+}
+
+func (s fakeNodesEC2Service) DescribeSpotFleetRequests(input *ec2.DescribeSpotFleetRequestsInput) (*ec2.DescribeSpotFleetRequestsOutput, error) {
+	return &ec2.DescribeSpotFleetRequestsOutput{SpotFleetRequestConfigs: s.fleets}, nil
+}
+
+type fakeNodesAutoScalingService struct {
+	groups        []*autoscaling.Group
+	launchConfigs []*autoscaling.LaunchConfiguration
+}
+
+func (s fakeNodesAutoScalingService) DescribeAutoScalingGroups(input *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: s.groups}, nil
+}
+
+func (s fakeNodesAutoScalingService) DescribeLaunchConfigurations(input *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	return &autoscaling.DescribeLaunchConfigurationsOutput{LaunchConfigurations: s.launchConfigs}, nil
+}
+
+func testInstance(id, az, instanceType, privateIP string, tags map[string]string) *ec2.Instance {
+	i := &ec2.Instance{
+		InstanceId:       aws.String(id),
+		InstanceType:     aws.String(instanceType),
+		Placement:        &ec2.Placement{AvailabilityZone: aws.String(az)},
+		PrivateIpAddress: aws.String(privateIP),
+		State:            &ec2.InstanceState{Name: aws.String("running")},
+	}
+	for k, v := range tags {
+		i.Tags = append(i.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return i
+}
+
+func TestListNodes(t *testing.T) {
+	etcd := testInstance("i-etcd0", "us-west-1a", "t2.medium", "10.0.0.10", map[string]string{
+		roleTagKey:      "etcd",
+		stackNameTagKey: "mycluster-Etcd-ABC",
+		asgNameTagKey:   "etcd0-asg",
+	})
+	etcd.BlockDeviceMappings = []*ec2.InstanceBlockDeviceMapping{
+		{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+		{DeviceName: aws.String("/dev/xvdf"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-etcd0")}},
+	}
+
+	controller := testInstance("i-controller", "us-west-1b", "m4.large", "10.0.1.10", map[string]string{
+		masterRoleTagKey: "",
+		stackNameTagKey:  "mycluster-Controlplane-DEF",
+		asgNameTagKey:    "controller-asg",
+	})
+	controller.PublicIpAddress = aws.String("54.0.0.1")
+
+	worker := testInstance("i-worker", "us-west-1a", "c4.large", "10.0.2.10", map[string]string{
+		nodePoolNameTagKey: "pool1",
+		stackNameTagKey:    "mycluster-Pool1-GHI",
+		asgNameTagKey:      "pool1-asg",
+	})
+
+	spot := testInstance("i-spot", "us-west-1b", "c4.xlarge", "10.0.2.11", map[string]string{
+		nodePoolNameTagKey:    "spotfleet",
+		spotFleetRequestIDKey: "sfr-123",
+	})
+	spot.InstanceLifecycle = aws.String("spot")
+
+	otherCluster := testInstance("i-other", "us-west-1a", "t2.medium", "10.0.3.10", map[string]string{
+		nodePoolNameTagKey: "pool1",
+		stackNameTagKey:    "mycluster2-Pool1-JKL",
+	})
+
+	ec2Svc := fakeNodesEC2Service{
+		instances: []*ec2.Instance{worker, spot, otherCluster, controller, etcd},
+		userData: map[string]string{
+			"i-etcd0":      "etcd-v1",
+			"i-controller": "controller-v1",
+			"i-worker":     "worker-v1",
+			"i-spot":       "spot-v2",
+		},
+		volumes: []*ec2.Volume{
+			{
+				VolumeId: aws.String("vol-etcd0"),
+				Tags: []*ec2.Tag{
+					{Key: aws.String("kube-aws:etcd:name"), Value: aws.String("etcd0")},
+					{Key: aws.String("kube-aws:etcd:advertised-hostname"), Value: aws.String("etcd0.internal")},
+				},
+			},
+		},
+		fleets: []*ec2.SpotFleetRequestConfig{
+			{
+				SpotFleetRequestId: aws.String("sfr-123"),
+				SpotFleetRequestConfig: &ec2.SpotFleetRequestConfigData{
+					LaunchSpecifications: []*ec2.SpotFleetLaunchSpecification{
+						{UserData: aws.String("spot-v2")},
+						{UserData: aws.String("spot-v2")},
+					},
+				},
+			},
+		},
+	}
+
+	asSvc := fakeNodesAutoScalingService{
+		groups: []*autoscaling.Group{
+			{AutoScalingGroupName: aws.String("etcd0-asg"), LaunchConfigurationName: aws.String("etcd-lc")},
+			{AutoScalingGroupName: aws.String("controller-asg"), LaunchConfigurationName: aws.String("controller-lc")},
+			{AutoScalingGroupName: aws.String("pool1-asg"), LaunchConfigurationName: aws.String("pool1-lc")},
+		},
+		launchConfigs: []*autoscaling.LaunchConfiguration{
+			{LaunchConfigurationName: aws.String("etcd-lc"), UserData: aws.String("etcd-v1")},
+			{LaunchConfigurationName: aws.String("controller-lc"), UserData: aws.String("controller-v1")},
+			{LaunchConfigurationName: aws.String("pool1-lc"), UserData: aws.String("worker-v2")},
+		},
+	}
+
+	lister := nodeLister{
+		clusterName: "mycluster",
+		stackName:   "mycluster",
+		etcd:        model.Etcd{},
+		ec2Svc:      ec2Svc,
+		asSvc:       asSvc,
+	}
+
+	nodes, err := lister.list()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Nodes{
+		{InstanceID: "i-etcd0", Role: "etcd", NodePool: "etcd0", AdvertisedFQDN: "etcd0.internal", AvailabilityZone: "us-west-1a", InstanceType: "t2.medium", PrivateIP: "10.0.0.10", Lifecycle: "on-demand", State: "running", Fingerprint: userDataFingerprint("etcd-v1")},
+		{InstanceID: "i-controller", Role: "controller", AvailabilityZone: "us-west-1b", InstanceType: "m4.large", PrivateIP: "10.0.1.10", PublicIP: "54.0.0.1", Lifecycle: "on-demand", State: "running", Fingerprint: userDataFingerprint("controller-v1")},
+		{InstanceID: "i-worker", Role: "worker", NodePool: "pool1", AvailabilityZone: "us-west-1a", InstanceType: "c4.large", PrivateIP: "10.0.2.10", Lifecycle: "on-demand", State: "running", Fingerprint: userDataFingerprint("worker-v1"), Outdated: true},
+		{InstanceID: "i-spot", Role: "worker", NodePool: "spotfleet", AvailabilityZone: "us-west-1b", InstanceType: "c4.xlarge", PrivateIP: "10.0.2.11", Lifecycle: "spot", State: "running", Fingerprint: userDataFingerprint("spot-v2")},
+	}
+
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("unexpected nodes:\nexpected: %+v\nactual:   %+v", expected, nodes)
+	}
+
+	if outdated := nodes.Outdated(); len(outdated) != 1 || outdated[0].InstanceID != "i-worker" {
+		t.Errorf("unexpected outdated nodes: %+v", outdated)
+	}
+
+	lines := strings.Split(strings.TrimSpace(nodes.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "INSTANCE ID") || !strings.Contains(lines[3], "yes") || !strings.Contains(lines[2], "54.0.0.1") {
+		t.Errorf("unexpected table:\n%s", nodes.String())
+	}
+}
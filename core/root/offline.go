@@ -0,0 +1,88 @@
+package root
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/cfnstack"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
+
+// RenderAssetsOffline renders every stack template and userdata part of the targeted stacks into dir without calling AWS APIs.
+// The resulting directory tree mirrors the layout of the S3 keys the assets are uploaded to by `kube-aws up` and `kube-aws update`.
+// Userdata parts are validated with coreos-cloudinit while being rendered.
+func (c clusterImpl) RenderAssetsOffline(dir string, opts ...OperationTargets) ([]model.Asset, error) {
+	targets := c.operationTargetsFromUserInput(opts)
+
+	// The root stack template is always rendered from scratch, rather than patching the one currently deployed,
+	// so that no API call is required
+	all, err := c.generateAssets(c.allOperationTargets())
+	if err != nil {
+		return nil, err
+	}
+
+	assets := []model.Asset{}
+	for _, a := range all.AsMap() {
+		if a.ID.StackName != c.stackName() && !targets.includeStack(a.ID.StackName) {
+			continue
+		}
+		assets = append(assets, a)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Key < assets[j].Key })
+
+	for _, a := range assets {
+		path := filepath.Join(dir, filepath.FromSlash(a.Key))
+		d := filepath.Dir(path)
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create directory \"%s\": %v", d, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(a.Content), 0600); err != nil {
+			return nil, fmt.Errorf("Error writing %s : %v", path, err)
+		}
+	}
+
+	return assets, nil
+}
+
+// ValidateStackOffline renders the assets into dir and validates them with local checks instead of uploading them to S3
+// and calling the CloudFormation API
+func (c clusterImpl) ValidateStackOffline(dir string, opts ...OperationTargets) (string, error) {
+	reports := []string{}
+
+	assets, err := c.RenderAssetsOffline(dir, opts...)
+	if err != nil {
+		return "", err
+	}
+	reports = append(reports, fmt.Sprintf("rendered %d assets into %s", len(assets), dir))
+
+	errs := []string{}
+	for _, a := range assets {
+		name := a.ID.StackName
+		if strings.HasPrefix(a.ID.Filename, cfnstack.EXTRAS_TEMPLATE_ASSET_NAME+"-") {
+			name += "/" + cfnstack.EXTRAS_STACK_NAME
+		} else if a.ID.Filename != REMOTE_STACK_TEMPLATE_FILENAME {
+			continue
+		}
+		if err := cfnstack.ValidateTemplateLocally(a.Content); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		reports = append(reports, fmt.Sprintf("%s: stack template is valid (%d bytes)", name, len(a.Content)))
+	}
+
+	if targets := c.operationTargetsFromUserInput(opts); targets.IncludeControlPlane() {
+		if err := c.controlPlane.ValidateCerts(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", OperationTargetControlPlane, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return strings.Join(reports, "\n"), fmt.Errorf("failed to validate stacks:\n%s", strings.Join(errs, "\n"))
+	}
+
+	return strings.Join(reports, "\n"), nil
+}
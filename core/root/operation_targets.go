@@ -69,6 +69,17 @@ func (ts OperationTargets) IncludeEtcd() bool {
 	return false
 }
 
+// includeStack returns true when the nested stack named stackName is targeted.
+// It works for node pools too, as the name of a node pool stack is the node pool name
+func (ts OperationTargets) includeStack(stackName string) bool {
+	for _, t := range ts {
+		if t == stackName {
+			return true
+		}
+	}
+	return false
+}
+
 func (ts OperationTargets) IsAll() bool {
 	for _, t := range ts {
 		if t == OperationTargetAll {
@@ -1,6 +1,9 @@
 package root
 
-import "github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+import (
+	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+	"github.com/kubernetes-incubator/kube-aws/model"
+)
 
 type options struct {
 	AssetsDir                         string
@@ -14,6 +17,10 @@ type options struct {
 	NodePoolStackTemplateTmplFile     string
 	SkipWait                          bool
 	PrettyPrint                       bool
+	// Offline renders the assets without encrypting them with KMS, so that no AWS API is called
+	Offline bool
+	// AWSCredentials overrides the awsCredentials in cluster.yaml, e.g. with the ones given via command-line flags
+	AWSCredentials model.AWSCredentials
 }
 
 func NewOptions(prettyPrint bool, skipWait bool) options {
@@ -0,0 +1,225 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/kubernetes-incubator/kube-aws/cfnstack"
+	"github.com/kubernetes-incubator/kube-aws/fingerprint"
+	"github.com/kubernetes-incubator/kube-aws/progress"
+)
+
+// Plan is a record of an update which can be reviewed and then applied later with `kube-aws apply`
+type Plan struct {
+	ClusterName string   `json:"clusterName"`
+	Targets     []string `json:"targets"`
+	// RootStackTemplateURL is the URL of the rendered root stack template the update is planned with
+	RootStackTemplateURL string `json:"rootStackTemplateURL"`
+	// RootStackLastUpdatedTime is the time the deployed root stack was last updated(or created) when the plan was made.
+	// It is used to detect changes made to the stack after the plan was made
+	RootStackLastUpdatedTime time.Time          `json:"rootStackLastUpdatedTime"`
+	Assets                   []PlannedAsset     `json:"assets"`
+	ChangeSets               []PlannedChangeSet `json:"changeSets"`
+}
+
+type PlannedAsset struct {
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// PlannedChangeSet is a change set created while planning.
+// Only the one for the root stack is executed, while those for the nested stacks are for previewing the changes to them
+type PlannedChangeSet struct {
+	// Name is the name of the kube-aws sub-stack. Empty for the root stack
+	Name        string `json:"name,omitempty"`
+	StackID     string `json:"stackId"`
+	ChangeSetID string `json:"changeSetId"`
+}
+
+type s3ObjectGetterService interface {
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+func PlanFromFile(planPath string) (*Plan, error) {
+	data, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %v", planPath, err)
+	}
+	if len(plan.ChangeSets) == 0 {
+		return nil, fmt.Errorf("invalid plan %s: no change sets found", planPath)
+	}
+	return plan, nil
+}
+
+func (p *Plan) WriteToFile(planPath string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %v", err)
+	}
+	if err := ioutil.WriteFile(planPath, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("Error writing %s : %v", planPath, err)
+	}
+	return nil
+}
+
+// verifyAssets checks that every asset uploaded while planning is still in S3 without modifications
+func (p *Plan) verifyAssets(s3Svc s3ObjectGetterService) error {
+	for _, a := range p.Assets {
+		resp, err := s3Svc.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(a.Bucket),
+			Key:    aws.String(a.Key),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get asset s3://%s/%s: %v", a.Bucket, a.Key, err)
+		}
+		content, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read asset s3://%s/%s: %v", a.Bucket, a.Key, err)
+		}
+		if fp := fingerprint.SHA256(string(content)); fp != a.Fingerprint {
+			return fmt.Errorf("asset s3://%s/%s has been modified since the plan was made: expected fingerprint %s but was %s", a.Bucket, a.Key, a.Fingerprint, fp)
+		}
+	}
+	return nil
+}
+
+// Plan uploads and validates assets, and creates change sets for updating the cluster without executing them.
+// The first change set is for the root stack, which is the only one executed by Apply.
+// The others are preview-only change sets showing the changes the root change set makes to the nested stacks
+func (c clusterImpl) Plan(targets OperationTargets) (*Plan, cfnstack.ChangeSets, error) {
+	cfSvc := c.clients.CloudFormation
+
+	lastUpdatedTime, err := c.getCurrentRootStackLastUpdatedTime()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts := c.operationTargetsFromUserInput([]OperationTargets{targets})
+	changeSetName := fmt.Sprintf("kube-aws-plan-%d", time.Now().Unix())
+
+	assets, err := c.generateAndUploadAssets(ts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The templates are validated where they are uploaded for the change sets, instead of being uploaded again by ValidateStack
+	if _, err := c.validateUploadedStacks(assets); err != nil {
+		return nil, nil, err
+	}
+
+	changeSets, err := c.createChangeSetsForAssets(cfSvc, assets, ts, changeSetName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rootStackTemplateURL, err := c.extractRootStackTemplateURL(assets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &Plan{
+		ClusterName:              c.controlPlane.ClusterName,
+		Targets:                  ts,
+		RootStackTemplateURL:     rootStackTemplateURL,
+		RootStackLastUpdatedTime: lastUpdatedTime,
+		Assets:                   []PlannedAsset{},
+		ChangeSets:               []PlannedChangeSet{},
+	}
+
+	for _, a := range assets.AsMap() {
+		plan.Assets = append(plan.Assets, PlannedAsset{
+			Bucket:      a.Bucket,
+			Key:         a.Key,
+			Fingerprint: fingerprint.SHA256(a.Content),
+		})
+	}
+	sort.Slice(plan.Assets, func(i, j int) bool { return plan.Assets[i].Key < plan.Assets[j].Key })
+
+	for _, s := range changeSets {
+		plan.ChangeSets = append(plan.ChangeSets, PlannedChangeSet{
+			Name:        s.Name,
+			StackID:     s.StackID,
+			ChangeSetID: s.ChangeSetID,
+		})
+	}
+
+	return plan, changeSets, nil
+}
+
+// Apply executes the update recorded in the plan.
+// It refuses to apply the plan when the root stack or any of the uploaded assets have changed since the plan was made.
+func (c clusterImpl) Apply(plan *Plan) error {
+	if plan.ClusterName != c.controlPlane.ClusterName {
+		return fmt.Errorf("the plan is for the cluster %s but the cluster is %s", plan.ClusterName, c.controlPlane.ClusterName)
+	}
+
+	lastUpdatedTime, err := c.getCurrentRootStackLastUpdatedTime()
+	if err != nil {
+		return err
+	}
+	if !lastUpdatedTime.Equal(plan.RootStackLastUpdatedTime) {
+		return fmt.Errorf("the stack %s has been updated at %s since the plan was made. Please make a new plan", c.stackName(), lastUpdatedTime)
+	}
+
+	if err := plan.verifyAssets(c.clients.S3); err != nil {
+		return err
+	}
+
+	cfSvc := c.clients.CloudFormation
+
+	// Only the change set for the root stack is executed, which updates the nested stacks as well.
+	// Those for the nested stacks are preview-only: they are recorded for reviewing the changes to each nested stack, and are never executed
+	rootChangeSet := plan.ChangeSets[0]
+	if rootChangeSet.Name != "" {
+		return fmt.Errorf("[bug] the first change set in the plan must be for the root stack but was for %s", rootChangeSet.Name)
+	}
+
+	q := make(chan struct{})
+	defer close(q)
+
+	c.startStreaming(cfSvc, q)
+
+	if err := c.stackProvisioner().ExecuteChangeSetAndWait(cfSvc, rootChangeSet.ChangeSetID); err != nil {
+		return err
+	}
+
+	// The preview-only change sets for the nested stacks can't be executed once the root change set is, so they are deleted.
+	// Failing to delete them leaves nothing but stale change sets, which doesn't fail the update already done
+	nested := cfnstack.ChangeSets{}
+	for _, s := range plan.ChangeSets[1:] {
+		nested = append(nested, &cfnstack.ChangeSet{Name: s.Name, StackID: s.StackID, ChangeSetID: s.ChangeSetID})
+	}
+	if err := c.stackProvisioner().DeleteChangeSets(cfSvc, nested); err != nil {
+		progress.Printf("WARN: failed to clean up change sets for nested stacks: %v\n", err)
+	}
+
+	return nil
+}
+
+func (c clusterImpl) getCurrentRootStackLastUpdatedTime() (time.Time, error) {
+	cfnSvc := c.clients.CloudFormation
+	resp, err := cfnSvc.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(c.stackName())})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to describe stack %s: %v", c.stackName(), err)
+	}
+	if len(resp.Stacks) == 0 {
+		return time.Time{}, fmt.Errorf("stack not found: %s", c.stackName())
+	}
+	stack := resp.Stacks[0]
+	if stack.LastUpdatedTime != nil {
+		return *stack.LastUpdatedTime, nil
+	}
+	return aws.TimeValue(stack.CreationTime), nil
+}
@@ -0,0 +1,80 @@
+package root
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/kubernetes-incubator/kube-aws/fingerprint"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+type dummyS3ObjectGetterService struct {
+	objects map[string]string
+}
+
+func (s dummyS3ObjectGetterService) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	content, ok := s.objects[*input.Key]
+	if !ok {
+		return nil, fmt.Errorf("NoSuchKey: %s", *input.Key)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(content))}, nil
+}
+
+func TestPlanFile(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		planPath := filepath.Join(dir, "plan.json")
+		plan := &Plan{
+			ClusterName:              "mycluster",
+			Targets:                  []string{"control-plane"},
+			RootStackTemplateURL:     "https://s3.amazonaws.com/mybucket/mycluster/stack.json",
+			RootStackLastUpdatedTime: time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC),
+			Assets:                   []PlannedAsset{{Bucket: "mybucket", Key: "mycluster/stack.json", Fingerprint: "abc"}},
+			ChangeSets:               []PlannedChangeSet{{StackID: "mycluster", ChangeSetID: "arn:aws:cloudformation:us-west-1:123456789012:changeSet/kube-aws-plan-1/xyz"}},
+		}
+		if err := plan.WriteToFile(planPath); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		loaded, err := PlanFromFile(planPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !loaded.RootStackLastUpdatedTime.Equal(plan.RootStackLastUpdatedTime) {
+			t.Errorf("unexpected last updated time: expected %v but was %v", plan.RootStackLastUpdatedTime, loaded.RootStackLastUpdatedTime)
+		}
+		if loaded.ChangeSets[0].ChangeSetID != plan.ChangeSets[0].ChangeSetID {
+			t.Errorf("unexpected change set id: %s", loaded.ChangeSets[0].ChangeSetID)
+		}
+
+		if err := ioutil.WriteFile(planPath, []byte(`{"clusterName":"mycluster"}`), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := PlanFromFile(planPath); err == nil {
+			t.Error("expected an error for the plan without change sets but got none")
+		}
+	})
+}
+
+func TestPlanVerifyAssets(t *testing.T) {
+	content := `{"Resources":{}}`
+	plan := &Plan{
+		Assets: []PlannedAsset{{Bucket: "mybucket", Key: "mycluster/stack.json", Fingerprint: fingerprint.SHA256(content)}},
+	}
+
+	if err := plan.verifyAssets(dummyS3ObjectGetterService{objects: map[string]string{"mycluster/stack.json": content}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := plan.verifyAssets(dummyS3ObjectGetterService{objects: map[string]string{"mycluster/stack.json": `{"Resources":{"Foo":{}}}`}}); err == nil {
+		t.Error("expected an error for the modified asset but got none")
+	}
+
+	if err := plan.verifyAssets(dummyS3ObjectGetterService{objects: map[string]string{}}); err == nil {
+		t.Error("expected an error for the missing asset but got none")
+	}
+}
@@ -0,0 +1,26 @@
+package root
+
+import (
+	"github.com/kubernetes-incubator/kube-aws/core/controlplane/config"
+	"github.com/kubernetes-incubator/kube-aws/tlscerts"
+)
+
+// ValidationReport is the result of `kube-aws validate` in a machine-readable form
+type ValidationReport struct {
+	Valid   bool     `json:"valid"`
+	Offline bool     `json:"offline"`
+	Targets []string `json:"targets"`
+	// Report is the concatenation of reports for the root stack and each nested stack
+	Report string `json:"report"`
+	// CredentialsProblems are the expiring, expired or otherwise invalid certificates in the credentials directory
+	CredentialsProblems []string `json:"credentialsProblems,omitempty"`
+	Error               string   `json:"error,omitempty"`
+}
+
+// CertificateFile is a file in the credentials directory and certificates contained in it
+type CertificateFile struct {
+	File         string                `json:"file"`
+	Certificates tlscerts.Certificates `json:"certificates"`
+	// Health is the result of checking each certificate in the file
+	Health []config.CertificateHealth `json:"health,omitempty"`
+}
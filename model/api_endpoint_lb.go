@@ -32,7 +32,7 @@ type APIEndpointLB struct {
 	// SecurityGroupIds represents SGs associated to this LB. Required when APIAccessAllowedSourceCIDRs is explicitly set to empty
 	SecurityGroupIds []string `yaml:"securityGroupIds"`
 	// Load balancer type. It is 'classic' by default, but can be changed to 'network'
-	Type *string `yaml:"type,omitempty"`
+	Type *string `yaml:"type,omitempty" enum:"classic,network"`
 }
 
 // UnmarshalYAML unmarshals YAML data to an APIEndpointLB object with defaults
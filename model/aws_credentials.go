@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	minAssumedRoleSessionDuration = 15 * time.Minute
+	maxAssumedRoleSessionDuration = 12 * time.Hour
+)
+
+// AWSCredentials is how kube-aws obtains the credentials to call AWS APIs with, e.g. to deploy to another account by assuming a role in it.
+// The default credential chain of the AWS SDK is used when omitted
+type AWSCredentials struct {
+	// Profile is the profile in the AWS shared config and credentials files, which defaults to AWS_PROFILE
+	Profile string `yaml:"profile,omitempty"`
+	// RoleARN is the IAM role assumed with the credentials of the profile
+	RoleARN string `yaml:"roleArn,omitempty"`
+	// ExternalID is passed on assuming the role, when required by the trust policy of the role
+	ExternalID string `yaml:"externalId,omitempty"`
+	// SessionDuration is how long the credentials of the assumed role are valid for, e.g. `1h`. Defaults to 15 minutes
+	SessionDuration string `yaml:"sessionDuration,omitempty"`
+}
+
+// WithOverrides returns the credentials with the settings in the overrides, e.g. given via command-line flags, taking precedence
+func (c AWSCredentials) WithOverrides(overrides AWSCredentials) AWSCredentials {
+	if overrides.Profile != "" {
+		c.Profile = overrides.Profile
+	}
+	if overrides.RoleARN != "" {
+		c.RoleARN = overrides.RoleARN
+	}
+	if overrides.ExternalID != "" {
+		c.ExternalID = overrides.ExternalID
+	}
+	if overrides.SessionDuration != "" {
+		c.SessionDuration = overrides.SessionDuration
+	}
+	return c
+}
+
+// Duration returns the duration of the session of the assumed role, or zero when omitted
+func (c AWSCredentials) Duration() (time.Duration, error) {
+	if c.SessionDuration == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.SessionDuration)
+	if err != nil {
+		return 0, fmt.Errorf("awsCredentials.sessionDuration must be a duration like 1h but was %q", c.SessionDuration)
+	}
+	return d, nil
+}
+
+func (c AWSCredentials) Validate() error {
+	if c.RoleARN == "" {
+		if c.ExternalID != "" || c.SessionDuration != "" {
+			return errors.New("awsCredentials.externalId and awsCredentials.sessionDuration can only be specified with awsCredentials.roleArn")
+		}
+		return nil
+	}
+	if !strings.HasPrefix(c.RoleARN, "arn:") || !strings.Contains(c.RoleARN, ":role/") {
+		return fmt.Errorf("awsCredentials.roleArn must be the ARN of an IAM role but was %q", c.RoleARN)
+	}
+	d, err := c.Duration()
+	if err != nil {
+		return err
+	}
+	if d != 0 && (d < minAssumedRoleSessionDuration || d > maxAssumedRoleSessionDuration) {
+		return fmt.Errorf("awsCredentials.sessionDuration must be between %v and %v but was %v", minAssumedRoleSessionDuration, maxAssumedRoleSessionDuration, d)
+	}
+	return nil
+}
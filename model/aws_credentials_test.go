@@ -0,0 +1,63 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAWSCredentialsWithOverrides(t *testing.T) {
+	c := AWSCredentials{Profile: "default", RoleARN: "arn:aws:iam::123456789012:role/deployer", ExternalID: "from-config"}
+
+	actual := c.WithOverrides(AWSCredentials{RoleARN: "arn:aws:iam::210987654321:role/deployer", SessionDuration: "2h"})
+	expected := AWSCredentials{
+		Profile:         "default",
+		RoleARN:         "arn:aws:iam::210987654321:role/deployer",
+		ExternalID:      "from-config",
+		SessionDuration: "2h",
+	}
+	if actual != expected {
+		t.Errorf("unexpected credentials: expected %+v but was %+v", expected, actual)
+	}
+
+	if actual := c.WithOverrides(AWSCredentials{}); actual != c {
+		t.Errorf("expected the credentials to be unchanged without overrides but was %+v", actual)
+	}
+
+	d, err := expected.Duration()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d != 2*time.Hour {
+		t.Errorf("unexpected session duration: %v", d)
+	}
+}
+
+func TestAWSCredentialsValidate(t *testing.T) {
+	testCases := []struct {
+		credentials AWSCredentials
+		err         string
+	}{
+		{AWSCredentials{}, ""},
+		{AWSCredentials{Profile: "prod"}, ""},
+		{AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/deployer", ExternalID: "abc", SessionDuration: "15m"}, ""},
+		{AWSCredentials{RoleARN: "arn:aws-cn:iam::123456789012:role/path/to/deployer", SessionDuration: "12h"}, ""},
+		{AWSCredentials{ExternalID: "abc"}, "can only be specified with awsCredentials.roleArn"},
+		{AWSCredentials{SessionDuration: "1h"}, "can only be specified with awsCredentials.roleArn"},
+		{AWSCredentials{RoleARN: "deployer"}, "awsCredentials.roleArn must be the ARN of an IAM role"},
+		{AWSCredentials{RoleARN: "arn:aws:iam::123456789012:user/deployer"}, "awsCredentials.roleArn must be the ARN of an IAM role"},
+		{AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/deployer", SessionDuration: "1 hour"}, "must be a duration like 1h"},
+		{AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/deployer", SessionDuration: "5m"}, "must be between 15m0s and 12h0m0s"},
+		{AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/deployer", SessionDuration: "24h"}, "must be between 15m0s and 12h0m0s"},
+	}
+
+	for _, tc := range testCases {
+		err := tc.credentials.Validate()
+		if tc.err == "" && err != nil {
+			t.Errorf("unexpected error for %+v: %v", tc.credentials, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("expected error containing %q for %+v but was %v", tc.err, tc.credentials, err)
+		}
+	}
+}
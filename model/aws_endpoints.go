@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// AWSEndpoints overrides the endpoints of the AWS APIs called by kube-aws, e.g. to use LocalStack or VPC endpoints.
+// The default endpoint for the region is used for each API omitted
+type AWSEndpoints struct {
+	CloudFormation string `yaml:"cloudFormation,omitempty"`
+	CloudWatchLogs string `yaml:"cloudWatchLogs,omitempty"`
+	EC2            string `yaml:"ec2,omitempty"`
+	KMS            string `yaml:"kms,omitempty"`
+	Route53        string `yaml:"route53,omitempty"`
+	S3             string `yaml:"s3,omitempty"`
+	// S3ForcePathStyle makes S3 requests in the path style, e.g. `https://endpoint/bucket/key`, which is required by LocalStack
+	S3ForcePathStyle bool   `yaml:"s3ForcePathStyle,omitempty"`
+	STS              string `yaml:"sts,omitempty"`
+}
+
+const awsS3ForcePathStyleEnvVar = "KUBE_AWS_S3_FORCE_PATH_STYLE"
+
+type awsEndpoint struct {
+	key    string
+	envVar string
+	url    *string
+}
+
+// endpoints returns the endpoints by the ids of the services in the AWS SDK, along with their keys in cluster.yaml and environment variables
+func (e *AWSEndpoints) endpoints() map[string]awsEndpoint {
+	return map[string]awsEndpoint{
+		"cloudformation": {"cloudFormation", "KUBE_AWS_CLOUDFORMATION_ENDPOINT", &e.CloudFormation},
+		"logs":           {"cloudWatchLogs", "KUBE_AWS_CLOUDWATCH_LOGS_ENDPOINT", &e.CloudWatchLogs},
+		"ec2":            {"ec2", "KUBE_AWS_EC2_ENDPOINT", &e.EC2},
+		"kms":            {"kms", "KUBE_AWS_KMS_ENDPOINT", &e.KMS},
+		"route53":        {"route53", "KUBE_AWS_ROUTE53_ENDPOINT", &e.Route53},
+		"s3":             {"s3", "KUBE_AWS_S3_ENDPOINT", &e.S3},
+		"sts":            {"sts", "KUBE_AWS_STS_ENDPOINT", &e.STS},
+	}
+}
+
+// ForService returns the endpoint overridden for the service, identified by its id in the AWS SDK like `cloudformation`, or an empty string
+func (e AWSEndpoints) ForService(service string) string {
+	if endpoint, ok := e.endpoints()[service]; ok {
+		return *endpoint.url
+	}
+	return ""
+}
+
+// IsEmpty returns true when no endpoint is overridden
+func (e AWSEndpoints) IsEmpty() bool {
+	return e == AWSEndpoints{}
+}
+
+// WithEnvOverrides returns the endpoints overridden by the environment variables like `KUBE_AWS_CLOUDFORMATION_ENDPOINT`,
+// which take precedence over the ones in cluster.yaml
+func (e AWSEndpoints) WithEnvOverrides(lookupEnv func(string) (string, bool)) (AWSEndpoints, error) {
+	for _, endpoint := range e.endpoints() {
+		if v, ok := lookupEnv(endpoint.envVar); ok && v != "" {
+			*endpoint.url = v
+		}
+	}
+	if v, ok := lookupEnv(awsS3ForcePathStyleEnvVar); ok && v != "" {
+		forcePathStyle, err := strconv.ParseBool(v)
+		if err != nil {
+			return e, fmt.Errorf("%s must be either true or false but was %q", awsS3ForcePathStyleEnvVar, v)
+		}
+		e.S3ForcePathStyle = forcePathStyle
+	}
+	return e, nil
+}
+
+func (e AWSEndpoints) Validate() error {
+	endpoints := e.endpoints()
+	services := make([]string, 0, len(endpoints))
+	for service := range endpoints {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		endpoint := endpoints[service]
+		if *endpoint.url == "" {
+			continue
+		}
+		u, err := url.Parse(*endpoint.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("awsEndpoints.%s must be an http or https URL like http://localhost:4566 but was %q", endpoint.key, *endpoint.url)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAWSEndpointsWithEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"KUBE_AWS_CLOUDFORMATION_ENDPOINT": "http://localhost:4581",
+		"KUBE_AWS_S3_ENDPOINT":             "http://localhost:4572",
+		"KUBE_AWS_S3_FORCE_PATH_STYLE":     "true",
+		"KUBE_AWS_KMS_ENDPOINT":            "",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	e, err := AWSEndpoints{CloudFormation: "https://cfn.example.com", KMS: "https://kms.example.com", STS: "https://sts.example.com"}.WithEnvOverrides(lookupEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := AWSEndpoints{
+		CloudFormation:   "http://localhost:4581",
+		KMS:              "https://kms.example.com",
+		S3:               "http://localhost:4572",
+		S3ForcePathStyle: true,
+		STS:              "https://sts.example.com",
+	}
+	if e != expected {
+		t.Errorf("unexpected endpoints: expected %+v but was %+v", expected, e)
+	}
+
+	for service, url := range map[string]string{"cloudformation": "http://localhost:4581", "logs": "", "s3": "http://localhost:4572", "sts": "https://sts.example.com", "elasticloadbalancing": ""} {
+		if actual := e.ForService(service); actual != url {
+			t.Errorf("unexpected endpoint for %s: expected %q but was %q", service, url, actual)
+		}
+	}
+
+	env["KUBE_AWS_S3_FORCE_PATH_STYLE"] = "yes please"
+	if _, err := (AWSEndpoints{}).WithEnvOverrides(lookupEnv); err == nil || !strings.Contains(err.Error(), "KUBE_AWS_S3_FORCE_PATH_STYLE must be either true or false") {
+		t.Errorf("expected an error for the invalid KUBE_AWS_S3_FORCE_PATH_STYLE but was %v", err)
+	}
+}
+
+func TestAWSEndpointsValidate(t *testing.T) {
+	testCases := []struct {
+		endpoints AWSEndpoints
+		err       string
+	}{
+		{AWSEndpoints{}, ""},
+		{AWSEndpoints{CloudFormation: "http://localhost:4566", EC2: "https://vpce-0123-abcd.ec2.us-west-1.vpce.amazonaws.com"}, ""},
+		{AWSEndpoints{S3: "localhost:4566"}, "awsEndpoints.s3 must be an http or https URL"},
+		{AWSEndpoints{CloudWatchLogs: "ftp://localhost"}, "awsEndpoints.cloudWatchLogs must be an http or https URL"},
+	}
+
+	for _, tc := range testCases {
+		err := tc.endpoints.Validate()
+		if tc.err == "" && err != nil {
+			t.Errorf("unexpected error for %+v: %v", tc.endpoints, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("expected error containing %q for %+v but was %v", tc.err, tc.endpoints, err)
+		}
+	}
+}
@@ -5,6 +5,6 @@ type EC2Instance struct {
 	CreateTimeout string `yaml:"createTimeout,omitempty"`
 	InstanceType  string `yaml:"instanceType,omitempty"`
 	RootVolume    `yaml:"rootVolume,omitempty"`
-	Tenancy       string            `yaml:"tenancy,omitempty"`
+	Tenancy       string            `yaml:"tenancy,omitempty" enum:"default,dedicated"`
 	InstanceTags  map[string]string `yaml:"instanceTags,omitempty"`
 }
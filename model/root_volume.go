@@ -1,10 +1,14 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/progress"
+)
 
 type RootVolume struct {
 	Size        int    `yaml:"size,omitempty"`
-	Type        string `yaml:"type,omitempty"`
+	Type        string `yaml:"type,omitempty" enum:"standard,gp2,io1"`
 	IOPS        int    `yaml:"iops,omitempty"`
 	UnknownKeys `yaml:",inline"`
 }
@@ -43,16 +47,16 @@ func (v RootVolume) Validate() error {
 }
 
 func (v RootVolume) RootVolumeIOPS() int {
-	fmt.Println("WARN: RootVolumeIOPS is deprecated and will be removed in v0.9.7. Please use RootVolume.IOPS instead")
+	progress.Println("WARN: RootVolumeIOPS is deprecated and will be removed in v0.9.7. Please use RootVolume.IOPS instead")
 	return v.IOPS
 }
 
 func (v RootVolume) RootVolumeType() string {
-	fmt.Println("WARN: RootVolumeType is deprecated and will be removed in v0.9.7. Please use RootVolume.Type instead")
+	progress.Println("WARN: RootVolumeType is deprecated and will be removed in v0.9.7. Please use RootVolume.Type instead")
 	return v.Type
 }
 
 func (v RootVolume) RootVolumeSize() int {
-	fmt.Println("WARN: RootVolumeSize is deprecated and will be removed in v0.9.7. Please use RootVolume.Size instead")
+	progress.Println("WARN: RootVolumeSize is deprecated and will be removed in v0.9.7. Please use RootVolume.Size instead")
 	return v.Size
 }
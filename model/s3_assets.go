@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	S3AssetsEncryptionAES256 = "AES256"
+	S3AssetsEncryptionKMS    = "aws:kms"
+)
+
+// S3Assets is the settings applied to the assets like userdata and stack templates uploaded to S3
+type S3Assets struct {
+	// ServerSideEncryption is the server-side encryption of the uploaded objects. The bucket default applies when omitted
+	ServerSideEncryption string `yaml:"serverSideEncryption,omitempty" enum:"AES256,aws:kms"`
+	// KMSKeyARN is the KMS key used for `aws:kms` encryption. The AWS managed key for S3 is used when omitted
+	KMSKeyARN string `yaml:"kmsKeyArn,omitempty"`
+	// ACL is the canned ACL of the uploaded objects, e.g. `bucket-owner-full-control` for a bucket in another account
+	ACL string `yaml:"acl,omitempty" enum:"private,public-read,public-read-write,authenticated-read,aws-exec-read,bucket-owner-read,bucket-owner-full-control"`
+	// Tags are added to the uploaded objects
+	Tags map[string]string `yaml:"tags,omitempty"`
+}
+
+func (s S3Assets) Validate() error {
+	switch s.ServerSideEncryption {
+	case "", S3AssetsEncryptionAES256, S3AssetsEncryptionKMS:
+	default:
+		return fmt.Errorf("s3Assets.serverSideEncryption must be either %q or %q but was %q", S3AssetsEncryptionAES256, S3AssetsEncryptionKMS, s.ServerSideEncryption)
+	}
+	if s.KMSKeyARN != "" && s.ServerSideEncryption != S3AssetsEncryptionKMS {
+		return fmt.Errorf("s3Assets.kmsKeyArn can only be specified with s3Assets.serverSideEncryption %q", S3AssetsEncryptionKMS)
+	}
+	if s.KMSKeyARN != "" && !strings.HasPrefix(s.KMSKeyARN, "arn:") {
+		return fmt.Errorf("s3Assets.kmsKeyArn must be an ARN but was %q", s.KMSKeyARN)
+	}
+	if len(s.Tags) > 10 {
+		return fmt.Errorf("s3Assets.tags can't contain more than 10 tags but contained %d", len(s.Tags))
+	}
+	return nil
+}
+
+// HasKMSKey returns true when the assets are encrypted with a customer managed KMS key, which nodes need permissions to decrypt userdata with
+func (s S3Assets) HasKMSKey() bool {
+	return s.ServerSideEncryption == S3AssetsEncryptionKMS && s.KMSKeyARN != ""
+}
+
+// Tagging returns the tags in the URL-encoded form of the `x-amz-tagging` header, or an empty string when there are no tags
+func (s S3Assets) Tagging() string {
+	keys := make([]string, 0, len(s.Tags))
+	for k := range s.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, url.QueryEscape(k)+"="+url.QueryEscape(s.Tags[k]))
+	}
+	return strings.Join(values, "&")
+}
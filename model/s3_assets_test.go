@@ -0,0 +1,43 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestS3AssetsValidate(t *testing.T) {
+	testCases := []struct {
+		s3Assets S3Assets
+		err      string
+	}{
+		{S3Assets{}, ""},
+		{S3Assets{ServerSideEncryption: "AES256", ACL: "bucket-owner-full-control"}, ""},
+		{S3Assets{ServerSideEncryption: "aws:kms"}, ""},
+		{S3Assets{ServerSideEncryption: "aws:kms", KMSKeyARN: "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxx"}, ""},
+		{S3Assets{ServerSideEncryption: "kms"}, "must be either"},
+		{S3Assets{ServerSideEncryption: "AES256", KMSKeyARN: "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxx"}, "can only be specified"},
+		{S3Assets{ServerSideEncryption: "aws:kms", KMSKeyARN: "xxx"}, "must be an ARN"},
+	}
+
+	for _, tc := range testCases {
+		err := tc.s3Assets.Validate()
+		if tc.err == "" && err != nil {
+			t.Errorf("unexpected error for %+v: %v", tc.s3Assets, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("expected error containing %q for %+v but was %v", tc.err, tc.s3Assets, err)
+		}
+	}
+}
+
+func TestS3AssetsTagging(t *testing.T) {
+	s := S3Assets{Tags: map[string]string{"Owner": "platform team", "Env": "prod&test"}}
+	expected := "Env=prod%26test&Owner=platform+team"
+	if actual := s.Tagging(); actual != expected {
+		t.Errorf("unexpected tagging: expected=%s actual=%s", expected, actual)
+	}
+
+	if actual := (S3Assets{}).Tagging(); actual != "" {
+		t.Errorf("expected empty tagging but was %s", actual)
+	}
+}
@@ -11,7 +11,7 @@ type SpotFleet struct {
 	TargetCapacity       int                   `yaml:"targetCapacity,omitempty"`
 	SpotPrice            string                `yaml:"spotPrice,omitempty"`
 	IAMFleetRoleARN      string                `yaml:"iamFleetRoleArn,omitempty"`
-	RootVolumeType       string                `yaml:"rootVolumeType"`
+	RootVolumeType       string                `yaml:"rootVolumeType" enum:"standard,gp2,io1"`
 	UnitRootVolumeSize   int                   `yaml:"unitRootVolumeSize"`
 	UnitRootVolumeIOPS   int                   `yaml:"unitRootVolumeIOPS"`
 	LaunchSpecifications []LaunchSpecification `yaml:"launchSpecifications,omitempty"`
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+// TLSKeyAlgorithms are the algorithms of the private keys kube-aws generates for each class of certificates.
+// A class without an algorithm falls back to Default, which defaults to rsa-2048.
+// The service account key is always an RSA key
+type TLSKeyAlgorithms struct {
+	Default               tlsutil.KeyAlgorithm `yaml:"default,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	CA                    tlsutil.KeyAlgorithm `yaml:"ca,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	APIServer             tlsutil.KeyAlgorithm `yaml:"apiserver,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	KubeControllerManager tlsutil.KeyAlgorithm `yaml:"kubeControllerManager,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	KubeScheduler         tlsutil.KeyAlgorithm `yaml:"kubeScheduler,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	Worker                tlsutil.KeyAlgorithm `yaml:"worker,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	Admin                 tlsutil.KeyAlgorithm `yaml:"admin,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	Etcd                  tlsutil.KeyAlgorithm `yaml:"etcd,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	EtcdClient            tlsutil.KeyAlgorithm `yaml:"etcdClient,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	KIAMServer            tlsutil.KeyAlgorithm `yaml:"kiamServer,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+	KIAMAgent             tlsutil.KeyAlgorithm `yaml:"kiamAgent,omitempty" enum:"rsa-2048,rsa-4096,ecdsa-p256,ecdsa-p384"`
+}
+
+// byCertificateName returns the algorithm for each certificate, keyed by the name of the certificate in the credentials directory
+func (a TLSKeyAlgorithms) byCertificateName() map[string]tlsutil.KeyAlgorithm {
+	return map[string]tlsutil.KeyAlgorithm{
+		"ca":                      a.CA,
+		"apiserver":               a.APIServer,
+		"kube-controller-manager": a.KubeControllerManager,
+		"kube-scheduler":          a.KubeScheduler,
+		"worker":                  a.Worker,
+		"admin":                   a.Admin,
+		"etcd":                    a.Etcd,
+		"etcd-client":             a.EtcdClient,
+		"kiam-server":             a.KIAMServer,
+		"kiam-agent":              a.KIAMAgent,
+	}
+}
+
+// For returns the algorithm of the key for the certificate named e.g. `apiserver` or `etcd-client`
+func (a TLSKeyAlgorithms) For(name string) tlsutil.KeyAlgorithm {
+	if alg := a.byCertificateName()[name]; alg != "" {
+		return alg
+	}
+	if a.Default != "" {
+		return a.Default
+	}
+	return tlsutil.DefaultKeyAlgorithm
+}
+
+func (a TLSKeyAlgorithms) Validate() error {
+	if err := a.Default.Validate(); err != nil {
+		return fmt.Errorf("invalid tlsKeyAlgorithms.default: %v", err)
+	}
+	for name, alg := range a.byCertificateName() {
+		if err := alg.Validate(); err != nil {
+			return fmt.Errorf("invalid tlsKeyAlgorithms for the %s certificate: %v", name, err)
+		}
+	}
+	return nil
+}
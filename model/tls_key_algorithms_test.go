@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/tlsutil"
+)
+
+func TestTLSKeyAlgorithmsFor(t *testing.T) {
+	testCases := []struct {
+		algorithms TLSKeyAlgorithms
+		name       string
+		expected   tlsutil.KeyAlgorithm
+	}{
+		{TLSKeyAlgorithms{}, "apiserver", tlsutil.RSA2048},
+		{TLSKeyAlgorithms{Default: tlsutil.ECDSAP256}, "etcd-client", tlsutil.ECDSAP256},
+		{TLSKeyAlgorithms{Default: tlsutil.ECDSAP256, CA: tlsutil.RSA4096}, "ca", tlsutil.RSA4096},
+		{TLSKeyAlgorithms{EtcdClient: tlsutil.ECDSAP384}, "etcd-client", tlsutil.ECDSAP384},
+		{TLSKeyAlgorithms{EtcdClient: tlsutil.ECDSAP384}, "etcd", tlsutil.RSA2048},
+		{TLSKeyAlgorithms{KIAMAgent: tlsutil.ECDSAP256}, "kiam-agent", tlsutil.ECDSAP256},
+	}
+
+	for _, tc := range testCases {
+		if actual := tc.algorithms.For(tc.name); actual != tc.expected {
+			t.Errorf("expected %s for %s with %+v but was %s", tc.expected, tc.name, tc.algorithms, actual)
+		}
+	}
+}
+
+func TestTLSKeyAlgorithmsValidate(t *testing.T) {
+	if err := (TLSKeyAlgorithms{Default: tlsutil.ECDSAP384, Worker: tlsutil.RSA4096}).Validate(); err != nil {
+		t.Errorf("expected valid algorithms but got: %v", err)
+	}
+	if err := (TLSKeyAlgorithms{Default: "dsa-1024"}).Validate(); err == nil {
+		t.Errorf("expected an error for an unsupported default algorithm")
+	}
+	if err := (TLSKeyAlgorithms{Etcd: "ecdsa-p521"}).Validate(); err == nil {
+		t.Errorf("expected an error for an unsupported etcd algorithm")
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 type VolumeMount struct {
-	Type   string `yaml:"type,omitempty"`
+	Type   string `yaml:"type,omitempty" enum:"standard,gp2,io1"`
 	Iops   int    `yaml:"iops,omitempty"`
 	Size   int    `yaml:"size,omitempty"`
 	Device string `yaml:"device,omitempty"`
@@ -0,0 +1,196 @@
+package pricing
+
+// defaultPriceTableJSON is the price table bundled with kube-aws.
+// It contains on-demand Linux prices in USD for regions sharing the same prices.
+// Provide your own price table via `kube-aws calculator --price-file` for other regions, instance types or up-to-date prices
+const defaultPriceTableJSON = `{
+  "regions": {
+    "us-east-1": {
+      "classicLoadBalancer": 0.025,
+      "ebs": {
+        "gp2": {
+          "gbMonth": 0.1
+        },
+        "io1": {
+          "gbMonth": 0.125,
+          "iopsMonth": 0.065
+        },
+        "sc1": {
+          "gbMonth": 0.025
+        },
+        "st1": {
+          "gbMonth": 0.045
+        },
+        "standard": {
+          "gbMonth": 0.05
+        }
+      },
+      "ec2": {
+        "c4.2xlarge": 0.398,
+        "c4.4xlarge": 0.796,
+        "c4.8xlarge": 1.591,
+        "c4.large": 0.1,
+        "c4.xlarge": 0.199,
+        "c5.18xlarge": 3.06,
+        "c5.2xlarge": 0.34,
+        "c5.4xlarge": 0.68,
+        "c5.9xlarge": 1.53,
+        "c5.large": 0.085,
+        "c5.xlarge": 0.17,
+        "m4.10xlarge": 2.0,
+        "m4.16xlarge": 3.2,
+        "m4.2xlarge": 0.4,
+        "m4.4xlarge": 0.8,
+        "m4.large": 0.1,
+        "m4.xlarge": 0.2,
+        "m5.12xlarge": 2.304,
+        "m5.24xlarge": 4.608,
+        "m5.2xlarge": 0.384,
+        "m5.4xlarge": 0.768,
+        "m5.large": 0.096,
+        "m5.xlarge": 0.192,
+        "r4.16xlarge": 4.256,
+        "r4.2xlarge": 0.532,
+        "r4.4xlarge": 1.064,
+        "r4.8xlarge": 2.128,
+        "r4.large": 0.133,
+        "r4.xlarge": 0.266,
+        "t2.2xlarge": 0.3712,
+        "t2.large": 0.0928,
+        "t2.medium": 0.0464,
+        "t2.micro": 0.0116,
+        "t2.nano": 0.0058,
+        "t2.small": 0.023,
+        "t2.xlarge": 0.1856
+      },
+      "eip": 0.005,
+      "natGateway": 0.045,
+      "networkLoadBalancer": 0.0225
+    },
+    "us-east-2": {
+      "classicLoadBalancer": 0.025,
+      "ebs": {
+        "gp2": {
+          "gbMonth": 0.1
+        },
+        "io1": {
+          "gbMonth": 0.125,
+          "iopsMonth": 0.065
+        },
+        "sc1": {
+          "gbMonth": 0.025
+        },
+        "st1": {
+          "gbMonth": 0.045
+        },
+        "standard": {
+          "gbMonth": 0.05
+        }
+      },
+      "ec2": {
+        "c4.2xlarge": 0.398,
+        "c4.4xlarge": 0.796,
+        "c4.8xlarge": 1.591,
+        "c4.large": 0.1,
+        "c4.xlarge": 0.199,
+        "c5.18xlarge": 3.06,
+        "c5.2xlarge": 0.34,
+        "c5.4xlarge": 0.68,
+        "c5.9xlarge": 1.53,
+        "c5.large": 0.085,
+        "c5.xlarge": 0.17,
+        "m4.10xlarge": 2.0,
+        "m4.16xlarge": 3.2,
+        "m4.2xlarge": 0.4,
+        "m4.4xlarge": 0.8,
+        "m4.large": 0.1,
+        "m4.xlarge": 0.2,
+        "m5.12xlarge": 2.304,
+        "m5.24xlarge": 4.608,
+        "m5.2xlarge": 0.384,
+        "m5.4xlarge": 0.768,
+        "m5.large": 0.096,
+        "m5.xlarge": 0.192,
+        "r4.16xlarge": 4.256,
+        "r4.2xlarge": 0.532,
+        "r4.4xlarge": 1.064,
+        "r4.8xlarge": 2.128,
+        "r4.large": 0.133,
+        "r4.xlarge": 0.266,
+        "t2.2xlarge": 0.3712,
+        "t2.large": 0.0928,
+        "t2.medium": 0.0464,
+        "t2.micro": 0.0116,
+        "t2.nano": 0.0058,
+        "t2.small": 0.023,
+        "t2.xlarge": 0.1856
+      },
+      "eip": 0.005,
+      "natGateway": 0.045,
+      "networkLoadBalancer": 0.0225
+    },
+    "us-west-2": {
+      "classicLoadBalancer": 0.025,
+      "ebs": {
+        "gp2": {
+          "gbMonth": 0.1
+        },
+        "io1": {
+          "gbMonth": 0.125,
+          "iopsMonth": 0.065
+        },
+        "sc1": {
+          "gbMonth": 0.025
+        },
+        "st1": {
+          "gbMonth": 0.045
+        },
+        "standard": {
+          "gbMonth": 0.05
+        }
+      },
+      "ec2": {
+        "c4.2xlarge": 0.398,
+        "c4.4xlarge": 0.796,
+        "c4.8xlarge": 1.591,
+        "c4.large": 0.1,
+        "c4.xlarge": 0.199,
+        "c5.18xlarge": 3.06,
+        "c5.2xlarge": 0.34,
+        "c5.4xlarge": 0.68,
+        "c5.9xlarge": 1.53,
+        "c5.large": 0.085,
+        "c5.xlarge": 0.17,
+        "m4.10xlarge": 2.0,
+        "m4.16xlarge": 3.2,
+        "m4.2xlarge": 0.4,
+        "m4.4xlarge": 0.8,
+        "m4.large": 0.1,
+        "m4.xlarge": 0.2,
+        "m5.12xlarge": 2.304,
+        "m5.24xlarge": 4.608,
+        "m5.2xlarge": 0.384,
+        "m5.4xlarge": 0.768,
+        "m5.large": 0.096,
+        "m5.xlarge": 0.192,
+        "r4.16xlarge": 4.256,
+        "r4.2xlarge": 0.532,
+        "r4.4xlarge": 1.064,
+        "r4.8xlarge": 2.128,
+        "r4.large": 0.133,
+        "r4.xlarge": 0.266,
+        "t2.2xlarge": 0.3712,
+        "t2.large": 0.0928,
+        "t2.medium": 0.0464,
+        "t2.micro": 0.0116,
+        "t2.nano": 0.0058,
+        "t2.small": 0.023,
+        "t2.xlarge": 0.1856
+      },
+      "eip": 0.005,
+      "natGateway": 0.045,
+      "networkLoadBalancer": 0.0225
+    }
+  }
+}
+`
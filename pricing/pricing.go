@@ -0,0 +1,165 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// HoursPerMonth is the number of hours in a month used for converting hourly prices to monthly ones
+const HoursPerMonth = 730
+
+// Source provides prices of AWS resources in USD
+type Source interface {
+	// InstanceHourly returns the on-demand hourly price of an EC2 instance
+	InstanceHourly(region string, instanceType string) (float64, error)
+	// VolumeMonthly returns the monthly price of an EBS volume
+	VolumeMonthly(region string, volumeType string, sizeGB int, iops int) (float64, error)
+	NATGatewayHourly(region string) (float64, error)
+	// LoadBalancerHourly returns the hourly price of a classic ELB or a NLB when network is true
+	LoadBalancerHourly(region string, network bool) (float64, error)
+	EIPHourly(region string) (float64, error)
+}
+
+// PriceTable is a Source backed by a static table of prices which can be loaded from a JSON file
+type PriceTable struct {
+	Regions map[string]*RegionalPrices `json:"regions"`
+}
+
+type RegionalPrices struct {
+	// EC2 is hourly on-demand prices of EC2 instances keyed by instance types
+	EC2 map[string]float64 `json:"ec2"`
+	// EBS is prices of EBS volumes keyed by volume types
+	EBS                 map[string]VolumePrice `json:"ebs"`
+	NATGateway          float64                `json:"natGateway"`
+	ClassicLoadBalancer float64                `json:"classicLoadBalancer"`
+	NetworkLoadBalancer float64                `json:"networkLoadBalancer"`
+	EIP                 float64                `json:"eip"`
+}
+
+type VolumePrice struct {
+	GBMonth   float64 `json:"gbMonth"`
+	IOPSMonth float64 `json:"iopsMonth,omitempty"`
+}
+
+// DefaultPriceTable returns the price table bundled with kube-aws
+func DefaultPriceTable() (*PriceTable, error) {
+	t, err := PriceTableFromBytes([]byte(defaultPriceTableJSON))
+	if err != nil {
+		return nil, fmt.Errorf("[bug] failed to load the default price table: %v", err)
+	}
+	return t, nil
+}
+
+func PriceTableFromBytes(data []byte) (*PriceTable, error) {
+	t := &PriceTable{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("failed to parse price table: %v", err)
+	}
+	return t, nil
+}
+
+func PriceTableFromFile(path string) (*PriceTable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := PriceTableFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// Merge returns a price table in which prices in the other table take precedence over the ones in this table.
+// Prices of instance and volume types are overridden one by one. Other prices are overridden only when they are non-zero
+func (t *PriceTable) Merge(other *PriceTable) *PriceTable {
+	merged := &PriceTable{Regions: map[string]*RegionalPrices{}}
+	for _, table := range []*PriceTable{t, other} {
+		for region, p := range table.Regions {
+			m, ok := merged.Regions[region]
+			if !ok {
+				m = &RegionalPrices{EC2: map[string]float64{}, EBS: map[string]VolumePrice{}}
+				merged.Regions[region] = m
+			}
+			for k, v := range p.EC2 {
+				m.EC2[k] = v
+			}
+			for k, v := range p.EBS {
+				m.EBS[k] = v
+			}
+			if p.NATGateway != 0 {
+				m.NATGateway = p.NATGateway
+			}
+			if p.ClassicLoadBalancer != 0 {
+				m.ClassicLoadBalancer = p.ClassicLoadBalancer
+			}
+			if p.NetworkLoadBalancer != 0 {
+				m.NetworkLoadBalancer = p.NetworkLoadBalancer
+			}
+			if p.EIP != 0 {
+				m.EIP = p.EIP
+			}
+		}
+	}
+	return merged
+}
+
+func (t *PriceTable) region(region string) (*RegionalPrices, error) {
+	p, ok := t.Regions[region]
+	if !ok {
+		return nil, fmt.Errorf("no prices found for the region %s in the price table", region)
+	}
+	return p, nil
+}
+
+func (t *PriceTable) InstanceHourly(region string, instanceType string) (float64, error) {
+	p, err := t.region(region)
+	if err != nil {
+		return 0, err
+	}
+	price, ok := p.EC2[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("no price found for the instance type %s in the region %s", instanceType, region)
+	}
+	return price, nil
+}
+
+func (t *PriceTable) VolumeMonthly(region string, volumeType string, sizeGB int, iops int) (float64, error) {
+	p, err := t.region(region)
+	if err != nil {
+		return 0, err
+	}
+	price, ok := p.EBS[volumeType]
+	if !ok {
+		return 0, fmt.Errorf("no price found for the volume type %s in the region %s", volumeType, region)
+	}
+	return price.GBMonth*float64(sizeGB) + price.IOPSMonth*float64(iops), nil
+}
+
+func (t *PriceTable) NATGatewayHourly(region string) (float64, error) {
+	p, err := t.region(region)
+	if err != nil {
+		return 0, err
+	}
+	return p.NATGateway, nil
+}
+
+func (t *PriceTable) LoadBalancerHourly(region string, network bool) (float64, error) {
+	p, err := t.region(region)
+	if err != nil {
+		return 0, err
+	}
+	if network {
+		return p.NetworkLoadBalancer, nil
+	}
+	return p.ClassicLoadBalancer, nil
+}
+
+func (t *PriceTable) EIPHourly(region string) (float64, error) {
+	p, err := t.region(region)
+	if err != nil {
+		return 0, err
+	}
+	return p.EIP, nil
+}
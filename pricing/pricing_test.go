@@ -0,0 +1,77 @@
+package pricing
+
+import (
+	"testing"
+)
+
+func TestDefaultPriceTable(t *testing.T) {
+	table, err := DefaultPriceTable()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hourly, err := table.InstanceHourly("us-east-1", "t2.medium")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hourly != 0.0464 {
+		t.Errorf("unexpected hourly price of t2.medium: %f", hourly)
+	}
+
+	monthly, err := table.VolumeMonthly("us-east-1", "io1", 100, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := 0.125*100 + 0.065*1000; monthly != expected {
+		t.Errorf("expected the monthly price of the io1 volume to be %f but was %f", expected, monthly)
+	}
+
+	if _, err := table.InstanceHourly("ap-northeast-1", "t2.medium"); err == nil {
+		t.Error("expected an error for a region missing in the price table but got none")
+	}
+	if _, err := table.InstanceHourly("us-east-1", "x1.unknown"); err == nil {
+		t.Error("expected an error for an instance type missing in the price table but got none")
+	}
+}
+
+func TestPriceTableMerge(t *testing.T) {
+	base, err := PriceTableFromBytes([]byte(`{"regions": {"us-east-1": {"ec2": {"t2.medium": 0.0464, "m4.large": 0.1}, "natGateway": 0.045, "eip": 0.005}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	override, err := PriceTableFromBytes([]byte(`{"regions": {
+  "us-east-1": {"ec2": {"t2.medium": 0.03}, "natGateway": 0.05},
+  "ap-northeast-1": {"ec2": {"t2.medium": 0.0608}}
+}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	merged := base.Merge(override)
+
+	testCases := []struct {
+		region, instanceType string
+		expected             float64
+	}{
+		{"us-east-1", "t2.medium", 0.03},
+		{"us-east-1", "m4.large", 0.1},
+		{"ap-northeast-1", "t2.medium", 0.0608},
+	}
+	for _, tc := range testCases {
+		actual, err := merged.InstanceHourly(tc.region, tc.instanceType)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("expected the price of %s in %s to be %f but was %f", tc.instanceType, tc.region, tc.expected, actual)
+		}
+	}
+
+	if ngw, _ := merged.NATGatewayHourly("us-east-1"); ngw != 0.05 {
+		t.Errorf("expected the price of NAT gateways to be overridden but was %f", ngw)
+	}
+	if eip, _ := merged.EIPHourly("us-east-1"); eip != 0.005 {
+		t.Errorf("expected the price of EIPs to be kept but was %f", eip)
+	}
+}
@@ -0,0 +1,37 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	mu     sync.RWMutex
+	writer io.Writer = os.Stdout
+)
+
+// SetWriter changes where the progress of kube-aws commands is printed to, and returns the writer previously used.
+// Commands writing structured output to stdout print the progress to stderr so that it doesn't corrupt the output
+func SetWriter(w io.Writer) io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+	prev := writer
+	writer = w
+	return prev
+}
+
+// Printf prints the progress to the writer in the format
+func Printf(format string, a ...interface{}) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fmt.Fprintf(writer, format, a...)
+}
+
+// Println prints the progress to the writer followed by a newline
+func Println(a ...interface{}) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fmt.Fprintln(writer, a...)
+}
@@ -0,0 +1,20 @@
+package progress
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	prev := SetWriter(buf)
+	defer SetWriter(prev)
+
+	Printf("generating assets for %s\n", "control-plane")
+	Println("Generating credentials...")
+
+	expected := "generating assets for control-plane\nGenerating credentials...\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected progress: expected=%q actual=%q", expected, buf.String())
+	}
+}
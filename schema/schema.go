@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+const (
+	draft = "http://json-schema.org/draft-07/schema#"
+
+	// pkgPrefix is trimmed from the names of definitions for readability
+	pkgPrefix = "github.com/kubernetes-incubator/kube-aws/"
+)
+
+// Schema is a JSON Schema describing a YAML document
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+	// AdditionalProperties is either false or a *Schema for values of a map
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+}
+
+// JSON returns the indented JSON representation of the schema
+func (s *Schema) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %v", err)
+	}
+	return data, nil
+}
+
+// unknownKeysSupport is implemented by model.UnknownKeys, which makes kube-aws fail on unknown keys when inlined in a struct
+type unknownKeysSupport interface {
+	FailWhenUnknownKeysFound(keyPath string) error
+}
+
+var (
+	unknownKeysSupportType = reflect.TypeOf((*unknownKeysSupport)(nil)).Elem()
+	unmarshalerType        = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// Generate returns the JSON Schema for the YAML documents unmarshalled into v, according to the yaml tags of its fields.
+// Fields without yaml tags are considered internal and omitted.
+// Allowed values for a string field can be declared with an `enum` tag, e.g. `enum:"classic,network"`
+func Generate(v interface{}, title string) *Schema {
+	g := &generator{definitions: map[string]*Schema{}}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	root := g.structSchema(t)
+	root.Schema = draft
+	root.Title = title
+	if len(g.definitions) > 0 {
+		root.Definitions = g.definitions
+	}
+	return root
+}
+
+type generator struct {
+	definitions map[string]*Schema
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := definitionName(t)
+		if _, ok := g.definitions[name]; !ok {
+			// Register the name beforehand so that recursive types terminate
+			g.definitions[name] = &Schema{}
+			*g.definitions[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/definitions/" + name}
+	default:
+		// Interfaces accept anything
+		return &Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	// A struct without any field to unmarshal into but with a custom unmarshaler, like model.CIDRRange, is a scalar in YAML
+	if reflect.PtrTo(t).Implements(unmarshalerType) && !hasYAMLFields(t) {
+		return &Schema{Type: "string"}
+	}
+
+	g.addFields(s, t)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("yaml")
+		if !ok || tag == "-" || f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if opts["inline"] {
+			switch ft.Kind() {
+			case reflect.Struct:
+				g.addFields(s, ft)
+			case reflect.Map:
+				if ft.Implements(unknownKeysSupportType) {
+					s.AdditionalProperties = false
+				} else {
+					s.AdditionalProperties = g.schemaOf(ft.Elem())
+				}
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fs := g.schemaOf(ft)
+		if enum, ok := f.Tag.Lookup("enum"); ok {
+			fs.Enum = strings.Split(enum, ",")
+		}
+		s.Properties[name] = fs
+	}
+}
+
+func hasYAMLFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+// definitionName returns the name of a definition for the type, e.g. `model.Subnet` or `core.controlplane.config.Cluster`
+func definitionName(t reflect.Type) string {
+	pkg := strings.Replace(strings.TrimPrefix(t.PkgPath(), pkgPrefix), "/", ".", -1)
+	return pkg + "." + t.Name()
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type strictKeys map[string]interface{}
+
+func (k strictKeys) FailWhenUnknownKeysFound(keyPath string) error {
+	return nil
+}
+
+type cidr struct {
+	str string
+}
+
+func (c *cidr) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return unmarshal(&c.str)
+}
+
+type common struct {
+	Name string `yaml:"name"`
+}
+
+type node struct {
+	Children []*node `yaml:"children,omitempty"`
+}
+
+type config struct {
+	common     `yaml:",inline"`
+	Type       *string           `yaml:"type,omitempty" enum:"classic,network"`
+	Count      int               `yaml:"count"`
+	Price      float64           `yaml:"price"`
+	Enabled    bool              `yaml:",omitempty"`
+	CIDRs      []cidr            `yaml:"cidrs"`
+	Tags       map[string]string `yaml:"tags"`
+	Custom     interface{}       `yaml:"custom"`
+	Tree       node              `yaml:"tree"`
+	Internal   string
+	Ignored    string `yaml:"-"`
+	strictKeys `yaml:",inline"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(&config{}, "test.yaml")
+
+	data, err := s.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := map[string]interface{}{}
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	nodeDef := "schema.node"
+	expected := map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title":   "test.yaml",
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"name":    map[string]interface{}{"type": "string"},
+			"type":    map[string]interface{}{"type": "string", "enum": []interface{}{"classic", "network"}},
+			"count":   map[string]interface{}{"type": "integer"},
+			"price":   map[string]interface{}{"type": "number"},
+			"enabled": map[string]interface{}{"type": "boolean"},
+			"cidrs":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/definitions/schema.cidr"}},
+			"tags":    map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+			"custom":  map[string]interface{}{},
+			"tree":    map[string]interface{}{"$ref": "#/definitions/" + nodeDef},
+		},
+		"additionalProperties": false,
+		"definitions": map[string]interface{}{
+			"schema.cidr": map[string]interface{}{"type": "string"},
+			nodeDef: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"children": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/definitions/" + nodeDef}},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected schema:\n%s", data)
+		for k, v := range expected {
+			if !reflect.DeepEqual(actual[k], v) {
+				t.Errorf("%s: expected %s but was %s", k, fmt.Sprint(v), fmt.Sprint(actual[k]))
+			}
+		}
+	}
+}
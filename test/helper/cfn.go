@@ -2,6 +2,7 @@ package helper
 
 import (
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 )
 
@@ -9,9 +10,13 @@ type DummyCloudformationService struct {
 	ExpectedTags []*cloudformation.Tag
 	StackEvents  []*cloudformation.StackEvent
 	StackStatus  string
+	Throttler    *Throttler
 }
 
 func (cfSvc *DummyCloudformationService) CreateStack(req *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	if err := cfSvc.Throttler.Throttle(); err != nil {
+		return nil, err
+	}
 
 	if len(cfSvc.ExpectedTags) != len(req.Tags) {
 		return nil, fmt.Errorf(
@@ -45,3 +50,39 @@ func (cfSvc *DummyCloudformationService) CreateStack(req *cloudformation.CreateS
 
 	return resp, nil
 }
+
+func (cfSvc *DummyCloudformationService) UpdateStack(req *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	if err := cfSvc.Throttler.Throttle(); err != nil {
+		return nil, err
+	}
+	return &cloudformation.UpdateStackOutput{StackId: req.StackName}, nil
+}
+
+func (cfSvc *DummyCloudformationService) DescribeStacks(req *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	if err := cfSvc.Throttler.Throttle(); err != nil {
+		return nil, err
+	}
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{
+				StackId:     req.StackName,
+				StackName:   req.StackName,
+				StackStatus: aws.String(cfSvc.StackStatus),
+			},
+		},
+	}, nil
+}
+
+func (cfSvc *DummyCloudformationService) DescribeStackEvents(req *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	if err := cfSvc.Throttler.Throttle(); err != nil {
+		return nil, err
+	}
+	return &cloudformation.DescribeStackEventsOutput{StackEvents: cfSvc.StackEvents}, nil
+}
+
+func (cfSvc *DummyCloudformationService) EstimateTemplateCost(req *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error) {
+	if err := cfSvc.Throttler.Throttle(); err != nil {
+		return nil, err
+	}
+	return &cloudformation.EstimateTemplateCostOutput{Url: aws.String("https://calculator.s3.amazonaws.com/")}, nil
+}
@@ -0,0 +1,812 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+const fakeAccountID = "123456789012"
+
+// FakeCloudFormation is an in-memory CloudFormation which creates, updates and deletes stacks along with their nested stacks.
+// Parameters, outputs, exports and the parameters of nested stacks are evaluated, while the other resources are only given physical IDs.
+// Operations complete synchronously, leaving the status transitions in the stack events.
+// Calls to the APIs it doesn't implement panic
+type FakeCloudFormation struct {
+	cloudformationiface.CloudFormationAPI
+
+	Region string
+	// S3 is where templates are read from TemplateURL
+	S3 *FakeS3
+	// FailingResources are the reasons of failures by the logical IDs of the resources failing to be created or updated.
+	// Resources in nested stacks are specified as `NestedStackLogicalID.ResourceLogicalID`
+	FailingResources map[string]string
+
+	mu        sync.Mutex
+	seq       int
+	stacks    []*fakeStack
+	exports   map[string]string
+	validated []string
+}
+
+type fakeStack struct {
+	id           string
+	name         string
+	path         string
+	status       string
+	reason       string
+	body         string
+	template     map[string]interface{}
+	params       map[string]string
+	outputs      map[string]string
+	exports      map[string]string
+	resources    map[string]*fakeResource
+	tags         []*cloudformation.Tag
+	events       []*cloudformation.StackEvent
+	creationTime time.Time
+	updatedTime  *time.Time
+}
+
+type fakeResource struct {
+	logicalID  string
+	physicalID string
+	typ        string
+	status     string
+	reason     string
+	definition interface{}
+	// nested is the nested stack for a resource of AWS::CloudFormation::Stack
+	nested *fakeStack
+}
+
+func NewFakeCloudFormation(region string, s3 *FakeS3) *FakeCloudFormation {
+	return &FakeCloudFormation{
+		Region:  region,
+		S3:      s3,
+		exports: map[string]string{},
+	}
+}
+
+// StackNames returns the sorted names of the stacks not deleted, including nested stacks
+func (f *FakeCloudFormation) StackNames() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := []string{}
+	for _, s := range f.stacks {
+		if s.status != cloudformation.StackStatusDeleteComplete {
+			names = append(names, s.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidatedTemplateURLs returns the URLs of the templates validated so far, in the order of validation
+func (f *FakeCloudFormation) ValidatedTemplateURLs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.validated...)
+}
+
+func (f *FakeCloudFormation) CreateStack(input *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(input.StackName)
+	if s := f.liveStack(name); s != nil {
+		return nil, awserr.New(cloudformation.ErrCodeAlreadyExistsException, fmt.Sprintf("Stack [%s] already exists", name), nil)
+	}
+
+	body, err := f.templateBody(input.TemplateBody, input.TemplateURL)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]string{}
+	for _, p := range input.Parameters {
+		params[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+	}
+
+	s := f.newStack(name, "", nil)
+	s.tags = input.Tags
+	if err := f.create(s, body, params); err != nil {
+		return nil, err
+	}
+	if s.status == cloudformation.StackStatusCreateFailed && aws.StringValue(input.OnFailure) != cloudformation.OnFailureDoNothing {
+		f.rollbackCreation(s)
+	}
+	return &cloudformation.CreateStackOutput{StackId: aws.String(s.id)}, nil
+}
+
+func (f *FakeCloudFormation) UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.findStack(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	switch s.status {
+	case cloudformation.StackStatusCreateComplete, cloudformation.StackStatusUpdateComplete, cloudformation.StackStatusUpdateRollbackComplete:
+	default:
+		return nil, validationError("Stack:%s is in %s state and can not be updated.", s.id, s.status)
+	}
+
+	body := s.body
+	if !aws.BoolValue(input.UsePreviousTemplate) {
+		if body, err = f.templateBody(input.TemplateBody, input.TemplateURL); err != nil {
+			return nil, err
+		}
+	}
+	params := map[string]string{}
+	for _, p := range input.Parameters {
+		key := aws.StringValue(p.ParameterKey)
+		if aws.BoolValue(p.UsePreviousValue) {
+			params[key] = s.params[key]
+		} else {
+			params[key] = aws.StringValue(p.ParameterValue)
+		}
+	}
+
+	changed, err := f.update(s, body, params)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, validationError("No updates are to be performed.")
+	}
+	return &cloudformation.UpdateStackOutput{StackId: aws.String(s.id)}, nil
+}
+
+func (f *FakeCloudFormation) DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Deleting a missing stack succeeds as CloudFormation does
+	if s, _ := f.findStack(aws.StringValue(input.StackName)); s != nil {
+		f.delete(s)
+	}
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func (f *FakeCloudFormation) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if input.StackName == nil {
+		out := &cloudformation.DescribeStacksOutput{}
+		for _, s := range f.stacks {
+			if s.status != cloudformation.StackStatusDeleteComplete {
+				out.Stacks = append(out.Stacks, s.describe())
+			}
+		}
+		return out, nil
+	}
+
+	s, err := f.findStack(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	return &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{s.describe()}}, nil
+}
+
+func (f *FakeCloudFormation) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.findStack(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	// Events are returned in the reverse chronological order
+	events := []*cloudformation.StackEvent{}
+	for i := len(s.events) - 1; i >= 0; i-- {
+		events = append(events, s.events[i])
+	}
+	return &cloudformation.DescribeStackEventsOutput{StackEvents: events}, nil
+}
+
+func (f *FakeCloudFormation) DescribeStackEventsPages(input *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error {
+	out, err := f.DescribeStackEvents(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *FakeCloudFormation) DescribeStackResource(input *cloudformation.DescribeStackResourceInput) (*cloudformation.DescribeStackResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.findStack(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	logicalID := aws.StringValue(input.LogicalResourceId)
+	r, ok := s.resources[logicalID]
+	if !ok {
+		return nil, validationError("Resource %s does not exist for stack %s", logicalID, s.name)
+	}
+	return &cloudformation.DescribeStackResourceOutput{
+		StackResourceDetail: &cloudformation.StackResourceDetail{
+			StackId:              aws.String(s.id),
+			StackName:            aws.String(s.name),
+			LogicalResourceId:    aws.String(r.logicalID),
+			PhysicalResourceId:   aws.String(r.physicalID),
+			ResourceType:         aws.String(r.typ),
+			ResourceStatus:       aws.String(r.status),
+			ResourceStatusReason: aws.String(r.reason),
+		},
+	}, nil
+}
+
+func (f *FakeCloudFormation) DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.findStack(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	out := &cloudformation.DescribeStackResourcesOutput{StackResources: []*cloudformation.StackResource{}}
+	for _, id := range sortedResourceIDs(s.resources) {
+		r := s.resources[id]
+		out.StackResources = append(out.StackResources, &cloudformation.StackResource{
+			StackId:            aws.String(s.id),
+			StackName:          aws.String(s.name),
+			LogicalResourceId:  aws.String(r.logicalID),
+			PhysicalResourceId: aws.String(r.physicalID),
+			ResourceType:       aws.String(r.typ),
+			ResourceStatus:     aws.String(r.status),
+		})
+	}
+	return out, nil
+}
+
+func (f *FakeCloudFormation) GetTemplate(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.findStack(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	return &cloudformation.GetTemplateOutput{TemplateBody: aws.String(s.body)}, nil
+}
+
+func (f *FakeCloudFormation) ValidateTemplate(input *cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error) {
+	body, err := f.templateBody(input.TemplateBody, input.TemplateURL)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := parseFakeTemplate(body)
+	if err != nil {
+		return nil, err
+	}
+	if input.TemplateURL != nil {
+		f.mu.Lock()
+		f.validated = append(f.validated, *input.TemplateURL)
+		f.mu.Unlock()
+	}
+	out := &cloudformation.ValidateTemplateOutput{}
+	params, _ := tmpl["Parameters"].(map[string]interface{})
+	for _, k := range sortedKeys(params) {
+		out.Parameters = append(out.Parameters, &cloudformation.TemplateParameter{ParameterKey: aws.String(k)})
+	}
+	return out, nil
+}
+
+func (f *FakeCloudFormation) EstimateTemplateCost(input *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error) {
+	if _, err := f.templateBody(input.TemplateBody, input.TemplateURL); err != nil {
+		return nil, err
+	}
+	return &cloudformation.EstimateTemplateCostOutput{Url: aws.String("https://calculator.s3.amazonaws.com/")}, nil
+}
+
+// liveStack returns the stack not deleted with the name
+func (f *FakeCloudFormation) liveStack(name string) *fakeStack {
+	for _, s := range f.stacks {
+		if s.name == name && s.status != cloudformation.StackStatusDeleteComplete {
+			return s
+		}
+	}
+	return nil
+}
+
+// findStack returns the stack with the id, including deleted ones, or the stack not deleted with the name
+func (f *FakeCloudFormation) findStack(nameOrID string) (*fakeStack, error) {
+	for _, s := range f.stacks {
+		if s.id == nameOrID {
+			return s, nil
+		}
+	}
+	if s := f.liveStack(nameOrID); s != nil {
+		return s, nil
+	}
+	return nil, validationError("Stack with id %s does not exist", nameOrID)
+}
+
+func (f *FakeCloudFormation) templateBody(body *string, url *string) (string, error) {
+	if body != nil {
+		return *body, nil
+	}
+	if f.S3 != nil {
+		if b, ok := f.S3.ObjectAtURL(aws.StringValue(url)); ok {
+			return b, nil
+		}
+	}
+	return "", validationError("TemplateURL must reference a valid S3 object to which you have access: %s", aws.StringValue(url))
+}
+
+func (f *FakeCloudFormation) newStack(name string, path string, parent *fakeStack) *fakeStack {
+	f.seq++
+	s := &fakeStack{
+		id:           fmt.Sprintf("arn:aws:cloudformation:%s:%s:stack/%s/%08x-0000-0000-0000-%012x", f.Region, fakeAccountID, name, f.seq, f.seq),
+		name:         name,
+		path:         path,
+		resources:    map[string]*fakeResource{},
+		creationTime: time.Now(),
+	}
+	if parent != nil {
+		s.tags = parent.tags
+	}
+	f.stacks = append(f.stacks, s)
+	return s
+}
+
+// create provisions the resources of the new stack in the order of their dependencies, stopping at the first failure
+func (f *FakeCloudFormation) create(s *fakeStack, body string, params map[string]string) error {
+	tmpl, values, err := f.prepare(body, params)
+	if err != nil {
+		s.status = cloudformation.StackStatusDeleteComplete
+		return err
+	}
+	s.body, s.template, s.params = body, tmpl, values
+
+	f.setStackStatus(s, cloudformation.StackStatusCreateInProgress, "User Initiated")
+	if reason := f.provision(s, nil, false); reason != "" {
+		f.setStackStatus(s, cloudformation.StackStatusCreateFailed, reason)
+		return nil
+	}
+	f.setStackStatus(s, cloudformation.StackStatusCreateComplete, "")
+	return nil
+}
+
+// update provisions the resources of the stack again from the template and the parameters, and rolls back on failure.
+// It returns false when neither the template nor the parameters have changed
+func (f *FakeCloudFormation) update(s *fakeStack, body string, params map[string]string) (bool, error) {
+	tmpl, values, err := f.prepare(body, params)
+	if err != nil {
+		return false, err
+	}
+	if !f.hasChanges(s, body, values) {
+		return false, nil
+	}
+
+	prevBody, prevTemplate, prevParams := s.body, s.template, s.params
+	prev := map[string]*fakeResource{}
+	for id, r := range s.resources {
+		copied := *r
+		prev[id] = &copied
+	}
+
+	s.body, s.template, s.params = body, tmpl, values
+	now := time.Now()
+	s.updatedTime = &now
+
+	f.setStackStatus(s, cloudformation.StackStatusUpdateInProgress, "User Initiated")
+	if reason := f.provision(s, prev, false); reason != "" {
+		f.setStackStatus(s, cloudformation.StackStatusUpdateRollbackInProgress, reason)
+		s.body, s.template, s.params = prevBody, prevTemplate, prevParams
+		f.provision(s, s.resources, true)
+		f.setStackStatus(s, cloudformation.StackStatusUpdateRollbackComplete, "")
+		return true, nil
+	}
+	f.setStackStatus(s, cloudformation.StackStatusUpdateCompleteCleanupInProgress, "")
+	f.setStackStatus(s, cloudformation.StackStatusUpdateComplete, "")
+	return true, nil
+}
+
+// prepare parses the template and resolves the values of its parameters
+func (f *FakeCloudFormation) prepare(body string, params map[string]string) (map[string]interface{}, map[string]string, error) {
+	tmpl, err := parseFakeTemplate(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defs, _ := tmpl["Parameters"].(map[string]interface{})
+	for k := range params {
+		if _, ok := defs[k]; !ok {
+			return nil, nil, validationError("Parameters: [%s] do not exist in the template", k)
+		}
+	}
+
+	values := map[string]string{}
+	missing := []string{}
+	for _, k := range sortedKeys(defs) {
+		if v, ok := params[k]; ok {
+			values[k] = v
+			continue
+		}
+		def, _ := defs[k].(map[string]interface{})
+		if d, ok := def["Default"]; ok {
+			values[k] = fmt.Sprint(d)
+			continue
+		}
+		missing = append(missing, k)
+	}
+	if len(missing) > 0 {
+		return nil, nil, validationError("Parameters: [%s] must have values", strings.Join(missing, ", "))
+	}
+	return tmpl, values, nil
+}
+
+// provision creates or updates the resources in the template of the stack, and deletes the resources in prev no longer in the template.
+// It returns the reason of the first failure, or an empty string on success
+func (f *FakeCloudFormation) provision(s *fakeStack, prev map[string]*fakeResource, rollback bool) string {
+	e := &fakeEvaluator{cf: f, stack: s}
+
+	order, err := resourceOrder(s.template)
+	if err != nil {
+		return err.Error()
+	}
+
+	resources := map[string]*fakeResource{}
+	for _, id := range order {
+		def, _ := s.template["Resources"].(map[string]interface{})[id].(map[string]interface{})
+		if cond, ok := def["Condition"].(string); ok {
+			if enabled, err := e.condition(cond); err != nil {
+				return err.Error()
+			} else if !enabled {
+				continue
+			}
+		}
+
+		typ, _ := def["Type"].(string)
+		r, existing := prev[id]
+		if !existing || r.typ != typ {
+			r = &fakeResource{logicalID: id, typ: typ, physicalID: f.physicalID(s, id, typ)}
+		}
+		resources[id] = r
+		s.resources = mergedResources(prev, resources)
+
+		action := "CREATE"
+		if existing {
+			action = "UPDATE"
+			if typ != "AWS::CloudFormation::Stack" && reflect.DeepEqual(r.definition, def) {
+				continue
+			}
+		}
+		f.setResourceStatus(s, r, action+"_IN_PROGRESS", "")
+
+		if reason, failing := f.FailingResources[strings.TrimPrefix(s.path+"."+id, ".")]; failing && !rollback {
+			f.setResourceStatus(s, r, action+"_FAILED", reason)
+			return fmt.Sprintf("The following resource(s) failed to %s: [%s]. ", strings.ToLower(action), id)
+		}
+
+		if typ == "AWS::CloudFormation::Stack" {
+			if reason := f.provisionNestedStack(s, r, def, e); reason != "" {
+				f.setResourceStatus(s, r, action+"_FAILED", reason)
+				return fmt.Sprintf("The following resource(s) failed to %s: [%s]. ", strings.ToLower(action), id)
+			}
+		}
+
+		r.definition = def
+		f.setResourceStatus(s, r, action+"_COMPLETE", "")
+	}
+
+	outputs, exports, err := e.outputs()
+	if err != nil {
+		return err.Error()
+	}
+	for name := range exports {
+		if owner, ok := f.exports[name]; ok && owner != s.id {
+			return fmt.Sprintf("Export with name %s is already exported by stack %s", name, owner)
+		}
+	}
+
+	for id, r := range prev {
+		if _, ok := resources[id]; !ok {
+			f.deleteResource(s, r)
+		}
+	}
+	s.resources = resources
+
+	for name := range s.exports {
+		delete(f.exports, name)
+	}
+	for name := range exports {
+		f.exports[name] = s.id
+	}
+	s.outputs, s.exports = outputs, exports
+	return ""
+}
+
+// hasChanges returns true when the template or the parameters of the stack or any of its nested stacks would change.
+// As CloudFormation does, nested stacks are updated when the content at their TemplateURL changes, even if the URL doesn't
+func (f *FakeCloudFormation) hasChanges(s *fakeStack, body string, values map[string]string) bool {
+	if body != s.body || !reflect.DeepEqual(values, s.params) {
+		return true
+	}
+	e := &fakeEvaluator{cf: f, stack: s}
+	for _, r := range s.resources {
+		if r.nested == nil {
+			continue
+		}
+		def, _ := r.definition.(map[string]interface{})
+		nestedBody, nestedParams, err := f.nestedStackInput(def, e)
+		if err != nil {
+			return true
+		}
+		_, nestedValues, err := f.prepare(nestedBody, nestedParams)
+		if err != nil || f.hasChanges(r.nested, nestedBody, nestedValues) {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedStackInput returns the template and the parameters of the nested stack defined by the resource
+func (f *FakeCloudFormation) nestedStackInput(def map[string]interface{}, e *fakeEvaluator) (string, map[string]string, error) {
+	props, _ := def["Properties"].(map[string]interface{})
+	url, err := e.evalString(props["TemplateURL"])
+	if err != nil {
+		return "", nil, err
+	}
+	body, err := f.templateBody(nil, aws.String(url))
+	if err != nil {
+		return "", nil, err
+	}
+	params := map[string]string{}
+	if ps, ok := props["Parameters"].(map[string]interface{}); ok {
+		for k, v := range ps {
+			if params[k], err = e.evalString(v); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return body, params, nil
+}
+
+func (f *FakeCloudFormation) provisionNestedStack(parent *fakeStack, r *fakeResource, def map[string]interface{}, e *fakeEvaluator) string {
+	body, params, err := f.nestedStackInput(def, e)
+	if err != nil {
+		return err.Error()
+	}
+
+	if r.nested == nil || r.nested.status == cloudformation.StackStatusDeleteComplete {
+		f.seq++
+		name := fmt.Sprintf("%s-%s-%012X", parent.name, r.logicalID, f.seq)
+		nested := f.newStack(name, strings.TrimPrefix(parent.path+"."+r.logicalID, "."), parent)
+		r.nested, r.physicalID = nested, nested.id
+		if err := f.create(nested, body, params); err != nil {
+			return err.Error()
+		}
+		if nested.status != cloudformation.StackStatusCreateComplete {
+			return fmt.Sprintf("Embedded stack %s was not successfully created: %s", nested.id, nested.reason)
+		}
+		return ""
+	}
+
+	if _, err := f.update(r.nested, body, params); err != nil {
+		return err.Error()
+	}
+	if r.nested.status == cloudformation.StackStatusUpdateRollbackComplete {
+		return fmt.Sprintf("Embedded stack %s was not successfully updated. Currently in %s", r.nested.id, r.nested.status)
+	}
+	return ""
+}
+
+func (f *FakeCloudFormation) rollbackCreation(s *fakeStack) {
+	f.setStackStatus(s, cloudformation.StackStatusRollbackInProgress, s.reason)
+	for _, id := range sortedResourceIDs(s.resources) {
+		f.deleteResource(s, s.resources[id])
+	}
+	s.resources = map[string]*fakeResource{}
+	f.setStackStatus(s, cloudformation.StackStatusRollbackComplete, "")
+}
+
+func (f *FakeCloudFormation) delete(s *fakeStack) {
+	if s.status == cloudformation.StackStatusDeleteComplete {
+		return
+	}
+	f.setStackStatus(s, cloudformation.StackStatusDeleteInProgress, "User Initiated")
+	order, _ := resourceOrder(s.template)
+	for i := len(order) - 1; i >= 0; i-- {
+		if r, ok := s.resources[order[i]]; ok {
+			f.deleteResource(s, r)
+		}
+	}
+	s.resources = map[string]*fakeResource{}
+	for name := range s.exports {
+		delete(f.exports, name)
+	}
+	s.exports = nil
+	f.setStackStatus(s, cloudformation.StackStatusDeleteComplete, "")
+}
+
+func (f *FakeCloudFormation) deleteResource(s *fakeStack, r *fakeResource) {
+	f.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteInProgress, "")
+	if r.nested != nil {
+		f.delete(r.nested)
+	}
+	f.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteComplete, "")
+}
+
+func (f *FakeCloudFormation) physicalID(s *fakeStack, logicalID string, typ string) string {
+	f.seq++
+	switch typ {
+	case "AWS::ElasticLoadBalancingV2::LoadBalancer":
+		return fmt.Sprintf("arn:aws:elasticloadbalancing:%s:%s:loadbalancer/net/%s/%016x", f.Region, fakeAccountID, truncate(logicalID, 16), f.seq)
+	case "AWS::ElasticLoadBalancing::LoadBalancer":
+		return fmt.Sprintf("%s-%012X", truncate(strings.Replace(s.name, "-", "", -1), 19), f.seq)
+	}
+	return fmt.Sprintf("%s-%s-%012X", s.name, logicalID, f.seq)
+}
+
+// liveResources returns the resources of the type in the stacks not deleted
+func (f *FakeCloudFormation) liveResources(typ string) []*fakeResource {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resources := []*fakeResource{}
+	for _, s := range f.stacks {
+		for _, r := range s.resources {
+			if r.typ == typ && r.status != cloudformation.ResourceStatusDeleteComplete {
+				resources = append(resources, r)
+			}
+		}
+	}
+	return resources
+}
+
+func (f *FakeCloudFormation) setStackStatus(s *fakeStack, status string, reason string) {
+	s.status, s.reason = status, reason
+	f.addEvent(s, s.name, s.id, "AWS::CloudFormation::Stack", status, reason)
+}
+
+func (f *FakeCloudFormation) setResourceStatus(s *fakeStack, r *fakeResource, status string, reason string) {
+	r.status, r.reason = status, reason
+	f.addEvent(s, r.logicalID, r.physicalID, r.typ, status, reason)
+}
+
+func (f *FakeCloudFormation) addEvent(s *fakeStack, logicalID string, physicalID string, typ string, status string, reason string) {
+	f.seq++
+	e := &cloudformation.StackEvent{
+		EventId:            aws.String(fmt.Sprintf("%s-%d", logicalID, f.seq)),
+		StackId:            aws.String(s.id),
+		StackName:          aws.String(s.name),
+		LogicalResourceId:  aws.String(logicalID),
+		PhysicalResourceId: aws.String(physicalID),
+		ResourceType:       aws.String(typ),
+		ResourceStatus:     aws.String(status),
+		Timestamp:          aws.Time(time.Now()),
+	}
+	if reason != "" {
+		e.ResourceStatusReason = aws.String(reason)
+	}
+	s.events = append(s.events, e)
+}
+
+func (s *fakeStack) describe() *cloudformation.Stack {
+	stack := &cloudformation.Stack{
+		StackId:         aws.String(s.id),
+		StackName:       aws.String(s.name),
+		StackStatus:     aws.String(s.status),
+		CreationTime:    aws.Time(s.creationTime),
+		LastUpdatedTime: s.updatedTime,
+		Tags:            s.tags,
+	}
+	if s.reason != "" {
+		stack.StackStatusReason = aws.String(s.reason)
+	}
+	for _, k := range sortedStringKeys(s.params) {
+		stack.Parameters = append(stack.Parameters, &cloudformation.Parameter{ParameterKey: aws.String(k), ParameterValue: aws.String(s.params[k])})
+	}
+	for _, k := range sortedStringKeys(s.outputs) {
+		stack.Outputs = append(stack.Outputs, &cloudformation.Output{OutputKey: aws.String(k), OutputValue: aws.String(s.outputs[k])})
+	}
+	return stack
+}
+
+// resourceOrder returns the logical IDs of the resources sorted so that each resource comes after the ones it depends on
+func resourceOrder(tmpl map[string]interface{}) ([]string, error) {
+	resources, _ := tmpl["Resources"].(map[string]interface{})
+	order := []string{}
+	visited := map[string]int{}
+	var visit func(string) error
+	visit = func(id string) error {
+		switch visited[id] {
+		case 1:
+			return validationError("Circular dependency between resources: [%s]", id)
+		case 2:
+			return nil
+		}
+		visited[id] = 1
+		for _, dep := range fakeReferences(resources[id]) {
+			if _, ok := resources[dep]; ok {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		visited[id] = 2
+		order = append(order, id)
+		return nil
+	}
+	for _, id := range sortedKeys(resources) {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func mergedResources(prev map[string]*fakeResource, current map[string]*fakeResource) map[string]*fakeResource {
+	merged := map[string]*fakeResource{}
+	for k, v := range prev {
+		merged[k] = v
+	}
+	for k, v := range current {
+		merged[k] = v
+	}
+	return merged
+}
+
+func parseFakeTemplate(body string) (map[string]interface{}, error) {
+	tmpl := map[string]interface{}{}
+	d := json.NewDecoder(strings.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&tmpl); err != nil {
+		return nil, validationError("Template format error: JSON not well-formed. %v", err)
+	}
+	if _, ok := tmpl["Resources"].(map[string]interface{}); !ok {
+		return nil, validationError("Template format error: At least one Resources member must be defined.")
+	}
+	return tmpl, nil
+}
+
+func validationError(format string, args ...interface{}) error {
+	return awserr.NewRequestFailure(awserr.New("ValidationError", fmt.Sprintf(format, args...), nil), 400, "")
+}
+
+func sortedResourceIDs(m map[string]*fakeResource) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
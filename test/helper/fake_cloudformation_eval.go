@@ -0,0 +1,513 @@
+package helper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var fakeSubVariablePattern = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// fakeNoValue is the value of `AWS::NoValue`, which removes the property or the list item containing it
+type fakeNoValue struct{}
+
+// fakeEvaluator evaluates the intrinsic functions in the template of a stack being provisioned
+type fakeEvaluator struct {
+	cf    *FakeCloudFormation
+	stack *fakeStack
+}
+
+func (e *fakeEvaluator) section(name string) map[string]interface{} {
+	m, _ := e.stack.template[name].(map[string]interface{})
+	return m
+}
+
+// outputs returns the values of the outputs and the output keys by the export names
+func (e *fakeEvaluator) outputs() (map[string]string, map[string]string, error) {
+	outputs := map[string]string{}
+	exports := map[string]string{}
+	for key, o := range e.section("Outputs") {
+		def, _ := o.(map[string]interface{})
+		if cond, ok := def["Condition"].(string); ok {
+			if enabled, err := e.condition(cond); err != nil {
+				return nil, nil, err
+			} else if !enabled {
+				continue
+			}
+		}
+		v, err := e.evalString(def["Value"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("Template error: output %s: %v", key, err)
+		}
+		outputs[key] = v
+
+		if export, ok := def["Export"].(map[string]interface{}); ok {
+			name, err := e.evalString(export["Name"])
+			if err != nil {
+				return nil, nil, fmt.Errorf("Template error: export of output %s: %v", key, err)
+			}
+			exports[name] = key
+		}
+	}
+	return outputs, exports, nil
+}
+
+func (e *fakeEvaluator) condition(name string) (bool, error) {
+	def, ok := e.section("Conditions")[name]
+	if !ok {
+		return false, fmt.Errorf("Template error: unresolved condition dependency %s", name)
+	}
+	return e.evalCondition(def)
+}
+
+func (e *fakeEvaluator) evalCondition(node interface{}) (bool, error) {
+	m, ok := node.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return false, fmt.Errorf("Template error: invalid condition %v", node)
+	}
+	for fn, arg := range m {
+		switch fn {
+		case "Condition":
+			name, _ := arg.(string)
+			return e.condition(name)
+		case "Fn::Equals":
+			args, ok := arg.([]interface{})
+			if !ok || len(args) != 2 {
+				return false, fmt.Errorf("Template error: Fn::Equals requires 2 arguments")
+			}
+			a, err := e.evalString(args[0])
+			if err != nil {
+				return false, err
+			}
+			b, err := e.evalString(args[1])
+			if err != nil {
+				return false, err
+			}
+			return a == b, nil
+		case "Fn::Not", "Fn::And", "Fn::Or":
+			args, _ := arg.([]interface{})
+			results := []bool{}
+			for _, a := range args {
+				r, err := e.evalCondition(a)
+				if err != nil {
+					return false, err
+				}
+				results = append(results, r)
+			}
+			switch fn {
+			case "Fn::Not":
+				if len(results) != 1 {
+					return false, fmt.Errorf("Template error: Fn::Not requires 1 argument")
+				}
+				return !results[0], nil
+			case "Fn::And":
+				for _, r := range results {
+					if !r {
+						return false, nil
+					}
+				}
+				return true, nil
+			default:
+				for _, r := range results {
+					if r {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+		}
+	}
+	return false, fmt.Errorf("Template error: invalid condition %v", node)
+}
+
+func (e *fakeEvaluator) evalString(node interface{}) (string, error) {
+	v, err := e.eval(node)
+	if err != nil {
+		return "", err
+	}
+	return fakeString(v)
+}
+
+func (e *fakeEvaluator) evalList(node interface{}) ([]string, error) {
+	v, err := e.eval(node)
+	if err != nil {
+		return nil, err
+	}
+	switch l := v.(type) {
+	case []interface{}:
+		items := []string{}
+		for _, i := range l {
+			s, err := fakeString(i)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, s)
+		}
+		return items, nil
+	case []string:
+		return l, nil
+	}
+	return nil, fmt.Errorf("Template error: expected a list but was %v", v)
+}
+
+func (e *fakeEvaluator) eval(node interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case []interface{}:
+		items := []interface{}{}
+		for _, i := range n {
+			v, err := e.eval(i)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := v.(fakeNoValue); !ok {
+				items = append(items, v)
+			}
+		}
+		return items, nil
+	case map[string]interface{}:
+		if len(n) == 1 {
+			for fn, arg := range n {
+				if fn == "Ref" || strings.HasPrefix(fn, "Fn::") {
+					return e.evalFunction(fn, arg)
+				}
+			}
+		}
+		m := map[string]interface{}{}
+		for k, i := range n {
+			v, err := e.eval(i)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := v.(fakeNoValue); !ok {
+				m[k] = v
+			}
+		}
+		return m, nil
+	}
+	return node, nil
+}
+
+func (e *fakeEvaluator) evalFunction(fn string, arg interface{}) (interface{}, error) {
+	switch fn {
+	case "Ref":
+		name, _ := arg.(string)
+		return e.ref(name)
+	case "Fn::GetAtt":
+		switch a := arg.(type) {
+		case []interface{}:
+			if len(a) == 2 {
+				id, _ := a[0].(string)
+				attr, err := e.evalString(a[1])
+				if err != nil {
+					return nil, err
+				}
+				return e.getAtt(id, attr)
+			}
+		case string:
+			if c := strings.SplitN(a, ".", 2); len(c) == 2 {
+				return e.getAtt(c[0], c[1])
+			}
+		}
+		return nil, fmt.Errorf("Template error: invalid Fn::GetAtt %v", arg)
+	case "Fn::Sub":
+		return e.sub(arg)
+	case "Fn::Join":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("Template error: Fn::Join requires 2 arguments")
+		}
+		delim, err := e.evalString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		items, err := e.evalList(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return strings.Join(items, delim), nil
+	case "Fn::Select":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("Template error: Fn::Select requires 2 arguments")
+		}
+		index, err := e.evalString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			return nil, fmt.Errorf("Template error: invalid index for Fn::Select: %s", index)
+		}
+		items, err := e.evalList(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= len(items) {
+			return nil, fmt.Errorf("Template error: Fn::Select cannot select nonexistent value at index %d", i)
+		}
+		return items[i], nil
+	case "Fn::Split":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("Template error: Fn::Split requires 2 arguments")
+		}
+		delim, err := e.evalString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		s, err := e.evalString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(s, delim), nil
+	case "Fn::If":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 3 {
+			return nil, fmt.Errorf("Template error: Fn::If requires 3 arguments")
+		}
+		name, _ := args[0].(string)
+		enabled, err := e.condition(name)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			return e.eval(args[1])
+		}
+		return e.eval(args[2])
+	case "Fn::FindInMap":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 3 {
+			return nil, fmt.Errorf("Template error: Fn::FindInMap requires 3 arguments")
+		}
+		keys := []string{}
+		for _, a := range args {
+			k, err := e.evalString(a)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		}
+		m, _ := e.section("Mappings")[keys[0]].(map[string]interface{})
+		second, _ := m[keys[1]].(map[string]interface{})
+		v, ok := second[keys[2]]
+		if !ok {
+			return nil, fmt.Errorf("Template error: unable to get mapping for %s::%s::%s", keys[0], keys[1], keys[2])
+		}
+		return v, nil
+	case "Fn::ImportValue":
+		name, err := e.evalString(arg)
+		if err != nil {
+			return nil, err
+		}
+		return e.importValue(name)
+	case "Fn::GetAZs":
+		return []interface{}{e.cf.Region + "a", e.cf.Region + "b", e.cf.Region + "c"}, nil
+	case "Fn::Base64":
+		s, err := e.evalString(arg)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(s)), nil
+	}
+	return nil, fmt.Errorf("Template error: unsupported intrinsic function %s", fn)
+}
+
+func (e *fakeEvaluator) ref(name string) (interface{}, error) {
+	switch name {
+	case "AWS::NoValue":
+		return fakeNoValue{}, nil
+	case "AWS::StackName":
+		return e.stack.name, nil
+	case "AWS::StackId":
+		return e.stack.id, nil
+	case "AWS::Region":
+		return e.cf.Region, nil
+	case "AWS::AccountId":
+		return fakeAccountID, nil
+	case "AWS::Partition":
+		return "aws", nil
+	case "AWS::URLSuffix":
+		return "amazonaws.com", nil
+	case "AWS::NotificationARNs":
+		return []interface{}{}, nil
+	}
+
+	if v, ok := e.stack.params[name]; ok {
+		def, _ := e.section("Parameters")[name].(map[string]interface{})
+		if typ, _ := def["Type"].(string); strings.HasPrefix(typ, "List<") || typ == "CommaDelimitedList" {
+			items := []interface{}{}
+			for _, i := range strings.Split(v, ",") {
+				items = append(items, i)
+			}
+			return items, nil
+		}
+		return v, nil
+	}
+
+	r, err := e.resource(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.physicalID, nil
+}
+
+func (e *fakeEvaluator) getAtt(id string, attr string) (interface{}, error) {
+	r, err := e.resource(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.nested != nil && strings.HasPrefix(attr, "Outputs.") {
+		key := strings.TrimPrefix(attr, "Outputs.")
+		v, ok := r.nested.outputs[key]
+		if !ok {
+			return nil, fmt.Errorf("Template error: the nested stack %s has no output %s", id, key)
+		}
+		return v, nil
+	}
+	switch attr {
+	case "Arn":
+		return fmt.Sprintf("arn:aws:%s:%s:%s:%s", strings.ToLower(strings.Split(r.typ, "::")[1]), e.cf.Region, fakeAccountID, r.physicalID), nil
+	case "DNSName":
+		return fakeLoadBalancerDNSName(r, e.cf.Region), nil
+	}
+	return fmt.Sprintf("%s.%s", r.physicalID, attr), nil
+}
+
+func (e *fakeEvaluator) resource(id string) (*fakeResource, error) {
+	r, ok := e.stack.resources[id]
+	if !ok || r.physicalID == "" {
+		return nil, fmt.Errorf("Template format error: Unresolved resource dependencies [%s] in the Resources block of the template", id)
+	}
+	return r, nil
+}
+
+func (e *fakeEvaluator) importValue(name string) (interface{}, error) {
+	owner, ok := e.cf.exports[name]
+	if !ok {
+		return nil, fmt.Errorf("No export named %s found", name)
+	}
+	s, err := e.cf.findStack(owner)
+	if err != nil {
+		return nil, err
+	}
+	return s.outputs[s.exports[name]], nil
+}
+
+func (e *fakeEvaluator) sub(arg interface{}) (interface{}, error) {
+	var s string
+	vars := map[string]interface{}{}
+	switch a := arg.(type) {
+	case string:
+		s = a
+	case []interface{}:
+		if len(a) != 2 {
+			return nil, fmt.Errorf("Template error: Fn::Sub requires 1 or 2 arguments")
+		}
+		s, _ = a[0].(string)
+		vars, _ = a[1].(map[string]interface{})
+	}
+
+	var err error
+	result := fakeSubVariablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := m[2 : len(m)-1]
+		var v interface{}
+		if def, ok := vars[name]; ok {
+			v, err = e.eval(def)
+		} else if c := strings.SplitN(name, ".", 2); len(c) == 2 && !strings.HasPrefix(name, "AWS::") {
+			v, err = e.getAtt(c[0], c[1])
+		} else {
+			v, err = e.ref(name)
+		}
+		if err != nil {
+			return m
+		}
+		str, strErr := fakeString(v)
+		if strErr != nil {
+			err = strErr
+		}
+		return str
+	})
+	if err != nil {
+		return nil, err
+	}
+	// `${!Literal}` is written as `${Literal}`
+	return strings.Replace(result, "${!", "${", -1), nil
+}
+
+// fakeReferences returns the logical ids referred from the node via `Ref`, `Fn::GetAtt`, `Fn::Sub` or `DependsOn`
+func fakeReferences(node interface{}) []string {
+	ids := []string{}
+	switch n := node.(type) {
+	case []interface{}:
+		for _, i := range n {
+			ids = append(ids, fakeReferences(i)...)
+		}
+	case map[string]interface{}:
+		for k, v := range n {
+			switch k {
+			case "Ref":
+				if s, ok := v.(string); ok {
+					ids = append(ids, s)
+				}
+			case "Fn::GetAtt":
+				if a, ok := v.([]interface{}); ok && len(a) > 0 {
+					if s, ok := a[0].(string); ok {
+						ids = append(ids, s)
+					}
+				} else if s, ok := v.(string); ok {
+					ids = append(ids, strings.SplitN(s, ".", 2)[0])
+				}
+			case "Fn::Sub":
+				s, _ := v.(string)
+				if a, ok := v.([]interface{}); ok && len(a) > 0 {
+					s, _ = a[0].(string)
+				}
+				for _, m := range fakeSubVariablePattern.FindAllStringSubmatch(s, -1) {
+					ids = append(ids, strings.SplitN(m[1], ".", 2)[0])
+				}
+			case "DependsOn":
+				switch d := v.(type) {
+				case string:
+					ids = append(ids, d)
+				case []interface{}:
+					for _, i := range d {
+						if s, ok := i.(string); ok {
+							ids = append(ids, s)
+						}
+					}
+				}
+			}
+			ids = append(ids, fakeReferences(v)...)
+		}
+	}
+	return ids
+}
+
+func fakeString(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case json.Number:
+		return s.String(), nil
+	case bool:
+		return strconv.FormatBool(s), nil
+	case []interface{}:
+		items := []string{}
+		for _, i := range s {
+			str, err := fakeString(i)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	case []string:
+		return strings.Join(s, ","), nil
+	case fakeNoValue:
+		return "", nil
+	}
+	return "", fmt.Errorf("Template error: expected a string but was %v", v)
+}
@@ -0,0 +1,27 @@
+package helper
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+)
+
+// FakeCloudWatchLogs returns the log events it holds, in a single page, from FilterLogEvents.
+// Calls to the APIs it doesn't implement panic
+type FakeCloudWatchLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+
+	// Events are the log events keyed by the name of the log group
+	Events map[string][]*cloudwatchlogs.FilteredLogEvent
+}
+
+// FilterLogEvents returns the events in the log group not older than the start time. Filter patterns are ignored
+func (f *FakeCloudWatchLogs) FilterLogEvents(input *cloudwatchlogs.FilterLogEventsInput) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	out := &cloudwatchlogs.FilterLogEventsOutput{}
+	for _, e := range f.Events[aws.StringValue(input.LogGroupName)] {
+		if input.StartTime == nil || aws.Int64Value(e.Timestamp) >= aws.Int64Value(input.StartTime) {
+			out.Events = append(out.Events, e)
+		}
+	}
+	return out, nil
+}
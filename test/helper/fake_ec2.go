@@ -0,0 +1,87 @@
+package helper
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// FakeEC2 answers the EC2 APIs called while validating stacks, from the key pairs, VPCs and subnets it holds.
+// Calls to the APIs it doesn't implement panic
+type FakeEC2 struct {
+	ec2iface.EC2API
+
+	KeyPairs []string
+	Vpcs     []*ec2.Vpc
+	Subnets  []*ec2.Subnet
+}
+
+func (f *FakeEC2) DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	out := &ec2.DescribeKeyPairsOutput{}
+	for _, name := range input.KeyNames {
+		found := false
+		for _, k := range f.KeyPairs {
+			found = found || k == aws.StringValue(name)
+		}
+		if !found {
+			return nil, awserr.NewRequestFailure(awserr.New("InvalidKeyPair.NotFound", fmt.Sprintf("The key pair '%s' does not exist", aws.StringValue(name)), nil), 400, "")
+		}
+		out.KeyPairs = append(out.KeyPairs, &ec2.KeyPairInfo{KeyName: name})
+	}
+	return out, nil
+}
+
+// CreateVolume supports dry-run requests only, which succeed as long as the volume type is known
+func (f *FakeEC2) CreateVolume(input *ec2.CreateVolumeInput) (*ec2.Volume, error) {
+	if !aws.BoolValue(input.DryRun) {
+		panic("FakeEC2 supports dry-run requests to CreateVolume only")
+	}
+	switch aws.StringValue(input.VolumeType) {
+	case "standard", "gp2", "io1", "st1", "sc1":
+		return nil, awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+	default:
+		return nil, awserr.New("InvalidParameterValue", fmt.Sprintf("Value (%s) for parameter volumeType is invalid", aws.StringValue(input.VolumeType)), nil)
+	}
+}
+
+func (f *FakeEC2) DescribeVpcs(input *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	out := &ec2.DescribeVpcsOutput{}
+	for _, id := range input.VpcIds {
+		for _, v := range f.Vpcs {
+			if aws.StringValue(v.VpcId) == aws.StringValue(id) {
+				out.Vpcs = append(out.Vpcs, v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// DescribeSubnets supports the vpc-id filter only
+func (f *FakeEC2) DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	out := &ec2.DescribeSubnetsOutput{}
+	for _, s := range f.Subnets {
+		matched := true
+		for _, filter := range input.Filters {
+			if aws.StringValue(filter.Name) != "vpc-id" {
+				panic(fmt.Sprintf("FakeEC2 doesn't support the filter %s", aws.StringValue(filter.Name)))
+			}
+			matched = matched && containsString(aws.StringValueSlice(filter.Values), aws.StringValue(s.VpcId))
+		}
+		if matched {
+			out.Subnets = append(out.Subnets, s)
+		}
+	}
+	return out, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+)
+
+// FakeELB describes the classic load balancers created in the fake CloudFormation.
+// Calls to the APIs it doesn't implement panic
+type FakeELB struct {
+	elbiface.ELBAPI
+
+	CloudFormation *FakeCloudFormation
+}
+
+func (f *FakeELB) DescribeLoadBalancers(input *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	resources := map[string]*fakeResource{}
+	for _, r := range f.CloudFormation.liveResources("AWS::ElasticLoadBalancing::LoadBalancer") {
+		resources[r.physicalID] = r
+	}
+
+	out := &elb.DescribeLoadBalancersOutput{}
+	for _, name := range input.LoadBalancerNames {
+		r, ok := resources[aws.StringValue(name)]
+		if !ok {
+			return nil, awserr.NewRequestFailure(awserr.New(elb.ErrCodeAccessPointNotFoundException, fmt.Sprintf("There is no ACTIVE Load Balancer named '%s'", aws.StringValue(name)), nil), 400, "")
+		}
+		out.LoadBalancerDescriptions = append(out.LoadBalancerDescriptions, &elb.LoadBalancerDescription{
+			LoadBalancerName: aws.String(r.physicalID),
+			DNSName:          aws.String(fakeLoadBalancerDNSName(r, f.CloudFormation.Region)),
+		})
+	}
+	return out, nil
+}
+
+// FakeELBV2 describes the network and application load balancers created in the fake CloudFormation.
+// Calls to the APIs it doesn't implement panic
+type FakeELBV2 struct {
+	elbv2iface.ELBV2API
+
+	CloudFormation *FakeCloudFormation
+}
+
+func (f *FakeELBV2) DescribeLoadBalancers(input *elbv2.DescribeLoadBalancersInput) (*elbv2.DescribeLoadBalancersOutput, error) {
+	resources := map[string]*fakeResource{}
+	for _, r := range f.CloudFormation.liveResources("AWS::ElasticLoadBalancingV2::LoadBalancer") {
+		resources[r.physicalID] = r
+	}
+
+	out := &elbv2.DescribeLoadBalancersOutput{}
+	for _, arn := range input.LoadBalancerArns {
+		r, ok := resources[aws.StringValue(arn)]
+		if !ok {
+			return nil, awserr.NewRequestFailure(awserr.New(elbv2.ErrCodeLoadBalancerNotFoundException, fmt.Sprintf("Load balancers '[%s]' not found", aws.StringValue(arn)), nil), 400, "")
+		}
+		out.LoadBalancers = append(out.LoadBalancers, &elbv2.LoadBalancer{
+			LoadBalancerArn: aws.String(r.physicalID),
+			DNSName:         aws.String(fakeLoadBalancerDNSName(r, f.CloudFormation.Region)),
+		})
+	}
+	return out, nil
+}
+
+// fakeLoadBalancerDNSName returns the DNS name of the load balancer, which is also the value of its `DNSName` attribute
+func fakeLoadBalancerDNSName(r *fakeResource, region string) string {
+	name := r.physicalID
+	if strings.HasPrefix(name, "arn:") {
+		components := strings.Split(name, "/")
+		name = components[len(components)-2]
+	}
+	return fmt.Sprintf("%s-123456789.%s.elb.amazonaws.com", strings.ToLower(name), region)
+}
@@ -0,0 +1,61 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+// FakeRoute53 answers the Route 53 APIs called while validating the DNS settings, from the hosted zones and the record sets it holds.
+// Calls to the APIs it doesn't implement panic
+type FakeRoute53 struct {
+	route53iface.Route53API
+
+	HostedZones []*route53.HostedZone
+	// RecordSets are the record sets keyed by the ID of the hosted zone
+	RecordSets map[string][]*route53.ResourceRecordSet
+}
+
+func (f *FakeRoute53) GetHostedZone(input *route53.GetHostedZoneInput) (*route53.GetHostedZoneOutput, error) {
+	hz := f.hostedZone(aws.StringValue(input.Id))
+	if hz == nil {
+		return nil, awserr.NewRequestFailure(awserr.New(route53.ErrCodeNoSuchHostedZone, fmt.Sprintf("No hosted zone found with ID: %s", aws.StringValue(input.Id)), nil), 404, "")
+	}
+	return &route53.GetHostedZoneOutput{HostedZone: hz}, nil
+}
+
+func (f *FakeRoute53) ListHostedZonesByName(input *route53.ListHostedZonesByNameInput) (*route53.ListHostedZonesByNameOutput, error) {
+	out := &route53.ListHostedZonesByNameOutput{DNSName: input.DNSName}
+	for _, hz := range f.HostedZones {
+		if input.DNSName == nil || aws.StringValue(hz.Name) >= aws.StringValue(input.DNSName) {
+			out.HostedZones = append(out.HostedZones, hz)
+		}
+	}
+	return out, nil
+}
+
+func (f *FakeRoute53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	hz := f.hostedZone(aws.StringValue(input.HostedZoneId))
+	if hz == nil {
+		return nil, awserr.NewRequestFailure(awserr.New(route53.ErrCodeNoSuchHostedZone, fmt.Sprintf("No hosted zone found with ID: %s", aws.StringValue(input.HostedZoneId)), nil), 404, "")
+	}
+	return &route53.ListResourceRecordSetsOutput{ResourceRecordSets: f.RecordSets[hostedZoneID(aws.StringValue(hz.Id))]}, nil
+}
+
+func (f *FakeRoute53) hostedZone(id string) *route53.HostedZone {
+	for _, hz := range f.HostedZones {
+		if hostedZoneID(aws.StringValue(hz.Id)) == hostedZoneID(id) {
+			return hz
+		}
+	}
+	return nil
+}
+
+// hostedZoneID strips the /hostedzone/ prefix, which the Route 53 APIs accept as well as the bare ID
+func hostedZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
@@ -0,0 +1,239 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// FakeS3 is an in-memory S3 holding objects in the buckets given on creation.
+// Calls to the APIs it doesn't implement panic
+type FakeS3 struct {
+	s3iface.S3API
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*fakeObject
+}
+
+type fakeObject struct {
+	body         string
+	metadata     map[string]*string
+	lastModified time.Time
+	input        s3.PutObjectInput
+}
+
+func NewFakeS3(buckets ...string) *FakeS3 {
+	s := &FakeS3{buckets: map[string]map[string]*fakeObject{}}
+	for _, b := range buckets {
+		s.buckets[b] = map[string]*fakeObject{}
+	}
+	return s
+}
+
+// Object returns the content of the object
+func (s *FakeS3) Object(bucket string, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.buckets[bucket][key]
+	if !ok {
+		return "", false
+	}
+	return o.body, true
+}
+
+// PutObjectInput returns the last request which put the object, without the body
+func (s *FakeS3) PutObjectInput(bucket string, key string) (*s3.PutObjectInput, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	input := o.input
+	return &input, true
+}
+
+// Keys returns the sorted keys of the objects in the bucket
+func (s *FakeS3) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedKeys(bucket, "")
+}
+
+// ObjectAtURL returns the content of the object at the path-style or virtual-hosted-style URL, e.g. the TemplateURL of a stack
+func (s *FakeS3) ObjectAtURL(rawurl string) (string, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", false
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	if strings.HasPrefix(u.Host, "s3.") || strings.HasPrefix(u.Host, "s3-") {
+		components := strings.SplitN(path, "/", 2)
+		if len(components) != 2 {
+			return "", false
+		}
+		return s.Object(components[0], components[1])
+	}
+	return s.Object(strings.SplitN(u.Host, ".", 2)[0], path)
+}
+
+func (s *FakeS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte{}
+	if input.Body != nil {
+		if body, err = ioutil.ReadAll(input.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	// Metadata keys are returned in the canonical form of HTTP headers as S3 does
+	metadata := map[string]*string{}
+	for k, v := range input.Metadata {
+		metadata[http.CanonicalHeaderKey(k)] = v
+	}
+
+	stored := *input
+	stored.Body = nil
+	bucket[aws.StringValue(input.Key)] = &fakeObject{
+		body:         string(body),
+		metadata:     metadata,
+		lastModified: time.Now(),
+		input:        stored,
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (s *FakeS3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, err := s.object(input.Bucket, input.Key, "NotFound")
+	if err != nil {
+		return nil, err
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(o.body))),
+		LastModified:  aws.Time(o.lastModified),
+		Metadata:      o.metadata,
+	}, nil
+}
+
+func (s *FakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, err := s.object(input.Bucket, input.Key, s3.ErrCodeNoSuchKey)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewBufferString(o.body)),
+		ContentLength: aws.Int64(int64(len(o.body))),
+		LastModified:  aws.Time(o.lastModified),
+		Metadata:      o.metadata,
+	}, nil
+}
+
+func (s *FakeS3) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	maxKeys := int(aws.Int64Value(input.MaxKeys))
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	// Continuation tokens are the last keys returned by the previous pages
+	out := &s3.ListObjectsV2Output{Contents: []*s3.Object{}, IsTruncated: aws.Bool(false)}
+	for _, key := range s.sortedKeys(aws.StringValue(input.Bucket), aws.StringValue(input.Prefix)) {
+		if key <= aws.StringValue(input.ContinuationToken) {
+			continue
+		}
+		if len(out.Contents) == maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = out.Contents[maxKeys-1].Key
+			break
+		}
+		o := bucket[key]
+		out.Contents = append(out.Contents, &s3.Object{
+			Key:          aws.String(key),
+			LastModified: aws.Time(o.lastModified),
+			Size:         aws.Int64(int64(len(o.body))),
+		})
+	}
+	out.KeyCount = aws.Int64(int64(len(out.Contents)))
+	return out, nil
+}
+
+func (s *FakeS3) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &s3.DeleteObjectsOutput{}
+	for _, id := range input.Delete.Objects {
+		delete(bucket, aws.StringValue(id.Key))
+		if !aws.BoolValue(input.Delete.Quiet) {
+			out.Deleted = append(out.Deleted, &s3.DeletedObject{Key: id.Key})
+		}
+	}
+	return out, nil
+}
+
+func (s *FakeS3) bucket(name *string) (map[string]*fakeObject, error) {
+	bucket, ok := s.buckets[aws.StringValue(name)]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil), 404, "")
+	}
+	return bucket, nil
+}
+
+func (s *FakeS3) object(bucketName *string, key *string, notFoundCode string) (*fakeObject, error) {
+	bucket, err := s.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := bucket[aws.StringValue(key)]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New(notFoundCode, fmt.Sprintf("The specified key does not exist: %s", aws.StringValue(key)), nil), 404, "")
+	}
+	return o, nil
+}
+
+func (s *FakeS3) sortedKeys(bucket string, prefix string) []string {
+	keys := []string{}
+	for k := range s.buckets[bucket] {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// FakeSTS returns the fixed identity from GetCallerIdentity.
+// Calls to the APIs it doesn't implement panic
+type FakeSTS struct {
+	stsiface.STSAPI
+
+	Account string
+	ARN     string
+}
+
+func (s *FakeSTS) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(s.Account),
+		Arn:     aws.String(s.ARN),
+		UserId:  aws.String("AIDAIOSFODNN7EXAMPLE"),
+	}, nil
+}
@@ -3,7 +3,10 @@ package helper
 import (
 	"bytes"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"io/ioutil"
 )
 
 type DummyS3ObjectPutterService struct {
@@ -12,9 +15,27 @@ type DummyS3ObjectPutterService struct {
 	ExpectedBody          string
 	ExpectedContentType   string
 	ExpectedContentLength int64
+	Throttler             *Throttler
+	// UploadedFingerprints are the fingerprints of assets already uploaded, by key
+	UploadedFingerprints map[string]string
+}
+
+func (s3Svc DummyS3ObjectPutterService) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	fp, ok := s3Svc.UploadedFingerprints[*input.Key]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "")
+	}
+	return &s3.HeadObjectOutput{
+		Metadata: map[string]*string{"Kube-Aws-Fingerprint": aws.String(fp)},
+	}, nil
 }
 
 func (s3Svc DummyS3ObjectPutterService) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if err := s3Svc.Throttler.Throttle(); err != nil {
+		// Consume the body as the SDK does on a failed request
+		ioutil.ReadAll(input.Body)
+		return nil, err
+	}
 
 	if s3Svc.ExpectedContentLength != *input.ContentLength {
 		return nil, fmt.Errorf(
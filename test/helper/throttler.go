@@ -0,0 +1,29 @@
+package helper
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Throttler fails the first Count calls to a dummy service with an AWS error, to simulate API rate limiting
+type Throttler struct {
+	Count int
+	// Code is the AWS error code returned. Defaults to "Throttling"
+	Code  string
+	Calls int
+}
+
+// Throttle records a call and returns an error while the calls are throttled
+func (t *Throttler) Throttle() error {
+	if t == nil {
+		return nil
+	}
+	t.Calls++
+	if t.Calls > t.Count {
+		return nil
+	}
+	code := t.Code
+	if code == "" {
+		code = "Throttling"
+	}
+	return awserr.New(code, "Rate exceeded", nil)
+}
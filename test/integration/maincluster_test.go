@@ -759,6 +759,85 @@ etcd:
 				},
 			},
 		},
+		{
+			context: "WithS3AssetsEncryptedWithKMSKey",
+			configYaml: minimalValidConfigYaml + `
+s3Assets:
+  serverSideEncryption: aws:kms
+  kmsKeyArn: arn:aws:kms:us-west-1:xxxxxxxxx:key/s3-assets
+  acl: bucket-owner-full-control
+  tags:
+    Owner: platform-team
+worker:
+  nodePools:
+  - name: pool1
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					expected := model.S3Assets{
+						ServerSideEncryption: "aws:kms",
+						KMSKeyARN:            "arn:aws:kms:us-west-1:xxxxxxxxx:key/s3-assets",
+						ACL:                  "bucket-owner-full-control",
+						Tags:                 map[string]string{"Owner": "platform-team"},
+					}
+					if !reflect.DeepEqual(c.S3Assets, expected) {
+						t.Errorf("s3Assets didn't match : expected=%+v actual=%+v", expected, c.S3Assets)
+					}
+					if !reflect.DeepEqual(c.NodePools[0].S3Assets, expected) {
+						t.Errorf("s3Assets should be inherited to a node pool but was not : expected=%+v actual=%+v", expected, c.NodePools[0].S3Assets)
+					}
+				},
+			},
+			assertCluster: []ClusterTester{
+				func(c root.Cluster, t *testing.T) {
+					decrypt := `{"Action":"kms:Decrypt","Effect":"Allow","Resource":"arn:aws:kms:us-west-1:xxxxxxxxx:key/s3-assets"}`
+					templates := map[string]func() (string, error){
+						"control plane": c.ControlPlane().RenderStackTemplateAsString,
+						"etcd":          c.Etcd().RenderStackTemplateAsString,
+						"pool1":         c.NodePools()[0].RenderStackTemplateAsString,
+					}
+					for name, render := range templates {
+						template, err := render()
+						if err != nil {
+							t.Errorf("failed to render %s stack template: %v", name, err)
+							continue
+						}
+						if !strings.Contains(template, decrypt) {
+							t.Errorf("%s nodes should be allowed to decrypt userdata with the s3Assets KMS key but were not", name)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithAWSCredentialsAssumingRole",
+			configYaml: minimalValidConfigYaml + `
+awsCredentials:
+  profile: deployer
+  roleArn: arn:aws:iam::123456789012:role/kube-aws
+  externalId: my-external-id
+  sessionDuration: 1h
+worker:
+  nodePools:
+  - name: pool1
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					expected := model.AWSCredentials{
+						Profile:         "deployer",
+						RoleARN:         "arn:aws:iam::123456789012:role/kube-aws",
+						ExternalID:      "my-external-id",
+						SessionDuration: "1h",
+					}
+					if c.AWSCredentials != expected {
+						t.Errorf("awsCredentials didn't match : expected=%+v actual=%+v", expected, c.AWSCredentials)
+					}
+					if c.NodePools[0].AWSCredentials != expected {
+						t.Errorf("awsCredentials should be inherited to a node pool but was not : expected=%+v actual=%+v", expected, c.NodePools[0].AWSCredentials)
+					}
+				},
+			},
+		},
 		{
 			context: "WithEtcdMemberIdentityProviderEIP",
 			configYaml: minimalValidConfigYaml + `
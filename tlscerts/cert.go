@@ -27,12 +27,12 @@ func (cs Certificates) GetBySubjectCommonNamePattern(subjectCNMatch string) (cer
 }
 
 type Certificate struct {
-	Issuer      DN
-	NotBefore   time.Time
-	NotAfter    time.Time
-	Subject     DN
-	DNSNames    []string
-	IPAddresses []net.IP
+	Issuer      DN        `json:"issuer"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	Subject     DN        `json:"subject"`
+	DNSNames    []string  `json:"dnsNames"`
+	IPAddresses []net.IP  `json:"ipAddresses"`
 }
 
 func (c Certificate) IsExpired() bool {
@@ -77,8 +77,8 @@ func (c Certificate) String() string {
 }
 
 type DN struct {
-	Organization []string
-	CommonName   string
+	Organization []string `json:"organization"`
+	CommonName   string   `json:"commonName"`
 }
 
 func (dn DN) String() string {
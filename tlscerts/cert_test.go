@@ -3,6 +3,7 @@ package tlscerts
 import (
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"github.com/kubernetes-incubator/kube-aws/tlsutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -112,3 +113,23 @@ func getSelfSignedCert(t *testing.T, commonName, organization string) *x509.Cert
 
 	return cert
 }
+
+func TestCertificateToJSON(t *testing.T) {
+
+	cert := Certificate{
+		Issuer:      DN{CommonName: "kube-ca"},
+		Subject:     DN{Organization: []string{"system:masters"}, CommonName: "kube-admin"},
+		NotAfter:    time.Date(2027, 1, 2, 3, 4, 5, 0, time.UTC),
+		DNSNames:    []string{"kubernetes"},
+		IPAddresses: []net.IP{net.IPv4(10, 3, 0, 1)},
+	}
+	b, err := json.Marshal(cert)
+	require.NoError(t, err)
+
+	s := string(b)
+	assert.Contains(t, s, `"issuer":{"organization":null,"commonName":"kube-ca"}`)
+	assert.Contains(t, s, `"subject":{"organization":["system:masters"],"commonName":"kube-admin"}`)
+	assert.Contains(t, s, `"notAfter":"2027-01-02T03:04:05Z"`)
+	assert.Contains(t, s, `"dnsNames":["kubernetes"]`)
+	assert.Contains(t, s, `"ipAddresses":["10.3.0.1"]`)
+}
@@ -0,0 +1,81 @@
+package tlsutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// KeyAlgorithm is the algorithm and the size of a private key
+type KeyAlgorithm string
+
+const (
+	RSA2048   KeyAlgorithm = "rsa-2048"
+	RSA4096   KeyAlgorithm = "rsa-4096"
+	ECDSAP256 KeyAlgorithm = "ecdsa-p256"
+	ECDSAP384 KeyAlgorithm = "ecdsa-p384"
+
+	// DefaultKeyAlgorithm is the algorithm of the keys generated by NewPrivateKey
+	DefaultKeyAlgorithm = RSA2048
+)
+
+// KeyAlgorithms are all the supported key algorithms
+var KeyAlgorithms = []KeyAlgorithm{RSA2048, RSA4096, ECDSAP256, ECDSAP384}
+
+// Validate returns an error when the algorithm is not supported. An empty algorithm is valid and means DefaultKeyAlgorithm
+func (a KeyAlgorithm) Validate() error {
+	if a == "" {
+		return nil
+	}
+	names := []string{}
+	for _, s := range KeyAlgorithms {
+		if a == s {
+			return nil
+		}
+		names = append(names, string(s))
+	}
+	return fmt.Errorf("unsupported key algorithm \"%s\". It must be one of: %s", a, strings.Join(names, ", "))
+}
+
+// NewPrivateKeyWithAlgorithm generates a private key of the algorithm, or of DefaultKeyAlgorithm when the algorithm is empty
+func NewPrivateKeyWithAlgorithm(a KeyAlgorithm) (crypto.Signer, error) {
+	switch a {
+	case "", RSA2048:
+		return rsa.GenerateKey(rand.Reader, RSAKeySize)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case ECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	}
+	return nil, a.Validate()
+}
+
+// PublicKeysEqual returns true when both of the public keys are the same.
+// Keys of different types or of types other than RSA and ECDSA are never equal
+func PublicKeysEqual(a, b crypto.PublicKey) bool {
+	switch k := a.(type) {
+	case *rsa.PublicKey:
+		o, ok := b.(*rsa.PublicKey)
+		return ok && k.N.Cmp(o.N) == 0 && k.E == o.E
+	case *ecdsa.PublicKey:
+		o, ok := b.(*ecdsa.PublicKey)
+		return ok && k.Curve == o.Curve && k.X.Cmp(o.X) == 0 && k.Y.Cmp(o.Y) == 0
+	}
+	return false
+}
+
+// keyUsage returns the key usage of a certificate for the key.
+// Key encipherment is for RSA keys only, as ECDSA keys are used for signatures but not for key transport
+func keyUsage(key crypto.Signer, usage x509.KeyUsage) x509.KeyUsage {
+	if _, ok := key.Public().(*rsa.PublicKey); ok {
+		return usage | x509.KeyUsageKeyEncipherment
+	}
+	return usage
+}
@@ -0,0 +1,126 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPrivateKeyWithAlgorithm(t *testing.T) {
+
+	rsaKey, err := NewPrivateKeyWithAlgorithm("")
+	require.NoError(t, err)
+	assert.Equal(t, RSAKeySize, rsaKey.(*rsa.PrivateKey).N.BitLen())
+
+	ecKey, err := NewPrivateKeyWithAlgorithm(ECDSAP384)
+	require.NoError(t, err)
+	assert.Equal(t, elliptic.P384(), ecKey.(*ecdsa.PrivateKey).Curve)
+
+	_, err = NewPrivateKeyWithAlgorithm("ecdsa-p521")
+	assert.Error(t, err)
+}
+
+func TestEncodeECPrivateKeyPEM(t *testing.T) {
+
+	key, err := NewPrivateKeyWithAlgorithm(ECDSAP256)
+	require.NoError(t, err)
+	b := EncodePrivateKeyPEM(key)
+
+	block, _ := pem.Decode(b)
+	require.NotNil(t, block)
+	assert.Equal(t, "EC PRIVATE KEY", block.Type)
+
+	decodedKey, err := DecodePrivateKeyPEM(b)
+	require.NoError(t, err)
+	assert.True(t, PublicKeysEqual(key.Public(), decodedKey.Public()))
+}
+
+func TestDecodeECPrivateKeyPEMWithParameters(t *testing.T) {
+
+	// `openssl ecparam -genkey` writes the curve parameters before the key
+	key, err := NewPrivateKeyWithAlgorithm(ECDSAP256)
+	require.NoError(t, err)
+	params := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}})
+
+	decodedKey, err := DecodePrivateKeyPEM(append(params, EncodePrivateKeyPEM(key)...))
+	require.NoError(t, err)
+	assert.True(t, PublicKeysEqual(key.Public(), decodedKey.Public()))
+}
+
+func TestDecodePKCS8PrivateKeyPEM(t *testing.T) {
+
+	key, err := NewPrivateKeyWithAlgorithm(ECDSAP256)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	decodedKey, err := DecodePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, PublicKeysEqual(key.Public(), decodedKey.Public()))
+}
+
+func TestDecodePrivateKeyPEMWithoutKey(t *testing.T) {
+
+	_, err := DecodePrivateKeyPEM(EncodeCertificatePEM(getSelfSignedCert(t, "test CN", "ABC organization")))
+	assert.Error(t, err)
+}
+
+func TestSignedCertificatesWithECDSAKeys(t *testing.T) {
+
+	caKey, err := NewPrivateKeyWithAlgorithm(ECDSAP384)
+	require.NoError(t, err)
+	caCert, err := NewSelfSignedCACertificate(CACertConfig{Duration: Duration365d, CommonName: "kube-ca", Organization: "kube-aws"}, caKey)
+	require.NoError(t, err)
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign, caCert.KeyUsage)
+
+	// An RSA key can be issued a certificate from an ECDSA CA and vice versa
+	for _, alg := range []KeyAlgorithm{ECDSAP256, RSA2048} {
+		key, err := NewPrivateKeyWithAlgorithm(alg)
+		require.NoError(t, err)
+		cert, err := NewSignedServerCertificate(ServerCertConfig{CommonName: "kube-apiserver", Duration: Duration365d}, key, caCert, caKey)
+		require.NoError(t, err)
+		require.NoError(t, cert.CheckSignatureFrom(caCert))
+		assert.True(t, PublicKeysEqual(key.Public(), cert.PublicKey))
+
+		expectedUsage := x509.KeyUsageDigitalSignature
+		if alg == RSA2048 {
+			expectedUsage |= x509.KeyUsageKeyEncipherment
+		}
+		assert.Equal(t, expectedUsage, cert.KeyUsage)
+	}
+}
+
+func TestPublicKeysEqual(t *testing.T) {
+
+	rsaKey, err := NewPrivateKeyWithAlgorithm(RSA2048)
+	require.NoError(t, err)
+	otherRSAKey, err := NewPrivateKeyWithAlgorithm(RSA2048)
+	require.NoError(t, err)
+	ecKey, err := NewPrivateKeyWithAlgorithm(ECDSAP256)
+	require.NoError(t, err)
+	otherECKey, err := NewPrivateKeyWithAlgorithm(ECDSAP256)
+	require.NoError(t, err)
+	p384Key, err := NewPrivateKeyWithAlgorithm(ECDSAP384)
+	require.NoError(t, err)
+
+	// Copies rather than the same pointers to compare the values
+	rsaPub := *rsaKey.Public().(*rsa.PublicKey)
+	ecPub := *ecKey.Public().(*ecdsa.PublicKey)
+
+	assert.True(t, PublicKeysEqual(rsaKey.Public(), &rsaPub))
+	assert.False(t, PublicKeysEqual(rsaKey.Public(), otherRSAKey.Public()))
+	assert.True(t, PublicKeysEqual(ecKey.Public(), &ecPub))
+	assert.False(t, PublicKeysEqual(ecKey.Public(), otherECKey.Public()))
+	assert.False(t, PublicKeysEqual(ecKey.Public(), p384Key.Public()))
+
+	assert.False(t, PublicKeysEqual(rsaKey.Public(), ecKey.Public()))
+	assert.False(t, PublicKeysEqual(ecKey.Public(), rsaKey.Public()))
+	assert.False(t, PublicKeysEqual(rsaPub, &rsaPub))
+	assert.False(t, PublicKeysEqual(nil, nil))
+}
@@ -1,22 +1,45 @@
 package tlsutil
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
+	"strings"
 
 	"errors"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-const certificateType = "CERTIFICATE"
+const (
+	certificateType        = "CERTIFICATE"
+	certificateRequestType = "CERTIFICATE REQUEST"
+)
 
-func EncodePrivateKeyPEM(key *rsa.PrivateKey) []byte {
-	block := pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
+// EncodePrivateKeyPEM encodes an RSA key in PKCS#1 and an ECDSA key in SEC 1, the formats of `openssl genrsa` and `openssl ecparam -genkey` respectively.
+// It panics for keys of any other type
+func EncodePrivateKeyPEM(key crypto.Signer) []byte {
+	var block pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			panic(fmt.Sprintf("failed to marshal ecdsa private key: %v", err))
+		}
+		block = pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}
+	default:
+		panic(fmt.Sprintf("unsupported private key type %T", key))
 	}
 	return pem.EncodeToMemory(&block)
 }
@@ -28,8 +51,20 @@ func promptPassphrase(prompt string) ([]byte, error) {
 	return passphrase, err
 }
 
-func DecodePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode(data)
+// DecodePrivateKeyPEM decodes an RSA or ECDSA private key in PKCS#1, SEC 1 or PKCS#8.
+// Blocks other than the private key, like the EC PARAMETERS block written by `openssl ecparam`, are skipped
+func DecodePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	var block *pem.Block
+	for {
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("failed to find a private key in PEM")
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			break
+		}
+	}
+
 	var blockBytes []byte
 	if x509.IsEncryptedPEMBlock(block) {
 		var passphrase []byte
@@ -50,7 +85,26 @@ func DecodePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
 	} else {
 		blockBytes = block.Bytes
 	}
-	return x509.ParsePKCS1PrivateKey(blockBytes)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(blockBytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(blockBytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(blockBytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case *ecdsa.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type \"%s\"", block.Type)
 }
 
 func EncodeCertificatePEM(cert *x509.Certificate) []byte {
@@ -85,6 +139,22 @@ func DecodeCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
 	return x509.ParseCertificates(decodedCerts)
 }
 
+func EncodeCertificateRequestPEM(csr *x509.CertificateRequest) []byte {
+	block := pem.Block{
+		Type:  certificateRequestType,
+		Bytes: csr.Raw,
+	}
+	return pem.EncodeToMemory(&block)
+}
+
+func DecodeCertificateRequestPEM(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != certificateRequestType {
+		return nil, errors.New("failed to parse certificate request PEM")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
 func IsCertificatePEM(data []byte) bool {
 	block, _ := pem.Decode(data)
 	return block != nil && block.Type == certificateType
@@ -72,6 +72,32 @@ func TestIsCertficatePEMIsTrueForSelfSignedCert(t *testing.T) {
 
 // --- helper functions ---
 
+func TestEncodeCertificateRequestPEM(t *testing.T) {
+
+	key := getPrivateKey(t)
+	cfg := ClientCertConfig{
+		CommonName:   "kube-admin",
+		Organization: []string{"system:masters"},
+		DNSNames:     []string{"kubernetes"},
+		IPAddresses:  []string{"10.3.0.1"},
+	}
+	csr, err := NewCertificateRequest(cfg, key)
+	require.NoError(t, err)
+
+	decodedCSR, err := DecodeCertificateRequestPEM(EncodeCertificateRequestPEM(csr))
+	require.NoError(t, err)
+	require.NoError(t, decodedCSR.CheckSignature())
+
+	assert.Equal(t, "kube-admin", decodedCSR.Subject.CommonName)
+	assert.Equal(t, []string{"system:masters"}, decodedCSR.Subject.Organization)
+	assert.Equal(t, []string{"kubernetes"}, decodedCSR.DNSNames)
+	assert.Equal(t, "10.3.0.1", decodedCSR.IPAddresses[0].String())
+	assert.True(t, PublicKeysEqual(key.Public(), decodedCSR.PublicKey))
+
+	_, err = DecodeCertificateRequestPEM(EncodePrivateKeyPEM(key))
+	assert.Error(t, err)
+}
+
 func getPrivateKey(t *testing.T) *rsa.PrivateKey {
 
 	key, err := NewPrivateKey()
@@ -1,8 +1,8 @@
 package tlsutil
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"errors"
@@ -37,7 +37,7 @@ type ClientCertConfig struct {
 	Duration     time.Duration
 }
 
-func NewSelfSignedCACertificate(cfg CACertConfig, key *rsa.PrivateKey) (*x509.Certificate, error) {
+func NewSelfSignedCACertificate(cfg CACertConfig, key crypto.Signer) (*x509.Certificate, error) {
 	if cfg.Duration <= 0 {
 		return nil, errors.New("self-signed CA cert duration must not be negative or zero")
 	}
@@ -50,9 +50,9 @@ func NewSelfSignedCACertificate(cfg CACertConfig, key *rsa.PrivateKey) (*x509.Ce
 		},
 		NotBefore:             time.Now().UTC(),
 		NotAfter:              time.Now().Add(cfg.Duration).UTC(),
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              keyUsage(key, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign),
 		BasicConstraintsValid: true,
-		IsCA: true,
+		IsCA:                  true,
 	}
 
 	certDERBytes, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, key.Public(), key)
@@ -62,7 +62,7 @@ func NewSelfSignedCACertificate(cfg CACertConfig, key *rsa.PrivateKey) (*x509.Ce
 	return x509.ParseCertificate(certDERBytes)
 }
 
-func NewSignedServerCertificate(cfg ServerCertConfig, key *rsa.PrivateKey, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*x509.Certificate, error) {
+func NewSignedServerCertificate(cfg ServerCertConfig, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
 	ips := make([]net.IP, len(cfg.IPAddresses))
 	for i, ipStr := range cfg.IPAddresses {
 		ips[i] = net.ParseIP(ipStr)
@@ -87,7 +87,7 @@ func NewSignedServerCertificate(cfg ServerCertConfig, key *rsa.PrivateKey, caCer
 		SerialNumber: serial,
 		NotBefore:    caCert.NotBefore,
 		NotAfter:     time.Now().Add(cfg.Duration).UTC(),
-		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:     keyUsage(key, x509.KeyUsageDigitalSignature),
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 	}
 	certDERBytes, err := x509.CreateCertificate(rand.Reader, &certTmpl, caCert, key.Public(), caKey)
@@ -97,7 +97,7 @@ func NewSignedServerCertificate(cfg ServerCertConfig, key *rsa.PrivateKey, caCer
 	return x509.ParseCertificate(certDERBytes)
 }
 
-func NewSignedClientCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*x509.Certificate, error) {
+func NewSignedClientCertificate(cfg ClientCertConfig, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
 	ips := make([]net.IP, len(cfg.IPAddresses))
 	for i, ipStr := range cfg.IPAddresses {
 		ips[i] = net.ParseIP(ipStr)
@@ -122,7 +122,7 @@ func NewSignedClientCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCer
 		SerialNumber: serial,
 		NotBefore:    caCert.NotBefore,
 		NotAfter:     time.Now().Add(cfg.Duration).UTC(),
-		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:     keyUsage(key, x509.KeyUsageDigitalSignature),
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 	}
 	certDERBytes, err := x509.CreateCertificate(rand.Reader, &certTmpl, caCert, key.Public(), caKey)
@@ -132,7 +132,7 @@ func NewSignedClientCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCer
 	return x509.ParseCertificate(certDERBytes)
 }
 
-func NewSignedKIAMCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*x509.Certificate, error) {
+func NewSignedKIAMCertificate(cfg ClientCertConfig, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
 	ips := make([]net.IP, len(cfg.IPAddresses))
 	for i, ipStr := range cfg.IPAddresses {
 		ips[i] = net.ParseIP(ipStr)
@@ -157,7 +157,7 @@ func NewSignedKIAMCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCert
 		SerialNumber: serial,
 		NotBefore:    caCert.NotBefore,
 		NotAfter:     time.Now().Add(cfg.Duration).UTC(),
-		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:     keyUsage(key, x509.KeyUsageDigitalSignature),
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 	}
 	certDERBytes, err := x509.CreateCertificate(rand.Reader, &certTmpl, caCert, key.Public(), caKey)
@@ -166,3 +166,26 @@ func NewSignedKIAMCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCert
 	}
 	return x509.ParseCertificate(certDERBytes)
 }
+
+// NewCertificateRequest creates the CSR for an external CA to issue the certificate configured by cfg.
+// The duration and the key usages of the certificate are up to the CA
+func NewCertificateRequest(cfg ClientCertConfig, key crypto.Signer) (*x509.CertificateRequest, error) {
+	ips := make([]net.IP, len(cfg.IPAddresses))
+	for i, ipStr := range cfg.IPAddresses {
+		ips[i] = net.ParseIP(ipStr)
+	}
+
+	csrTmpl := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   cfg.CommonName,
+			Organization: cfg.Organization,
+		},
+		DNSNames:    cfg.DNSNames,
+		IPAddresses: ips,
+	}
+	csrDERBytes, err := x509.CreateCertificateRequest(rand.Reader, &csrTmpl, key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificateRequest(csrDERBytes)
+}